@@ -23,8 +23,8 @@ Usage:
 {{if not .CommandUsage}}
 Arguments:
   socket	- name of unix socket to communicate with wal-g daemon
-  command	- command to send to the daemon: wal-push, wal-fetch
-  command_args	- command specific arguments
+  command	- command to send to the daemon: wal-push, wal-fetch, backup-push, backup-list, wal-show, status
+  command_args	- command specific arguments, backup-push, backup-list and wal-show pass their flags to the daemon
 {{end}}
 Flags:
 `
@@ -34,6 +34,9 @@ type commandOpts struct {
 	name    string
 	msgType daemon.SocketMessageType
 	args    []string
+	// variadic commands send all the arguments except the client flags to the daemon
+	variadic       bool
+	defaultTimeout time.Duration
 
 	options *daemon.RunOptions
 }
@@ -60,11 +63,30 @@ var (
 			msgType: daemon.WalFetchType,
 			args:    []string{"wal_name", "destination_filename"},
 		},
+		"backup-push": {
+			msgType:        daemon.BackupPushType,
+			args:           []string{"[db_directory]", "[backup-push flags]"},
+			variadic:       true,
+			defaultTimeout: 24 * time.Hour,
+		},
+		"backup-list": {
+			msgType:  daemon.BackupListType,
+			args:     []string{"[--pretty]", "[--json]", "[--detail]"},
+			variadic: true,
+		},
+		"wal-show": {
+			msgType:  daemon.WalShowType,
+			args:     []string{"[--detailed-json]", "[--without-backups]"},
+			variadic: true,
+		},
+		"status": {
+			msgType: daemon.StatusType,
+		},
 	}
 )
 
 func parseArgs(args []string) (*commandOpts, *flag.FlagSet, error) {
-	opts := &daemon.RunOptions{}
+	opts := &daemon.RunOptions{Output: os.Stdout}
 	fs := flag.NewFlagSet("", flag.ContinueOnError)
 	fs.DurationVar(&opts.DaemonOperationTimeout, "timeout", 60*time.Second, "daemon operation execution timeout")
 	fs.DurationVar(&opts.DaemonSocketConnectionTimeout, "connection-timeout", 5*time.Second, "daemon socket connection timeout")
@@ -85,12 +107,26 @@ func parseArgs(args []string) (*commandOpts, *flag.FlagSet, error) {
 		msgType: template.msgType,
 		args:    template.args,
 	}
+	opts.MessageType = cmd.msgType
+	if template.defaultTimeout > 0 {
+		opts.DaemonOperationTimeout = template.defaultTimeout
+	}
+
+	if template.variadic {
+		messageArgs, clientFlags := splitClientFlags(fs, args[2:])
+		err := fs.Parse(clientFlags)
+		if err != nil {
+			return nil, fs, err
+		}
+		opts.MessageArgs = messageArgs
+		cmd.options = opts
+		return cmd, fs, nil
+	}
 
 	if len(args) < 2+len(cmd.args) {
 		return cmd, fs, errCommandArguments
 	}
 	opts.MessageArgs = args[2 : 2+len(cmd.args)]
-	opts.MessageType = cmd.msgType
 
 	if len(args) > 2+len(cmd.args) {
 		err := fs.Parse(args[2+len(cmd.args):])
@@ -103,6 +139,23 @@ func parseArgs(args []string) (*commandOpts, *flag.FlagSet, error) {
 	return cmd, fs, nil
 }
 
+// splitClientFlags separates the flags of the client from the arguments to send to the daemon
+func splitClientFlags(fs *flag.FlagSet, args []string) (messageArgs []string, clientFlags []string) {
+	for i := 0; i < len(args); i++ {
+		name, _, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "-") || fs.Lookup(name) == nil {
+			messageArgs = append(messageArgs, args[i])
+			continue
+		}
+		clientFlags = append(clientFlags, args[i])
+		if !hasValue && i+1 < len(args) {
+			i++
+			clientFlags = append(clientFlags, args[i])
+		}
+	}
+	return messageArgs, clientFlags
+}
+
 func main() {
 	usageTemplate, err := template.New("usage").Parse(cmdUsageMessageTemplate)
 	if err != nil {
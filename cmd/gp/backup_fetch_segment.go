@@ -62,7 +62,7 @@ var segBackupFetchCmd = &cobra.Command{
 			extractProv = greenplum.ExtractProviderImpl{}
 		}
 
-		pgFetcher := postgres.GetFetcherOld(args[0], fileMask, restoreSpec, extractProv)
+		pgFetcher := postgres.GetFetcherOld(args[0], fileMask, restoreSpec, nil, extractProv)
 		internal.HandleBackupFetch(cmd.Context(), rootFolder, targetBackupSelector, pgFetcher)
 	},
 }
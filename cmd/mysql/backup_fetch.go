@@ -14,6 +14,7 @@ const (
 	targetUserDataDescription   = "Fetch storage backup which has the specified user data"
 	useXbtoolExtractDescription = "Use internal xbtool to extract data from xbstream"
 	inplaceDescription          = "(DANGEROUS) Apply diff-s inplace (reduce required disk space)"
+	tablesDescription           = "Restore only the tables matching 'db.table' patterns (e.g. 'shop.*') from logical backup"
 )
 
 var (
@@ -26,21 +27,23 @@ var (
 			internal.ConfigureLimiters()
 			storage, err := internal.ConfigureStorage(cmd.Context())
 			tracelog.ErrorLogger.FatalOnError(err)
-			restoreCmd, err := internal.GetCommandSettingContext(cmd.Context(), conf.NameStreamRestoreCmd)
-			if !useXbtoolExtract {
-				tracelog.ErrorLogger.FatalOnError(err)
-			}
+			// the restore command is checked once the backup tool is known: clone backups don't need it
+			restoreCmd, _ := internal.GetCommandSettingContext(cmd.Context(), conf.NameStreamRestoreCmd)
 			prepareCmd, _ := internal.GetCommandSettingContext(cmd.Context(), conf.MysqlBackupPrepareCmd)
 
 			targetBackupSelector, err := createTargetBackupSelector(args, fetchTargetUserData)
 			tracelog.ErrorLogger.FatalOnError(err)
+			tableFilter, err := mysql.NewLogicalTableFilter(fetchTables)
+			tracelog.ErrorLogger.FatalOnError(err)
 
-			mysql.HandleBackupFetch(cmd.Context(), storage.RootFolder(), targetBackupSelector, restoreCmd, prepareCmd, useXbtoolExtract, inplace)
+			mysql.HandleBackupFetch(cmd.Context(), storage.RootFolder(), targetBackupSelector, restoreCmd, prepareCmd,
+				useXbtoolExtract, inplace, tableFilter)
 		},
 	}
 	fetchTargetUserData string
 	useXbtoolExtract    bool
 	inplace             bool
+	fetchTables         []string
 )
 
 func createTargetBackupSelector(args []string, fetchTargetUserData string) (internal.BackupSelector, error) {
@@ -59,6 +62,7 @@ func init() {
 	backupFetchCmd.Flags().StringVar(&fetchTargetUserData, "target-user-data", "", targetUserDataDescription)
 	backupFetchCmd.Flags().BoolVar(&useXbtoolExtract, "use-xbtool-extract", false, useXbtoolExtractDescription)
 	backupFetchCmd.Flags().BoolVar(&inplace, "inplace", false, inplaceDescription)
+	backupFetchCmd.Flags().StringSliceVar(&fetchTables, "tables", nil, tablesDescription)
 	_ = backupFetchCmd.Flags().MarkHidden("use-xbtool-extract")
 	_ = backupFetchCmd.Flags().MarkHidden("inplace")
 }
@@ -16,9 +16,17 @@ const fetchUntilFlagShortDescr = "time in RFC3339 for PITR"
 const fetchUntilBinlogLastModifiedFlagShortDescr = "time in RFC3339 that is used to prevent wal-g from replaying" +
 	" binlogs that was created/modified after this time"
 
+const untilGTIDFlagShortDescr = "GTID ('uuid:number' or MariaDB 'domain-server-sequence') " +
+	"of the transaction to stop right before"
+const untilGTIDInclusiveFlagShortDescr = "apply the --until-gtid transaction as well"
+const untilPositionFlagShortDescr = "position 'binlog_name:offset' to stop at, the event starting at the offset is not applied"
+
 var fetchBackupName string
 var fetchUntilTS string
 var fetchUntilBinlogLastModifiedTS string
+var fetchUntilGTID string
+var fetchUntilGTIDInclusive bool
+var fetchUntilPosition string
 
 // binlogPushCmd represents the cron command
 var binlogFetchCmd = &cobra.Command{
@@ -28,7 +36,10 @@ var binlogFetchCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		storage, err := internal.ConfigureStorage(cmd.Context())
 		tracelog.ErrorLogger.FatalOnError(err)
-		mysql.HandleBinlogFetch(cmd.Context(), storage.RootFolder(), fetchBackupName, fetchUntilTS, fetchUntilBinlogLastModifiedTS)
+		stopTarget, err := mysql.NewBinlogStopTarget(fetchUntilGTID, fetchUntilGTIDInclusive, fetchUntilPosition)
+		tracelog.ErrorLogger.FatalOnError(err)
+		mysql.HandleBinlogFetch(cmd.Context(), storage.RootFolder(), fetchBackupName, fetchUntilTS,
+			fetchUntilBinlogLastModifiedTS, stopTarget)
 	},
 	PreRun: func(cmd *cobra.Command, args []string) {
 		conf.RequiredSettings[conf.MysqlBinlogDstSetting] = true
@@ -47,5 +58,9 @@ func init() {
 		"until-binlog-last-modified-time",
 		"",
 		fetchUntilBinlogLastModifiedFlagShortDescr)
+	binlogFetchCmd.PersistentFlags().StringVar(&fetchUntilGTID, "until-gtid", "", untilGTIDFlagShortDescr)
+	binlogFetchCmd.PersistentFlags().BoolVar(&fetchUntilGTIDInclusive, "until-gtid-inclusive", false,
+		untilGTIDInclusiveFlagShortDescr)
+	binlogFetchCmd.PersistentFlags().StringVar(&fetchUntilPosition, "until-position", "", untilPositionFlagShortDescr)
 	cmd.AddCommand(binlogFetchCmd)
 }
@@ -19,6 +19,9 @@ const replayUntilBinlogLastModifiedFlagShortDescr = "time in RFC3339 that is use
 var replayBackupName string
 var replayUntilTS string
 var replayUntilBinlogLastModifiedTS string
+var replayUntilGTID string
+var replayUntilGTIDInclusive bool
+var replayUntilPosition string
 
 var binlogReplayCmd = &cobra.Command{
 	Use:   "binlog-replay",
@@ -27,7 +30,10 @@ var binlogReplayCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		storage, err := internal.ConfigureStorage(cmd.Context())
 		tracelog.ErrorLogger.FatalOnError(err)
-		mysql.HandleBinlogReplay(cmd.Context(), storage.RootFolder(), replayBackupName, replayUntilTS, replayUntilBinlogLastModifiedTS)
+		stopTarget, err := mysql.NewBinlogStopTarget(replayUntilGTID, replayUntilGTIDInclusive, replayUntilPosition)
+		tracelog.ErrorLogger.FatalOnError(err)
+		mysql.HandleBinlogReplay(cmd.Context(), storage.RootFolder(), replayBackupName, replayUntilTS,
+			replayUntilBinlogLastModifiedTS, stopTarget)
 	},
 	PreRun: func(cmd *cobra.Command, args []string) {
 		conf.RequiredSettings[conf.MysqlBinlogReplayCmd] = true
@@ -42,5 +48,9 @@ func init() {
 		utility.TimeNowCrossPlatformUTC().Format(time.RFC3339), replayUntilFlagShortDescr)
 	binlogReplayCmd.PersistentFlags().StringVar(&replayUntilBinlogLastModifiedTS, "until-binlog-last-modified-time",
 		"", replayUntilBinlogLastModifiedFlagShortDescr)
+	binlogReplayCmd.PersistentFlags().StringVar(&replayUntilGTID, "until-gtid", "", untilGTIDFlagShortDescr)
+	binlogReplayCmd.PersistentFlags().BoolVar(&replayUntilGTIDInclusive, "until-gtid-inclusive", false,
+		untilGTIDInclusiveFlagShortDescr)
+	binlogReplayCmd.PersistentFlags().StringVar(&replayUntilPosition, "until-position", "", untilPositionFlagShortDescr)
 	cmd.AddCommand(binlogReplayCmd)
 }
@@ -17,11 +17,20 @@ const (
 	untilFlagShortDescr                   = "time in RFC3339 for PITR"
 	untilBinlogLastModifiedFlagShortDescr = "time in RFC3339 that is used to prevent wal-g from streaming" +
 		" binlogs that was created/modified after this time"
+	replicasFlagShortDescr     = "number of replicas that should catch up before the server exits (more than 1 requires semi-sync)"
+	followFlagShortDescr       = "keep serving binlogs uploaded after the start instead of exiting"
+	pollIntervalFlagShortDescr = "how often storage is checked for new binlogs with --follow"
 )
 
 var untilTS string
 var untilBinlogLastModifiedTS string
+var untilGTID string
+var untilGTIDInclusive bool
+var untilPosition string
 var BinlogBackupName string
+var binlogServerReplicas int
+var binlogServerFollow bool
+var binlogServerPollInterval time.Duration
 
 var (
 	binlogServerCmd = &cobra.Command{
@@ -39,7 +48,20 @@ var (
 			tracelog.ErrorLogger.FatalOnError(err)
 		},
 		Run: func(cmd *cobra.Command, args []string) {
-			mysql.HandleBinlogServer(cmd.Context(), BinlogBackupName, untilTS, untilBinlogLastModifiedTS)
+			if binlogServerReplicas < 1 {
+				tracelog.ErrorLogger.Fatalf("--replicas should be positive")
+			}
+			if binlogServerFollow {
+				for _, flag := range []string{"until", "until-binlog-last-modified-time", "until-gtid", "until-position"} {
+					if cmd.Flags().Changed(flag) {
+						tracelog.ErrorLogger.Fatalf("--follow can't be used with --%s", flag)
+					}
+				}
+			}
+			stopTarget, err := mysql.NewBinlogStopTarget(untilGTID, untilGTIDInclusive, untilPosition)
+			tracelog.ErrorLogger.FatalOnError(err)
+			mysql.HandleBinlogServer(cmd.Context(), BinlogBackupName, untilTS, untilBinlogLastModifiedTS, stopTarget,
+				binlogServerReplicas, binlogServerFollow, binlogServerPollInterval)
 		},
 	}
 )
@@ -52,5 +74,11 @@ func init() {
 		untilFlagShortDescr)
 	binlogServerCmd.Flags().StringVar(&untilBinlogLastModifiedTS, "until-binlog-last-modified-time",
 		"", untilBinlogLastModifiedFlagShortDescr)
+	binlogServerCmd.Flags().StringVar(&untilGTID, "until-gtid", "", untilGTIDFlagShortDescr)
+	binlogServerCmd.Flags().BoolVar(&untilGTIDInclusive, "until-gtid-inclusive", false, untilGTIDInclusiveFlagShortDescr)
+	binlogServerCmd.Flags().StringVar(&untilPosition, "until-position", "", untilPositionFlagShortDescr)
+	binlogServerCmd.Flags().IntVar(&binlogServerReplicas, "replicas", 1, replicasFlagShortDescr)
+	binlogServerCmd.Flags().BoolVar(&binlogServerFollow, "follow", false, followFlagShortDescr)
+	binlogServerCmd.Flags().DurationVar(&binlogServerPollInterval, "poll-interval", 10*time.Second, pollIntervalFlagShortDescr)
 	cmd.AddCommand(binlogServerCmd)
 }
@@ -0,0 +1,46 @@
+package mysql
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/databases/mysql"
+)
+
+const (
+	binlogStreamShortDescription = "Stream binlogs to the storage continuously acting as a replica"
+	flushIntervalFlagShortDescr  = "how often the current binlog is uploaded up to the last complete transaction"
+)
+
+var streamFlushInterval time.Duration
+
+var binlogStreamCmd = &cobra.Command{
+	Use:   "binlog-stream",
+	Short: binlogStreamShortDescription,
+	Args:  cobra.NoArgs,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		conf.RequiredSettings[conf.MysqlDatasourceNameSetting] = true
+		conf.RequiredSettings[conf.MysqlBinlogDstSetting] = true
+		conf.RequiredSettings[conf.MysqlBinlogStreamServerID] = true
+		err := internal.AssertRequiredSettingsSet()
+		tracelog.ErrorLogger.FatalOnError(err)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		serverID, err := conf.GetRequiredSetting(conf.MysqlBinlogStreamServerID)
+		tracelog.ErrorLogger.FatalOnError(err)
+		serverIDNum, err := strconv.ParseUint(serverID, 10, 32)
+		tracelog.ErrorLogger.FatalOnError(err)
+		uploader, err := internal.ConfigureUploader(cmd.Context())
+		tracelog.ErrorLogger.FatalOnError(err)
+		mysql.HandleBinlogStream(cmd.Context(), uploader, uint32(serverIDNum), streamFlushInterval)
+	},
+}
+
+func init() {
+	binlogStreamCmd.Flags().DurationVar(&streamFlushInterval, "flush-interval", 5*time.Second, flushIntervalFlagShortDescr)
+	cmd.AddCommand(binlogStreamCmd)
+}
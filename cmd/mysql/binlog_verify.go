@@ -0,0 +1,42 @@
+package mysql
+
+import (
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/mysql"
+)
+
+const (
+	binlogVerifyShortDescription = "Verify binlogs in storage: numbering, Previous_gtids continuity and backup coverage"
+	binlogVerifyLongDescription  = "Check that there are no gaps between binlogs in storage and that each backup " +
+		"can be rolled forward to the last binlog. The report with OK/WARNING/FAILURE statuses is printed in JSON."
+	maxLagDescription = "Report WARNING when the last binlog in storage is older than this duration (e.g. '1h')"
+)
+
+var (
+	binlogVerifyCmd = &cobra.Command{
+		Use:   "binlog-verify",
+		Short: binlogVerifyShortDescription,
+		Long:  binlogVerifyLongDescription,
+		Args:  cobra.NoArgs,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			err := internal.AssertRequiredSettingsSet()
+			tracelog.ErrorLogger.FatalOnError(err)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			storage, err := internal.ConfigureStorage(cmd.Context())
+			tracelog.ErrorLogger.FatalOnError(err)
+			mysql.HandleBinlogVerify(cmd.Context(), storage.RootFolder(), verifyMaxLag, os.Stdout)
+		},
+	}
+	verifyMaxLag time.Duration
+)
+
+func init() {
+	binlogVerifyCmd.Flags().DurationVar(&verifyMaxLag, "max-lag", 0, maxLagDescription)
+	cmd.AddCommand(binlogVerifyCmd)
+}
@@ -0,0 +1,80 @@
+package mysql
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/databases/mysql"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	cloneBackupPushShortDescription = "Creates new backup with the clone plugin and pushes it to storage"
+	stagingDirFlag                  = "staging-dir"
+	donorFlag                       = "donor"
+)
+
+var (
+	cloneBackupPushCmd = &cobra.Command{
+		Use:   "clone-backup-push",
+		Short: cloneBackupPushShortDescription,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			conf.RequiredSettings[conf.MysqlDatasourceNameSetting] = true
+			err := internal.AssertRequiredSettingsSet()
+			tracelog.ErrorLogger.FatalOnError(err)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			internal.ConfigureLimiters()
+
+			var donor *mysql.CloneDonor
+			if cloneDonor != "" {
+				var err error
+				donor, err = mysql.ParseCloneDonor(cloneDonor, viper.GetString(conf.MysqlCloneDonorPassword))
+				tracelog.ErrorLogger.FatalOnError(err)
+			}
+
+			uploader, err := internal.ConfigureSplitUploader(cmd.Context())
+			tracelog.ErrorLogger.FatalOnError(err)
+			folder := uploader.Folder()
+			uploader.ChangeDirectory(utility.BaseBackupPath)
+
+			if cloneUserData == "" {
+				cloneUserData = viper.GetString(conf.SentinelUserDataSetting)
+			}
+
+			mysql.HandleCloneBackupPush(
+				cmd.Context(),
+				folder,
+				uploader,
+				cloneStagingDir,
+				donor,
+				clonePermanent,
+				cloneCountJournals,
+				cloneUserData,
+			)
+		},
+	}
+	cloneStagingDir    = ""
+	cloneDonor         = ""
+	clonePermanent     = false
+	cloneCountJournals = false
+	cloneUserData      = ""
+)
+
+func init() {
+	cmd.AddCommand(cloneBackupPushCmd)
+
+	cloneBackupPushCmd.Flags().StringVar(&cloneStagingDir, stagingDirFlag,
+		"", "Directory the clone is written to by mysqld, must not exist and is removed after upload")
+	cloneBackupPushCmd.Flags().StringVar(&cloneDonor, donorFlag,
+		"", "Clone the remote donor 'user@host:port' instead of the local instance")
+	cloneBackupPushCmd.Flags().BoolVarP(&clonePermanent, permanentFlag, permanentShorthand,
+		false, "Pushes permanent backup")
+	cloneBackupPushCmd.Flags().StringVar(&cloneUserData, addUserDataFlag,
+		"", "Write the provided user data to the backup sentinel and metadata files.")
+	cloneBackupPushCmd.Flags().BoolVar(&cloneCountJournals, countJournalsFlag,
+		false, "Create 'journal_<backup>' file in the bucket and maintain the binlog sizes required to get from one backup to the next one")
+	_ = cloneBackupPushCmd.MarkFlagRequired(stagingDirFlag)
+}
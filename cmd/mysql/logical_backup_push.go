@@ -0,0 +1,68 @@
+package mysql
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/databases/mysql"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const logicalBackupPushShortDescription = "Creates new logical backup with each table in a separate object and pushes it to storage"
+
+var (
+	logicalBackupPushCmd = &cobra.Command{
+		Use:   "logical-backup-push",
+		Short: logicalBackupPushShortDescription,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			conf.RequiredSettings[conf.MysqlLogicalDumpCmd] = true
+			conf.RequiredSettings[conf.MysqlDatasourceNameSetting] = true
+			err := internal.AssertRequiredSettingsSet()
+			tracelog.ErrorLogger.FatalOnError(err)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			internal.ConfigureLimiters()
+
+			uploader, err := internal.ConfigureUploader(cmd.Context())
+			tracelog.ErrorLogger.FatalOnError(err)
+			folder := uploader.Folder()
+			uploader.ChangeDirectory(utility.BaseBackupPath)
+			dumpCmd, err := internal.GetCommandSettingContext(cmd.Context(), conf.MysqlLogicalDumpCmd)
+			tracelog.ErrorLogger.FatalOnError(err)
+
+			if logicalUserData == "" {
+				logicalUserData = viper.GetString(conf.SentinelUserDataSetting)
+			}
+
+			mysql.HandleLogicalBackupPush(
+				cmd.Context(),
+				folder,
+				uploader,
+				dumpCmd,
+				logicalMydumper,
+				logicalPermanent,
+				logicalCountJournals,
+				logicalUserData,
+			)
+		},
+	}
+	logicalPermanent     = false
+	logicalMydumper      = false
+	logicalCountJournals = false
+	logicalUserData      = ""
+)
+
+func init() {
+	cmd.AddCommand(logicalBackupPushCmd)
+
+	logicalBackupPushCmd.Flags().BoolVarP(&logicalPermanent, permanentFlag, permanentShorthand,
+		false, "Pushes permanent backup")
+	logicalBackupPushCmd.Flags().BoolVar(&logicalMydumper, "mydumper",
+		false, "Upload the files written by mydumper into $"+mysql.MydumperDirEnv+" instead of splitting the mysqldump output")
+	logicalBackupPushCmd.Flags().StringVar(&logicalUserData, addUserDataFlag,
+		"", "Write the provided user data to the backup sentinel and metadata files.")
+	logicalBackupPushCmd.Flags().BoolVar(&logicalCountJournals, countJournalsFlag,
+		false, "Create 'journal_<backup>' file in the bucket and maintain the binlog sizes required to get from one backup to the next one")
+}
@@ -0,0 +1,61 @@
+package mysql
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/databases/mysql"
+)
+
+const (
+	tableRestoreShortDescription = "Restores single InnoDB table from xtrabackup or mariabackup backup"
+	targetDirFlag                = "target-dir"
+	importFlag                   = "import"
+)
+
+var (
+	tableRestoreCmd = &cobra.Command{
+		Use:   "table-restore db.table [backup-name]",
+		Short: tableRestoreShortDescription,
+		Args:  cobra.RangeArgs(1, 2),
+		PreRun: func(cmd *cobra.Command, args []string) {
+			conf.RequiredSettings[conf.MysqlBackupPrepareCmd] = true
+			if tableRestoreImport {
+				conf.RequiredSettings[conf.MysqlDatasourceNameSetting] = true
+			}
+			err := internal.AssertRequiredSettingsSet()
+			tracelog.ErrorLogger.FatalOnError(err)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			internal.ConfigureLimiters()
+
+			table, err := mysql.ParseRestoreTable(args[0])
+			tracelog.ErrorLogger.FatalOnError(err)
+
+			storage, err := internal.ConfigureStorage(cmd.Context())
+			tracelog.ErrorLogger.FatalOnError(err)
+			prepareCmd, err := internal.GetCommandSettingContext(cmd.Context(), conf.MysqlBackupPrepareCmd)
+			tracelog.ErrorLogger.FatalOnError(err)
+
+			targetBackupSelector, err := createTargetBackupSelector(args[1:], tableRestoreTargetUserData)
+			tracelog.ErrorLogger.FatalOnError(err)
+
+			mysql.HandleTableRestore(cmd.Context(), storage.RootFolder(), targetBackupSelector, table,
+				tableRestoreTargetDir, prepareCmd, tableRestoreImport)
+		},
+	}
+	tableRestoreTargetDir      string
+	tableRestoreImport         bool
+	tableRestoreTargetUserData string
+)
+
+func init() {
+	cmd.AddCommand(tableRestoreCmd)
+	tableRestoreCmd.Flags().StringVar(&tableRestoreTargetDir, targetDirFlag, "",
+		"Directory to extract and prepare the table files in, must not exist")
+	tableRestoreCmd.Flags().BoolVar(&tableRestoreImport, importFlag, false,
+		"Import the table into the existing table of the local server instead of printing the import steps")
+	tableRestoreCmd.Flags().StringVar(&tableRestoreTargetUserData, "target-user-data", "", targetUserDataDescription)
+	_ = tableRestoreCmd.MarkFlagRequired(targetDirFlag)
+}
@@ -16,6 +16,7 @@ const (
 	fullBackupFlag        = "full"
 	deltaFromUserDataFlag = "delta-from-user-data"
 	deltaFromNameFlag     = "delta-from-name"
+	autoBackupFlag        = "auto"
 
 	fullBackupShorthand = "f"
 )
@@ -57,6 +58,19 @@ var (
 				userData = viper.GetString(conf.SentinelUserDataSetting)
 			}
 
+			var deltaBackupConfigurator mysql.DeltaBackupConfigurator = mysql.NewRegularDeltaBackupConfigurator(folder, deltaBaseSelector)
+			if autoBackup {
+				if cmd.Flags().Changed(fullBackupFlag) {
+					tracelog.ErrorLogger.Fatalf("--%s can't be used with --%s", autoBackupFlag, fullBackupFlag)
+				}
+				fullBackup = false
+				maxRedoRatio, _, err := conf.GetFloatSetting(conf.MysqlAutoDeltaMaxRedoRatio)
+				tracelog.ErrorLogger.FatalOnError(err)
+				maxBinlogRatio, _, err := conf.GetFloatSetting(conf.MysqlAutoDeltaMaxBinlogRatio)
+				tracelog.ErrorLogger.FatalOnError(err)
+				deltaBackupConfigurator = mysql.NewAutoDeltaBackupConfigurator(folder, deltaBaseSelector, maxRedoRatio, maxBinlogRatio)
+			}
+
 			mysql.HandleBackupPush(
 				cmd.Context(),
 				folder,
@@ -66,13 +80,14 @@ var (
 				countJournals,
 				fullBackup,
 				userData,
-				mysql.NewRegularDeltaBackupConfigurator(folder, deltaBaseSelector),
+				deltaBackupConfigurator,
 			)
 		},
 	}
 	fullBackup        = true
 	deltaFromName     = ""
 	deltaFromUserData = ""
+	autoBackup        = false
 )
 
 func init() {
@@ -84,6 +99,8 @@ func init() {
 		false, "Pushes permanent backup")
 	xtrabackupPushCmd.Flags().BoolVarP(&fullBackup, fullBackupFlag, fullBackupShorthand,
 		true, "Make full backup-push")
+	xtrabackupPushCmd.Flags().BoolVar(&autoBackup, autoBackupFlag,
+		false, "Make incremental or full backup depending on the amount of changes since the last backup")
 	xtrabackupPushCmd.Flags().StringVar(&deltaFromName, deltaFromNameFlag,
 		"", "Select the backup specified by name as the target for the delta backup")
 	xtrabackupPushCmd.Flags().StringVar(&deltaFromUserData, deltaFromUserDataFlag,
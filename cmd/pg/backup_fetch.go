@@ -25,6 +25,11 @@ For information about pattern syntax view: https://golang.org/pkg/path/filepath/
 	restoreOnlyDescription        = `[Experimental] Downloads only databases or tables specified by passed names.
 Separate parameters with comma. Use 'database' or 'database/namespace.table' as a parameter ('public' namespace can be omitted).  
 Sets reverse delta unpack & skip redundant tars options automatically. Always downloads system databases and tables.`
+	tablespaceMapDescription = `Restores the tablespace to the new location, OLD is the original location or the OID of the tablespace.
+Can be repeated. Generates the restore specification from the backup, can't be used with --restore-spec`
+	pgDataDescription = `Rewrites the original data directory path to NEW in the tablespace locations inside it
+and in postgresql.conf and postgresql.auto.conf, OLD defaults to the one stored in the backup`
+	skipSpaceCheckDescription = "Do not check the free space of the relocated data directory and tablespaces"
 )
 
 var fileMask string
@@ -33,6 +38,9 @@ var reverseDeltaUnpack bool
 var skipRedundantTars bool
 var fetchTargetUserData string
 var partialRestoreArgs []string
+var tablespaceMap []string
+var pgDataMap string
+var skipSpaceCheck bool
 
 var backupFetchCmd = &cobra.Command{
 	Use:   "backup-fetch destination_directory [backup_name | --target-user-data <data>]",
@@ -66,6 +74,12 @@ var backupFetchCmd = &cobra.Command{
 		reverseDeltaUnpack = reverseDeltaUnpack || viper.GetBool(conf.UseReverseUnpackSetting)
 		skipRedundantTars = skipRedundantTars || viper.GetBool(conf.SkipRedundantTarsSetting)
 
+		relocation, err := postgres.NewRestoreRelocation(tablespaceMap, pgDataMap, skipSpaceCheck)
+		tracelog.ErrorLogger.FatalOnError(err)
+		if restoreSpec != "" && !relocation.Empty() {
+			tracelog.ErrorLogger.Fatal("--restore-spec can't be used with --tablespace-map or --pgdata")
+		}
+
 		var extractProv postgres.ExtractProvider
 
 		if partialRestoreArgs != nil {
@@ -76,9 +90,9 @@ var backupFetchCmd = &cobra.Command{
 
 		var pgFetcher internal.Fetcher
 		if reverseDeltaUnpack {
-			pgFetcher = postgres.GetFetcherNew(args[0], fileMask, restoreSpec, skipRedundantTars, extractProv)
+			pgFetcher = postgres.GetFetcherNew(args[0], fileMask, restoreSpec, relocation, skipRedundantTars, extractProv)
 		} else {
-			pgFetcher = postgres.GetFetcherOld(args[0], fileMask, restoreSpec, extractProv)
+			pgFetcher = postgres.GetFetcherOld(args[0], fileMask, restoreSpec, relocation, extractProv)
 		}
 
 		internal.HandleBackupFetch(cmd.Context(), rootFolder, targetBackupSelector, pgFetcher)
@@ -114,6 +128,12 @@ func init() {
 		nil, restoreOnlyDescription)
 	backupFetchCmd.Flags().StringVar(&targetStorage, "target-storage",
 		"", targetStorageDescription)
+	backupFetchCmd.Flags().StringArrayVar(&tablespaceMap, "tablespace-map",
+		nil, tablespaceMapDescription)
+	backupFetchCmd.Flags().StringVar(&pgDataMap, "pgdata",
+		"", pgDataDescription)
+	backupFetchCmd.Flags().BoolVar(&skipSpaceCheck, "skip-space-check",
+		false, skipSpaceCheckDescription)
 
 	Cmd.AddCommand(backupFetchCmd)
 }
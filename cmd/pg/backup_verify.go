@@ -0,0 +1,45 @@
+package pg
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const (
+	backupVerifyShortDescription = "Verifies a backup against its backup manifest"
+	backupVerifyLongDescription  = `Checks sizes and checksums of the files against the backup_manifest stored with the backup.
+Without --pgdata the stored backup tars are checked, otherwise the data directory restored by backup-fetch.`
+	backupVerifyPgDataDescription       = "Data directory restored from the backup"
+	backupVerifySaveManifestDescription = "Save backup_manifest into the data directory after successful verification, " +
+		"so pg_verifybackup can be used on it"
+)
+
+var (
+	backupVerifyPgData       string
+	backupVerifySaveManifest bool
+
+	backupVerifyCmd = &cobra.Command{
+		Use:   "backup-verify backup_name [--pgdata <directory>]",
+		Short: backupVerifyShortDescription,
+		Long:  backupVerifyLongDescription,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			storage, err := internal.ConfigureStorage(cmd.Context())
+			tracelog.ErrorLogger.FatalOnError(err)
+
+			backupSelector, err := internal.NewTargetBackupSelector("", args[0], postgres.NewGenericMetaFetcher())
+			tracelog.ErrorLogger.FatalOnError(err)
+
+			postgres.HandleBackupVerify(cmd.Context(), storage.RootFolder(), backupSelector,
+				backupVerifyPgData, backupVerifySaveManifest)
+		},
+	}
+)
+
+func init() {
+	backupVerifyCmd.Flags().StringVar(&backupVerifyPgData, "pgdata", "", backupVerifyPgDataDescription)
+	backupVerifyCmd.Flags().BoolVar(&backupVerifySaveManifest, "save-manifest", false, backupVerifySaveManifestDescription)
+	Cmd.AddCommand(backupVerifyCmd)
+}
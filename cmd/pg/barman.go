@@ -0,0 +1,29 @@
+package pg
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+const barmanCommandDescription = "Interact with barman backups (beta)"
+
+var barmanCmd = &cobra.Command{
+	Use:   "barman",
+	Short: barmanCommandDescription,
+}
+
+func init() {
+	Cmd.AddCommand(barmanCmd)
+}
+
+func configureBarmanSettings(ctx context.Context) (folder storage.Folder, server string) {
+	st, err := internal.ConfigureStorage(ctx)
+	tracelog.ErrorLogger.FatalOnError(err)
+	server, _ = conf.GetSetting(conf.BarmanServer)
+	return st.RootFolder(), server
+}
@@ -0,0 +1,28 @@
+package pg
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres/barman"
+)
+
+var barmanBackupFetchCmd = &cobra.Command{
+	Use:   "backup-fetch destination-directory backup-name",
+	Short: backupFetchShortDescription,
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		internal.ConfigureLimiters()
+
+		destinationDirectory := args[0]
+		backupName := args[1]
+		folder, server := configureBarmanSettings(cmd.Context())
+		backupSelector := barman.NewBackupSelector(backupName, server)
+		err := barman.HandleBackupFetch(cmd.Context(), folder, server, destinationDirectory, backupSelector)
+		tracelog.ErrorLogger.FatalOnError(err)
+	},
+}
+
+func init() {
+	barmanCmd.AddCommand(barmanBackupFetchCmd)
+}
@@ -0,0 +1,26 @@
+package pg
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/databases/postgres/barman"
+)
+
+var barmanBackupListCmd = &cobra.Command{
+	Use:   "backup-list",
+	Short: backupListShortDescription,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		folder, server := configureBarmanSettings(cmd.Context())
+		err := barman.HandleBackupList(cmd.Context(), folder, server, detail, pretty, json)
+		tracelog.ErrorLogger.FatalOnError(err)
+	},
+}
+
+func init() {
+	barmanCmd.AddCommand(barmanBackupListCmd)
+
+	barmanBackupListCmd.Flags().BoolVar(&pretty, PrettyFlag, false, "Prints more readable output")
+	barmanBackupListCmd.Flags().BoolVar(&json, JSONFlag, false, "Prints output in json format")
+	barmanBackupListCmd.Flags().BoolVar(&detail, DetailFlag, false, "Prints extra backup details")
+}
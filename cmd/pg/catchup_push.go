@@ -13,20 +13,27 @@ const (
 var (
 	// catchupPushCmd represents the catchup-push command
 	catchupPushCmd = &cobra.Command{
-		Use:   "catchup-push PGDATA --from-lsn LSN",
+		Use:   "catchup-push PGDATA --from-lsn LSN [--from-chkp-num CHKP_NUM]",
 		Short: catchupPushShortDescription,
 		Args:  cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			internal.ConfigureLimiters()
 
-			postgres.HandleCatchupPush(cmd.Context(), args[0], postgres.LSN(fromLSN))
+			var chkpNum *uint32
+			if cmd.Flags().Changed("from-chkp-num") {
+				chkpNum = &fromChkpNum
+			}
+			postgres.HandleCatchupPush(cmd.Context(), args[0], postgres.LSN(fromLSN), chkpNum)
 		},
 	}
-	fromLSN uint64
+	fromLSN     uint64
+	fromChkpNum uint32
 )
 
 func init() {
 	Cmd.AddCommand(catchupPushCmd)
 
 	catchupPushCmd.Flags().Uint64Var(&fromLSN, "from-lsn", 0, "LSN to start incremental backup")
+	catchupPushCmd.Flags().Uint32Var(&fromChkpNum, "from-chkp-num", 0,
+		"OrioleDB checkpoint number to start incremental backup of orioledb data files")
 }
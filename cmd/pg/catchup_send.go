@@ -2,6 +2,7 @@ package pg
 
 import (
 	"github.com/spf13/cobra"
+	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/databases/postgres"
 )
 
@@ -15,6 +16,7 @@ var (
 		Short: catchupSendShortDescription,
 		Args:  cobra.ExactArgs(2),
 		Run: func(cmd *cobra.Command, args []string) {
+			internal.ConfigureLimiters()
 			postgres.HandleCatchupSend(cmd.Context(), args[0], args[1])
 		},
 		Annotations: map[string]string{"NoStorage": ""},
@@ -2,6 +2,8 @@ package pg
 
 import (
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	conf "github.com/wal-g/wal-g/internal/config"
 	"github.com/wal-g/wal-g/internal/databases/postgres"
 )
 
@@ -14,7 +16,9 @@ var daemonCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		daemonOpts := postgres.DaemonOptions{
-			SocketPath: args[0],
+			SocketPath:  args[0],
+			PeerAddress: viper.GetString(conf.PgDaemonPeerAddress),
+			Version:     cmd.Root().Version,
 		}
 		postgres.HandleDaemon(cmd.Context(), daemonOpts)
 	},
@@ -16,12 +16,15 @@ const UseSentinelTimeFlag = "use-sentinel-time"
 const UseSentinelTimeDescription = "Use backup creation time from sentinel for backups ordering."
 const DeleteGarbageExamples = `  garbage           Deletes outdated WAL archives and leftover backups files from storage
   garbage ARCHIVES  Deletes only outdated WAL archives from storage
-  garbage BACKUPS   Deletes only leftover backups files from storage`
+  garbage BACKUPS   Deletes only leftover backups files from storage
+  garbage --timelines  Deletes only WAL and .history files of the timelines no backup can be recovered to`
 const DeleteGarbageUse = "garbage [ARCHIVES|BACKUPS]"
 const afterFlag = "after"
+const timelinesFlag = "timelines"
 
 var confirmed = false
 var deleteWithoutBackups = false
+var deleteTimelines = false
 var useSentinelTime = false
 var deleteTargetUserData = ""
 
@@ -141,7 +144,11 @@ func runDeleteGarbage(cmd *cobra.Command, args []string) {
 	deleteHandler, err := postgres.NewDeleteHandler(cmd.Context(), folder, permanentBackups, permanentWals, false)
 	tracelog.ErrorLogger.FatalOnError(err)
 
-	err = deleteHandler.HandleDeleteGarbage(cmd.Context(), args, confirmed, deleteWithoutBackups)
+	if deleteTimelines {
+		err = deleteHandler.HandleDeleteGarbageTimelines(cmd.Context(), confirmed, deleteWithoutBackups)
+	} else {
+		err = deleteHandler.HandleDeleteGarbage(cmd.Context(), args, confirmed, deleteWithoutBackups)
+	}
 	tracelog.ErrorLogger.FatalOnError(err)
 }
 
@@ -156,6 +163,9 @@ func configureFolder(ctx context.Context) storage.Folder {
 }
 
 func DeleteGarbageArgsValidator(cmd *cobra.Command, args []string) error {
+	if deleteTimelines {
+		return cobra.NoArgs(cmd, args)
+	}
 	modifiers := []string{postgres.DeleteGarbageArchivesModifier, postgres.DeleteGarbageBackupsModifier}
 	return internal.DeleteArgsValidator(args, modifiers, 0, 1)
 }
@@ -168,6 +178,8 @@ func init() {
 	deleteRetainCmd.Flags().StringP(afterFlag, "a", "", "Set the time after which retain backups")
 
 	deleteGarbageCmd.Flags().BoolVar(&deleteWithoutBackups, "without-backup-check", false, "skip check for existing non-permanent backups")
+	deleteGarbageCmd.Flags().BoolVar(&deleteTimelines, timelinesFlag, false,
+		"delete only WAL and .history files of the dead timelines which no backup can be recovered to")
 
 	deleteCmd.AddCommand(deleteRetainCmd, deleteBeforeCmd, deleteEverythingCmd, deleteTargetCmd, deleteGarbageCmd)
 	deleteCmd.PersistentFlags().BoolVar(&confirmed, internal.ConfirmFlag, false, "Confirms backup deletion")
@@ -0,0 +1,58 @@
+package pg
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres/pgbackrest"
+)
+
+const (
+	pgbackrestImportShortDescription = "Import pgbackrest backups and WAL into wal-g storage"
+	pgbackrestImportLongDescription  = "Transcode backups of the pgbackrest stanza into wal-g full and delta backups " +
+		"and copy the WAL archive. Backups and WAL files already present in wal-g storage are skipped, " +
+		"so an interrupted import can be restarted."
+
+	pgbackrestImportFromDescription       = "Storage config of the pgbackrest repository, the wal-g storage is used if not set"
+	pgbackrestImportBackupNameDescription = "Import the backup with this label and the backups it depends on only"
+	withoutWalFlag                        = "without-wal"
+	withoutWalDescription                 = "Do not import the WAL archive"
+	pgbackrestImportVerifyFlag            = "verify"
+	pgbackrestImportVerifyDescription     = "Read the imported backups and WAL back and compare them with the pgbackrest checksums"
+)
+
+var (
+	pgbackrestImportFromConfigFile string
+	pgbackrestImportOptions        pgbackrest.ImportOptions
+
+	pgbackrestImportCmd = &cobra.Command{
+		Use:   "import",
+		Short: pgbackrestImportShortDescription,
+		Long:  pgbackrestImportLongDescription,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			internal.ConfigureLimiters()
+
+			target, stanza := configurePgbackrestSettings(cmd.Context())
+			source := target
+			if pgbackrestImportFromConfigFile != "" {
+				sourceStorage, err := internal.StorageFromConfig(cmd.Context(), pgbackrestImportFromConfigFile)
+				tracelog.ErrorLogger.FatalOnError(err)
+				source = sourceStorage.RootFolder()
+			}
+			err := pgbackrest.HandleImport(cmd.Context(), source, target, stanza, pgbackrestImportOptions)
+			tracelog.ErrorLogger.FatalOnError(err)
+		},
+	}
+)
+
+func init() {
+	pgbackrestCmd.AddCommand(pgbackrestImportCmd)
+
+	pgbackrestImportCmd.Flags().StringVar(&pgbackrestImportFromConfigFile, fromFlag, "", pgbackrestImportFromDescription)
+	pgbackrestImportCmd.Flags().StringVar(&pgbackrestImportOptions.BackupName, backupNameFlag, "",
+		pgbackrestImportBackupNameDescription)
+	pgbackrestImportCmd.Flags().BoolVar(&pgbackrestImportOptions.WithoutWal, withoutWalFlag, false, withoutWalDescription)
+	pgbackrestImportCmd.Flags().BoolVar(&pgbackrestImportOptions.Verify, pgbackrestImportVerifyFlag, false,
+		pgbackrestImportVerifyDescription)
+}
@@ -0,0 +1,29 @@
+package pg
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+const pgprobackupCommandDescription = "Interact with pg_probackup backups (beta)"
+
+var pgprobackupCmd = &cobra.Command{
+	Use:   "pgprobackup",
+	Short: pgprobackupCommandDescription,
+}
+
+func init() {
+	Cmd.AddCommand(pgprobackupCmd)
+}
+
+func configurePgprobackupSettings(ctx context.Context) (folder storage.Folder, instance string) {
+	st, err := internal.ConfigureStorage(ctx)
+	tracelog.ErrorLogger.FatalOnError(err)
+	instance, _ = conf.GetSetting(conf.PgProbackupInstance)
+	return st.RootFolder(), instance
+}
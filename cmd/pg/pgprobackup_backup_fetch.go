@@ -0,0 +1,28 @@
+package pg
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres/pgprobackup"
+)
+
+var pgprobackupBackupFetchCmd = &cobra.Command{
+	Use:   "backup-fetch destination-directory backup-name",
+	Short: backupFetchShortDescription,
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		internal.ConfigureLimiters()
+
+		destinationDirectory := args[0]
+		backupName := args[1]
+		folder, instance := configurePgprobackupSettings(cmd.Context())
+		backupSelector := pgprobackup.NewBackupSelector(backupName, instance)
+		err := pgprobackup.HandleBackupFetch(cmd.Context(), folder, instance, destinationDirectory, backupSelector)
+		tracelog.ErrorLogger.FatalOnError(err)
+	},
+}
+
+func init() {
+	pgprobackupCmd.AddCommand(pgprobackupBackupFetchCmd)
+}
@@ -0,0 +1,26 @@
+package pg
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/databases/postgres/pgprobackup"
+)
+
+var pgprobackupBackupListCmd = &cobra.Command{
+	Use:   "backup-list",
+	Short: backupListShortDescription,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		folder, instance := configurePgprobackupSettings(cmd.Context())
+		err := pgprobackup.HandleBackupList(cmd.Context(), folder, instance, detail, pretty, json)
+		tracelog.ErrorLogger.FatalOnError(err)
+	},
+}
+
+func init() {
+	pgprobackupCmd.AddCommand(pgprobackupBackupListCmd)
+
+	pgprobackupBackupListCmd.Flags().BoolVar(&pretty, PrettyFlag, false, "Prints more readable output")
+	pgprobackupBackupListCmd.Flags().BoolVar(&json, JSONFlag, false, "Prints output in json format")
+	pgprobackupBackupListCmd.Flags().BoolVar(&detail, DetailFlag, false, "Prints extra backup details")
+}
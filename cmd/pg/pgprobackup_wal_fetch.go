@@ -0,0 +1,22 @@
+package pg
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/databases/postgres/pgprobackup"
+)
+
+var pgprobackupWalFetchCmd = &cobra.Command{
+	Use:   "wal-fetch wal_name destination_filename",
+	Short: WalFetchShortDescription,
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		folder, instance := configurePgprobackupSettings(cmd.Context())
+		err := pgprobackup.HandleWalFetch(cmd.Context(), folder, instance, args[0], args[1])
+		tracelog.ErrorLogger.FatalOnError(err)
+	},
+}
+
+func init() {
+	pgprobackupCmd.AddCommand(pgprobackupWalFetchCmd)
+}
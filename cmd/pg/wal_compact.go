@@ -0,0 +1,45 @@
+package pg
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const (
+	walCompactShortDescription = "Packs archived WAL segments older than the latest backup into bundles"
+	walCompactLongDescription  = `Packs ranges of WAL segments preceding the start of the latest backup into bundle objects with an index.
+Bundled segments are still available to wal-fetch, wal-show, wal-verify and delete.
+Without --confirm only the bundles which would be made are printed.`
+	walCompactMaxBundleSizeFlag        = "max-bundle-size"
+	walCompactMaxBundleSizeDescription = "Maximum size of a bundle in bytes, 0 means unlimited"
+
+	defaultWalBundleSize = 1 << 30
+)
+
+var (
+	walCompactConfirmed     bool
+	walCompactMaxBundleSize int64
+
+	walCompactCmd = &cobra.Command{
+		Use:   "wal-compact [--confirm]",
+		Short: walCompactShortDescription,
+		Long:  walCompactLongDescription,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			storage, err := internal.ConfigureStorage(cmd.Context())
+			tracelog.ErrorLogger.FatalOnError(err)
+
+			err = postgres.HandleWalCompact(cmd.Context(), storage.RootFolder(), walCompactMaxBundleSize, walCompactConfirmed)
+			tracelog.ErrorLogger.FatalOnError(err)
+		},
+	}
+)
+
+func init() {
+	walCompactCmd.Flags().BoolVar(&walCompactConfirmed, internal.ConfirmFlag, false, "Confirms WAL compaction")
+	walCompactCmd.Flags().Int64Var(&walCompactMaxBundleSize, walCompactMaxBundleSizeFlag, defaultWalBundleSize,
+		walCompactMaxBundleSizeDescription)
+	Cmd.AddCommand(walCompactCmd)
+}
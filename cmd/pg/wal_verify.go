@@ -14,7 +14,7 @@ import (
 
 const (
 	WalVerifyUsage            = "wal-verify"
-	WalVerifyShortDescription = "Verify WAL storage folder. Available checks: integrity, timeline."
+	WalVerifyShortDescription = "Verify WAL storage folder. Available checks: integrity, timeline, records, backup-coverage."
 	WalVerifyLongDescription  = "Run a set of specified checks to ensure WAL storage health."
 
 	useJSONOutputFlag        = "json"
@@ -29,14 +29,18 @@ const (
 	useSpecifiedBackupFlag        = "backup-name"
 	useSpecifiedBackupDescription = "Verify WAL starting from the specified backup."
 
-	checkIntegrityArg = "integrity"
-	checkTimelineArg  = "timeline"
+	checkIntegrityArg      = "integrity"
+	checkTimelineArg       = "timeline"
+	checkRecordsArg        = "records"
+	checkBackupCoverageArg = "backup-coverage"
 )
 
 var (
 	availableChecks = map[string]postgres.WalVerifyCheckType{
-		checkIntegrityArg: postgres.WalVerifyIntegrityCheck,
-		checkTimelineArg:  postgres.WalVerifyTimelineCheck,
+		checkIntegrityArg:      postgres.WalVerifyIntegrityCheck,
+		checkTimelineArg:       postgres.WalVerifyTimelineCheck,
+		checkRecordsArg:        postgres.WalVerifyRecordsCheck,
+		checkBackupCoverageArg: postgres.WalVerifyBackupCoverageCheck,
 	}
 	// walVerifyCmd represents the walVerify command
 	walVerifyCmd = &cobra.Command{
@@ -2,6 +2,8 @@ package internal
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"os"
 
 	"github.com/wal-g/tracelog"
@@ -10,9 +12,17 @@ import (
 )
 
 func HandleDefaultBackupList(ctx context.Context, folder storage.Folder, pretty, json bool) {
+	err := WriteDefaultBackupList(ctx, folder, os.Stdout, pretty, json)
+	tracelog.ErrorLogger.FatalOnError(err)
+}
+
+// WriteDefaultBackupList writes the list of backups to the output
+func WriteDefaultBackupList(ctx context.Context, folder storage.Folder, output io.Writer, pretty, json bool) error {
 	backupTimes, err := GetBackups(ctx, folder)
 	err = FilterOutNoBackupFoundError(err, json)
-	tracelog.ErrorLogger.FatalfOnError("Get backups from folder: %v", err)
+	if err != nil {
+		return fmt.Errorf("get backups from folder: %w", err)
+	}
 
 	SortBackupTimeSlices(backupTimes)
 
@@ -20,6 +30,9 @@ func HandleDefaultBackupList(ctx context.Context, folder storage.Folder, pretty,
 	for i := range backupTimes {
 		printableEntities[i] = backupTimes[i]
 	}
-	err = printlist.List(printableEntities, os.Stdout, pretty, json)
-	tracelog.ErrorLogger.FatalfOnError("Print backups: %v", err)
+	err = printlist.List(printableEntities, output, pretty, json)
+	if err != nil {
+		return fmt.Errorf("print backups: %w", err)
+	}
+	return nil
 }
@@ -26,15 +26,24 @@ func NewBackupMarkHandler(metaInteractor GenericMetaInteractor, storageRootFolde
 
 // MarkBackup marks a backup as permanent or impermanent
 func (h *BackupMarkHandler) MarkBackup(ctx context.Context, backupName string, toPermanent bool) {
+	tracelog.ErrorLogger.FatalOnError(h.Mark(ctx, backupName, toPermanent))
+}
+
+// Mark marks a backup as permanent or impermanent and returns the error instead of terminating the process
+func (h *BackupMarkHandler) Mark(ctx context.Context, backupName string, toPermanent bool) error {
 	tracelog.InfoLogger.Printf("Retrieving previous related backups to be marked: toPermanent=%t", toPermanent)
 	backupsToMark, err := h.GetBackupsToMark(ctx, backupName, toPermanent)
-
-	tracelog.ErrorLogger.FatalfOnError("Failed to get previous backups: %v", err)
+	if err != nil {
+		return errors.Wrap(err, "failed to get previous backups")
+	}
 	tracelog.InfoLogger.Printf("Retrieved backups to be marked, marking: %v", backupsToMark)
 	for _, backupName := range backupsToMark {
 		err = h.metaInteractor.SetIsPermanent(ctx, backupName, h.baseBackupFolder, toPermanent)
-		tracelog.ErrorLogger.FatalfOnError("Failed to mark backups: %v", err)
+		if err != nil {
+			return errors.Wrap(err, "failed to mark backups")
+		}
 	}
+	return nil
 }
 
 // GetBackupsToMark retrieves all previous permanent or
@@ -40,7 +40,8 @@ func CompressAndEncrypt(source io.Reader, compressor compression.Compressor, cry
 		writeCloser, err = crypter.Encrypt(dstWriter)
 
 		if err != nil {
-			panic(err)
+			_ = dstWriter.CloseWithError(errors.Wrap(err, "CompressAndEncrypt: encryption failed"))
+			return compressedReader
 		}
 	}
 
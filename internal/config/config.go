@@ -97,11 +97,19 @@ const (
 	PgWalSize               = "WALG_PG_WAL_SIZE"
 	PgWalPageSize           = "WALG_PG_WAL_PAGE_SIZE"
 	PgBlockSize             = "WALG_PG_BLOCK_SIZE"
+	PgDeletePITRWindow      = "WALG_DELETE_PITR_WINDOW"
+	PgCatchupTLSCertFile    = "WALG_CATCHUP_TLS_CERT_FILE"
+	PgCatchupTLSKeyFile     = "WALG_CATCHUP_TLS_KEY_FILE"
+	PgCatchupTLSCAFile      = "WALG_CATCHUP_TLS_CA_FILE"
+	PgCatchupPSK            = "WALG_CATCHUP_PSK"
+	PgCatchupProgressPeriod = "WALG_CATCHUP_PROGRESS_INTERVAL"
 	TotalBgUploadedLimit    = "TOTAL_BG_UPLOADED_LIMIT"
 	NameStreamCreateCmd     = "WALG_STREAM_CREATE_COMMAND"
 	NameStreamRestoreCmd    = "WALG_STREAM_RESTORE_COMMAND"
 	MaxDelayedSegmentsCount = "WALG_INTEGRITY_MAX_DELAYED_WALS"
 	PrefetchDir             = "WALG_PREFETCH_DIR"
+	PrefetchMaxLookahead    = "WALG_PREFETCH_MAX_LOOKAHEAD"
+	PrefetchMaxDiskUsage    = "WALG_PREFETCH_MAX_DISK_USAGE"
 	PgReadyRename           = "PG_READY_RENAME"
 	// Deprecated: streaming JSON is the only mode; setting is retained to suppress unknown-setting warnings
 	SerializerTypeSetting                = "WALG_SERIALIZER_TYPE"
@@ -126,6 +134,16 @@ const (
 	PgDaemonWALUploadTimeout             = "WALG_DAEMON_WAL_UPLOAD_TIMEOUT"
 	PgTargetStorage                      = "WALG_TARGET_STORAGE"
 	DisablePartialRestore                = "WALG_DISABLE_PARTIAL_RESTORE"
+	StandbyMaxReplayLag                  = "WALG_STANDBY_MAX_REPLAY_LAG"
+	StandbyPrimaryCheckpoint             = "WALG_STANDBY_PRIMARY_CHECKPOINT"
+	PgDaemonPeerAddress                  = "WALG_DAEMON_PEER_ADDRESS"
+	WalFetchPeers                        = "WALG_WAL_FETCH_PEERS"
+	WalFetchPeerConnInfo                 = "WALG_WAL_FETCH_PEER_CONNINFO"
+	WalFetchPeerTimeout                  = "WALG_WAL_FETCH_PEER_TIMEOUT"
+	PgPeerTLSCertFile                    = "WALG_PEER_TLS_CERT_FILE"
+	PgPeerTLSKeyFile                     = "WALG_PEER_TLS_KEY_FILE"
+	PgPeerTLSCAFile                      = "WALG_PEER_TLS_CA_FILE"
+	PgPeerPSK                            = "WALG_PEER_PSK"
 
 	ProfileSamplingRatio = "PROFILE_SAMPLING_RATIO"
 	ProfileMode          = "PROFILE_MODE"
@@ -163,9 +181,15 @@ const (
 	MysqlBinlogServerPassword      = "WALG_MYSQL_BINLOG_SERVER_PASSWORD"
 	MysqlBinlogServerID            = "WALG_MYSQL_BINLOG_SERVER_ID"
 	MysqlBinlogServerReplicaSource = "WALG_MYSQL_BINLOG_SERVER_REPLICA_SOURCE"
+	MysqlBinlogStreamServerID      = "WALG_MYSQL_BINLOG_STREAM_SERVER_ID"
+	MysqlCloneDonorPassword        = "WALG_MYSQL_CLONE_DONOR_PASSWORD"
+	MysqlLogicalDumpCmd            = "WALG_MYSQL_LOGICAL_DUMP_COMMAND"
+	MysqlMydumperDir               = "WALG_MYSQL_MYDUMPER_DIR"
 	MysqlBackupDownloadMaxRetry    = "WALG_BACKUP_DOWNLOAD_MAX_RETRY"
 	MysqlIncrementalBackupDst      = "WALG_MYSQL_INCREMENTAL_BACKUP_DST"
 	MysqlDataDir                   = "WALG_MYSQL_DATA_DIR"
+	MysqlAutoDeltaMaxRedoRatio     = "WALG_MYSQL_AUTO_DELTA_MAX_REDO_RATIO"
+	MysqlAutoDeltaMaxBinlogRatio   = "WALG_MYSQL_AUTO_DELTA_MAX_BINLOG_RATIO"
 	// Deprecated: unused
 	MysqlTakeBinlogsFromMaster = "WALG_MYSQL_TAKE_BINLOGS_FROM_MASTER"
 
@@ -225,7 +249,9 @@ const (
 	YcKmsKeyIDSetting  = "YC_CSE_KMS_KEY_ID"
 	YcSaKeyFileSetting = "YC_SERVICE_ACCOUNT_KEY_FILE"
 
-	PgBackRestStanza = "PGBACKREST_STANZA"
+	PgBackRestStanza    = "PGBACKREST_STANZA"
+	BarmanServer        = "BARMAN_SERVER"
+	PgProbackupInstance = "PG_PROBACKUP_INSTANCE"
 
 	AzureStorageAccount   = "AZURE_STORAGE_ACCOUNT"
 	AzureStorageAccessKey = "AZURE_STORAGE_ACCESS_KEY"
@@ -315,9 +341,11 @@ var (
 	}
 
 	MysqlDefaultSettings = map[string]string{
-		StreamSplitterBlockSize:     "1048576",
-		MysqlBackupDownloadMaxRetry: "1",
-		MysqlIncrementalBackupDst:   "/tmp",
+		StreamSplitterBlockSize:      "1048576",
+		MysqlBackupDownloadMaxRetry:  "1",
+		MysqlIncrementalBackupDst:    "/tmp",
+		MysqlAutoDeltaMaxRedoRatio:   "0.5",
+		MysqlAutoDeltaMaxBinlogRatio: "0.5",
 	}
 
 	SQLServerDefaultSettings = map[string]string{
@@ -329,9 +357,13 @@ var (
 		PgWalPageSize:             "8192",
 		PgBlockSize:               "8192",
 		PgBackRestStanza:          "main",
+		BarmanServer:              "main",
+		PgProbackupInstance:       "main",
 		PgAliveCheckInterval:      "1m",
+		PrefetchMaxLookahead:      "64",
 		FailoverStoragesCheckSize: "1mb",
 		PgDaemonWALUploadTimeout:  "60s",
+		WalFetchPeerTimeout:       "10s",
 		ForceWalDetal:             "false",
 		PgAppName:                 "wal-g",
 	}
@@ -528,9 +560,19 @@ var (
 		PgWalSize:                            true,
 		PgWalPageSize:                        true,
 		PgBlockSize:                          true,
+		PgDeletePITRWindow:                   true,
+		PgCatchupTLSCertFile:                 true,
+		PgCatchupTLSKeyFile:                  true,
+		PgCatchupTLSCAFile:                   true,
+		PgCatchupPSK:                         true,
+		PgCatchupProgressPeriod:              true,
 		PrefetchDir:                          true,
+		PrefetchMaxLookahead:                 true,
+		PrefetchMaxDiskUsage:                 true,
 		PgReadyRename:                        true,
 		PgBackRestStanza:                     true,
+		BarmanServer:                         true,
+		PgProbackupInstance:                  true,
 		PgAliveCheckInterval:                 true,
 		PgStopBackupTimeout:                  true,
 		FailoverStorages:                     true,
@@ -548,6 +590,16 @@ var (
 		DisablePartialRestore:                true,
 		ForceWalDetal:                        true,
 		PgAppName:                            true,
+		StandbyMaxReplayLag:                  true,
+		StandbyPrimaryCheckpoint:             true,
+		PgDaemonPeerAddress:                  true,
+		WalFetchPeers:                        true,
+		WalFetchPeerConnInfo:                 true,
+		WalFetchPeerTimeout:                  true,
+		PgPeerTLSCertFile:                    true,
+		PgPeerTLSKeyFile:                     true,
+		PgPeerTLSCAFile:                      true,
+		PgPeerPSK:                            true,
 	}
 
 	MongoAllowedSettings = map[string]bool{
@@ -599,9 +651,15 @@ var (
 		MysqlBinlogServerPassword:      true,
 		MysqlBinlogServerID:            true,
 		MysqlBinlogServerReplicaSource: true,
+		MysqlBinlogStreamServerID:      true,
+		MysqlCloneDonorPassword:        true,
+		MysqlLogicalDumpCmd:            true,
+		MysqlMydumperDir:               true,
 		MysqlBackupDownloadMaxRetry:    true,
 		MysqlIncrementalBackupDst:      true,
 		MysqlDataDir:                   true,
+		MysqlAutoDeltaMaxRedoRatio:     true,
+		MysqlAutoDeltaMaxBinlogRatio:   true,
 	}
 
 	RedisAllowedSettings = map[string]bool{
@@ -671,6 +729,8 @@ var (
 		AlicloudSecurityToken:         true,
 		LibsodiumKeySetting:           true,
 		PgPasswordSetting:             true,
+		PgCatchupPSK:                  true,
+		PgPeerPSK:                     true,
 		PgpKeyPassphraseSetting:       true,
 		PgpKeySetting:                 true,
 		PgpEnvelopeKeySetting:         true,
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"math"
 	"net"
 	"time"
@@ -13,13 +14,19 @@ type RunOptions struct {
 	MessageType SocketMessageType
 	SocketName  string
 	MessageArgs []string
+	// Output receives the command output sent by the daemon, it is discarded if nil
+	Output io.Writer
 
 	DaemonOperationTimeout        time.Duration
 	DaemonSocketConnectionTimeout time.Duration
 }
 
 func getMessage(messageType SocketMessageType, messageArgs []string) ([]byte, error) {
-	switch len(messageArgs) {
+	argsCount := len(messageArgs)
+	if messageType.HasArgsList() {
+		argsCount = -1
+	}
+	switch argsCount {
 	case 0:
 		return binary.BigEndian.AppendUint16(messageType.ToBytes(), uint16(3)), nil
 	case 1:
@@ -64,13 +71,25 @@ func SendCommand(opts *RunOptions) (SocketMessageType, error) {
 	}
 
 	resp := make([]byte, 1)
-	n, err := socketConnection.Read(resp)
-	if err != nil {
-		return ErrorType, fmt.Errorf("unix socket read error: %w", err)
+	output := opts.Output
+	if output == nil {
+		output = io.Discard
 	}
-
-	if n < 1 {
-		return ErrorType, fmt.Errorf("daemon response error [message type: %v, args: %v]", string(opts.MessageType), opts.MessageArgs)
+	for {
+		n, err := socketConnection.Read(resp)
+		if err != nil {
+			return ErrorType, fmt.Errorf("unix socket read error: %w", err)
+		}
+		if n < 1 {
+			return ErrorType, fmt.Errorf("daemon response error [message type: %v, args: %v]", string(opts.MessageType), opts.MessageArgs)
+		}
+		if !OutputType.IsEqual(resp[0]) {
+			break
+		}
+		err = ReadOutputFrame(socketConnection, output)
+		if err != nil {
+			return ErrorType, fmt.Errorf("daemon output read error: %w", err)
+		}
 	}
 
 	if !OkType.IsEqual(resp[0]) {
@@ -79,3 +98,46 @@ func SendCommand(opts *RunOptions) (SocketMessageType, error) {
 	}
 	return OkType, nil
 }
+
+// FetchFromPeer requests the file from pg_wal of the peer daemon over the established connection and writes it to dst.
+// ArchiveNonExistenceType is returned without error if the peer has no such file.
+func FetchFromPeer(ctx context.Context, peerConnection net.Conn, fileName string, dst io.Writer) (SocketMessageType, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		err := peerConnection.SetDeadline(deadline)
+		if err != nil {
+			return ErrorType, fmt.Errorf("peer set deadline error: %w", err)
+		}
+	}
+
+	msg, err := getMessage(PeerWalFetchType, []string{fileName})
+	if err != nil {
+		return ErrorType, err
+	}
+	_, err = peerConnection.Write(msg)
+	if err != nil {
+		return ErrorType, fmt.Errorf("peer write error: %w", err)
+	}
+
+	resp := make([]byte, 1)
+	_, err = io.ReadFull(peerConnection, resp)
+	if err != nil {
+		return ErrorType, fmt.Errorf("peer read error: %w", err)
+	}
+	if ArchiveNonExistenceType.IsEqual(resp[0]) {
+		return ArchiveNonExistenceType, nil
+	}
+	if !OkType.IsEqual(resp[0]) {
+		return SocketMessageType(resp[0]), fmt.Errorf("peer fetch error [file: %v, peer response: %v]", fileName, string(resp[0]))
+	}
+
+	var size uint64
+	err = binary.Read(peerConnection, binary.BigEndian, &size)
+	if err != nil {
+		return ErrorType, fmt.Errorf("peer read file size error: %w", err)
+	}
+	_, err = io.CopyN(dst, peerConnection, int64(size))
+	if err != nil {
+		return ErrorType, fmt.Errorf("peer read file error: %w", err)
+	}
+	return OkType, nil
+}
@@ -3,6 +3,7 @@ package daemon
 import (
 	"encoding/binary"
 	"fmt"
+	"io"
 	"math"
 )
 
@@ -16,6 +17,17 @@ const (
 
 	WalPushType  SocketMessageType = 'F'
 	WalFetchType SocketMessageType = 'f'
+
+	// PeerWalFetchType requests a file from pg_wal of the daemon host, the file contents are sent back
+	PeerWalFetchType SocketMessageType = 'P'
+
+	BackupPushType SocketMessageType = 'B'
+	BackupListType SocketMessageType = 'L'
+	WalShowType    SocketMessageType = 'S'
+	StatusType     SocketMessageType = 's'
+
+	// OutputType frames carry the command output sent to the client before the final response
+	OutputType SocketMessageType = 'o'
 )
 
 var (
@@ -30,6 +42,49 @@ func (msg SocketMessageType) IsEqual(value byte) bool {
 	return byte(msg) == value
 }
 
+// HasArgsList reports whether the message body is always encoded by ArgsToBytes.
+// The older message types send a single argument as is.
+func (msg SocketMessageType) HasArgsList() bool {
+	switch msg {
+	case BackupPushType, BackupListType, WalShowType, StatusType:
+		return true
+	default:
+		return false
+	}
+}
+
+// OutputWriter sends everything written to it as OutputType frames
+type OutputWriter struct {
+	conn io.Writer
+}
+
+func NewOutputWriter(conn io.Writer) *OutputWriter {
+	return &OutputWriter{conn: conn}
+}
+
+func (w *OutputWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	frame := binary.BigEndian.AppendUint32(OutputType.ToBytes(), uint32(len(p)))
+	_, err := w.conn.Write(append(frame, p...))
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// ReadOutputFrame reads the body of an OutputType frame, the frame type must be already read
+func ReadOutputFrame(conn io.Reader, dst io.Writer) error {
+	var size uint32
+	err := binary.Read(conn, binary.BigEndian, &size)
+	if err != nil {
+		return err
+	}
+	_, err = io.CopyN(dst, conn, int64(size))
+	return err
+}
+
 func ArgsToBytes(args ...string) ([]byte, error) {
 	argsLen := len(args)
 	if argsLen > 255 {
@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"bytes"
 	"fmt"
 	"testing"
 
@@ -26,3 +27,23 @@ func TestDaemon_MessageBodyArrayConversion(t *testing.T) {
 		})
 	}
 }
+
+func TestDaemon_OutputFrames(t *testing.T) {
+	var conn bytes.Buffer
+	writer := NewOutputWriter(&conn)
+	_, err := writer.Write([]byte("first line\n"))
+	assert.NoError(t, err)
+	_, err = writer.Write(nil)
+	assert.NoError(t, err)
+	_, err = writer.Write([]byte("second line\n"))
+	assert.NoError(t, err)
+
+	var output bytes.Buffer
+	for conn.Len() > 0 {
+		frameType, err := conn.ReadByte()
+		assert.NoError(t, err)
+		assert.True(t, OutputType.IsEqual(frameType))
+		assert.NoError(t, ReadOutputFrame(&conn, &output))
+	}
+	assert.Equal(t, "first line\nsecond line\n", output.String())
+}
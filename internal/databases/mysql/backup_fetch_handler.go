@@ -6,6 +6,7 @@ import (
 
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
 	"github.com/wal-g/wal-g/pkg/storages/storage"
 )
 
@@ -15,6 +16,7 @@ func HandleBackupFetch(ctx context.Context, folder storage.Folder,
 	prepareCmd *exec.Cmd,
 	useXbtoolExtract bool,
 	inplace bool,
+	tableFilter LogicalTableFilter,
 ) {
 	backup, err := targetBackupSelector.Select(ctx, folder)
 	tracelog.ErrorLogger.FatalfOnError("Failed to get backup: %v", err)
@@ -23,10 +25,29 @@ func HandleBackupFetch(ctx context.Context, folder storage.Folder,
 	err = backup.FetchSentinel(ctx, &sentinel)
 	tracelog.ErrorLogger.FatalfOnError("Failed to fetch sentinel: %v", err)
 
+	if len(tableFilter) > 0 && sentinel.Tool != WalgLogicalTool {
+		tracelog.ErrorLogger.Fatalf("Tables can be selected only from backups created by logical-backup-push")
+	}
+
+	isXtrabackup := sentinel.Tool == WalgXtrabackupTool || sentinel.Tool == WalgMariabackupTool
+	// clone backups and the backups extracted by xbtool don't need WALG_STREAM_RESTORE_COMMAND
+	if restoreCmd == nil && sentinel.Tool != WalgCloneTool && !(isXtrabackup && useXbtoolExtract) {
+		tracelog.ErrorLogger.Fatalf("%s is not configured", conf.NameStreamRestoreCmd)
+	}
+
 	// we should ba able to read & restore any backup we ever created:
-	if sentinel.Tool == WalgXtrabackupTool {
-		internal.HandleBackupFetch(ctx, folder, targetBackupSelector, GetXtrabackupFetcher(restoreCmd, prepareCmd, useXbtoolExtract, inplace))
-	} else {
+	switch sentinel.Tool {
+	case WalgXtrabackupTool, WalgMariabackupTool:
+		fetcher := GetXtrabackupFetcher(restoreCmd, prepareCmd, useXbtoolExtract, inplace)
+		internal.HandleBackupFetch(ctx, folder, targetBackupSelector, fetcher)
+	case WalgCloneTool:
+		if prepareCmd != nil {
+			tracelog.InfoLogger.Printf("Clone backup is ready to start, %s is not used", conf.MysqlBackupPrepareCmd)
+		}
+		internal.HandleBackupFetch(ctx, folder, targetBackupSelector, GetCloneBackupFetcher())
+	case WalgLogicalTool:
+		internal.HandleBackupFetch(ctx, folder, targetBackupSelector, GetLogicalBackupFetcher(restoreCmd, tableFilter))
+	default:
 		internal.HandleBackupFetch(ctx, folder, targetBackupSelector, internal.GetBackupToCommandFetcher(restoreCmd))
 		if prepareCmd != nil {
 			err = prepareCmd.Run()
@@ -6,6 +6,7 @@ import (
 	"os/exec"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
@@ -56,9 +57,13 @@ func HandleBackupPush(
 	var prevBackupInfo PrevBackupInfo
 	var incrementCount int
 	var xtrabackupInfo XtrabackupExtInfo
+	var autoBackup *AutoBackupDecision
 	if isXtrabackup(backupCmd) {
 		prevBackupInfo, incrementCount, err = deltaBackupConfigurator.Configure(ctx, isFullBackup, hostname, serverUUID, version)
 		tracelog.ErrorLogger.FatalfOnError("failed to get previous backup for delta backup: %v", err)
+		if auto, ok := deltaBackupConfigurator.(*AutoDeltaBackupConfigurator); ok {
+			autoBackup = auto.decision
+		}
 
 		backupName, xtrabackupInfo, err = handleXtrabackupBackup(ctx, uploader, backupCmd, isFullBackup, &prevBackupInfo)
 	} else {
@@ -89,37 +94,52 @@ func HandleBackupPush(
 	}
 
 	var tool = WalgUnspecifiedStreamBackupTool
-	if isXtrabackup(backupCmd) {
+	if isMariabackup(backupCmd) {
+		tool = WalgMariabackupTool
+	} else if isXtrabackup(backupCmd) {
 		tool = WalgXtrabackupTool
 	}
+	// the backup tool reports the exact binlog position & GTIDs of the consistency point
+	if xtrabackupInfo.BinlogFile != "" {
+		binlogStart = xtrabackupInfo.BinlogFile
+	}
 
 	sentinel := StreamSentinelDto{
-		Tool:              tool,
-		BinLogStart:       binlogStart,
-		BinLogEnd:         binlogEnd,
-		StartLocalTime:    timeStart,
-		StopLocalTime:     timeStop,
-		CompressedSize:    uploadedSize,
-		UncompressedSize:  rawSize,
-		Hostname:          hostname,
-		ServerUUID:        serverUUID,
-		ServerVersion:     version,
-		ServerArch:        xtrabackupInfo.ServerArch,
-		ServerOS:          xtrabackupInfo.ServerOS,
-		IsPermanent:       isPermanent,
-		IsIncremental:     incrementCount != 0,
-		UserData:          userData,
-		LSN:               xtrabackupInfo.ToLSN,
-		IncrementFromLSN:  xtrabackupInfo.FromLSN,
-		IncrementFrom:     incrementFrom,
-		IncrementFullName: prevBackupInfo.fullBackupName,
-		IncrementCount:    &incrementCount,
+		Tool:                tool,
+		BinLogStart:         binlogStart,
+		BinLogEnd:           binlogEnd,
+		StartLocalTime:      timeStart,
+		StopLocalTime:       timeStop,
+		BinLogStartPosition: xtrabackupInfo.BinlogPosition,
+		GTIDExecuted:        xtrabackupInfo.GTIDExecuted,
+		CompressedSize:      uploadedSize,
+		UncompressedSize:    rawSize,
+		Hostname:            hostname,
+		ServerUUID:          serverUUID,
+		ServerVersion:       version,
+		ServerArch:          xtrabackupInfo.ServerArch,
+		ServerOS:            xtrabackupInfo.ServerOS,
+		IsPermanent:         isPermanent,
+		IsIncremental:       incrementCount != 0,
+		UserData:            userData,
+		LSN:                 xtrabackupInfo.ToLSN,
+		IncrementFromLSN:    xtrabackupInfo.FromLSN,
+		IncrementFrom:       incrementFrom,
+		IncrementFullName:   prevBackupInfo.fullBackupName,
+		IncrementCount:      &incrementCount,
+		AutoBackup:          autoBackup,
 	}
 	tracelog.InfoLogger.Printf("Backup sentinel: %s", sentinel.String())
 
 	err = internal.UploadSentinel(ctx, uploader, &sentinel, backupName)
 	tracelog.ErrorLogger.FatalOnError(err)
 
+	uploadBackupJournalInfo(ctx, folder, backupName, timeStop, isPermanent, countJournals)
+}
+
+// uploadBackupJournalInfo maintains the binlog sizes required to get from one backup to the next one
+func uploadBackupJournalInfo(ctx context.Context, folder storage.Folder, backupName string, timeStop time.Time,
+	isPermanent, countJournals bool) {
 	if !countJournals {
 		tracelog.InfoLogger.Printf("binlog counting mode is disabled: option is disabled")
 		return
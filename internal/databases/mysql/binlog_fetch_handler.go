@@ -43,7 +43,8 @@ func (ih *indexHandler) createIndexFile() error {
 	return nil
 }
 
-func HandleBinlogFetch(ctx context.Context, folder storage.Folder, backupName string, untilTS string, untilBinlogLastModifiedTS string) {
+func HandleBinlogFetch(ctx context.Context, folder storage.Folder, backupName string, untilTS string,
+	untilBinlogLastModifiedTS string, stopTarget *BinlogStopTarget) {
 	dstDir, err := internal.GetLogsDstSettings(conf.MysqlBinlogDstSetting)
 	tracelog.ErrorLogger.FatalOnError(err)
 
@@ -53,7 +54,7 @@ func HandleBinlogFetch(ctx context.Context, folder storage.Folder, backupName st
 	handler := newIndexHandler(dstDir)
 
 	tracelog.InfoLogger.Printf("Fetching binlogs since %s until %s", startTS, endTS)
-	err = fetchLogs(ctx, folder, dstDir, startTS, endTS, endBinlogTS, handler)
+	err = fetchLogs(ctx, folder, dstDir, startTS, endTS, endBinlogTS, stopTarget, handler)
 	tracelog.ErrorLogger.FatalfOnError("Failed to fetch binlogs: %v", err)
 
 	err = handler.createIndexFile()
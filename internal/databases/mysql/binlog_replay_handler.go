@@ -21,11 +21,17 @@ type replayHandler struct {
 	logCh chan string
 	errCh chan error
 	endTS string
+	// startBinlog is replayed from startPosition: the consistency point of the backup
+	// (MariaDB doesn't skip already executed GTIDs like MySQL does)
+	startBinlog   string
+	startPosition uint64
 }
 
-func newReplayHandler(ctx context.Context, endTS time.Time) *replayHandler {
+func newReplayHandler(ctx context.Context, endTS time.Time, startBinlog string, startPosition uint64) *replayHandler {
 	rh := new(replayHandler)
 	rh.endTS = endTS.Local().Format(TimeMysqlFormat)
+	rh.startBinlog = startBinlog
+	rh.startPosition = startPosition
 	rh.logCh = make(chan string, binlogFetchAhead)
 	rh.errCh = make(chan error, 1)
 	go rh.replayLogs(ctx)
@@ -34,6 +40,11 @@ func newReplayHandler(ctx context.Context, endTS time.Time) *replayHandler {
 
 func (rh *replayHandler) replayLogs(ctx context.Context) {
 	for binlogPath := range rh.logCh {
+		if rh.isBeforeStart(binlogPath) {
+			tracelog.InfoLogger.Printf("skipping %s: it precedes the backup binlog %s", path.Base(binlogPath), rh.startBinlog)
+			os.Remove(binlogPath)
+			continue
+		}
 		tracelog.InfoLogger.Printf("replaying %s ...", path.Base(binlogPath))
 		err := rh.replayLog(ctx, binlogPath)
 		os.Remove(binlogPath)
@@ -54,11 +65,28 @@ func (rh *replayHandler) replayLog(ctx context.Context, binlogPath string) error
 	env := os.Environ()
 	env = append(env,
 		fmt.Sprintf("%s=%s", "WALG_MYSQL_CURRENT_BINLOG", binlogPath),
-		fmt.Sprintf("%s=%s", "WALG_MYSQL_BINLOG_END_TS", rh.endTS))
+		fmt.Sprintf("%s=%s", "WALG_MYSQL_BINLOG_END_TS", rh.endTS),
+		fmt.Sprintf("%s=%d", "WALG_MYSQL_BINLOG_START_POSITION", rh.binlogStartPosition(binlogPath)))
 	cmd.Env = env
 	return cmd.Run()
 }
 
+// isBeforeStart reports whether the binlog precedes startBinlog, its transactions are already in the backup
+func (rh *replayHandler) isBeforeStart(binlogPath string) bool {
+	binlogName := path.Base(binlogPath)
+	if rh.startBinlog == "" || BinlogPrefix(binlogName) != BinlogPrefix(rh.startBinlog) {
+		return false
+	}
+	return BinlogNum(binlogName) < BinlogNum(rh.startBinlog)
+}
+
+func (rh *replayHandler) binlogStartPosition(binlogPath string) uint64 {
+	if rh.startPosition > 0 && path.Base(binlogPath) == rh.startBinlog {
+		return rh.startPosition
+	}
+	return 4 // binlog magic header
+}
+
 func (rh *replayHandler) wait() error {
 	close(rh.logCh)
 	return <-rh.errCh
@@ -73,17 +101,24 @@ func (rh *replayHandler) handleBinlog(binlogPath string) error {
 	}
 }
 
-func HandleBinlogReplay(ctx context.Context, folder storage.Folder, backupName string, untilTS string, untilBinlogLastModifiedTS string) {
+func HandleBinlogReplay(ctx context.Context, folder storage.Folder, backupName string, untilTS string,
+	untilBinlogLastModifiedTS string, stopTarget *BinlogStopTarget) {
 	dstDir, err := internal.GetLogsDstSettings(conf.MysqlBinlogDstSetting)
 	tracelog.ErrorLogger.FatalOnError(err)
 
 	startTS, endTS, endBinlogTS, err := getTimestamps(ctx, folder, backupName, untilTS, untilBinlogLastModifiedTS)
 	tracelog.ErrorLogger.FatalOnError(err)
 
-	handler := newReplayHandler(ctx, endTS)
+	backup, err := internal.GetBackupByName(ctx, backupName, utility.BaseBackupPath, folder)
+	tracelog.ErrorLogger.FatalOnError(err)
+	var sentinel StreamSentinelDto
+	err = backup.FetchSentinel(ctx, &sentinel)
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	handler := newReplayHandler(ctx, endTS, sentinel.BinLogStart, sentinel.BinLogStartPosition)
 
 	tracelog.InfoLogger.Printf("Fetching binlogs since %s until %s", startTS, endTS)
-	err = fetchLogs(ctx, folder, dstDir, startTS, endTS, endBinlogTS, handler)
+	err = fetchLogs(ctx, folder, dstDir, startTS, endTS, endBinlogTS, stopTarget, handler)
 	tracelog.ErrorLogger.FatalfOnError("Failed to fetch binlogs: %v", err)
 
 	err = handler.wait()
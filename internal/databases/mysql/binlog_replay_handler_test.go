@@ -0,0 +1,22 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplayHandlerSkipsBinlogsBeforeStart(t *testing.T) {
+	rh := &replayHandler{startBinlog: "mysql-bin.999999", startPosition: 1234}
+
+	assert.True(t, rh.isBeforeStart("/tmp/binlogs/mysql-bin.999998"))
+	assert.False(t, rh.isBeforeStart("/tmp/binlogs/mysql-bin.999999"))
+	assert.False(t, rh.isBeforeStart("/tmp/binlogs/mysql-bin.1000000"))
+	assert.False(t, rh.isBeforeStart("/tmp/binlogs/other-bin.000001"))
+
+	assert.Equal(t, uint64(1234), rh.binlogStartPosition("/tmp/binlogs/mysql-bin.999999"))
+	assert.Equal(t, uint64(4), rh.binlogStartPosition("/tmp/binlogs/mysql-bin.1000000"))
+
+	rh = &replayHandler{}
+	assert.False(t, rh.isBeforeStart("/tmp/binlogs/mysql-bin.000001"))
+}
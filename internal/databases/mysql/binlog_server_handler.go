@@ -3,12 +3,16 @@ package mysql
 import (
 	"context"
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"hash/crc32"
 	"net"
 	"os"
 	"path"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-mysql-org/go-mysql/client"
@@ -19,21 +23,65 @@ import (
 	"github.com/wal-g/wal-g/internal"
 	conf "github.com/wal-g/wal-g/internal/config"
 	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
 )
 
-// Handler is the go-mysql replication handler for one replica connection.
-// It implements both server.ReplicationHandler (go-mysql interface) and
-// the internal binlogHandler interface (fetchLogs callback)
-type Handler struct {
-	server.EmptyReplicationHandler
-	ctx           context.Context //nolint:containedctx // detached binlog replication server outlives any request
-	cancel        context.CancelFunc
+// binlogServer is the state shared by all replica connections of the binlog server
+type binlogServer struct {
 	replicaSource string
 	rootFolder    storage.Folder
 	dstDir        string
 	startTS       time.Time
 	untilTS       time.Time
 	endBinlogTS   time.Time
+	stopTarget    *BinlogStopTarget
+
+	// follow keeps streaming binlogs uploaded after the start, storage is polled every pollInterval
+	follow       bool
+	pollInterval time.Duration
+
+	// replicas is the number of replicas that should catch up before the server exits (when not following)
+	replicas int
+	mu       sync.Mutex
+	caughtUp map[string]bool
+	done     chan struct{}
+}
+
+// replicaCaughtUp counts the replica which received all binlogs, reconnected replica is counted once
+func (s *binlogServer) replicaCaughtUp(replica string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.caughtUp[replica] || len(s.caughtUp) >= s.replicas {
+		return
+	}
+	s.caughtUp[replica] = true
+	tracelog.InfoLogger.Printf("Replica %s has caught up (%d of %d)", replica, len(s.caughtUp), s.replicas)
+	if len(s.caughtUp) == s.replicas {
+		close(s.done)
+	}
+}
+
+// checkCatchUpDetectable refuses replicas without semi-sync when several replicas should catch up:
+// their catch-up is checked by the single WALG_MYSQL_BINLOG_SERVER_REPLICA_SOURCE, which can't tell them apart
+func (s *binlogServer) checkCatchUpDetectable(replica string, semiSync bool) error {
+	if semiSync || s.follow || s.replicas <= 1 {
+		return nil
+	}
+	return fmt.Errorf("replica %s has not enabled semi-sync, it is required when %d replicas should catch up",
+		replica, s.replicas)
+}
+
+// Handler is the go-mysql replication handler for one replica connection.
+// It implements both server.ReplicationHandler (go-mysql interface) and
+// the internal binlogHandler interface (fetchLogs callback)
+type Handler struct {
+	server.EmptyReplicationHandler
+	ctx    context.Context //nolint:containedctx // detached binlog replication server outlives any request
+	cancel context.CancelFunc
+	server *binlogServer
+	// replica is the remote address, or the server id after COM_REGISTER_SLAVE
+	replica string
+	conn    net.Conn
 
 	// streamer is the go-mysql event queue for the current replica connection.
 	// It is set once in HandleBinlogDump / HandleBinlogDumpGTID before the
@@ -48,6 +96,12 @@ type Handler struct {
 	requiredGTIDs  *mysql.MysqlGTIDSet
 	skipCurrentTxn bool
 
+	// semiSync is set by the replica before the binlog dump: events are sent with the semi-sync header
+	// and the replica acknowledges the end of each transaction
+	semiSync      bool
+	requestedAcks atomic.Int64
+	receivedAcks  atomic.Int64
+
 	// --- streaming pipeline fields (set by initStreaming, used by fetchLogs) ---
 
 	// parser parses raw binlog files and forwards events to handleEvent.
@@ -65,20 +119,44 @@ type Handler struct {
 	errCh chan error
 }
 
-func newHandler(ctx context.Context, replicaSource string, root storage.Folder, dst string,
-	startTS, untilTS, endBinlogTS time.Time) *Handler {
+func newHandler(ctx context.Context, s *binlogServer, conn net.Conn) *Handler {
 	ctx, cancel := context.WithCancel(ctx)
 	sent, _ := mysql.ParseGTIDSet(mysql.MySQLFlavor, "")
 	return &Handler{
-		ctx:           ctx,
-		cancel:        cancel,
-		replicaSource: replicaSource,
-		rootFolder:    root,
-		dstDir:        dst,
-		startTS:       startTS,
-		untilTS:       untilTS,
-		endBinlogTS:   endBinlogTS,
-		sentGTIDs:     sent,
+		ctx:       ctx,
+		cancel:    cancel,
+		server:    s,
+		replica:   conn.RemoteAddr().String(),
+		conn:      conn,
+		sentGTIDs: sent,
+	}
+}
+
+// addEvent sends the event to the replica, semi-sync replica is asked to acknowledge the transaction end
+func (h *Handler) addEvent(e *replication.BinlogEvent) error {
+	if !h.semiSync {
+		return h.streamer.AddEventToStreamer(e)
+	}
+	needReply := isTransactionEnd(e.Header.EventType)
+	if needReply {
+		h.requestedAcks.Add(1)
+	}
+	return h.streamer.AddEventToStreamer(semiSyncEvent(e, needReply))
+}
+
+// readSemiSyncAcks counts acknowledgements of the replica until the connection is closed.
+// go-mysql doesn't read from the connection during the binlog dump, so they are read from the raw connection.
+func (h *Handler) readSemiSyncAcks() {
+	for {
+		pos, err := readSemiSyncAck(h.conn)
+		if err != nil {
+			if h.ctx.Err() == nil {
+				tracelog.WarningLogger.Printf("Stopped reading semi-sync acks of replica %s: %v", h.replica, err)
+			}
+			return
+		}
+		h.receivedAcks.Add(1)
+		tracelog.DebugLogger.Printf("Replica %s acknowledged %s:%d", h.replica, pos.Name, pos.Pos)
 	}
 }
 
@@ -102,7 +180,7 @@ func (h *Handler) addRotateEvent(pos mysql.Position) error {
 	tracelog.ErrorLogger.FatalOnError(err)
 
 	// create rotate event
-	rotateBinlogEvent := replication.BinlogEvent{}
+	rotateBinlogEvent := replication.BinlogEvent{Header: &replication.EventHeader{EventType: replication.ROTATE_EVENT}}
 
 	messageBodySize := 8 + len(pos.Name) + 1
 	eventLength := replication.EventHeaderSize + messageBodySize + replication.BinlogChecksumLength
@@ -140,18 +218,24 @@ func (h *Handler) addRotateEvent(pos mysql.Position) error {
 	checksum := crc32.ChecksumIEEE(rotateBinlogEvent.RawData[0 : replication.EventHeaderSize+messageBodySize])
 	binary.LittleEndian.PutUint32(rotateBinlogEvent.RawData[binlogEventPos:], checksum)
 
-	return h.streamer.AddEventToStreamer(&rotateBinlogEvent)
+	return h.addEvent(&rotateBinlogEvent)
 }
 
-func (h *Handler) waitReplicationIsDoneSafe() {
+// waitReplicationIsDone waits until the replica catches up: semi-sync replica should acknowledge
+// all the sent transactions, otherwise WALG_MYSQL_BINLOG_SERVER_REPLICA_SOURCE should execute them.
+// It returns false when the replica disconnects.
+func (h *Handler) waitReplicationIsDone() bool {
 	if h.sentGTIDs.IsEmpty() {
-		tracelog.InfoLogger.Println("S3 objects finished. No GTIDs were sent. Shutting down immediately.")
-		os.Exit(0)
+		tracelog.InfoLogger.Printf("S3 objects finished. No GTIDs were sent to replica %s.", h.replica)
+		return true
+	}
+	if h.semiSync {
+		return h.waitSemiSyncAcks()
 	}
 
 	tracelog.InfoLogger.Printf("All S3 binlogs processed. Waiting for replica to catch up to GTID: %s", h.sentGTIDs.String())
 
-	dsn, err := parseMySQLDatasource(h.replicaSource)
+	dsn, err := parseMySQLDatasource(h.server.replicaSource)
 	if err != nil {
 		tracelog.ErrorLogger.Fatalf("Failed to parse replica datasource: %v", err)
 	}
@@ -166,7 +250,7 @@ func (h *Handler) waitReplicationIsDoneSafe() {
 	for {
 		if h.ctx.Err() != nil {
 			tracelog.WarningLogger.Println("Client disconnected while waiting for completion. Handler shutting down, awaiting reconnect...")
-			return
+			return false
 		}
 
 		if conn == nil {
@@ -199,19 +283,32 @@ func (h *Handler) waitReplicationIsDoneSafe() {
 		replicaSet, _ := mysql.ParseGTIDSet("mysql", executedStr)
 		if replicaSet != nil && replicaSet.Contain(h.sentGTIDs) {
 			tracelog.InfoLogger.Println("Replica has successfully caught up! We are safely done.")
-			os.Exit(0)
+			return true
 		}
 
 		time.Sleep(1 * time.Second)
 	}
 }
 
+func (h *Handler) waitSemiSyncAcks() bool {
+	tracelog.InfoLogger.Printf("All S3 binlogs processed. Waiting for semi-sync replica %s to acknowledge GTID: %s",
+		h.replica, h.sentGTIDs.String())
+	for h.receivedAcks.Load() < h.requestedAcks.Load() {
+		if h.ctx.Err() != nil {
+			tracelog.WarningLogger.Printf("Replica %s disconnected while waiting for acknowledgements", h.replica)
+			return false
+		}
+		time.Sleep(1 * time.Second)
+	}
+	return true
+}
+
 // handleEvent is the per-event callback passed to BinlogParser.ParseFile.
 func (h *Handler) handleEvent(e *replication.BinlogEvent) error {
 	if h.ctx.Err() != nil {
 		return h.ctx.Err()
 	}
-	if int64(e.Header.Timestamp) > h.untilTS.Unix() {
+	if int64(e.Header.Timestamp) > h.server.untilTS.Unix() {
 		return nil
 	}
 	switch e.Header.EventType {
@@ -229,7 +326,7 @@ func (h *Handler) handleEvent(e *replication.BinlogEvent) error {
 			return nil
 		}
 	}
-	return h.streamer.AddEventToStreamer(e)
+	return h.addEvent(e)
 }
 
 // decideSkipForGTID updates skip state from a GTID_EVENT; returns true if
@@ -304,7 +401,7 @@ func (h *Handler) streamLog(binlogPath string) error {
 		h.firstFile = false
 	}
 
-	tracelog.InfoLogger.Printf("Streaming %s to replica", path.Base(binlogPath))
+	tracelog.InfoLogger.Printf("Streaming %s to replica %s", path.Base(binlogPath), h.replica)
 	err := h.parser.ParseFile(binlogPath, offset, h.handleEvent)
 	return err
 }
@@ -325,8 +422,16 @@ func (h *Handler) wait() error {
 }
 
 func (h *Handler) streamBinlogFiles(startPos mysql.Position) {
-	err := os.MkdirAll(h.dstDir, 0777)
+	err := os.MkdirAll(h.server.dstDir, 0777)
 	tracelog.ErrorLogger.FatalfOnError("Failed to make dst dir: %v", err)
+	// replicas are served concurrently, so each one downloads binlogs into its own directory
+	dstDir, err := os.MkdirTemp(h.server.dstDir, "replica_")
+	if err != nil {
+		tracelog.ErrorLogger.Printf("Failed to make replica dst dir: %v", err)
+		h.handleEventError(err)
+		return
+	}
+	defer os.RemoveAll(dstDir)
 
 	if err := h.addRotateEvent(startPos); err != nil {
 		tracelog.ErrorLogger.Printf("Error while sending rotate event: %v", err)
@@ -335,9 +440,14 @@ func (h *Handler) streamBinlogFiles(startPos mysql.Position) {
 	}
 
 	h.initStreaming(startPos)
-	tracelog.InfoLogger.Printf("Start event streaming")
+	tracelog.InfoLogger.Printf("Start event streaming to replica %s", h.replica)
 
-	err = fetchLogs(h.ctx, h.rootFolder, h.dstDir, h.startTS, h.untilTS, h.endBinlogTS, h)
+	s := h.server
+	if s.follow {
+		err = followLogs(h.ctx, s.rootFolder, dstDir, s.startTS, s.pollInterval, h)
+	} else {
+		err = fetchLogs(h.ctx, s.rootFolder, dstDir, s.startTS, s.untilTS, s.endBinlogTS, s.stopTarget, h)
+	}
 	if err != nil {
 		tracelog.ErrorLogger.Printf("Error during logs streaming: %v", err)
 		_ = h.wait()
@@ -353,29 +463,93 @@ func (h *Handler) streamBinlogFiles(startPos mysql.Position) {
 		return
 	}
 
-	h.waitReplicationIsDoneSafe()
+	if h.waitReplicationIsDone() {
+		s.replicaCaughtUp(h.replica)
+	}
+}
+
+// followLogs streams binlogs uploaded after startTS and then waits for the new ones until ctx is done
+func followLogs(ctx context.Context, folder storage.Folder, dstDir string, startTS time.Time,
+	pollInterval time.Duration, handler binlogHandler) error {
+	logFolder := folder.GetSubFolder(BinlogPath)
+	// several binlogs may have the same modification time, so streamed ones are skipped by name
+	streamed := make(map[string]bool)
+	for {
+		logsToFetch, err := getLogsCoveringInterval(ctx, logFolder, startTS, true, utility.MaxTime)
+		if err != nil {
+			return err
+		}
+		for _, logFile := range logsToFetch {
+			binlogName := utility.TrimFileExtension(logFile.GetName())
+			if streamed[binlogName] {
+				continue
+			}
+			if logFile.GetLastModified().After(startTS) {
+				startTS = logFile.GetLastModified()
+				clear(streamed)
+			}
+			streamed[binlogName] = true
+			binlogPath := path.Join(dstDir, binlogName)
+			tracelog.InfoLogger.Printf("downloading %s into %s", binlogName, binlogPath)
+			if err = internal.DownloadFileTo(ctx, internal.NewFolderReader(logFolder), binlogName, binlogPath); err != nil {
+				return err
+			}
+			if err = handler.handleBinlog(binlogPath); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
 }
 
 func (h *Handler) HandleRegisterSlave(data []byte) error {
+	if len(data) >= 4 {
+		h.replica = fmt.Sprintf("server_id %d", binary.LittleEndian.Uint32(data))
+	}
 	return nil
 }
 
 func (h *Handler) HandleBinlogDump(pos mysql.Position) (*replication.BinlogStreamer, error) {
-	tracelog.InfoLogger.Printf("HandleBinlogDump: requested position %s:%d", pos.Name, pos.Pos)
-	h.streamer = replication.NewBinlogStreamer()
+	tracelog.InfoLogger.Printf("HandleBinlogDump: replica %s requested position %s:%d", h.replica, pos.Name, pos.Pos)
+	if err := h.startDump(); err != nil {
+		return nil, err
+	}
 	go h.streamBinlogFiles(pos)
 	return h.streamer, nil
 }
 
 func (h *Handler) HandleBinlogDumpGTID(gtidSet *mysql.MysqlGTIDSet) (*replication.BinlogStreamer, error) {
-	tracelog.InfoLogger.Printf("HandleBinlogDumpGTID: GTID=%s", gtidSet.String())
+	tracelog.InfoLogger.Printf("HandleBinlogDumpGTID: replica %s requested GTID=%s", h.replica, gtidSet.String())
 	h.requiredGTIDs = gtidSet
-	h.streamer = replication.NewBinlogStreamer()
+	if err := h.startDump(); err != nil {
+		return nil, err
+	}
 	go h.streamBinlogFiles(mysql.Position{Name: "host-binlog-file", Pos: 4})
 	return h.streamer, nil
 }
 
+func (h *Handler) startDump() error {
+	if err := h.server.checkCatchUpDetectable(h.replica, h.semiSync); err != nil {
+		tracelog.ErrorLogger.Printf("Refusing binlog dump: %v", err)
+		return err
+	}
+	h.streamer = replication.NewBinlogStreamer()
+	if h.semiSync {
+		tracelog.InfoLogger.Printf("Replica %s uses semi-sync replication", h.replica)
+		go h.readSemiSyncAcks()
+	}
+	return nil
+}
+
 func (h *Handler) HandleQuery(query string) (*mysql.Result, error) {
+	if isSemiSyncReplicaQuery(query) {
+		h.semiSync = true
+		return nil, nil
+	}
 	switch strings.ToLower(query) {
 	case "select @master_binlog_checksum":
 		resultSet, _ := mysql.BuildSimpleTextResultset([]string{"master_binlog_checksum"}, [][]interface{}{{"CRC32"}})
@@ -402,10 +576,10 @@ func (h *Handler) HandleQuery(query string) (*mysql.Result, error) {
 		resultSet, _ := mysql.BuildSimpleTextResultset([]string{"SERVER_UUID"}, [][]interface{}{{"0"}})
 		return &mysql.Result{Status: 34, Warnings: 0, InsertId: 0, AffectedRows: 0, Resultset: resultSet}, nil
 	case "select @@global.rpl_semi_sync_master_enabled":
-		resultSet, _ := mysql.BuildSimpleTextResultset([]string{"@@global.rpl_semi_sync_master_enabled"}, [][]interface{}{{"0"}})
+		resultSet, _ := mysql.BuildSimpleTextResultset([]string{"@@global.rpl_semi_sync_master_enabled"}, [][]interface{}{{"1"}})
 		return &mysql.Result{Status: 34, Warnings: 0, InsertId: 0, AffectedRows: 0, Resultset: resultSet}, nil
 	case "select @@global.rpl_semi_sync_source_enabled":
-		resultSet, _ := mysql.BuildSimpleTextResultset([]string{"@@global.rpl_semi_sync_source_enabled"}, [][]interface{}{{"0"}})
+		resultSet, _ := mysql.BuildSimpleTextResultset([]string{"@@global.rpl_semi_sync_source_enabled"}, [][]interface{}{{"1"}})
 		return &mysql.Result{Status: 34, Warnings: 0, InsertId: 0, AffectedRows: 0, Resultset: resultSet}, nil
 	default:
 		tracelog.DebugLogger.Printf("Unhandled query: %s", query)
@@ -413,12 +587,16 @@ func (h *Handler) HandleQuery(query string) (*mysql.Result, error) {
 	}
 }
 
-func HandleBinlogServer(ctx context.Context, since string, until string, untilBinlogLastModified string) {
+func HandleBinlogServer(ctx context.Context, since string, until string, untilBinlogLastModified string,
+	stopTarget *BinlogStopTarget, replicas int, follow bool, pollInterval time.Duration) {
 	// get necessary settings
 	st, err := internal.ConfigureStorage(ctx)
 	tracelog.ErrorLogger.FatalOnError(err)
 	startTS, untilTS, endBinlogTS, err := getTimestamps(ctx, st.RootFolder(), since, until, untilBinlogLastModified)
 	tracelog.ErrorLogger.FatalOnError(err)
+	if follow {
+		untilTS = utility.MaxTime
+	}
 
 	// validate WALG_MYSQL_BINLOG_SERVER_REPLICA_SOURCE
 	replicaSource, err := conf.GetRequiredSetting(conf.MysqlBinlogServerReplicaSource)
@@ -436,13 +614,41 @@ func HandleBinlogServer(ctx context.Context, since string, until string, untilBi
 
 	l, err := net.Listen("tcp", serverAddress+":"+serverPort)
 	tracelog.ErrorLogger.FatalOnError(err)
+	defer l.Close()
 	tracelog.InfoLogger.Printf("Listening on %s, wait connection", l.Addr())
 
+	s := &binlogServer{
+		replicaSource: replicaSource,
+		rootFolder:    st.RootFolder(),
+		dstDir:        dstDir,
+		startTS:       startTS,
+		untilTS:       untilTS,
+		endBinlogTS:   endBinlogTS,
+		stopTarget:    stopTarget,
+		follow:        follow,
+		pollInterval:  pollInterval,
+		replicas:      replicas,
+		caughtUp:      make(map[string]bool),
+		done:          make(chan struct{}),
+	}
 	srv := server.NewServer("5.7.42", mysql.DEFAULT_COLLATION_ID, mysql.AUTH_NATIVE_PASSWORD, nil, nil)
-	// This loop continues accepting connections until the process exits.
-	// It will be terminated by os.Exit() call in waitReplicationIsDoneSafe.
+	go s.serve(ctx, l, srv)
+
+	// the server works until all the replicas catch up, following server works until it is stopped
+	select {
+	case <-s.done:
+		tracelog.InfoLogger.Printf("All %d replicas have caught up. Shutting down.", replicas)
+	case <-ctx.Done():
+	}
+}
+
+// serve accepts connections until the listener is closed
+func (s *binlogServer) serve(ctx context.Context, l net.Listener, srv *server.Server) {
 	for {
 		c, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
 		if err != nil {
 			tracelog.ErrorLogger.Printf("Error accepting connection: %v", err)
 			continue
@@ -462,8 +668,7 @@ func HandleBinlogServer(ctx context.Context, since string, until string, untilBi
 			continue
 		}
 
-		go handleBinlogConnection(ctx, c, srv, replicaSource, st.RootFolder(), dstDir,
-			startTS, untilTS, endBinlogTS, user, password)
+		go handleBinlogConnection(ctx, c, srv, s, user, password)
 	}
 }
 
@@ -471,18 +676,19 @@ func handleBinlogConnection(
 	ctx context.Context,
 	c net.Conn,
 	srv *server.Server,
-	replicaSource string,
-	folder storage.Folder,
-	dstDir string,
-	startTS, untilTS, endBinlogTS time.Time,
+	s *binlogServer,
 	user string,
 	password string,
 ) {
-	h := newHandler(ctx, replicaSource, folder, dstDir, startTS, untilTS, endBinlogTS)
+	h := newHandler(ctx, s, c)
 	defer func() {
 		h.cancel()
+		if h.streamer != nil {
+			// unblock streaming of the disconnected replica
+			h.streamer.AddErrorToStreamer(h.ctx.Err())
+		}
 		c.Close()
-		tracelog.InfoLogger.Printf("Client disconnected, waiting for new connection...")
+		tracelog.InfoLogger.Printf("Client %s disconnected, waiting for new connection...", h.replica)
 	}()
 
 	authHandler := server.NewInMemoryAuthenticationHandler(mysql.AUTH_NATIVE_PASSWORD)
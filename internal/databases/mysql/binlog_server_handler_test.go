@@ -1,6 +1,8 @@
 package mysql
 
 import (
+	"bytes"
+	"context"
 	"encoding/binary"
 	"testing"
 
@@ -8,6 +10,8 @@ import (
 	"github.com/go-mysql-org/go-mysql/replication"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/utility"
 )
 
 // gtidEvent builds a BinlogEvent whose RawData decodes to the given (sid, gno).
@@ -65,3 +69,75 @@ func TestDecideSkipForGTID(t *testing.T) {
 		assert.False(t, h.skipCurrentTxn)
 	})
 }
+
+func TestIsSemiSyncReplicaQuery(t *testing.T) {
+	assert.True(t, isSemiSyncReplicaQuery("SET @rpl_semi_sync_slave= 1"))
+	assert.True(t, isSemiSyncReplicaQuery("SET @rpl_semi_sync_replica = 1"))
+	assert.False(t, isSemiSyncReplicaQuery("SET @rpl_semi_sync_replica = 0"))
+	assert.False(t, isSemiSyncReplicaQuery("SET @master_binlog_checksum= @@global.binlog_checksum"))
+}
+
+func TestSemiSyncEvents(t *testing.T) {
+	h := &Handler{
+		ctx:      context.Background(),
+		server:   &binlogServer{untilTS: utility.MaxTime},
+		streamer: replication.NewBinlogStreamer(),
+		semiSync: true,
+	}
+	for _, eventType := range []replication.EventType{replication.QUERY_EVENT, replication.WRITE_ROWS_EVENTv2,
+		replication.XID_EVENT} {
+		raw := make([]byte, replication.EventHeaderSize)
+		raw[4] = byte(eventType)
+		require.NoError(t, h.handleEvent(&replication.BinlogEvent{
+			Header: &replication.EventHeader{EventType: eventType}, RawData: raw}))
+	}
+	assert.Equal(t, int64(2), h.requestedAcks.Load())
+
+	var flags []byte
+	for range 3 {
+		e, err := h.streamer.GetEvent(context.Background())
+		require.NoError(t, err)
+		require.Len(t, e.RawData, 2+replication.EventHeaderSize)
+		assert.Equal(t, byte(semiSyncMagic), e.RawData[0])
+		flags = append(flags, e.RawData[1])
+	}
+	assert.Equal(t, []byte{semiSyncNeedReply, 0, semiSyncNeedReply}, flags)
+}
+
+func TestReadSemiSyncAck(t *testing.T) {
+	payload := append([]byte{semiSyncMagic}, binary.LittleEndian.AppendUint64(nil, 1234)...)
+	payload = append(payload, "mysql-bin.000007"...)
+	packet := append([]byte{byte(len(payload)), 0, 0, 0}, payload...)
+	pos, err := readSemiSyncAck(bytes.NewReader(packet))
+	require.NoError(t, err)
+	assert.Equal(t, mysql.Position{Name: "mysql-bin.000007", Pos: 1234}, pos)
+
+	_, err = readSemiSyncAck(bytes.NewReader([]byte{1, 0, 0, 0, 0x0E}))
+	assert.Error(t, err)
+}
+
+func TestReplicaCaughtUp(t *testing.T) {
+	s := &binlogServer{replicas: 2, caughtUp: make(map[string]bool), done: make(chan struct{})}
+	s.replicaCaughtUp("server_id 1")
+	s.replicaCaughtUp("server_id 1")
+	select {
+	case <-s.done:
+		t.Fatal("the server is done after one replica")
+	default:
+	}
+	s.replicaCaughtUp("server_id 2")
+	s.replicaCaughtUp("server_id 3")
+	<-s.done
+}
+
+func TestCheckCatchUpDetectable(t *testing.T) {
+	s := &binlogServer{replicas: 1}
+	assert.NoError(t, s.checkCatchUpDetectable("server_id 1", false))
+
+	s.replicas = 2
+	assert.NoError(t, s.checkCatchUpDetectable("server_id 1", true))
+	assert.Error(t, s.checkCatchUpDetectable("server_id 1", false))
+
+	s.follow = true
+	assert.NoError(t, s.checkCatchUpDetectable("server_id 1", false))
+}
@@ -0,0 +1,67 @@
+package mysql
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+// Semi-synchronous replication protocol:
+// https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_replication_binlog_event.html
+const (
+	semiSyncMagic     = 0xEF
+	semiSyncNeedReply = 0x01
+	// magic number + 8-byte binlog position, binlog name follows
+	semiSyncAckHeaderSize = 9
+)
+
+// isSemiSyncReplicaQuery checks for `SET @rpl_semi_sync_slave = 1` (`SET @rpl_semi_sync_replica = 1` since 8.0.26)
+// which the replica with enabled semi-sync sends before the binlog dump
+func isSemiSyncReplicaQuery(query string) bool {
+	query = strings.ToLower(strings.Join(strings.Fields(query), ""))
+	return query == "set@rpl_semi_sync_slave=1" || query == "set@rpl_semi_sync_replica=1"
+}
+
+// isTransactionEnd reports whether the source asks semi-sync replica to acknowledge the event.
+// DDL transactions end with QUERY_EVENT; acknowledged BEGIN events are harmless.
+func isTransactionEnd(eventType replication.EventType) bool {
+	switch eventType {
+	case replication.XID_EVENT, replication.QUERY_EVENT, replication.XA_PREPARE_LOG_EVENT:
+		return true
+	}
+	return false
+}
+
+// semiSyncEvent prepends the semi-sync header to the event
+func semiSyncEvent(e *replication.BinlogEvent, needReply bool) *replication.BinlogEvent {
+	raw := make([]byte, 2, 2+len(e.RawData))
+	raw[0] = semiSyncMagic
+	if needReply {
+		raw[1] = semiSyncNeedReply
+	}
+	return &replication.BinlogEvent{Header: e.Header, RawData: append(raw, e.RawData...)}
+}
+
+// readSemiSyncAck reads one acknowledgement packet of the replica. Sequence numbers of the packets are ignored:
+// replica resets them for each acknowledgement while the binlog dump continues.
+func readSemiSyncAck(r io.Reader) (mysql.Position, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return mysql.Position{}, err
+	}
+	payload := make([]byte, int(header[0])|int(header[1])<<8|int(header[2])<<16)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return mysql.Position{}, err
+	}
+	if len(payload) < semiSyncAckHeaderSize || payload[0] != semiSyncMagic {
+		return mysql.Position{}, fmt.Errorf("unexpected packet from replica: %x", payload)
+	}
+	return mysql.Position{
+		Name: string(payload[semiSyncAckHeaderSize:]),
+		Pos:  uint32(binary.LittleEndian.Uint64(payload[1:semiSyncAckHeaderSize])),
+	}, nil
+}
@@ -0,0 +1,249 @@
+package mysql
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/google/uuid"
+	"github.com/wal-g/tracelog"
+)
+
+var errBinlogStopFound = errors.New("binlog stop point found")
+
+// BinlogStopTarget is the point where binlog-fetch, binlog-replay and binlog-server stop:
+// either the transaction with the given GTID or the position in the binlog file.
+// The last binlog is truncated right before the target event, so the tools which apply
+// the binlogs don't have to support the target themselves.
+type BinlogStopTarget struct {
+	flavor string
+	// sid, tag and gno identify the MySQL GTID
+	sid uuid.UUID
+	tag mysql.Tag
+	gno int64
+	// mariadbGTID identifies the MariaDB GTID
+	mariadbGTID *mysql.MariadbGTID
+	// inclusive means the target transaction is applied as well
+	inclusive bool
+	position  *mysql.Position
+}
+
+// NewBinlogStopTarget parses the --until-gtid and --until-position values, it returns nil if both are empty.
+// The GTID is either MySQL 'uuid[:tag]:number' or MariaDB 'domain-server-sequence',
+// the position is 'binlog_name:offset'.
+func NewBinlogStopTarget(untilGTID string, inclusive bool, untilPosition string) (*BinlogStopTarget, error) {
+	switch {
+	case untilGTID != "" && untilPosition != "":
+		return nil, fmt.Errorf("only one of GTID and position stop targets can be set")
+	case untilGTID != "":
+		target := &BinlogStopTarget{inclusive: inclusive}
+		if strings.Contains(untilGTID, ":") {
+			return target, target.parseMysqlGTID(untilGTID)
+		}
+		gtid, err := mysql.ParseMariadbGTID(untilGTID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GTID %q: %w", untilGTID, err)
+		}
+		target.flavor = mysql.MariaDBFlavor
+		target.mariadbGTID = gtid
+		return target, nil
+	case untilPosition != "":
+		separator := strings.LastIndex(untilPosition, ":")
+		if separator <= 0 {
+			return nil, fmt.Errorf("invalid binlog position %q, expected binlog_name:offset", untilPosition)
+		}
+		offset, err := strconv.ParseUint(untilPosition[separator+1:], 10, 32)
+		if err != nil || offset < uint64(len(replication.BinLogFileHeader)) {
+			return nil, fmt.Errorf("invalid binlog offset in %q", untilPosition)
+		}
+		return &BinlogStopTarget{
+			flavor:   mysql.MySQLFlavor,
+			position: &mysql.Position{Name: untilPosition[:separator], Pos: uint32(offset)},
+		}, nil
+	}
+	return nil, nil
+}
+
+func (target *BinlogStopTarget) parseMysqlGTID(untilGTID string) error {
+	set, err := mysql.ParseMysqlGTIDSet(untilGTID)
+	if err != nil {
+		return fmt.Errorf("invalid GTID %q: %w", untilGTID, err)
+	}
+	count := 0
+	for sid, tags := range *set.(*mysql.MysqlGTIDSet) {
+		for tag, intervals := range tags {
+			for _, interval := range intervals {
+				target.sid, target.tag, target.gno = sid, tag, interval.Start
+				count += int(interval.Stop - interval.Start)
+			}
+		}
+	}
+	if count != 1 {
+		return fmt.Errorf("GTID %q must identify exactly one transaction", untilGTID)
+	}
+	target.flavor = mysql.MySQLFlavor
+	return nil
+}
+
+func (target *BinlogStopTarget) String() string {
+	switch {
+	case target.position != nil:
+		return fmt.Sprintf("position %s", target.position)
+	case target.inclusive:
+		return fmt.Sprintf("GTID %s inclusive", target.gtidString())
+	default:
+		return fmt.Sprintf("GTID %s exclusive", target.gtidString())
+	}
+}
+
+func (target *BinlogStopTarget) gtidString() string {
+	if target.mariadbGTID != nil {
+		return target.mariadbGTID.String()
+	}
+	if target.tag == mysql.NewTag("") {
+		return fmt.Sprintf("%s:%d", target.sid, target.gno)
+	}
+	return fmt.Sprintf("%s:%s:%d", target.sid, target.tag, target.gno)
+}
+
+// isPassed reports whether the binlog follows the binlog of the target position,
+// so the target position was not found in the previous binlogs
+func (target *BinlogStopTarget) isPassed(binlogName string) bool {
+	return target.position != nil &&
+		BinlogPrefix(binlogName) == BinlogPrefix(target.position.Name) &&
+		BinlogNum(binlogName) > BinlogNum(target.position.Name)
+}
+
+// findStop returns the offset the binlog should be truncated at if the binlog contains the target
+func (target *BinlogStopTarget) findStop(binlogPath string) (offset int64, found bool, err error) {
+	if target.position != nil && path.Base(binlogPath) != target.position.Name {
+		return 0, false, nil
+	}
+	info, err := os.Stat(binlogPath)
+	if err != nil {
+		return 0, false, err
+	}
+
+	parser := replication.NewBinlogParser()
+	parser.SetFlavor(target.flavor)
+	parser.SetVerifyChecksum(false)
+	parser.SetRawMode(true)
+
+	offset = int64(len(replication.BinLogFileHeader))
+	checksumLength := 0
+	transactionIncluded := false
+	err = parser.ParseFile(binlogPath, 0, func(event *replication.BinlogEvent) error {
+		if event.Header.EventType == replication.FORMAT_DESCRIPTION_EVENT {
+			checksumLength = formatChecksumLength(event)
+		}
+		if target.position != nil && offset >= int64(target.position.Pos) ||
+			target.position == nil && target.isStopEvent(event, checksumLength, &transactionIncluded) {
+			return errBinlogStopFound
+		}
+		offset += int64(event.Header.EventSize)
+		return nil
+	})
+	if errors.Is(err, errBinlogStopFound) {
+		return offset, true, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to parse binlog %s: %w", path.Base(binlogPath), err)
+	}
+	// the binlog of the target position or the binlog ending with the target transaction is applied in full
+	return info.Size(), target.position != nil || transactionIncluded, nil
+}
+
+// isStopEvent reports whether the binlog should be truncated right before the event of the GTID target.
+// transactionIncluded is set when the inclusive target transaction starts, it ends at the next transaction boundary.
+func (target *BinlogStopTarget) isStopEvent(event *replication.BinlogEvent, checksumLength int, transactionIncluded *bool) bool {
+	data := event.RawData[replication.EventHeaderSize : len(event.RawData)-checksumLength]
+	switch event.Header.EventType {
+	case replication.GTID_EVENT, replication.GTID_TAGGED_LOG_EVENT:
+		if *transactionIncluded {
+			return true
+		}
+		if target.mariadbGTID != nil {
+			return false
+		}
+		return target.reachedMysqlGTID(event.Header.EventType, data, transactionIncluded)
+	case replication.MARIADB_GTID_EVENT:
+		if *transactionIncluded {
+			return true
+		}
+		if target.mariadbGTID == nil {
+			return false
+		}
+		gtidEvent := &replication.MariadbGTIDEvent{}
+		if gtidEvent.Decode(data) != nil {
+			return false
+		}
+		return target.reached(gtidEvent.GTID.DomainID == target.mariadbGTID.DomainID,
+			int64(gtidEvent.GTID.SequenceNumber), int64(target.mariadbGTID.SequenceNumber), transactionIncluded)
+	case replication.ANONYMOUS_GTID_EVENT, replication.ROTATE_EVENT, replication.STOP_EVENT:
+		return *transactionIncluded
+	}
+	return false
+}
+
+func (target *BinlogStopTarget) reachedMysqlGTID(eventType replication.EventType, data []byte,
+	transactionIncluded *bool) bool {
+	gtidEvent, err := decodeMysqlGTIDEvent(eventType, data)
+	if err != nil {
+		return false
+	}
+	sid, err := uuid.FromBytes(gtidEvent.SID)
+	if err != nil {
+		return false
+	}
+	return target.reached(sid == target.sid && gtidEvent.Tag == target.tag, gtidEvent.GNO, target.gno, transactionIncluded)
+}
+
+// decodeMysqlGTIDEvent decodes the body of the untagged or tagged MySQL GTID event
+func decodeMysqlGTIDEvent(eventType replication.EventType, data []byte) (*replication.GTIDEvent, error) {
+	if eventType == replication.GTID_TAGGED_LOG_EVENT {
+		taggedEvent := &replication.GtidTaggedLogEvent{}
+		if err := taggedEvent.Decode(data); err != nil {
+			return nil, err
+		}
+		return &taggedEvent.GTIDEvent, nil
+	}
+	gtidEvent := &replication.GTIDEvent{}
+	if err := gtidEvent.Decode(data); err != nil {
+		return nil, err
+	}
+	return gtidEvent, nil
+}
+
+// reached compares the sequence number of the transaction of the same source with the target one.
+// The transactions following the target stop the binlog even if the target itself is missing.
+func (target *BinlogStopTarget) reached(sameSource bool, sequence, targetSequence int64, transactionIncluded *bool) bool {
+	if !sameSource || sequence < targetSequence {
+		return false
+	}
+	if sequence == targetSequence && target.inclusive {
+		*transactionIncluded = true
+		return false
+	}
+	if sequence > targetSequence {
+		tracelog.WarningLogger.Printf("GTID %s is not found, stopping at the next transaction of its source",
+			target.gtidString())
+	}
+	return true
+}
+
+// formatChecksumLength returns the length of the event checksums declared by the format description event
+func formatChecksumLength(event *replication.BinlogEvent) int {
+	format := &replication.FormatDescriptionEvent{}
+	if format.Decode(event.RawData[replication.EventHeaderSize:]) != nil {
+		return 0
+	}
+	if format.ChecksumAlgorithm == replication.BINLOG_CHECKSUM_ALG_CRC32 {
+		return replication.BinlogChecksumLength
+	}
+	return 0
+}
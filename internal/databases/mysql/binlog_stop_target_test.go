@@ -0,0 +1,230 @@
+package mysql
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testStopSourceUUID = "6abc8ecb-bf5c-11e9-9821-c897993b5a14"
+
+// testBinlogWriter builds a binlog with the format description event of the small test binlog
+type testBinlogWriter struct {
+	t       *testing.T
+	data    []byte
+	offsets []int64
+}
+
+func newTestBinlogWriter(t *testing.T) *testBinlogWriter {
+	small, err := os.ReadFile(testFilenameSmall)
+	require.NoError(t, err)
+	formatEnd := len(replication.BinLogFileHeader) +
+		int(binary.LittleEndian.Uint32(small[len(replication.BinLogFileHeader)+9:]))
+	return &testBinlogWriter{t: t, data: append([]byte{}, small[:formatEnd]...)}
+}
+
+func (writer *testBinlogWriter) addEvent(eventType replication.EventType, body []byte) int64 {
+	offset := int64(len(writer.data))
+	size := replication.EventHeaderSize + len(body) + replication.BinlogChecksumLength
+	header := make([]byte, replication.EventHeaderSize)
+	binary.LittleEndian.PutUint32(header[0:], 1566047760)
+	header[4] = byte(eventType)
+	binary.LittleEndian.PutUint32(header[5:], 1)
+	binary.LittleEndian.PutUint32(header[9:], uint32(size))
+	binary.LittleEndian.PutUint32(header[13:], uint32(offset)+uint32(size))
+	event := append(header, body...)
+	event = binary.LittleEndian.AppendUint32(event, crc32.ChecksumIEEE(event))
+	writer.data = append(writer.data, event...)
+	writer.offsets = append(writer.offsets, offset)
+	return offset
+}
+
+// addMysqlTransaction adds GTID, query and XID events and returns the offset of the GTID event
+func (writer *testBinlogWriter) addMysqlTransaction(sid string, gno int64) int64 {
+	body := make([]byte, 42)
+	sidBytes := uuid.MustParse(sid)
+	copy(body[1:], sidBytes[:])
+	binary.LittleEndian.PutUint64(body[17:], uint64(gno))
+	body[25] = replication.LogicalTimestampTypeCode
+	offset := writer.addEvent(replication.GTID_EVENT, body)
+	writer.addEvent(replication.QUERY_EVENT, []byte("BEGIN"))
+	writer.addEvent(replication.XID_EVENT, make([]byte, 8))
+	return offset
+}
+
+func (writer *testBinlogWriter) addMariadbTransaction(domain uint32, sequence uint64) int64 {
+	body := make([]byte, 13)
+	binary.LittleEndian.PutUint64(body, sequence)
+	binary.LittleEndian.PutUint32(body[8:], domain)
+	offset := writer.addEvent(replication.MARIADB_GTID_EVENT, body)
+	writer.addEvent(replication.QUERY_EVENT, []byte("BEGIN"))
+	writer.addEvent(replication.XID_EVENT, make([]byte, 8))
+	return offset
+}
+
+func (writer *testBinlogWriter) write(name string) string {
+	binlogPath := filepath.Join(writer.t.TempDir(), name)
+	require.NoError(writer.t, os.WriteFile(binlogPath, writer.data, 0600))
+	return binlogPath
+}
+
+func TestNewBinlogStopTarget(t *testing.T) {
+	target, err := NewBinlogStopTarget("", false, "")
+	assert.NoError(t, err)
+	assert.Nil(t, target)
+
+	target, err = NewBinlogStopTarget(testStopSourceUUID+":12345", false, "")
+	require.NoError(t, err)
+	assert.Equal(t, "GTID "+testStopSourceUUID+":12345 exclusive", target.String())
+
+	target, err = NewBinlogStopTarget("0-1-100", true, "")
+	require.NoError(t, err)
+	assert.Equal(t, "GTID 0-1-100 inclusive", target.String())
+
+	target, err = NewBinlogStopTarget("", false, "mysql-bin.000012:1234")
+	require.NoError(t, err)
+	assert.Equal(t, "position (mysql-bin.000012, 1234)", target.String())
+
+	for _, testCase := range []struct{ gtid, position string }{
+		{gtid: testStopSourceUUID + ":1", position: "mysql-bin.000012:1234"},
+		{gtid: testStopSourceUUID + ":1-3"},
+		{gtid: "not-a-gtid"},
+		{position: "mysql-bin.000012"},
+		{position: "mysql-bin.000012:2"},
+		{position: ":1234"},
+	} {
+		_, err = NewBinlogStopTarget(testCase.gtid, false, testCase.position)
+		assert.Error(t, err, testCase)
+	}
+}
+
+func TestBinlogStopTarget_MysqlGTID(t *testing.T) {
+	writer := newTestBinlogWriter(t)
+	writer.addMysqlTransaction(testStopSourceUUID, 1)
+	// transactions of other sources don't affect the target
+	writer.addMysqlTransaction("11111111-2222-3333-4444-555555555555", 7)
+	second := writer.addMysqlTransaction(testStopSourceUUID, 2)
+	third := writer.addMysqlTransaction(testStopSourceUUID, 3)
+	binlogPath := writer.write("mysql-bin.000001")
+
+	for _, testCase := range []struct {
+		gtid      string
+		inclusive bool
+		offset    int64
+		found     bool
+	}{
+		{gtid: testStopSourceUUID + ":2", offset: second, found: true},
+		{gtid: testStopSourceUUID + ":2", inclusive: true, offset: third, found: true},
+		{gtid: testStopSourceUUID + ":3", inclusive: true, offset: int64(len(writer.data)), found: true},
+		{gtid: testStopSourceUUID + ":4"},
+		{gtid: "99999999-2222-3333-4444-555555555555:1"},
+	} {
+		target, err := NewBinlogStopTarget(testCase.gtid, testCase.inclusive, "")
+		require.NoError(t, err)
+		offset, found, err := target.findStop(binlogPath)
+		require.NoError(t, err)
+		assert.Equal(t, testCase.found, found, testCase)
+		if testCase.found {
+			assert.Equal(t, testCase.offset, offset, testCase)
+		}
+	}
+}
+
+func TestBinlogStopTarget_MissingMysqlGTID(t *testing.T) {
+	writer := newTestBinlogWriter(t)
+	writer.addMysqlTransaction(testStopSourceUUID, 1)
+	third := writer.addMysqlTransaction(testStopSourceUUID, 3)
+	binlogPath := writer.write("mysql-bin.000001")
+
+	for _, inclusive := range []bool{false, true} {
+		target, err := NewBinlogStopTarget(testStopSourceUUID+":2", inclusive, "")
+		require.NoError(t, err)
+		offset, found, err := target.findStop(binlogPath)
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, third, offset)
+	}
+}
+
+func TestBinlogStopTarget_MariadbGTID(t *testing.T) {
+	writer := newTestBinlogWriter(t)
+	writer.addMariadbTransaction(0, 100)
+	writer.addMariadbTransaction(1, 500)
+	second := writer.addMariadbTransaction(0, 101)
+	third := writer.addMariadbTransaction(0, 102)
+	binlogPath := writer.write("mariadb-bin.000001")
+
+	target, err := NewBinlogStopTarget("0-1-101", false, "")
+	require.NoError(t, err)
+	offset, found, err := target.findStop(binlogPath)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, second, offset)
+
+	target, err = NewBinlogStopTarget("0-1-101", true, "")
+	require.NoError(t, err)
+	offset, found, err = target.findStop(binlogPath)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, third, offset)
+}
+
+func TestBinlogStopTarget_Position(t *testing.T) {
+	writer := newTestBinlogWriter(t)
+	writer.addMysqlTransaction(testStopSourceUUID, 1)
+	second := writer.addMysqlTransaction(testStopSourceUUID, 2)
+	binlogPath := writer.write("mysql-bin.000002")
+
+	// the stop is at the first event starting at the position or after it
+	target, err := NewBinlogStopTarget("", false, "mysql-bin.000002:"+strconv.FormatInt(second+1, 10))
+	require.NoError(t, err)
+	offset, found, err := target.findStop(binlogPath)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, writer.offsets[len(writer.offsets)-2], offset)
+
+	target, err = NewBinlogStopTarget("", false, "mysql-bin.000002:"+strconv.FormatInt(second, 10))
+	require.NoError(t, err)
+	offset, found, err = target.findStop(binlogPath)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, second, offset)
+
+	assert.False(t, target.isPassed("mysql-bin.000001"))
+	assert.False(t, target.isPassed("mysql-bin.000002"))
+	assert.True(t, target.isPassed("mysql-bin.000003"))
+
+	target, err = NewBinlogStopTarget("", false, "mysql-bin.000003:4")
+	require.NoError(t, err)
+	_, found, err = target.findStop(binlogPath)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestTruncateBinlogAtStop(t *testing.T) {
+	writer := newTestBinlogWriter(t)
+	writer.addMysqlTransaction(testStopSourceUUID, 1)
+	second := writer.addMysqlTransaction(testStopSourceUUID, 2)
+	binlogPath := writer.write("mysql-bin.000001")
+
+	stopped, err := truncateBinlogAtStop(binlogPath, nil)
+	require.NoError(t, err)
+	assert.False(t, stopped)
+
+	target, err := NewBinlogStopTarget(testStopSourceUUID+":2", false, "")
+	require.NoError(t, err)
+	stopped, err = truncateBinlogAtStop(binlogPath, target)
+	require.NoError(t, err)
+	assert.True(t, stopped)
+	truncated, err := os.ReadFile(binlogPath)
+	require.NoError(t, err)
+	assert.Equal(t, writer.data[:second], truncated)
+}
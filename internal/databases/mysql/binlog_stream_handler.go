@@ -0,0 +1,574 @@
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/client"
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/google/uuid"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	BinlogStreamCheckpointPath = "binlog_stream_checkpoint_" + utility.VersionStr + ".json"
+	// BinlogPartialPath is the subfolder of the binlog folder with the binlogs being streamed,
+	// binlog-fetch, binlog-replay and binlog-server see only the finished binlogs.
+	BinlogPartialPath = "partial/"
+
+	binlogStreamHeartbeatPeriod = 10 * time.Second
+	binlogStreamRetryInterval   = 5 * time.Second
+)
+
+// BinlogStreamCheckpoint is the state binlog-stream resumes from: all the transactions before Position
+// in Binlog are in the storage. Binlog is stored as a partial object unless Position is the start of the binlog.
+type BinlogStreamCheckpoint struct {
+	Binlog   string `json:"Binlog"`
+	Position uint32 `json:"Position"`
+	GTIDSet  string `json:"GtidSet,omitempty"`
+	Flavor   string `json:"Flavor"`
+}
+
+func (checkpoint *BinlogStreamCheckpoint) String() string {
+	result, _ := json.Marshal(checkpoint)
+	return string(result)
+}
+
+// binlogStreamError is returned by the event handling, unlike the connection errors it is not retried
+type binlogStreamError struct {
+	error
+}
+
+// binlogStream writes the binlog events received as a replica into the local copy of the current binlog.
+// The copy is uploaded as a partial object up to the end of the last complete transaction
+// every flush interval and as a finished binlog on rotate.
+type binlogStream struct {
+	uploader      internal.Uploader
+	rootFolder    storage.Folder
+	partialFolder storage.Folder
+	dstDir        string
+	flavor        string
+	flushInterval time.Duration
+	lastFlush     time.Time
+
+	// binlog is the binlog being written into file, nextBinlog is the binlog announced by the last rotate
+	binlog         string
+	nextBinlog     string
+	file           *os.File
+	offset         int64
+	checksumLength int
+	// boundary is the end of the last complete transaction, flushed is the boundary uploaded last
+	boundary int64
+	flushed  int64
+	// gtidSet contains the transactions before boundary, pendingGTID is the transaction being received
+	gtidSet       mysql.GTIDSet
+	pendingGTID   string
+	inTransaction bool
+}
+
+// HandleBinlogStream connects to the MySQL as a replica and streams its binlogs into the storage until ctx is done
+func HandleBinlogStream(ctx context.Context, uploader internal.Uploader, serverID uint32, flushInterval time.Duration) {
+	rootFolder := uploader.Folder()
+	uploader.ChangeDirectory(BinlogPath)
+
+	dstDir, err := conf.GetRequiredSetting(conf.MysqlBinlogDstSetting)
+	tracelog.ErrorLogger.FatalOnError(err)
+	conn, err := getMySQLConnection(ctx)
+	tracelog.ErrorLogger.FatalOnError(err)
+	flavor, err := getMySQLFlavor(conn)
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	stream := &binlogStream{
+		uploader:      uploader,
+		rootFolder:    rootFolder,
+		partialFolder: rootFolder.GetSubFolder(BinlogPath).GetSubFolder(BinlogPartialPath),
+		dstDir:        dstDir,
+		flavor:        flavor,
+		flushInterval: flushInterval,
+	}
+	resumed, err := stream.resume(ctx)
+	tracelog.ErrorLogger.FatalOnError(err)
+	if !resumed {
+		stream.nextBinlog, err = getStreamStartBinlog(ctx, conn, rootFolder)
+		tracelog.ErrorLogger.FatalOnError(err)
+		tracelog.InfoLogger.Printf("Starting binlog stream from %s", stream.nextBinlog)
+	}
+	utility.LoggedClose(conn, "")
+
+	syncerConfig, err := getBinlogSyncerConfig(serverID, flavor)
+	tracelog.ErrorLogger.FatalOnError(err)
+	err = stream.run(ctx, syncerConfig)
+	tracelog.ErrorLogger.FatalOnError(err)
+}
+
+// resume restores the stream state from the checkpoint, it returns false if there is no checkpoint
+func (stream *binlogStream) resume(ctx context.Context) (bool, error) {
+	var checkpoint BinlogStreamCheckpoint
+	err := fetchBinlogStreamCheckpoint(ctx, stream.rootFolder, &checkpoint)
+	if _, ok := err.(storage.ObjectNotFoundError); ok {
+		stream.gtidSet, err = mysql.ParseGTIDSet(stream.flavor, "")
+		return false, err
+	}
+	if err != nil {
+		return false, err
+	}
+	if checkpoint.Flavor != stream.flavor {
+		return false, fmt.Errorf("binlog stream checkpoint of %s can't be resumed from %s", checkpoint.Flavor, stream.flavor)
+	}
+	stream.gtidSet, err = mysql.ParseGTIDSet(stream.flavor, checkpoint.GTIDSet)
+	if err != nil {
+		return false, fmt.Errorf("invalid binlog stream checkpoint GTID set %q: %w", checkpoint.GTIDSet, err)
+	}
+	tracelog.InfoLogger.Printf("Resuming binlog stream from checkpoint %s", checkpoint.String())
+	stream.nextBinlog = checkpoint.Binlog
+	if checkpoint.Position <= uint32(len(replication.BinLogFileHeader)) {
+		return true, nil
+	}
+
+	binlogPath := path.Join(stream.dstDir, checkpoint.Binlog)
+	err = internal.DownloadFileTo(ctx, internal.NewFolderReader(stream.partialFolder), checkpoint.Binlog, binlogPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to download partial binlog %s: %w", checkpoint.Binlog, err)
+	}
+	info, err := os.Stat(binlogPath)
+	if err != nil {
+		return false, err
+	}
+	if info.Size() < int64(checkpoint.Position) {
+		return false, fmt.Errorf("partial binlog %s is shorter than the checkpoint position %d", checkpoint.Binlog, checkpoint.Position)
+	}
+	// the partial object may be uploaded after the checkpoint
+	if err = os.Truncate(binlogPath, int64(checkpoint.Position)); err != nil {
+		return false, err
+	}
+	stream.file, err = os.OpenFile(binlogPath, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return false, err
+	}
+	stream.binlog = checkpoint.Binlog
+	stream.offset = int64(checkpoint.Position)
+	stream.boundary = stream.offset
+	stream.flushed = stream.offset
+	return true, nil
+}
+
+// run streams the binlogs reconnecting to the source on the connection errors,
+// the current binlog is flushed when ctx is done
+func (stream *binlogStream) run(ctx context.Context, syncerConfig replication.BinlogSyncerConfig) error {
+	defer func() {
+		if stream.file != nil {
+			utility.LoggedClose(stream.file, "")
+		}
+	}()
+	for {
+		err := stream.runSession(ctx, syncerConfig)
+		if ctx.Err() != nil {
+			tracelog.InfoLogger.Printf("Binlog stream is stopped, flushing %s", stream.binlog)
+			return stream.flush(context.WithoutCancel(ctx))
+		}
+		if _, ok := err.(binlogStreamError); ok {
+			return err
+		}
+		tracelog.ErrorLogger.Printf("Binlog stream failed, reconnecting in %v: %v", binlogStreamRetryInterval, err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(binlogStreamRetryInterval):
+		}
+		if err = stream.rewind(); err != nil {
+			return err
+		}
+	}
+}
+
+func (stream *binlogStream) runSession(ctx context.Context, syncerConfig replication.BinlogSyncerConfig) error {
+	syncer := replication.NewBinlogSyncer(syncerConfig)
+	defer syncer.Close()
+
+	var streamer *replication.BinlogStreamer
+	var err error
+	switch {
+	case !stream.gtidSet.IsEmpty():
+		streamer, err = syncer.StartSyncGTID(stream.gtidSet.Clone())
+	case stream.file != nil:
+		streamer, err = syncer.StartSync(mysql.Position{Name: stream.binlog, Pos: uint32(stream.offset)})
+	default:
+		streamer, err = syncer.StartSync(mysql.Position{Name: stream.nextBinlog, Pos: uint32(len(replication.BinLogFileHeader))})
+	}
+	if err != nil {
+		return err
+	}
+
+	for {
+		eventCtx, cancel := context.WithTimeout(ctx, stream.flushInterval)
+		event, err := streamer.GetEvent(eventCtx)
+		cancel()
+		if err != nil && (ctx.Err() != nil || !errors.Is(err, context.DeadlineExceeded)) {
+			return err
+		}
+		if event != nil {
+			if err = stream.handleEvent(ctx, event); err != nil {
+				return binlogStreamError{err}
+			}
+		}
+		if time.Since(stream.lastFlush) >= stream.flushInterval {
+			if err = stream.flush(ctx); err != nil {
+				return err
+			}
+			stream.lastFlush = time.Now()
+		}
+	}
+}
+
+// rewind drops the incomplete transaction before reconnecting, it is received again
+func (stream *binlogStream) rewind() error {
+	stream.pendingGTID = ""
+	stream.inTransaction = false
+	if stream.file == nil || stream.offset == stream.boundary {
+		return nil
+	}
+	stream.offset = stream.boundary
+	return stream.file.Truncate(stream.boundary)
+}
+
+func (stream *binlogStream) handleEvent(ctx context.Context, event *replication.BinlogEvent) error {
+	switch event.Header.EventType {
+	case replication.HEARTBEAT_EVENT, replication.HEARTBEAT_LOG_EVENT_V2:
+		return nil
+	case replication.FORMAT_DESCRIPTION_EVENT:
+		return stream.handleFormatDescription(event)
+	case replication.ROTATE_EVENT:
+		if event.Header.LogPos == 0 || event.Header.Flags&replication.LOG_EVENT_ARTIFICIAL_F != 0 {
+			return stream.handleFakeRotate(ctx, event)
+		}
+	}
+	// artificial events are sent on connect, the events up to offset are already streamed before reconnect
+	if event.Header.LogPos == 0 || event.Header.Flags&replication.LOG_EVENT_ARTIFICIAL_F != 0 ||
+		int64(event.Header.LogPos) <= stream.offset {
+		return nil
+	}
+	if stream.file == nil {
+		return fmt.Errorf("binlog event %s is received before the format description event", event.Header.EventType)
+	}
+	if start := int64(event.Header.LogPos) - int64(event.Header.EventSize); start != stream.offset {
+		return fmt.Errorf("binlog event %s at %d of %s is received, expected at %d",
+			event.Header.EventType, start, stream.binlog, stream.offset)
+	}
+	if err := stream.writeEvent(event); err != nil {
+		return err
+	}
+	if event.Header.EventType == replication.ROTATE_EVENT {
+		return stream.finish(ctx, string(event.Event.(*replication.RotateEvent).NextLogName))
+	}
+	return nil
+}
+
+func (stream *binlogStream) handleFormatDescription(event *replication.BinlogEvent) error {
+	stream.checksumLength = formatChecksumLength(event)
+	if stream.file != nil {
+		// the format description is sent again on reconnect
+		return nil
+	}
+	if stream.nextBinlog == "" {
+		return fmt.Errorf("format description event is received before the binlog name")
+	}
+	file, err := os.OpenFile(path.Join(stream.dstDir, stream.nextBinlog), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(replication.BinLogFileHeader); err != nil {
+		utility.LoggedClose(file, "")
+		return err
+	}
+	tracelog.InfoLogger.Printf("Streaming binlog %s", stream.nextBinlog)
+	stream.file = file
+	stream.binlog = stream.nextBinlog
+	stream.offset = int64(len(replication.BinLogFileHeader))
+	stream.boundary = stream.offset
+	stream.flushed = 0
+	return stream.writeEvent(event)
+}
+
+// handleFakeRotate handles the rotate sent on connect. It names the binlog the source streams from,
+// the current binlog is finished if the source moves on: the binlog ends without rotate after the source restart.
+func (stream *binlogStream) handleFakeRotate(ctx context.Context, event *replication.BinlogEvent) error {
+	next := string(event.Event.(*replication.RotateEvent).NextLogName)
+	if stream.file != nil && next != stream.binlog {
+		if BinlogPrefix(next) == BinlogPrefix(stream.binlog) && BinlogNum(next) < BinlogNum(stream.binlog) {
+			return fmt.Errorf("source streams binlog %s preceding the streamed binlog %s", next, stream.binlog)
+		}
+		tracelog.WarningLogger.Printf("Binlog %s ends without rotate, the source streams %s", stream.binlog, next)
+		if err := stream.finish(ctx, next); err != nil {
+			return err
+		}
+	}
+	stream.nextBinlog = next
+	return nil
+}
+
+func (stream *binlogStream) writeEvent(event *replication.BinlogEvent) error {
+	switch event.Header.EventType {
+	case replication.GTID_EVENT, replication.GTID_TAGGED_LOG_EVENT, replication.ANONYMOUS_GTID_EVENT,
+		replication.MARIADB_GTID_EVENT:
+		stream.completeTransaction()
+		gtid, err := stream.eventGTID(event)
+		if err != nil {
+			return err
+		}
+		stream.pendingGTID = gtid
+		stream.inTransaction = true
+	case replication.PREVIOUS_GTIDS_EVENT, replication.MARIADB_GTID_LIST_EVENT:
+		if err := stream.addPreviousGTIDs(event); err != nil {
+			return err
+		}
+	}
+	if _, err := stream.file.Write(event.RawData); err != nil {
+		return err
+	}
+	stream.offset += int64(len(event.RawData))
+	if event.Header.EventType == replication.XID_EVENT || event.Header.EventType == replication.XA_PREPARE_LOG_EVENT {
+		stream.completeTransaction()
+	}
+	if !stream.inTransaction {
+		stream.boundary = stream.offset
+	}
+	return nil
+}
+
+// completeTransaction marks the transaction received so far as complete
+func (stream *binlogStream) completeTransaction() {
+	if stream.pendingGTID != "" {
+		if err := stream.gtidSet.Update(stream.pendingGTID); err != nil {
+			tracelog.WarningLogger.Printf("Failed to add GTID %s to the streamed set: %v", stream.pendingGTID, err)
+		}
+	}
+	stream.pendingGTID = ""
+	stream.inTransaction = false
+	stream.boundary = stream.offset
+}
+
+// eventGTID returns the GTID of the transaction started by the event, it's empty for anonymous transactions
+func (stream *binlogStream) eventGTID(event *replication.BinlogEvent) (string, error) {
+	data := event.RawData[replication.EventHeaderSize : len(event.RawData)-stream.checksumLength]
+	switch event.Header.EventType {
+	case replication.GTID_EVENT, replication.GTID_TAGGED_LOG_EVENT:
+		gtidEvent, err := decodeMysqlGTIDEvent(event.Header.EventType, data)
+		if err != nil {
+			return "", err
+		}
+		sid, err := uuid.FromBytes(gtidEvent.SID)
+		if err != nil {
+			return "", err
+		}
+		if gtidEvent.Tag == mysql.NewTag("") {
+			return fmt.Sprintf("%s:%d", sid, gtidEvent.GNO), nil
+		}
+		return fmt.Sprintf("%s:%s:%d", sid, gtidEvent.Tag, gtidEvent.GNO), nil
+	case replication.MARIADB_GTID_EVENT:
+		gtidEvent := &replication.MariadbGTIDEvent{}
+		if err := gtidEvent.Decode(data); err != nil {
+			return "", err
+		}
+		gtidEvent.GTID.ServerID = event.Header.ServerID
+		return gtidEvent.GTID.String(), nil
+	}
+	return "", nil
+}
+
+// addPreviousGTIDs adds the transactions preceding the binlog to the streamed set
+func (stream *binlogStream) addPreviousGTIDs(event *replication.BinlogEvent) error {
+	data := event.RawData[replication.EventHeaderSize : len(event.RawData)-stream.checksumLength]
+	if event.Header.EventType == replication.PREVIOUS_GTIDS_EVENT {
+		previousEvent := &replication.PreviousGTIDsEvent{}
+		if err := previousEvent.Decode(data); err != nil {
+			return err
+		}
+		if previousEvent.GTIDSets == "" {
+			return nil
+		}
+		return stream.gtidSet.Update(previousEvent.GTIDSets)
+	}
+	listEvent := &replication.MariadbGTIDListEvent{}
+	if err := listEvent.Decode(data); err != nil {
+		return err
+	}
+	// MariaDB set holds the last position of each domain, the streamed positions are not moved back
+	gtidSet := stream.gtidSet.(*mysql.MariadbGTIDSet)
+	for i := range listEvent.GTIDs {
+		if _, ok := gtidSet.Sets[listEvent.GTIDs[i].DomainID]; !ok {
+			if err := gtidSet.AddSet(&listEvent.GTIDs[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// flush uploads the current binlog up to the last complete transaction as a partial object and saves the checkpoint
+func (stream *binlogStream) flush(ctx context.Context) error {
+	if stream.file == nil || stream.boundary == stream.flushed {
+		return nil
+	}
+	file, err := os.Open(stream.file.Name())
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(file, "")
+	compressed := internal.CompressAndEncrypt(io.LimitReader(file, stream.boundary),
+		stream.uploader.Compression(), internal.ConfigureCrypter())
+	partialPath := BinlogPartialPath + utility.AddFileExtension(stream.binlog, stream.uploader.Compression().FileExtension())
+	if err = stream.uploader.Upload(ctx, partialPath, compressed); err != nil {
+		return fmt.Errorf("failed to upload partial binlog %s: %w", stream.binlog, err)
+	}
+	if err = stream.uploadCheckpoint(ctx, stream.binlog, stream.boundary); err != nil {
+		return err
+	}
+	tracelog.DebugLogger.Printf("Flushed %s up to %d", stream.binlog, stream.boundary)
+	stream.flushed = stream.boundary
+	return nil
+}
+
+// finish uploads the current binlog as a finished one and removes its partial object
+func (stream *binlogStream) finish(ctx context.Context, nextBinlog string) error {
+	stream.completeTransaction()
+	binlog, binlogPath := stream.binlog, stream.file.Name()
+	if err := stream.file.Close(); err != nil {
+		return err
+	}
+	stream.file = nil
+
+	err := archiveBinLog(ctx, stream.uploader, stream.dstDir, binlog)
+	if err != nil {
+		return err
+	}
+	if !stream.gtidSet.IsEmpty() {
+		err = UploadBinlogSentinel(ctx, stream.rootFolder, &BinlogSentinelDto{GTIDArchived: stream.gtidSet.String()})
+		if err != nil {
+			return err
+		}
+	}
+	if err = stream.uploadCheckpoint(ctx, nextBinlog, int64(len(replication.BinLogFileHeader))); err != nil {
+		return err
+	}
+	if err = stream.deletePartials(ctx); err != nil {
+		return err
+	}
+	stream.binlog = ""
+	stream.nextBinlog = nextBinlog
+	stream.offset, stream.boundary, stream.flushed = 0, 0, 0
+	return os.Remove(binlogPath)
+}
+
+// deletePartials removes the partial objects, the checkpoint never refers to them when they are deleted
+func (stream *binlogStream) deletePartials(ctx context.Context) error {
+	partials, _, err := stream.partialFolder.ListFolder(ctx)
+	if err != nil {
+		return err
+	}
+	return stream.partialFolder.DeleteObjects(ctx, partials)
+}
+
+func (stream *binlogStream) uploadCheckpoint(ctx context.Context, binlog string, position int64) error {
+	checkpoint := BinlogStreamCheckpoint{
+		Binlog:   binlog,
+		Position: uint32(position),
+		GTIDSet:  stream.gtidSet.String(),
+		Flavor:   stream.flavor,
+	}
+	return uploadBinlogStreamCheckpoint(ctx, stream.rootFolder, &checkpoint)
+}
+
+func fetchBinlogStreamCheckpoint(ctx context.Context, folder storage.Folder, checkpoint *BinlogStreamCheckpoint) error {
+	reader, err := folder.ReadObject(ctx, BinlogStreamCheckpointPath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return json.NewDecoder(reader).Decode(checkpoint)
+}
+
+func uploadBinlogStreamCheckpoint(ctx context.Context, folder storage.Folder, checkpoint *BinlogStreamCheckpoint) error {
+	body, err := json.Marshal(checkpoint)
+	if err != nil {
+		return internal.NewSentinelMarshallingError(BinlogStreamCheckpointPath, err)
+	}
+	return folder.PutObject(ctx, BinlogStreamCheckpointPath, bytes.NewReader(body))
+}
+
+// getStreamStartBinlog returns the first binlog of the source following the last uploaded binlog
+func getStreamStartBinlog(ctx context.Context, conn *client.Conn, rootFolder storage.Folder) (string, error) {
+	result, err := conn.Execute("SHOW BINARY LOGS")
+	if err != nil {
+		return "", fmt.Errorf("failed to list binary logs: %w", err)
+	}
+	defer result.Close()
+	if result.RowNumber() == 0 {
+		return "", fmt.Errorf("binary logging is not enabled on the source")
+	}
+	lastUploaded, err := getLastUploadedBinlog(ctx, rootFolder)
+	if err != nil {
+		return "", err
+	}
+	for row := 0; row < result.RowNumber(); row++ {
+		binlog, err := result.GetString(row, 0)
+		if err != nil {
+			return "", err
+		}
+		if lastUploaded == "" || BinlogPrefix(binlog) != BinlogPrefix(lastUploaded) ||
+			BinlogNum(binlog) > BinlogNum(lastUploaded) {
+			return binlog, nil
+		}
+	}
+	return result.GetString(result.RowNumber()-1, 0)
+}
+
+// getBinlogSyncerConfig builds the replica connection settings from WALG_MYSQL_DATASOURCE_NAME
+func getBinlogSyncerConfig(serverID uint32, flavor string) (replication.BinlogSyncerConfig, error) {
+	datasourceName, err := conf.GetRequiredSetting(conf.MysqlDatasourceNameSetting)
+	if err != nil {
+		return replication.BinlogSyncerConfig{}, err
+	}
+	dsn, err := parseMySQLDatasource(datasourceName)
+	if err != nil {
+		return replication.BinlogSyncerConfig{}, err
+	}
+	host, _, err := net.SplitHostPort(dsn.addr)
+	if err != nil {
+		host = dsn.addr // unix socket
+	}
+	caFile, _ := conf.GetSetting(conf.MysqlSslCaSetting)
+	tlsConfig, _, err := dsn.resolveTLS(host, caFile)
+	if err != nil {
+		return replication.BinlogSyncerConfig{}, err
+	}
+	network := dsn.network
+	return replication.BinlogSyncerConfig{
+		ServerID:        serverID,
+		Flavor:          flavor,
+		Host:            dsn.addr,
+		User:            dsn.user,
+		Password:        dsn.password,
+		TLSConfig:       tlsConfig,
+		RawModeEnabled:  true,
+		HeartbeatPeriod: binlogStreamHeartbeatPeriod,
+		ReadTimeout:     3 * binlogStreamHeartbeatPeriod,
+		// reconnects are done by binlogStream, it drops the incomplete transaction first
+		DisableRetrySync: true,
+		Dialer: func(ctx context.Context, _, address string) (net.Conn, error) {
+			dialer := net.Dialer{}
+			return dialer.DialContext(ctx, network, address)
+		},
+	}, nil
+}
@@ -0,0 +1,189 @@
+package mysql
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+func newTestBinlogStream(t *testing.T, rootFolder storage.Folder) *binlogStream {
+	uploader := internal.NewRegularUploader(compression.Compressors[lz4.AlgorithmName], rootFolder)
+	uploader.ChangeDirectory(BinlogPath)
+	gtidSet, err := mysql.ParseGTIDSet(mysql.MySQLFlavor, "")
+	require.NoError(t, err)
+	return &binlogStream{
+		uploader:      uploader,
+		rootFolder:    rootFolder,
+		partialFolder: rootFolder.GetSubFolder(BinlogPath).GetSubFolder(BinlogPartialPath),
+		dstDir:        t.TempDir(),
+		flavor:        mysql.MySQLFlavor,
+		gtidSet:       gtidSet,
+	}
+}
+
+func (writer *testBinlogWriter) addRotate(nextBinlog string) int64 {
+	body := binary.LittleEndian.AppendUint64(nil, uint64(len(replication.BinLogFileHeader)))
+	return writer.addEvent(replication.ROTATE_EVENT, append(body, nextBinlog...))
+}
+
+// events parses the written binlog the way the replication stream delivers it
+func (writer *testBinlogWriter) events(name string) []*replication.BinlogEvent {
+	fakeRotate := &replication.BinlogEvent{
+		Header: &replication.EventHeader{EventType: replication.ROTATE_EVENT, Flags: replication.LOG_EVENT_ARTIFICIAL_F},
+		Event:  &replication.RotateEvent{Position: uint64(len(replication.BinLogFileHeader)), NextLogName: []byte(name)},
+	}
+	events := []*replication.BinlogEvent{fakeRotate}
+	parser := replication.NewBinlogParser()
+	parser.SetRawMode(true)
+	err := parser.ParseFile(writer.write(name), 0, func(event *replication.BinlogEvent) error {
+		event.RawData = append([]byte{}, event.RawData...)
+		events = append(events, event)
+		return nil
+	})
+	require.NoError(writer.t, err)
+	return events
+}
+
+func readStreamCheckpoint(t *testing.T, rootFolder storage.Folder) BinlogStreamCheckpoint {
+	var checkpoint BinlogStreamCheckpoint
+	require.NoError(t, fetchBinlogStreamCheckpoint(context.Background(), rootFolder, &checkpoint))
+	return checkpoint
+}
+
+func downloadStreamed(t *testing.T, folder storage.Folder, binlog string) []byte {
+	binlogPath := filepath.Join(t.TempDir(), binlog)
+	require.NoError(t, internal.DownloadFileTo(context.Background(), internal.NewFolderReader(folder), binlog, binlogPath))
+	data, err := os.ReadFile(binlogPath)
+	require.NoError(t, err)
+	return data
+}
+
+func TestBinlogStream_FlushAndFinish(t *testing.T) {
+	ctx := context.Background()
+	writer := newTestBinlogWriter(t)
+	writer.addMysqlTransaction(testStopSourceUUID, 1)
+	second := writer.addMysqlTransaction(testStopSourceUUID, 2)
+	writer.addRotate("mysql-bin.000002")
+	events := writer.events("mysql-bin.000001")
+
+	rootFolder := memory.NewFolder("", memory.NewKVS())
+	stream := newTestBinlogStream(t, rootFolder)
+	// the second transaction is incomplete
+	for _, event := range events[:len(events)-2] {
+		require.NoError(t, stream.handleEvent(ctx, event))
+	}
+	require.NoError(t, stream.flush(ctx))
+
+	partial := downloadStreamed(t, stream.partialFolder, "mysql-bin.000001")
+	assert.Equal(t, writer.data[:second], partial)
+	assert.Equal(t, BinlogStreamCheckpoint{
+		Binlog:   "mysql-bin.000001",
+		Position: uint32(second),
+		GTIDSet:  testStopSourceUUID + ":1",
+		Flavor:   mysql.MySQLFlavor,
+	}, readStreamCheckpoint(t, rootFolder))
+
+	for _, event := range events[len(events)-2:] {
+		require.NoError(t, stream.handleEvent(ctx, event))
+	}
+	finished := downloadStreamed(t, rootFolder.GetSubFolder(BinlogPath), "mysql-bin.000001")
+	assert.Equal(t, writer.data, finished)
+	partials, _, err := stream.partialFolder.ListFolder(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, partials)
+	assert.Equal(t, BinlogStreamCheckpoint{
+		Binlog:   "mysql-bin.000002",
+		Position: uint32(len(replication.BinLogFileHeader)),
+		GTIDSet:  testStopSourceUUID + ":1-2",
+		Flavor:   mysql.MySQLFlavor,
+	}, readStreamCheckpoint(t, rootFolder))
+
+	var sentinel BinlogSentinelDto
+	require.NoError(t, FetchBinlogSentinel(ctx, rootFolder, &sentinel))
+	assert.Equal(t, testStopSourceUUID+":1-2", sentinel.GTIDArchived)
+}
+
+func TestBinlogStream_Resume(t *testing.T) {
+	ctx := context.Background()
+	writer := newTestBinlogWriter(t)
+	writer.addMysqlTransaction(testStopSourceUUID, 1)
+	second := writer.addMysqlTransaction(testStopSourceUUID, 2)
+	writer.addMysqlTransaction(testStopSourceUUID, 3)
+	writer.addRotate("mysql-bin.000002")
+	events := writer.events("mysql-bin.000001")
+
+	rootFolder := memory.NewFolder("", memory.NewKVS())
+	stream := newTestBinlogStream(t, rootFolder)
+	for _, event := range events[:len(events)-5] {
+		require.NoError(t, stream.handleEvent(ctx, event))
+	}
+	require.NoError(t, stream.flush(ctx))
+	require.NoError(t, stream.file.Close())
+
+	resumed := newTestBinlogStream(t, rootFolder)
+	ok, err := resumed.resume(ctx)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "mysql-bin.000001", resumed.binlog)
+	assert.Equal(t, second, resumed.offset)
+	assert.Equal(t, testStopSourceUUID+":1", resumed.gtidSet.String())
+
+	// the source streams the binlog from its start again, the streamed events are skipped
+	for _, event := range events {
+		require.NoError(t, resumed.handleEvent(ctx, event))
+	}
+	finished := downloadStreamed(t, rootFolder.GetSubFolder(BinlogPath), "mysql-bin.000001")
+	assert.Equal(t, writer.data, finished)
+	assert.Equal(t, testStopSourceUUID+":1-3", readStreamCheckpoint(t, rootFolder).GTIDSet)
+}
+
+func TestBinlogStream_Rewind(t *testing.T) {
+	ctx := context.Background()
+	writer := newTestBinlogWriter(t)
+	writer.addMysqlTransaction(testStopSourceUUID, 1)
+	second := writer.addMysqlTransaction(testStopSourceUUID, 2)
+	events := writer.events("mysql-bin.000001")
+
+	stream := newTestBinlogStream(t, memory.NewFolder("", memory.NewKVS()))
+	for _, event := range events[:len(events)-1] {
+		require.NoError(t, stream.handleEvent(ctx, event))
+	}
+	require.NoError(t, stream.rewind())
+	assert.Equal(t, second, stream.offset)
+	info, err := stream.file.Stat()
+	require.NoError(t, err)
+	assert.Equal(t, second, info.Size())
+
+	// the incomplete transaction is received again after reconnect
+	for _, event := range events[len(events)-3:] {
+		require.NoError(t, stream.handleEvent(ctx, event))
+	}
+	assert.Equal(t, int64(len(writer.data)), stream.boundary)
+	assert.Equal(t, testStopSourceUUID+":1-2", stream.gtidSet.String())
+}
+
+func TestBinlogStream_Gap(t *testing.T) {
+	ctx := context.Background()
+	writer := newTestBinlogWriter(t)
+	writer.addMysqlTransaction(testStopSourceUUID, 1)
+	writer.addMysqlTransaction(testStopSourceUUID, 2)
+	events := writer.events("mysql-bin.000001")
+
+	stream := newTestBinlogStream(t, memory.NewFolder("", memory.NewKVS()))
+	for _, event := range events[:3] {
+		require.NoError(t, stream.handleEvent(ctx, event))
+	}
+	assert.Error(t, stream.handleEvent(ctx, events[len(events)-1]))
+}
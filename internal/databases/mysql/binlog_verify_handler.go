@@ -0,0 +1,306 @@
+package mysql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+type BinlogVerifyStatus int
+
+const (
+	BinlogVerifyStatusOk BinlogVerifyStatus = iota + 1
+	BinlogVerifyStatusWarning
+	BinlogVerifyStatusFailure
+)
+
+func (status BinlogVerifyStatus) String() string {
+	return [...]string{"", "OK", "WARNING", "FAILURE"}[status]
+}
+
+// MarshalText marshals the BinlogVerifyStatus enum as a string
+func (status BinlogVerifyStatus) MarshalText() ([]byte, error) {
+	return utility.MarshalEnumToString(status)
+}
+
+// BinlogVerifyResult is the report of binlog-verify, each check has the worst status of its findings
+type BinlogVerifyResult struct {
+	Integrity      BinlogIntegrityResult      `json:"integrity"`
+	BackupCoverage BinlogBackupCoverageResult `json:"backup-coverage"`
+}
+
+// BinlogIntegrityResult describes the sequence of binlogs in storage (in the upload order)
+type BinlogIntegrityResult struct {
+	Status         BinlogVerifyStatus `json:"status"`
+	BinlogCount    int                `json:"binlog_count"`
+	FirstBinlog    string             `json:"first_binlog,omitempty"`
+	LastBinlog     string             `json:"last_binlog,omitempty"`
+	LastBinlogTime *time.Time         `json:"last_binlog_time,omitempty"`
+	Problems       []BinlogProblem    `json:"problems,omitempty"`
+}
+
+// BinlogProblem is found between PreviousBinlog and Binlog
+type BinlogProblem struct {
+	Status         BinlogVerifyStatus `json:"status"`
+	Binlog         string             `json:"binlog"`
+	PreviousBinlog string             `json:"previous_binlog,omitempty"`
+	Description    string             `json:"description"`
+}
+
+type BinlogBackupCoverageResult struct {
+	Status BinlogVerifyStatus `json:"status"`
+	// ArchiveLag is reported when the last binlog is older than the allowed lag
+	ArchiveLag string                 `json:"archive_lag,omitempty"`
+	Backups    []BinlogBackupCoverage `json:"backups"`
+}
+
+// BinlogBackupCoverage tells whether binlogs from the backup binlog position to the last binlog can be replayed
+type BinlogBackupCoverage struct {
+	BackupName  string             `json:"backup_name"`
+	Status      BinlogVerifyStatus `json:"status"`
+	BinlogStart string             `json:"binlog_start,omitempty"`
+	Description string             `json:"description,omitempty"`
+}
+
+type verifiedBinlog struct {
+	name          string
+	prefix        string
+	number        int
+	modified      time.Time
+	previousGTIDs gomysql.GTIDSet
+}
+
+type verifiedBackup struct {
+	name        string
+	binlogStart string
+	flavor      string
+}
+
+// HandleBinlogVerify checks that binlogs in storage have no gaps in numbering and Previous_gtids,
+// and that each backup can be rolled forward to the last binlog
+func HandleBinlogVerify(ctx context.Context, folder storage.Folder, maxLag time.Duration, output io.Writer) {
+	backups, err := getVerifiedBackups(ctx, folder)
+	tracelog.ErrorLogger.FatalfOnError("Failed to list backups: %v", err)
+
+	flavor := gomysql.MySQLFlavor
+	if len(backups) > 0 {
+		flavor = backups[len(backups)-1].flavor
+	}
+	binlogs, problems, err := getVerifiedBinlogs(ctx, folder.GetSubFolder(BinlogPath), flavor)
+	tracelog.ErrorLogger.FatalfOnError("Failed to list binlogs: %v", err)
+
+	result := BinlogVerifyResult{Integrity: verifyBinlogIntegrity(binlogs, problems)}
+	result.BackupCoverage = verifyBinlogBackupCoverage(backups, binlogs, result.Integrity.Problems, maxLag, time.Now())
+
+	encoder := json.NewEncoder(output)
+	encoder.SetIndent("", "    ")
+	err = encoder.Encode(result)
+	tracelog.ErrorLogger.FatalOnError(err)
+}
+
+func getVerifiedBackups(ctx context.Context, folder storage.Folder) ([]verifiedBackup, error) {
+	backupTimes, err := internal.GetBackups(ctx, folder.GetSubFolder(utility.BaseBackupPath))
+	if _, ok := err.(internal.NoBackupsFoundError); ok {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	internal.SortBackupTimeSlices(backupTimes)
+
+	backups := make([]verifiedBackup, 0, len(backupTimes))
+	for _, backupTime := range backupTimes {
+		backup, err := internal.NewBackupInStorage(ctx, folder.GetSubFolder(utility.BaseBackupPath),
+			backupTime.BackupName, backupTime.StorageName)
+		if err != nil {
+			return nil, err
+		}
+		var sentinel StreamSentinelDto
+		if err = backup.FetchSentinel(ctx, &sentinel); err != nil {
+			return nil, fmt.Errorf("failed to fetch sentinel of %s: %w", backup.Name, err)
+		}
+		flavor := gomysql.MySQLFlavor
+		if strings.Contains(sentinel.ServerVersion, "MariaDB") {
+			flavor = gomysql.MariaDBFlavor
+		}
+		backups = append(backups, verifiedBackup{name: backup.Name, binlogStart: sentinel.BinLogStart, flavor: flavor})
+	}
+	return backups, nil
+}
+
+// getVerifiedBinlogs lists binlogs in the upload order and reads their Previous_gtids,
+// unreadable binlogs are reported as problems
+func getVerifiedBinlogs(ctx context.Context, folder storage.Folder, flavor string) ([]verifiedBinlog, []BinlogProblem, error) {
+	objects, _, err := folder.ListFolder(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	slices.SortStableFunc(objects, func(a, b storage.Object) int {
+		return a.GetLastModified().Compare(b.GetLastModified())
+	})
+
+	binlogs := make([]verifiedBinlog, 0, len(objects))
+	var problems []BinlogProblem
+	for _, object := range objects {
+		name := utility.TrimFileExtension(object.GetName())
+		prefix, number, ok := parseBinlogName(name)
+		if !ok {
+			problems = append(problems, BinlogProblem{Status: BinlogVerifyStatusWarning, Binlog: object.GetName(),
+				Description: "unexpected object in the binlog folder"})
+			continue
+		}
+		previousGTIDs, err := GetBinlogPreviousGTIDsRemote(ctx, folder, object.GetName(), flavor)
+		if err != nil {
+			problems = append(problems, BinlogProblem{Status: BinlogVerifyStatusFailure, Binlog: name,
+				Description: fmt.Sprintf("cannot read Previous_gtids: %v", err)})
+		}
+		binlogs = append(binlogs, verifiedBinlog{
+			name:          name,
+			prefix:        prefix,
+			number:        number,
+			modified:      object.GetLastModified(),
+			previousGTIDs: previousGTIDs,
+		})
+	}
+	return binlogs, problems, nil
+}
+
+// parseBinlogName splits 'mysql-bin.000042' into basename and sequence number
+func parseBinlogName(name string) (string, int, bool) {
+	p := strings.LastIndex(name, ".")
+	if p <= 0 {
+		return "", 0, false
+	}
+	number, err := strconv.Atoi(name[p+1:])
+	if err != nil || number < 0 {
+		return "", 0, false
+	}
+	return name[:p], number, true
+}
+
+func verifyBinlogIntegrity(binlogs []verifiedBinlog, problems []BinlogProblem) BinlogIntegrityResult {
+	result := BinlogIntegrityResult{BinlogCount: len(binlogs), Problems: problems}
+	if len(binlogs) == 0 {
+		result.Problems = append(result.Problems, BinlogProblem{Status: BinlogVerifyStatusWarning,
+			Description: "no binlogs in storage"})
+	} else {
+		last := binlogs[len(binlogs)-1]
+		result.FirstBinlog = binlogs[0].name
+		result.LastBinlog = last.name
+		result.LastBinlogTime = &last.modified
+	}
+	for i := 1; i < len(binlogs); i++ {
+		if problem, ok := checkBinlogSequence(binlogs[i-1], binlogs[i]); ok {
+			result.Problems = append(result.Problems, problem)
+		}
+		if problem, ok := checkBinlogGTIDs(binlogs[i-1], binlogs[i]); ok {
+			result.Problems = append(result.Problems, problem)
+		}
+	}
+	result.Status = BinlogVerifyStatusOk
+	for _, problem := range result.Problems {
+		result.Status = max(result.Status, problem.Status)
+	}
+	return result
+}
+
+func checkBinlogSequence(previous, current verifiedBinlog) (BinlogProblem, bool) {
+	problem := BinlogProblem{Binlog: current.name, PreviousBinlog: previous.name}
+	switch {
+	case previous.prefix != current.prefix:
+		problem.Status = BinlogVerifyStatusWarning
+		problem.Description = "binlog basename changed"
+	case current.number == previous.number+1:
+		return BinlogProblem{}, false
+	case current.number > previous.number+1:
+		problem.Status = BinlogVerifyStatusFailure
+		problem.Description = fmt.Sprintf("%d binlogs are missing", current.number-previous.number-1)
+	default:
+		problem.Status = BinlogVerifyStatusWarning
+		problem.Description = "binlog numbering restarted"
+	}
+	return problem, true
+}
+
+// checkBinlogGTIDs tests that all transactions before the previous binlog are before the current binlog too
+func checkBinlogGTIDs(previous, current verifiedBinlog) (BinlogProblem, bool) {
+	if previous.previousGTIDs == nil || current.previousGTIDs == nil || current.previousGTIDs.Contain(previous.previousGTIDs) {
+		return BinlogProblem{}, false
+	}
+	return BinlogProblem{
+		Status:         BinlogVerifyStatusFailure,
+		Binlog:         current.name,
+		PreviousBinlog: previous.name,
+		Description: fmt.Sprintf("Previous_gtids '%s' doesn't contain Previous_gtids '%s' of the previous binlog",
+			current.previousGTIDs, previous.previousGTIDs),
+	}, true
+}
+
+func verifyBinlogBackupCoverage(backups []verifiedBackup, binlogs []verifiedBinlog, problems []BinlogProblem,
+	maxLag time.Duration, now time.Time) BinlogBackupCoverageResult {
+	result := BinlogBackupCoverageResult{Status: BinlogVerifyStatusOk, Backups: make([]BinlogBackupCoverage, 0, len(backups))}
+	// index of the last binlog that can't be reached from the previous binlogs
+	brokenAt := -1
+	for i, binlog := range binlogs {
+		for _, problem := range problems {
+			if problem.Status == BinlogVerifyStatusFailure && problem.Binlog == binlog.name {
+				brokenAt = i
+			}
+		}
+	}
+	for _, backup := range backups {
+		coverage := checkBackupCoverage(backup, binlogs, brokenAt)
+		result.Backups = append(result.Backups, coverage)
+		result.Status = max(result.Status, coverage.Status)
+	}
+	if maxLag > 0 && len(binlogs) > 0 {
+		if lag := now.Sub(binlogs[len(binlogs)-1].modified); lag > maxLag {
+			result.ArchiveLag = lag.Truncate(time.Second).String()
+			result.Status = max(result.Status, BinlogVerifyStatusWarning)
+		}
+	}
+	return result
+}
+
+func checkBackupCoverage(backup verifiedBackup, binlogs []verifiedBinlog, brokenAt int) BinlogBackupCoverage {
+	coverage := BinlogBackupCoverage{BackupName: backup.name, BinlogStart: backup.binlogStart, Status: BinlogVerifyStatusOk}
+	if backup.binlogStart == "" {
+		coverage.Status = BinlogVerifyStatusWarning
+		coverage.Description = "backup has no binlog position"
+		return coverage
+	}
+	if len(binlogs) == 0 {
+		coverage.Status = BinlogVerifyStatusWarning
+		coverage.Description = "no binlogs in storage"
+		return coverage
+	}
+	start := slices.IndexFunc(binlogs, func(binlog verifiedBinlog) bool { return binlog.name == backup.binlogStart })
+	if start < 0 {
+		// the backup started in the binlog that isn't archived yet
+		last := binlogs[len(binlogs)-1]
+		prefix, number, ok := parseBinlogName(backup.binlogStart)
+		if ok && prefix == last.prefix && number == last.number+1 {
+			coverage.Description = "binlog isn't archived yet"
+			return coverage
+		}
+		coverage.Status = BinlogVerifyStatusFailure
+		coverage.Description = "binlog is missing in storage"
+		return coverage
+	}
+	if brokenAt > start {
+		coverage.Status = BinlogVerifyStatusFailure
+		coverage.Description = fmt.Sprintf("binlogs can't be replayed past %s", binlogs[brokenAt-1].name)
+	}
+	return coverage
+}
@@ -0,0 +1,105 @@
+package mysql
+
+import (
+	"testing"
+	"time"
+
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testVerifyUUID = "6abc8ecb-bf5c-11e9-9821-c897993b5a14"
+
+func newVerifiedBinlog(t *testing.T, name string, modified time.Time, gtids string) verifiedBinlog {
+	prefix, number, ok := parseBinlogName(name)
+	require.True(t, ok)
+	previousGTIDs, err := gomysql.ParseMysqlGTIDSet(gtids)
+	require.NoError(t, err)
+	return verifiedBinlog{name: name, prefix: prefix, number: number, modified: modified, previousGTIDs: previousGTIDs}
+}
+
+func TestParseBinlogName(t *testing.T) {
+	prefix, number, ok := parseBinlogName("mysql-bin.000042")
+	assert.True(t, ok)
+	assert.Equal(t, "mysql-bin", prefix)
+	assert.Equal(t, 42, number)
+	prefix, number, ok = parseBinlogName("host.example.com-bin.1000000")
+	assert.True(t, ok)
+	assert.Equal(t, "host.example.com-bin", prefix)
+	assert.Equal(t, 1000000, number)
+	_, _, ok = parseBinlogName("mysql-bin")
+	assert.False(t, ok)
+	_, _, ok = parseBinlogName("mysql-bin.index")
+	assert.False(t, ok)
+}
+
+func TestVerifyBinlogs(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	binlogs := []verifiedBinlog{
+		newVerifiedBinlog(t, "mysql-bin.000001", ts, testVerifyUUID+":1-10"),
+		newVerifiedBinlog(t, "mysql-bin.000002", ts.Add(time.Minute), testVerifyUUID+":1-20"),
+		newVerifiedBinlog(t, "mysql-bin.000003", ts.Add(2*time.Minute), testVerifyUUID+":1-30"),
+		newVerifiedBinlog(t, "mysql-bin.000004", ts.Add(3*time.Minute), testVerifyUUID+":1-40"),
+	}
+	backups := []verifiedBackup{
+		{name: "stream_1", binlogStart: "mysql-bin.000001"},
+		{name: "stream_2", binlogStart: "mysql-bin.000003"},
+		{name: "stream_3", binlogStart: "mysql-bin.000005"},
+		{name: "stream_4"},
+	}
+
+	integrity := verifyBinlogIntegrity(binlogs, nil)
+	assert.Equal(t, BinlogVerifyStatusOk, integrity.Status)
+	assert.Equal(t, 4, integrity.BinlogCount)
+	assert.Equal(t, "mysql-bin.000001", integrity.FirstBinlog)
+	assert.Equal(t, "mysql-bin.000004", integrity.LastBinlog)
+	assert.Empty(t, integrity.Problems)
+
+	coverage := verifyBinlogBackupCoverage(backups, binlogs, integrity.Problems, time.Hour, ts.Add(2*time.Hour))
+	assert.Equal(t, BinlogVerifyStatusWarning, coverage.Status)
+	assert.Equal(t, "1h57m0s", coverage.ArchiveLag)
+	assert.Equal(t, []BinlogVerifyStatus{BinlogVerifyStatusOk, BinlogVerifyStatusOk, BinlogVerifyStatusOk,
+		BinlogVerifyStatusWarning}, coverageStatuses(coverage))
+
+	// mysql-bin.000002 is missing and mysql-bin.000003 has lost transactions
+	broken := []verifiedBinlog{binlogs[0], binlogs[2], newVerifiedBinlog(t, "mysql-bin.000004", ts, testVerifyUUID+":1-25")}
+	integrity = verifyBinlogIntegrity(broken, nil)
+	assert.Equal(t, BinlogVerifyStatusFailure, integrity.Status)
+	assert.Equal(t, []BinlogProblem{
+		{Status: BinlogVerifyStatusFailure, Binlog: "mysql-bin.000003", PreviousBinlog: "mysql-bin.000001",
+			Description: "1 binlogs are missing"},
+		{Status: BinlogVerifyStatusFailure, Binlog: "mysql-bin.000004", PreviousBinlog: "mysql-bin.000003",
+			Description: "Previous_gtids '" + testVerifyUUID + ":1-25' doesn't contain Previous_gtids '" +
+				testVerifyUUID + ":1-30' of the previous binlog"},
+	}, integrity.Problems)
+
+	coverage = verifyBinlogBackupCoverage(backups, broken, integrity.Problems, 0, ts)
+	assert.Equal(t, BinlogVerifyStatusFailure, coverage.Status)
+	assert.Empty(t, coverage.ArchiveLag)
+	assert.Equal(t, []BinlogVerifyStatus{BinlogVerifyStatusFailure, BinlogVerifyStatusFailure, BinlogVerifyStatusOk,
+		BinlogVerifyStatusWarning}, coverageStatuses(coverage))
+	assert.Equal(t, "binlogs can't be replayed past mysql-bin.000003", coverage.Backups[1].Description)
+
+	// backups older than the first binlog in storage can't be rolled forward
+	integrity = verifyBinlogIntegrity(binlogs[2:], nil)
+	coverage = verifyBinlogBackupCoverage(backups[:2], binlogs[2:], integrity.Problems, 0, ts)
+	assert.Equal(t, []BinlogVerifyStatus{BinlogVerifyStatusFailure, BinlogVerifyStatusOk}, coverageStatuses(coverage))
+
+	// numbering restart after RESET MASTER
+	restarted := append(binlogs[:2:2], newVerifiedBinlog(t, "mysql-bin.000001", ts.Add(time.Hour), testVerifyUUID+":1-20"))
+	integrity = verifyBinlogIntegrity(restarted, nil)
+	assert.Equal(t, BinlogVerifyStatusWarning, integrity.Status)
+	assert.Equal(t, "binlog numbering restarted", integrity.Problems[0].Description)
+
+	integrity = verifyBinlogIntegrity(nil, nil)
+	assert.Equal(t, BinlogVerifyStatusWarning, integrity.Status)
+}
+
+func coverageStatuses(coverage BinlogBackupCoverageResult) []BinlogVerifyStatus {
+	statuses := make([]BinlogVerifyStatus, 0, len(coverage.Backups))
+	for _, backup := range coverage.Backups {
+		statuses = append(statuses, backup.Status)
+	}
+	return statuses
+}
@@ -0,0 +1,67 @@
+package mysql
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+// GetCloneBackupFetcher extracts the backup created by clone-backup-push into WALG_MYSQL_DATA_DIR.
+// The clone is a ready to start data directory, so it isn't prepared.
+func GetCloneBackupFetcher() internal.Fetcher {
+	return func(ctx context.Context, folder storage.Folder, backup internal.Backup) {
+		dataDir, err := internal.GetLogsDstSettings(conf.MysqlDataDir)
+		tracelog.ErrorLogger.FatalfOnError("Failed to get config value: %v", err)
+		fetcher, err := internal.GetBackupStreamFetcher(ctx, backup)
+		tracelog.ErrorLogger.FatalfOnError("Failed to detect backup format: %v", err)
+
+		tracelog.InfoLogger.Printf("Extracting %s into %s", backup.Name, dataDir)
+		err = extractCloneStream(ctx, backup, fetcher, dataDir)
+		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v", err)
+		tracelog.InfoLogger.Printf("Extracted %s", backup.Name)
+	}
+}
+
+// extractCloneStream extracts the tar stream of the clone into dataDir
+func extractCloneStream(ctx context.Context, backup internal.Backup, fetcher internal.StreamFetcher, dataDir string) error {
+	reader, writer := io.Pipe()
+	extractErr := make(chan error, 1)
+	go func() {
+		err := extractTar(reader, internal.NewFileTarInterpreter(dataDir))
+		// unblocks the fetcher if the extraction has stopped before the end of the stream
+		_ = reader.CloseWithError(err)
+		extractErr <- err
+	}()
+
+	err := fetcher(ctx, backup, writer)
+	// the extraction reads until the pipe is closed, so it is closed on failure too
+	_ = writer.CloseWithError(err)
+	if tarErr := <-extractErr; err == nil {
+		err = tarErr
+	}
+	return err
+}
+
+func extractTar(reader io.Reader, tarInterpreter internal.TarInterpreter) error {
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			// the stream may be padded after the end of the archive
+			_, err = io.Copy(io.Discard, reader)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		err = tarInterpreter.Interpret(tarReader, header)
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package mysql
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+)
+
+func TestExtractCloneStream(t *testing.T) {
+	cloneDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(cloneDir, "shop"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(cloneDir, "ibdata1"), []byte("system tablespace"), 0640))
+	require.NoError(t, os.WriteFile(filepath.Join(cloneDir, "shop", "orders.ibd"), []byte("orders"), 0640))
+	fetcher := func(ctx context.Context, backup internal.Backup, writeCloser io.WriteCloser) error {
+		tarWriter := tar.NewWriter(writeCloser)
+		err := tarWriter.AddFS(os.DirFS(cloneDir))
+		if err == nil {
+			err = tarWriter.Close()
+		}
+		if err == nil {
+			// the stream may be longer than the archive
+			_, err = writeCloser.Write(make([]byte, 4096))
+		}
+		return err
+	}
+
+	dataDir := t.TempDir()
+	require.NoError(t, extractCloneStream(t.Context(), internal.Backup{}, fetcher, dataDir))
+	content, err := os.ReadFile(filepath.Join(dataDir, "ibdata1"))
+	require.NoError(t, err)
+	assert.Equal(t, "system tablespace", string(content))
+	content, err = os.ReadFile(filepath.Join(dataDir, "shop", "orders.ibd"))
+	require.NoError(t, err)
+	assert.Equal(t, "orders", string(content))
+
+	fetchErr := errors.New("fetch failed")
+	err = extractCloneStream(t.Context(), internal.Backup{}, func(context.Context, internal.Backup, io.WriteCloser) error {
+		return fetchErr
+	}, t.TempDir())
+	assert.ErrorIs(t, err, fetchErr)
+}
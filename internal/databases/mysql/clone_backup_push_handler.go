@@ -0,0 +1,340 @@
+package mysql
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-mysql-org/go-mysql/client"
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/limiters"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	cloneStatusQuery = "SELECT STATE, ERROR_MESSAGE, BINLOG_FILE, BINLOG_POSITION, GTID_EXECUTED " +
+		"FROM performance_schema.clone_status ORDER BY ID DESC LIMIT 1"
+	cloneCompletedState = "Completed"
+
+	// redo log layout, see log0constants.h (LOG_CHECKPOINT_LSN, LOG_BLOCK_CHECKSUM)
+	redoBlockSize       = 512
+	redoCheckpointLSN   = 8
+	redoBlockChecksum   = 508
+	redoLegacyFileName  = "ib_logfile0"
+	redoDirectoryName   = "#innodb_redo"
+	redoFilePrefix      = "#ib_redo"
+	redoSpareFileSuffix = "_tmp"
+)
+
+var (
+	// LOG_CHECKPOINT_1 and LOG_CHECKPOINT_2 blocks of the redo file header
+	redoCheckpointBlocks = []int64{redoBlockSize, 3 * redoBlockSize}
+	redoChecksumTable    = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// CloneDonor is the remote instance cloned by CLONE INSTANCE, the instance wal-g connects to is cloned locally without donor
+type CloneDonor struct {
+	User     string
+	Password string
+	Host     string
+	Port     int
+}
+
+// ParseCloneDonor parses the donor as 'user@host:port'
+func ParseCloneDonor(donor, password string) (*CloneDonor, error) {
+	at := strings.LastIndex(donor, "@")
+	if at <= 0 {
+		return nil, fmt.Errorf("invalid clone donor %q, expected user@host:port", donor)
+	}
+	host, port, err := splitHostPort(donor[at+1:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid clone donor %q: %w", donor, err)
+	}
+	return &CloneDonor{User: donor[:at], Password: password, Host: host, Port: port}, nil
+}
+
+func splitHostPort(address string) (string, int, error) {
+	separator := strings.LastIndex(address, ":")
+	if separator <= 0 {
+		return "", 0, fmt.Errorf("port is missing in %q", address)
+	}
+	port, err := strconv.Atoi(address[separator+1:])
+	if err != nil || port <= 0 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port in %q", address)
+	}
+	return strings.Trim(address[:separator], "[]"), port, nil
+}
+
+// cloneStatement returns CLONE LOCAL or CLONE INSTANCE statement writing the clone into stagingDir
+func cloneStatement(stagingDir string, donor *CloneDonor) string {
+	if donor == nil {
+		return fmt.Sprintf("CLONE LOCAL DATA DIRECTORY = '%s'", gomysql.Escape(stagingDir))
+	}
+	return fmt.Sprintf("CLONE INSTANCE FROM '%s'@'%s':%d IDENTIFIED BY '%s' DATA DIRECTORY = '%s'",
+		gomysql.Escape(donor.User), gomysql.Escape(donor.Host), donor.Port, gomysql.Escape(donor.Password),
+		gomysql.Escape(stagingDir))
+}
+
+type cloneStatus struct {
+	binlogFile     string
+	binlogPosition uint64
+	gtidExecuted   string
+}
+
+func getCloneStatus(conn *client.Conn) (cloneStatus, error) {
+	result, err := conn.Execute(cloneStatusQuery)
+	if err != nil {
+		return cloneStatus{}, fmt.Errorf("failed to query clone status: %w", err)
+	}
+	defer result.Close()
+	if result.RowNumber() == 0 {
+		return cloneStatus{}, fmt.Errorf("clone status is empty")
+	}
+	state, _ := result.GetString(0, 0)
+	if state != cloneCompletedState {
+		message, _ := result.GetString(0, 1)
+		return cloneStatus{}, fmt.Errorf("clone is not completed: %s %s", state, message)
+	}
+	var status cloneStatus
+	status.binlogFile, _ = result.GetString(0, 2)
+	status.binlogPosition, _ = result.GetUint(0, 3)
+	status.gtidExecuted, _ = result.GetString(0, 4)
+	// GTID_EXECUTED is reported with the line breaks of SHOW MASTER STATUS
+	status.gtidExecuted = strings.ReplaceAll(status.gtidExecuted, "\n", "")
+	return status, nil
+}
+
+// readRedoCheckpointLSN returns the last checkpoint LSN of the redo log in the data directory
+func readRedoCheckpointLSN(dataDir string) (*LSN, error) {
+	redoFiles, err := filepath.Glob(filepath.Join(dataDir, redoDirectoryName, redoFilePrefix+"*"))
+	if err != nil {
+		return nil, err
+	}
+	redoFiles = append(redoFiles, filepath.Join(dataDir, redoLegacyFileName))
+	sort.Strings(redoFiles)
+
+	var result *LSN
+	for _, redoFile := range redoFiles {
+		if strings.HasSuffix(redoFile, redoSpareFileSuffix) {
+			continue
+		}
+		lsn, err := readRedoFileCheckpointLSN(redoFile)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if lsn != nil && (result == nil || *lsn > *result) {
+			result = lsn
+		}
+	}
+	if result == nil {
+		return nil, fmt.Errorf("no redo log checkpoint found in %s", dataDir)
+	}
+	return result, nil
+}
+
+func readRedoFileCheckpointLSN(redoFile string) (*LSN, error) {
+	file, err := os.Open(redoFile)
+	if err != nil {
+		return nil, err
+	}
+	defer utility.LoggedClose(file, "")
+
+	var result *LSN
+	block := make([]byte, redoBlockSize)
+	for _, offset := range redoCheckpointBlocks {
+		if _, err = file.ReadAt(block, offset); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		// the checkpoint block being written has invalid checksum
+		if crc32.Checksum(block[:redoBlockChecksum], redoChecksumTable) !=
+			binary.BigEndian.Uint32(block[redoBlockChecksum:]) {
+			continue
+		}
+		lsn := LSN(binary.BigEndian.Uint64(block[redoCheckpointLSN:]))
+		if result == nil || lsn > *result {
+			result = &lsn
+		}
+	}
+	return result, nil
+}
+
+// pushDirectoryTar uploads the directory as a tar stream, the backup is restored by extracting it into the data directory
+func pushDirectoryTar(ctx context.Context, uploader internal.Uploader, dir string) (string, error) {
+	reader, writer := io.Pipe()
+	go func() {
+		tarWriter := tar.NewWriter(writer)
+		err := tarWriter.AddFS(os.DirFS(dir))
+		if err == nil {
+			err = tarWriter.Close()
+		}
+		_ = writer.CloseWithError(err)
+	}()
+	backupName, err := uploader.PushStream(ctx, limiters.NewDiskLimitReader(ctx, reader))
+	_ = reader.CloseWithError(err)
+	return backupName, err
+}
+
+// HandleCloneBackupPush clones the local instance or the donor with the clone plugin into stagingDir
+// and uploads it as a stream backup
+func HandleCloneBackupPush(
+	ctx context.Context,
+	folder storage.Folder,
+	uploader internal.Uploader,
+	stagingDir string,
+	donor *CloneDonor,
+	isPermanent bool,
+	countJournals bool,
+	userDataRaw string,
+) {
+	err := cloneBackupPush(ctx, folder, uploader, stagingDir, donor, isPermanent, countJournals, userDataRaw)
+	tracelog.ErrorLogger.FatalOnError(err)
+}
+
+// cloneBackupPush returns the errors instead of terminating the process, so the clone in stagingDir is always removed
+//
+//nolint:funlen
+func cloneBackupPush(
+	ctx context.Context,
+	folder storage.Folder,
+	uploader internal.Uploader,
+	stagingDir string,
+	donor *CloneDonor,
+	isPermanent bool,
+	countJournals bool,
+	userDataRaw string,
+) error {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = ""
+		tracelog.WarningLogger.Printf("Failed to obtain the OS hostname")
+	}
+	userData, err := internal.UnmarshalSentinelUserData(userDataRaw)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal the provided UserData: %w", err)
+	}
+
+	conn, err := getMySQLConnection(ctx)
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(conn, "")
+
+	version, err := getMySQLVersion(conn)
+	if err != nil {
+		return err
+	}
+	flavor, err := getMySQLFlavor(conn)
+	if err != nil {
+		return err
+	}
+	if flavor != gomysql.MySQLFlavor {
+		return fmt.Errorf("clone plugin is not supported by %s", flavor)
+	}
+	serverUUID := ""
+	if donor == nil {
+		serverUUID, err = getServerUUID(conn, flavor)
+		if err != nil {
+			return err
+		}
+	} else {
+		// the backup belongs to the donor
+		hostname = donor.Host
+	}
+
+	if _, err = os.Stat(stagingDir); !os.IsNotExist(err) {
+		return fmt.Errorf("clone staging directory %s must not exist", stagingDir)
+	}
+	defer func() {
+		if err := os.RemoveAll(stagingDir); err != nil {
+			tracelog.WarningLogger.Printf("Failed to remove clone staging directory %s: %v", stagingDir, err)
+		}
+	}()
+
+	timeStart := utility.TimeNowCrossPlatformLocal()
+	tracelog.InfoLogger.Printf("Cloning into %s", stagingDir)
+	_, err = conn.Execute(cloneStatement(stagingDir, donor))
+	if err != nil {
+		return fmt.Errorf("clone failed: %w", err)
+	}
+	status, err := getCloneStatus(conn)
+	if err != nil {
+		return err
+	}
+	tracelog.InfoLogger.Printf("Clone consistency point: %s:%d, GTID set '%s'",
+		status.binlogFile, status.binlogPosition, status.gtidExecuted)
+
+	lsn, err := readRedoCheckpointLSN(stagingDir)
+	if err != nil {
+		tracelog.WarningLogger.Printf("failed to read redo log checkpoint LSN of the clone: %v", err)
+	}
+
+	backupName, err := pushDirectoryTar(ctx, uploader, stagingDir)
+	if err != nil {
+		return fmt.Errorf("failed to push backup: %w", err)
+	}
+
+	binlogEnd, err := getLastUploadedBinlog(ctx, folder)
+	if err != nil {
+		return fmt.Errorf("failed to get last uploaded binlog (after): %w", err)
+	}
+	timeStop := utility.TimeNowCrossPlatformLocal()
+
+	uploadedSize, err := uploader.UploadedDataSize()
+	if err != nil {
+		tracelog.ErrorLogger.Printf("Failed to calc uploaded data size: %v", err)
+	}
+	rawSize, err := uploader.RawDataSize()
+	if err != nil {
+		tracelog.ErrorLogger.Printf("Failed to calc raw data size: %v", err)
+	}
+
+	incrementCount := 0
+	sentinel := StreamSentinelDto{
+		Tool:                WalgCloneTool,
+		BinLogStart:         status.binlogFile,
+		BinLogEnd:           binlogEnd,
+		StartLocalTime:      timeStart,
+		StopLocalTime:       timeStop,
+		BinLogStartPosition: status.binlogPosition,
+		GTIDExecuted:        status.gtidExecuted,
+		CompressedSize:      uploadedSize,
+		UncompressedSize:    rawSize,
+		Hostname:            hostname,
+		ServerUUID:          serverUUID,
+		ServerVersion:       version,
+		// clone requires the same platform of the donor and the recipient
+		ServerArch:     runtime.GOARCH,
+		ServerOS:       runtime.GOOS,
+		IsPermanent:    isPermanent,
+		UserData:       userData,
+		LSN:            lsn,
+		IncrementCount: &incrementCount,
+	}
+	tracelog.InfoLogger.Printf("Backup sentinel: %s", sentinel.String())
+
+	err = internal.UploadSentinel(ctx, uploader, &sentinel, backupName)
+	if err != nil {
+		return err
+	}
+
+	uploadBackupJournalInfo(ctx, folder, backupName, timeStop, isPermanent, countJournals)
+	return nil
+}
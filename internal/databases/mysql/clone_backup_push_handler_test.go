@@ -0,0 +1,112 @@
+package mysql
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+)
+
+func TestParseCloneDonor(t *testing.T) {
+	donor, err := ParseCloneDonor("clone@user@db1.example.com:3306", "secret")
+	require.NoError(t, err)
+	assert.Equal(t, CloneDonor{User: "clone@user", Password: "secret", Host: "db1.example.com", Port: 3306}, *donor)
+
+	donor, err = ParseCloneDonor("clone@[::1]:3307", "")
+	require.NoError(t, err)
+	assert.Equal(t, "::1", donor.Host)
+	assert.Equal(t, 3307, donor.Port)
+
+	for _, invalid := range []string{"db1:3306", "clone@db1", "clone@db1:port", "clone@db1:70000"} {
+		_, err = ParseCloneDonor(invalid, "")
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestCloneStatement(t *testing.T) {
+	assert.Equal(t, `CLONE LOCAL DATA DIRECTORY = '/var/lib/clone/it\'s'`, cloneStatement("/var/lib/clone/it's", nil))
+	donor := &CloneDonor{User: "clone", Password: "p'w", Host: "db1", Port: 3306}
+	assert.Equal(t, `CLONE INSTANCE FROM 'clone'@'db1':3306 IDENTIFIED BY 'p\'w' DATA DIRECTORY = '/clone'`,
+		cloneStatement("/clone", donor))
+}
+
+func writeRedoFile(t *testing.T, redoPath string, lsns ...uint64) {
+	data := make([]byte, 4*redoBlockSize)
+	for i, lsn := range lsns {
+		block := data[redoCheckpointBlocks[i] : redoCheckpointBlocks[i]+redoBlockSize]
+		binary.BigEndian.PutUint64(block[redoCheckpointLSN:], lsn)
+		binary.BigEndian.PutUint32(block[redoBlockChecksum:], crc32.Checksum(block[:redoBlockChecksum], redoChecksumTable))
+	}
+	require.NoError(t, os.MkdirAll(filepath.Dir(redoPath), 0700))
+	require.NoError(t, os.WriteFile(redoPath, data, 0600))
+}
+
+func TestReadRedoCheckpointLSN(t *testing.T) {
+	dataDir := t.TempDir()
+	writeRedoFile(t, filepath.Join(dataDir, redoDirectoryName, "#ib_redo10"), 1000, 2000)
+	writeRedoFile(t, filepath.Join(dataDir, redoDirectoryName, "#ib_redo11"), 3000)
+	// spare files are not used yet
+	writeRedoFile(t, filepath.Join(dataDir, redoDirectoryName, "#ib_redo12_tmp"), 9000)
+
+	lsn, err := readRedoCheckpointLSN(dataDir)
+	require.NoError(t, err)
+	assert.Equal(t, LSN(3000), *lsn)
+
+	legacyDir := t.TempDir()
+	writeRedoFile(t, filepath.Join(legacyDir, redoLegacyFileName), 5000, 4000)
+	lsn, err = readRedoCheckpointLSN(legacyDir)
+	require.NoError(t, err)
+	assert.Equal(t, LSN(5000), *lsn)
+
+	_, err = readRedoCheckpointLSN(t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestPushDirectoryTar(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "mysql"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ibdata1"), []byte("system tablespace"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "mysql", "user.ibd"), []byte("user table"), 0600))
+
+	folder := memory.NewFolder("", memory.NewKVS())
+	uploader := internal.NewRegularUploader(compression.Compressors[lz4.AlgorithmName], folder)
+	backupName, err := pushDirectoryTar(context.Background(), uploader, dir)
+	require.NoError(t, err)
+
+	objects, _, err := folder.GetSubFolder(backupName).ListFolder(context.Background())
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	reader, err := folder.GetSubFolder(backupName).ReadObject(context.Background(), objects[0].GetName())
+	require.NoError(t, err)
+	decompressed, err := compression.GetDecompressorByCompressor(uploader.Compression()).Decompress(reader)
+	require.NoError(t, err)
+
+	files := map[string]string{}
+	tarReader := tar.NewReader(decompressed)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(tarReader)
+		require.NoError(t, err)
+		files[header.Name] = string(content)
+	}
+	assert.Equal(t, map[string]string{
+		"ibdata1":        "system tablespace",
+		"mysql/":         "",
+		"mysql/user.ibd": "user table",
+	}, files)
+}
@@ -2,6 +2,7 @@ package mysql
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
@@ -109,6 +110,12 @@ func (c RegularDeltaBackupConfigurator) Configure(
 		}
 	}
 
+	if prevBackupSentinelDto.Tool == WalgCloneTool {
+		tracelog.InfoLogger.Println("Previous backup was made by clone plugin, it can't be a base for delta. " +
+			"Fallback to full backup.")
+		return PrevBackupInfo{}, 0, nil
+	}
+
 	if prevBackupSentinelDto.LSN == nil {
 		tracelog.InfoLogger.Println("Previous backup was made without support for delta feature. " +
 			"Fallback to full backup with LSN marker for future deltas.")
@@ -141,3 +148,140 @@ func (c RegularDeltaBackupConfigurator) Configure(
 
 	return prevBackupInfo, incrementCount, nil
 }
+
+// AutoBackupDecision explains the backup type chosen by AutoDeltaBackupConfigurator
+type AutoBackupDecision struct {
+	Incremental bool   `json:"Incremental"`
+	Reason      string `json:"Reason"`
+	// Base is the backup the changes are estimated from
+	Base        string  `json:"Base,omitempty"`
+	RedoBytes   int64   `json:"RedoBytes,omitempty"`
+	RedoRatio   float64 `json:"RedoRatio,omitempty"`
+	BinlogBytes int64   `json:"BinlogBytes,omitempty"`
+	BinlogRatio float64 `json:"BinlogRatio,omitempty"`
+}
+
+// AutoDeltaBackupConfigurator makes incremental backup when RegularDeltaBackupConfigurator allows it and
+// the changes since its base are small enough, otherwise full backup is made.
+// Changed pages are estimated by the redo log written since the base backup LSN (compared with the data size
+// of the full backup) and by the binlogs uploaded since the base backup (compared with the compressed size).
+type AutoDeltaBackupConfigurator struct {
+	regular        RegularDeltaBackupConfigurator
+	folder         storage.Folder
+	maxRedoRatio   float64
+	maxBinlogRatio float64
+
+	decision *AutoBackupDecision
+}
+
+func NewAutoDeltaBackupConfigurator(folder storage.Folder, deltaBaseSelector internal.BackupSelector,
+	maxRedoRatio, maxBinlogRatio float64) *AutoDeltaBackupConfigurator {
+	return &AutoDeltaBackupConfigurator{
+		regular:        NewRegularDeltaBackupConfigurator(folder, deltaBaseSelector),
+		folder:         folder,
+		maxRedoRatio:   maxRedoRatio,
+		maxBinlogRatio: maxBinlogRatio,
+	}
+}
+
+func (c *AutoDeltaBackupConfigurator) Configure(
+	ctx context.Context,
+	_ bool,
+	hostname string,
+	serverUUID string,
+	serverVersion string) (PrevBackupInfo, int, error) {
+	prevBackupInfo, incrementCount, err := c.regular.Configure(ctx, false, hostname, serverUUID, serverVersion)
+	if err != nil {
+		return PrevBackupInfo{}, 0, err
+	}
+	if prevBackupInfo.name == "" {
+		c.decision = &AutoBackupDecision{Reason: "there is no suitable base for incremental backup"}
+		return PrevBackupInfo{}, 0, nil
+	}
+
+	estimate, err := c.estimateChanges(ctx, prevBackupInfo)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to estimate changes since %s: %v", prevBackupInfo.name, err)
+		c.decision = &AutoBackupDecision{Reason: fmt.Sprintf("failed to estimate changes: %v", err)}
+		return PrevBackupInfo{}, 0, nil
+	}
+	c.decision = decideAutoBackup(estimate, c.maxRedoRatio, c.maxBinlogRatio)
+	tracelog.InfoLogger.Printf("Automatic backup type: %s", c.decision.Reason)
+	if !c.decision.Incremental {
+		return PrevBackupInfo{}, 0, nil
+	}
+	return prevBackupInfo, incrementCount, nil
+}
+
+type changeEstimate struct {
+	base           string
+	redoBytes      int64
+	dataSize       int64
+	binlogBytes    int64
+	compressedSize int64
+}
+
+func (c *AutoDeltaBackupConfigurator) estimateChanges(ctx context.Context, prevBackupInfo PrevBackupInfo) (changeEstimate, error) {
+	base := prevBackupInfo.sentinel
+	full := base
+	if base.IsIncremental {
+		if prevBackupInfo.fullBackupName == nil {
+			return changeEstimate{}, fmt.Errorf("full backup of %s is unknown", prevBackupInfo.name)
+		}
+		backup, err := internal.NewBackup(c.folder.GetSubFolder(utility.BaseBackupPath), *prevBackupInfo.fullBackupName)
+		if err != nil {
+			return changeEstimate{}, err
+		}
+		if err = backup.FetchSentinel(ctx, &full); err != nil {
+			return changeEstimate{}, err
+		}
+	}
+
+	conn, err := getMySQLConnection(ctx)
+	if err != nil {
+		return changeEstimate{}, err
+	}
+	defer utility.LoggedClose(conn, "")
+	currentLSN, err := getInnoDBCurrentLSN(conn)
+	if err != nil {
+		return changeEstimate{}, err
+	}
+
+	binlogBytes, err := getUploadedBinlogsSize(ctx, c.folder, base.StartLocalTime)
+	if err != nil {
+		return changeEstimate{}, err
+	}
+	return changeEstimate{
+		base:           prevBackupInfo.name,
+		redoBytes:      int64(currentLSN) - int64(*base.LSN),
+		dataSize:       full.UncompressedSize,
+		binlogBytes:    binlogBytes,
+		compressedSize: full.CompressedSize,
+	}, nil
+}
+
+func decideAutoBackup(estimate changeEstimate, maxRedoRatio, maxBinlogRatio float64) *AutoBackupDecision {
+	decision := &AutoBackupDecision{
+		Base:        estimate.base,
+		RedoBytes:   estimate.redoBytes,
+		BinlogBytes: estimate.binlogBytes,
+	}
+	if estimate.dataSize <= 0 || estimate.compressedSize <= 0 {
+		decision.Reason = "size of the full backup is unknown"
+		return decision
+	}
+	decision.RedoRatio = float64(estimate.redoBytes) / float64(estimate.dataSize)
+	decision.BinlogRatio = float64(estimate.binlogBytes) / float64(estimate.compressedSize)
+	switch {
+	case decision.RedoRatio > maxRedoRatio:
+		decision.Reason = fmt.Sprintf("redo log written since %s is %.2f of the data size (max %.2f)",
+			estimate.base, decision.RedoRatio, maxRedoRatio)
+	case decision.BinlogRatio > maxBinlogRatio:
+		decision.Reason = fmt.Sprintf("binlogs uploaded since %s are %.2f of the backup size (max %.2f)",
+			estimate.base, decision.BinlogRatio, maxBinlogRatio)
+	default:
+		decision.Incremental = true
+		decision.Reason = fmt.Sprintf("changes since %s are below the thresholds", estimate.base)
+	}
+	return decision
+}
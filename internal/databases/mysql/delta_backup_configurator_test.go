@@ -0,0 +1,32 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecideAutoBackup(t *testing.T) {
+	estimate := changeEstimate{base: "stream_1", redoBytes: 100, dataSize: 1000, binlogBytes: 10, compressedSize: 200}
+	decision := decideAutoBackup(estimate, 0.5, 0.5)
+	assert.True(t, decision.Incremental)
+	assert.Equal(t, "stream_1", decision.Base)
+	assert.InDelta(t, 0.1, decision.RedoRatio, 1e-9)
+	assert.InDelta(t, 0.05, decision.BinlogRatio, 1e-9)
+
+	decision = decideAutoBackup(estimate, 0.05, 0.5)
+	assert.False(t, decision.Incremental)
+	assert.Equal(t, "redo log written since stream_1 is 0.10 of the data size (max 0.05)", decision.Reason)
+
+	decision = decideAutoBackup(estimate, 0.5, 0.01)
+	assert.False(t, decision.Incremental)
+	assert.Equal(t, "binlogs uploaded since stream_1 are 0.05 of the backup size (max 0.01)", decision.Reason)
+
+	estimate.dataSize = 0
+	assert.False(t, decideAutoBackup(estimate, 0.5, 0.5).Incremental)
+}
+
+func TestInnoDBLSNRegexp(t *testing.T) {
+	status := "---\nLOG\n---\nLog sequence number          19056412\nLog buffer assigned up to    19056412\n"
+	assert.Equal(t, []string{"Log sequence number          19056412", "19056412"}, innodbLSNRegexp.FindStringSubmatch(status))
+}
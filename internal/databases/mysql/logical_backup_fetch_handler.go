@@ -0,0 +1,129 @@
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// LogicalTableFilter selects the tables restored from the logical backup by 'db.table' patterns
+// with shell wildcards (e.g. 'shop.*'), all tables are restored when the filter is empty
+type LogicalTableFilter []RestoreTable
+
+func NewLogicalTableFilter(patterns []string) (LogicalTableFilter, error) {
+	filter := make(LogicalTableFilter, 0, len(patterns))
+	for _, pattern := range patterns {
+		table, err := ParseRestoreTable(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = path.Match(table.Database, ""); err != nil {
+			return nil, fmt.Errorf("invalid table pattern %q: %w", pattern, err)
+		}
+		if _, err = path.Match(table.Table, ""); err != nil {
+			return nil, fmt.Errorf("invalid table pattern %q: %w", pattern, err)
+		}
+		filter = append(filter, table)
+	}
+	return filter, nil
+}
+
+// accept selects the part of the backup, the preamble is always restored and
+// the database level statements are restored when any table of the database is selected
+func (filter LogicalTableFilter) accept(part LogicalBackupPart) bool {
+	if len(filter) == 0 || part.Database == "" {
+		return true
+	}
+	for _, pattern := range filter {
+		if databaseMatch, _ := path.Match(pattern.Database, part.Database); !databaseMatch {
+			continue
+		}
+		if tableMatch, _ := path.Match(pattern.Table, part.Table); part.Table == "" || tableMatch {
+			return true
+		}
+	}
+	return false
+}
+
+func GetLogicalBackupFetcher(restoreCmd *exec.Cmd, filter LogicalTableFilter) internal.Fetcher {
+	return func(ctx context.Context, folder storage.Folder, backup internal.Backup) {
+		err := logicalBackupFetch(ctx, backup, restoreCmd, filter)
+		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v", err)
+	}
+}
+
+func logicalBackupFetch(ctx context.Context, backup internal.Backup, restoreCmd *exec.Cmd, filter LogicalTableFilter) error {
+	var sentinel StreamSentinelDto
+	err := backup.FetchSentinel(ctx, &sentinel)
+	if err != nil {
+		return err
+	}
+	if sentinel.Logical == nil {
+		return fmt.Errorf("backup %s has no logical backup parts", backup.Name)
+	}
+	if sentinel.Logical.Format == MydumperFormat {
+		return restoreMydumperParts(ctx, backup, sentinel.Logical.Parts, filter, restoreCmd)
+	}
+
+	stdin, err := restoreCmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stderr := &bytes.Buffer{}
+	restoreCmd.Stderr = stderr
+
+	tracelog.InfoLogger.Printf("Restoring %s with cmd %v", backup.Name, restoreCmd.Args)
+	err = restoreCmd.Start()
+	if err != nil {
+		return err
+	}
+	err = writeLogicalParts(ctx, backup.Folder, sentinel.Logical.Parts, filter, stdin)
+	utility.LoggedClose(stdin, "")
+	cmdErr := restoreCmd.Wait()
+	if cmdErr != nil {
+		tracelog.ErrorLogger.Printf("Restore command output:\n%s", stderr.String())
+		err = cmdErr
+	}
+	if err != nil {
+		return err
+	}
+	tracelog.InfoLogger.Printf("Restored %s", backup.Name)
+	return nil
+}
+
+// writeLogicalParts writes the selected parts of the dump in the dump order
+func writeLogicalParts(ctx context.Context, folder storage.Folder, parts []LogicalBackupPart,
+	filter LogicalTableFilter, output io.Writer) error {
+	folderReader := internal.NewFolderReader(folder)
+	tables := 0
+	for _, part := range parts {
+		if !filter.accept(part) {
+			continue
+		}
+		if part.Table != "" {
+			tracelog.InfoLogger.Printf("Restoring %s", RestoreTable{Database: part.Database, Table: part.Table})
+			tables++
+		}
+		reader, err := internal.DownloadAndDecompressStorageFile(ctx, folderReader, part.Object)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(output, reader)
+		utility.LoggedClose(reader, "")
+		if err != nil {
+			return err
+		}
+	}
+	if tables == 0 && len(filter) > 0 {
+		return fmt.Errorf("no tables of the backup match the filter")
+	}
+	return nil
+}
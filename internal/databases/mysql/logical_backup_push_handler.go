@@ -0,0 +1,358 @@
+package mysql
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/limiters"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	LogicalBackupPartsPath = "logical"
+
+	// section markers of the mysqldump output (see dump_table, dump_all_tables_in_db, dump_routines_for_db)
+	dumpCurrentDatabaseMarker = "-- Current Database: "
+	dumpTableStructureMarker  = "-- Table structure for table "
+	dumpTemporaryViewMarker   = "-- Temporary view structure for view "
+	dumpFinalViewMarker       = "-- Final view structure for view "
+	dumpRoutinesMarker        = "-- Dumping routines for database "
+	dumpEventsMarker          = "-- Dumping events for database "
+	dumpGTIDPurgedPrefix      = "SET @@GLOBAL.GTID_PURGED="
+)
+
+var (
+	dumpBinlogCoordinatesRegexp = regexp.MustCompile(`(?:MASTER|SOURCE)_LOG_FILE='([^']+)', (?:MASTER|SOURCE)_LOG_POS=(\d+)`)
+	dumpMariadbGTIDRegexp       = regexp.MustCompile(`gtid_slave_pos='([^']*)'`)
+)
+
+// LogicalBackupPart is the part of the dump uploaded as a separate object:
+// the preamble (no database), the database level statements (no table) or the table (or view) of the database
+type LogicalBackupPart struct {
+	Database string `json:"Database,omitempty"`
+	Table    string `json:"Table,omitempty"`
+	// Object is the path of the part relative to the backups folder, without compression extension
+	Object string `json:"Object"`
+	Size   int64  `json:"Size"`
+	// File is the name of the file in the mydumper output directory
+	File string `json:"File,omitempty"`
+}
+
+// LogicalBackupInfo describes the objects of the backup created by logical-backup-push
+type LogicalBackupInfo struct {
+	// Format is MydumperFormat for the files of mydumper, empty for the mysqldump output
+	Format string `json:"Format,omitempty"`
+	// Parts are in the dump order
+	Parts []LogicalBackupPart `json:"Parts"`
+}
+
+// logicalDumpPosition is the consistency point of the dump
+type logicalDumpPosition struct {
+	binlogFile     string
+	binlogPosition uint64
+	gtidExecuted   string
+}
+
+// logicalDumpSplitter splits the mysqldump output into the parts and collects the binlog coordinates of the dump
+type logicalDumpSplitter struct {
+	newPart  func(database, table string) (io.WriteCloser, error)
+	current  io.WriteCloser
+	database string
+
+	position logicalDumpPosition
+	gtidLine *strings.Builder
+}
+
+func (splitter *logicalDumpSplitter) split(dump io.Reader) error {
+	reader := bufio.NewReaderSize(dump, 64*1024)
+	err := splitter.startPart("", "")
+	lineStart := true
+	for err == nil {
+		var line []byte
+		line, err = reader.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			err = nil
+		}
+		if len(line) == 0 {
+			continue
+		}
+		if lineStart {
+			if markerErr := splitter.handleLine(line); markerErr != nil {
+				return markerErr
+			}
+		}
+		lineStart = line[len(line)-1] == '\n'
+		if _, writeErr := splitter.current.Write(line); writeErr != nil {
+			return writeErr
+		}
+	}
+	if err != io.EOF {
+		return err
+	}
+	return splitter.current.Close()
+}
+
+func (splitter *logicalDumpSplitter) handleLine(line []byte) error {
+	text := string(bytes.TrimRight(line, "\r\n"))
+	if splitter.gtidLine != nil || strings.HasPrefix(text, dumpGTIDPurgedPrefix) {
+		splitter.handleGTIDPurged(text)
+	}
+	if !strings.HasPrefix(text, "-- ") {
+		return nil
+	}
+	if match := dumpBinlogCoordinatesRegexp.FindStringSubmatch(text); match != nil && splitter.position.binlogFile == "" {
+		splitter.position.binlogFile = match[1]
+		splitter.position.binlogPosition, _ = strconv.ParseUint(match[2], 10, 64)
+	}
+	if match := dumpMariadbGTIDRegexp.FindStringSubmatch(text); match != nil {
+		splitter.position.gtidExecuted = match[1]
+	}
+
+	switch {
+	case strings.HasPrefix(text, dumpCurrentDatabaseMarker):
+		splitter.database = parseDumpIdentifier(strings.TrimPrefix(text, dumpCurrentDatabaseMarker))
+		return splitter.startPart(splitter.database, "")
+	case strings.HasPrefix(text, dumpRoutinesMarker), strings.HasPrefix(text, dumpEventsMarker):
+		return splitter.startPart(splitter.database, "")
+	}
+	for _, marker := range []string{dumpTableStructureMarker, dumpTemporaryViewMarker, dumpFinalViewMarker} {
+		if strings.HasPrefix(text, marker) {
+			return splitter.startPart(splitter.database, parseDumpIdentifier(strings.TrimPrefix(text, marker)))
+		}
+	}
+	return nil
+}
+
+// handleGTIDPurged collects SET @@GLOBAL.GTID_PURGED statement, mysqldump splits the GTID set into several lines
+func (splitter *logicalDumpSplitter) handleGTIDPurged(text string) {
+	if splitter.gtidLine == nil {
+		splitter.gtidLine = &strings.Builder{}
+	}
+	splitter.gtidLine.WriteString(text)
+	statement := splitter.gtidLine.String()
+	if !strings.HasSuffix(statement, "';") {
+		return
+	}
+	statement = strings.TrimSuffix(statement, "';")
+	splitter.position.gtidExecuted = statement[strings.LastIndex(statement, "'")+1:]
+	splitter.gtidLine = nil
+}
+
+func (splitter *logicalDumpSplitter) startPart(database, table string) error {
+	if splitter.current != nil {
+		if err := splitter.current.Close(); err != nil {
+			return err
+		}
+	}
+	var err error
+	splitter.current, err = splitter.newPart(database, table)
+	return err
+}
+
+// parseDumpIdentifier unquotes `name` (database names of the routines section are quoted with ')
+func parseDumpIdentifier(quoted string) string {
+	if len(quoted) >= 2 && (quoted[0] == '`' || quoted[0] == '\'') && quoted[len(quoted)-1] == quoted[0] {
+		quote := quoted[:1]
+		return strings.ReplaceAll(quoted[1:len(quoted)-1], quote+quote, quote)
+	}
+	return quoted
+}
+
+// logicalPartUploader uploads each part of the dump as a separate compressed and encrypted object
+type logicalPartUploader struct {
+	ctx        context.Context
+	uploader   internal.Uploader
+	backupName string
+	parts      []*LogicalBackupPart
+}
+
+func (partUploader *logicalPartUploader) info(format string) *LogicalBackupInfo {
+	info := &LogicalBackupInfo{Format: format, Parts: make([]LogicalBackupPart, 0, len(partUploader.parts))}
+	for _, part := range partUploader.parts {
+		info.Parts = append(info.Parts, *part)
+	}
+	return info
+}
+
+type logicalPartWriter struct {
+	*io.PipeWriter
+	size   *int64
+	result chan error
+}
+
+func (writer *logicalPartWriter) Write(data []byte) (int, error) {
+	n, err := writer.PipeWriter.Write(data)
+	*writer.size += int64(n)
+	return n, err
+}
+
+func (writer *logicalPartWriter) Close() error {
+	if err := writer.PipeWriter.Close(); err != nil {
+		return err
+	}
+	return <-writer.result
+}
+
+func (partUploader *logicalPartUploader) newPart(database, table string) (io.WriteCloser, error) {
+	object := path.Join(partUploader.backupName, LogicalBackupPartsPath, fmt.Sprintf("%05d.sql", len(partUploader.parts)))
+	part := &LogicalBackupPart{Database: database, Table: table, Object: object}
+	partUploader.parts = append(partUploader.parts, part)
+
+	reader, writer := io.Pipe()
+	result := make(chan error, 1)
+	go func() {
+		dstPath := utility.AddFileExtension(object, partUploader.uploader.Compression().FileExtension())
+		err := partUploader.uploader.PushStreamToDestination(partUploader.ctx,
+			limiters.NewDiskLimitReader(partUploader.ctx, reader), dstPath)
+		_ = reader.CloseWithError(err)
+		result <- err
+	}()
+	return &logicalPartWriter{PipeWriter: writer, size: &part.Size, result: result}, nil
+}
+
+// newFilePart starts the part uploading the file of the dump directory
+func (partUploader *logicalPartUploader) newFilePart(database, table, file string) (io.WriteCloser, error) {
+	writer, err := partUploader.newPart(database, table)
+	if err != nil {
+		return nil, err
+	}
+	partUploader.parts[len(partUploader.parts)-1].File = file
+	return writer, nil
+}
+
+// runMysqldump splits the output of the dump command (mysqldump) into the parts
+func runMysqldump(dumpCmd *exec.Cmd, partUploader *logicalPartUploader) (logicalDumpPosition, error) {
+	splitter := &logicalDumpSplitter{newPart: partUploader.newPart}
+	stdout, stderr, err := utility.StartCommandWithStdoutStderr(dumpCmd)
+	if err != nil {
+		return logicalDumpPosition{}, fmt.Errorf("failed to start logical dump command: %w", err)
+	}
+	err = splitter.split(stdout)
+	if err != nil {
+		return logicalDumpPosition{}, fmt.Errorf("failed to push logical backup: %w", err)
+	}
+	err = dumpCmd.Wait()
+	if err != nil {
+		tracelog.ErrorLogger.Printf("Logical dump command output:\n%s", stderr.String())
+		return logicalDumpPosition{}, fmt.Errorf("logical dump command failed: %w", err)
+	}
+	return splitter.position, nil
+}
+
+// HandleLogicalBackupPush runs the dump command and uploads each database and table of the dump
+// as a separate object: the mysqldump output is split by its comments, the mydumper files are uploaded one by one
+//
+//nolint:funlen
+func HandleLogicalBackupPush(
+	ctx context.Context,
+	folder storage.Folder,
+	uploader internal.Uploader,
+	dumpCmd *exec.Cmd,
+	mydumper bool,
+	isPermanent bool,
+	countJournals bool,
+	userDataRaw string,
+) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = ""
+		tracelog.WarningLogger.Printf("Failed to obtain the OS hostname")
+	}
+
+	conn, err := getMySQLConnection(ctx)
+	tracelog.ErrorLogger.FatalOnError(err)
+	defer utility.LoggedClose(conn, "")
+
+	version, err := getMySQLVersion(conn)
+	tracelog.ErrorLogger.FatalOnError(err)
+	flavor, err := getMySQLFlavor(conn)
+	tracelog.ErrorLogger.FatalOnError(err)
+	serverUUID, err := getServerUUID(conn, flavor)
+	tracelog.ErrorLogger.FatalOnError(err)
+	gtidStart, err := getMySQLGTIDExecuted(conn, flavor)
+	tracelog.ErrorLogger.FatalOnError(err)
+	binlogStart, err := getLastUploadedBinlogBeforeGTID(ctx, folder, gtidStart, flavor)
+	tracelog.ErrorLogger.FatalfOnError("failed to get last uploaded binlog: %v", err)
+	timeStart := utility.TimeNowCrossPlatformLocal()
+
+	backupName := internal.StreamPrefix + utility.TimeNowCrossPlatformUTC().Format(utility.BackupTimeFormat)
+	partUploader := &logicalPartUploader{ctx: ctx, uploader: uploader, backupName: backupName}
+
+	var position logicalDumpPosition
+	format := ""
+	if mydumper {
+		format = MydumperFormat
+		dumpDir, err := newMydumperDir()
+		tracelog.ErrorLogger.FatalfOnError("failed to make mydumper directory: %v", err)
+		position, err = runMydumper(dumpCmd, dumpDir, partUploader)
+		_ = removeTemporaryDirectory(dumpDir)
+		tracelog.ErrorLogger.FatalfOnError("failed to push logical backup: %v", err)
+	} else {
+		position, err = runMysqldump(dumpCmd, partUploader)
+		tracelog.ErrorLogger.FatalOnError(err)
+	}
+
+	gtidExecuted := position.gtidExecuted
+	if position.binlogFile != "" {
+		binlogStart = position.binlogFile
+	} else {
+		tracelog.WarningLogger.Printf("Dump doesn't contain binlog coordinates, add --source-data=2 to the mysqldump command " +
+			"to continue binlog-replay from the dump consistency point")
+	}
+	tracelog.InfoLogger.Printf("Dump consistency point: %s:%d, GTID set '%s'", binlogStart, position.binlogPosition, gtidExecuted)
+
+	binlogEnd, err := getLastUploadedBinlog(ctx, folder)
+	tracelog.ErrorLogger.FatalfOnError("failed to get last uploaded binlog (after): %v", err)
+	timeStop := utility.TimeNowCrossPlatformLocal()
+
+	uploadedSize, err := uploader.UploadedDataSize()
+	if err != nil {
+		tracelog.ErrorLogger.Printf("Failed to calc uploaded data size: %v", err)
+	}
+	rawSize, err := uploader.RawDataSize()
+	if err != nil {
+		tracelog.ErrorLogger.Printf("Failed to calc raw data size: %v", err)
+	}
+
+	userData, err := internal.UnmarshalSentinelUserData(userDataRaw)
+	tracelog.ErrorLogger.FatalfOnError("Failed to unmarshal the provided UserData: %s", err)
+
+	incrementCount := 0
+	sentinel := StreamSentinelDto{
+		Tool:                WalgLogicalTool,
+		BinLogStart:         binlogStart,
+		BinLogEnd:           binlogEnd,
+		StartLocalTime:      timeStart,
+		StopLocalTime:       timeStop,
+		BinLogStartPosition: position.binlogPosition,
+		GTIDExecuted:        gtidExecuted,
+		Logical:             partUploader.info(format),
+		CompressedSize:      uploadedSize,
+		UncompressedSize:    rawSize,
+		Hostname:            hostname,
+		ServerUUID:          serverUUID,
+		ServerVersion:       version,
+		IsPermanent:         isPermanent,
+		UserData:            userData,
+		IncrementCount:      &incrementCount,
+	}
+	tracelog.InfoLogger.Printf("Backup sentinel: %s", sentinel.String())
+
+	err = internal.UploadSentinel(ctx, uploader, &sentinel, backupName)
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	uploadBackupJournalInfo(ctx, folder, backupName, timeStop, isPermanent, countJournals)
+}
@@ -0,0 +1,114 @@
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+)
+
+const testDumpPreamble = `-- MySQL dump 10.13  Distrib 8.0.36, for Linux (x86_64)
+/*!40101 SET NAMES utf8mb4 */;
+SET @@SESSION.SQL_LOG_BIN= 0;
+
+--
+-- GTID state at the beginning of the backup
+--
+
+SET @@GLOBAL.GTID_PURGED=/*!80000 '+'*/ '11111111-2222-3333-4444-555555555555:1-20,
+6abc8ecb-bf5c-11e9-9821-c897993b5a14:1-100';
+
+--
+-- Position to start replication or point-in-time recovery from
+--
+
+-- CHANGE REPLICATION SOURCE TO SOURCE_LOG_FILE='mysql-bin.000042', SOURCE_LOG_POS=1337;
+
+--
+`
+
+const testDumpShop = "-- Current Database: `shop`\n" +
+	"--\n\nCREATE DATABASE `shop`;\n\nUSE `shop`;\n\n--\n"
+
+const testDumpOrders = "-- Table structure for table `orders`\n" +
+	"--\n\nCREATE TABLE `orders` (`id` int);\n\n--\n" +
+	"-- Dumping data for table `orders`\n--\n\nINSERT INTO `orders` VALUES (1),(2);\n\n--\n"
+
+const testDumpOrderItems = "-- Table structure for table `order``items`\n" +
+	"--\n\nCREATE TABLE `order``items` (`id` int);\n\n--\n"
+
+const testDumpShopRoutines = "-- Dumping routines for database 'shop'\n--\n\n--\n"
+
+const testDumpCrm = "-- Current Database: `crm`\n" +
+	"--\n\nCREATE DATABASE `crm`;\n\nUSE `crm`;\n\n--\n"
+
+const testDumpCustomers = "-- Table structure for table `customers`\n" +
+	"--\n\nCREATE TABLE `customers` (`id` int);\n" +
+	"SET @@SESSION.SQL_LOG_BIN = @MYSQLDUMP_TEMP_LOG_BIN;\n-- Dump completed on 2024-01-01 10:00:00\n"
+
+func TestLogicalDumpSplitter(t *testing.T) {
+	ctx := context.Background()
+	dump := testDumpPreamble + testDumpShop + testDumpOrders + testDumpOrderItems + testDumpShopRoutines +
+		testDumpCrm + testDumpCustomers
+
+	folder := memory.NewFolder("", memory.NewKVS())
+	uploader := internal.NewRegularUploader(compression.Compressors[lz4.AlgorithmName], folder)
+	partUploader := &logicalPartUploader{ctx: ctx, uploader: uploader, backupName: "stream_20240101T100000Z"}
+	splitter := &logicalDumpSplitter{newPart: partUploader.newPart}
+	require.NoError(t, splitter.split(strings.NewReader(dump)))
+
+	assert.Equal(t, "mysql-bin.000042", splitter.position.binlogFile)
+	assert.Equal(t, uint64(1337), splitter.position.binlogPosition)
+	assert.Equal(t, "11111111-2222-3333-4444-555555555555:1-20,6abc8ecb-bf5c-11e9-9821-c897993b5a14:1-100",
+		splitter.position.gtidExecuted)
+
+	info := partUploader.info("")
+	expected := []LogicalBackupPart{
+		{Object: "stream_20240101T100000Z/logical/00000.sql", Size: int64(len(testDumpPreamble))},
+		{Database: "shop", Object: "stream_20240101T100000Z/logical/00001.sql", Size: int64(len(testDumpShop))},
+		{Database: "shop", Table: "orders", Object: "stream_20240101T100000Z/logical/00002.sql", Size: int64(len(testDumpOrders))},
+		{Database: "shop", Table: "order`items", Object: "stream_20240101T100000Z/logical/00003.sql",
+			Size: int64(len(testDumpOrderItems))},
+		{Database: "shop", Object: "stream_20240101T100000Z/logical/00004.sql", Size: int64(len(testDumpShopRoutines))},
+		{Database: "crm", Object: "stream_20240101T100000Z/logical/00005.sql", Size: int64(len(testDumpCrm))},
+		{Database: "crm", Table: "customers", Object: "stream_20240101T100000Z/logical/00006.sql",
+			Size: int64(len(testDumpCustomers))},
+	}
+	assert.Equal(t, expected, info.Parts)
+
+	var restored bytes.Buffer
+	require.NoError(t, writeLogicalParts(ctx, folder, info.Parts, nil, &restored))
+	assert.Equal(t, dump, restored.String())
+
+	filter, err := NewLogicalTableFilter([]string{"shop.o*rs"})
+	require.NoError(t, err)
+	restored.Reset()
+	require.NoError(t, writeLogicalParts(ctx, folder, info.Parts, filter, &restored))
+	assert.Equal(t, testDumpPreamble+testDumpShop+testDumpOrders+testDumpShopRoutines, restored.String())
+
+	filter, err = NewLogicalTableFilter([]string{"sales.*"})
+	require.NoError(t, err)
+	assert.Error(t, writeLogicalParts(ctx, folder, info.Parts, filter, &restored))
+}
+
+func TestNewLogicalTableFilter(t *testing.T) {
+	filter, err := NewLogicalTableFilter([]string{"shop.*", "crm.customers"})
+	require.NoError(t, err)
+	assert.True(t, filter.accept(LogicalBackupPart{}))
+	assert.True(t, filter.accept(LogicalBackupPart{Database: "shop", Table: "orders"}))
+	assert.True(t, filter.accept(LogicalBackupPart{Database: "crm"}))
+	assert.False(t, filter.accept(LogicalBackupPart{Database: "crm", Table: "leads"}))
+	assert.False(t, filter.accept(LogicalBackupPart{Database: "sales"}))
+
+	_, err = NewLogicalTableFilter([]string{"shop"})
+	assert.Error(t, err)
+	_, err = NewLogicalTableFilter([]string{"shop.[orders"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,251 @@
+package mysql
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	// MydumperFormat marks the logical backups of the mydumper output directory
+	MydumperFormat = "mydumper"
+	// MydumperDirEnv passes the directory of the mydumper files to the dump and the restore commands
+	MydumperDirEnv = "WALG_MYSQL_DUMP_DIR"
+
+	mydumperMetadataFile = "metadata"
+)
+
+var (
+	// the data files of the table are split into the numbered chunks, e.g. db.table.00000.sql
+	mydumperChunkRegexp = regexp.MustCompile(`(\.\d+)+$`)
+	// the suffixes of the table files, the longest ones go first
+	mydumperTableSuffixes = []string{"-schema-triggers", "-schema-sequence", "-schema-view", "-schema", "-metadata",
+		"-checksum"}
+)
+
+// parseMydumperFile returns the database and the table of the file written by mydumper,
+// the database level files (schema-create, schema-post) have no table and the dump metadata has no database
+func parseMydumperFile(name string) (database, table string) {
+	for _, extension := range []string{".gz", ".zst"} {
+		name = strings.TrimSuffix(name, extension)
+	}
+	name = strings.TrimSuffix(name, ".sql")
+	for _, suffix := range []string{"-schema-create", "-schema-post"} {
+		if strings.HasSuffix(name, suffix) && !strings.Contains(name, ".") {
+			return strings.TrimSuffix(name, suffix), ""
+		}
+	}
+	database, table, found := strings.Cut(name, ".")
+	if !found {
+		return "", ""
+	}
+	table = mydumperChunkRegexp.ReplaceAllString(table, "")
+	for _, suffix := range mydumperTableSuffixes {
+		if strings.HasSuffix(table, suffix) {
+			table = strings.TrimSuffix(table, suffix)
+			break
+		}
+	}
+	return database, table
+}
+
+// parseMydumperMetadata reads the binlog coordinates and the GTID set of the dump consistency point.
+// mydumper before 0.12 writes 'SHOW MASTER STATUS:' section with 'Log', 'Pos' and 'GTID' lines,
+// newer versions write '[master]' (or '[source]') section with 'File', 'Position' and 'Executed_Gtid_Set' keys.
+// The coordinates of the replication source ('SHOW SLAVE STATUS', '[replication]') are ignored.
+func parseMydumperMetadata(metadata io.Reader) (logicalDumpPosition, error) {
+	var position logicalDumpPosition
+	scanner := bufio.NewScanner(metadata)
+	inSourceSection := false
+	continuesGTID := false
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if continuesGTID {
+			// the GTID set of several sources is split into lines after the commas
+			position.gtidExecuted += line
+			continuesGTID = strings.HasSuffix(line, ",")
+			continue
+		}
+		switch {
+		case line == "SHOW MASTER STATUS:" || line == "[master]" || line == "[source]":
+			inSourceSection = true
+			continue
+		case strings.HasSuffix(line, ":") && !strings.Contains(line, " = ") || strings.HasPrefix(line, "["):
+			inSourceSection = false
+			continue
+		}
+		if !inSourceSection || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			key, value, found = strings.Cut(line, ":")
+		}
+		if !found {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.Trim(strings.TrimSpace(value), `'"`)
+		switch key {
+		case "Log", "File":
+			position.binlogFile = value
+		case "Pos", "Position":
+			var err error
+			position.binlogPosition, err = strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return logicalDumpPosition{}, fmt.Errorf("invalid binlog position %q in mydumper metadata", value)
+			}
+		case "GTID", "Executed_Gtid_Set":
+			position.gtidExecuted = value
+			continuesGTID = strings.HasSuffix(value, ",")
+		}
+	}
+	return position, scanner.Err()
+}
+
+// newMydumperDir makes the temporary directory for the mydumper files in WALG_MYSQL_MYDUMPER_DIR
+func newMydumperDir() (string, error) {
+	parentDir := viper.GetString(conf.MysqlMydumperDir)
+	if parentDir != "" {
+		if err := os.MkdirAll(parentDir, 0750); err != nil {
+			return "", err
+		}
+	}
+	return os.MkdirTemp(parentDir, "wal-g-mydumper")
+}
+
+// runMydumper runs the dump command writing the mydumper files into dir and uploads each file as a separate part
+func runMydumper(dumpCmd *exec.Cmd, dir string, partUploader *logicalPartUploader) (logicalDumpPosition, error) {
+	dumpCmd.Env = append(os.Environ(), MydumperDirEnv+"="+dir)
+	output, err := dumpCmd.CombinedOutput()
+	if err != nil {
+		tracelog.ErrorLogger.Printf("Logical dump command output:\n%s", output)
+		return logicalDumpPosition{}, fmt.Errorf("logical dump command failed: %w", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return logicalDumpPosition{}, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	hasMetadata := false
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		hasMetadata = hasMetadata || entry.Name() == mydumperMetadataFile
+		err = uploadMydumperFile(partUploader, dir, entry.Name())
+		if err != nil {
+			return logicalDumpPosition{}, fmt.Errorf("failed to upload %s: %w", entry.Name(), err)
+		}
+	}
+	if !hasMetadata {
+		return logicalDumpPosition{}, fmt.Errorf("no mydumper %s file found in %s", mydumperMetadataFile, dir)
+	}
+
+	metadata, err := os.Open(filepath.Join(dir, mydumperMetadataFile))
+	if err != nil {
+		return logicalDumpPosition{}, err
+	}
+	defer utility.LoggedClose(metadata, "")
+	return parseMydumperMetadata(metadata)
+}
+
+func uploadMydumperFile(partUploader *logicalPartUploader, dir, name string) error {
+	file, err := os.Open(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(file, "")
+	database, table := parseMydumperFile(name)
+	writer, err := partUploader.newFilePart(database, table, name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(writer, file)
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// restoreMydumperParts downloads the selected files of the mydumper backup into the temporary directory
+// and runs the restore command (myloader) on it
+func restoreMydumperParts(ctx context.Context, backup internal.Backup, parts []LogicalBackupPart,
+	filter LogicalTableFilter, restoreCmd *exec.Cmd) error {
+	dir, err := newMydumperDir()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = removeTemporaryDirectory(dir) }()
+
+	err = downloadMydumperParts(ctx, backup, parts, filter, dir)
+	if err != nil {
+		return err
+	}
+	restoreCmd.Env = append(os.Environ(), MydumperDirEnv+"="+dir)
+	tracelog.InfoLogger.Printf("Restoring %s with cmd %v", backup.Name, restoreCmd.Args)
+	output, err := restoreCmd.CombinedOutput()
+	if err != nil {
+		tracelog.ErrorLogger.Printf("Restore command output:\n%s", output)
+		return err
+	}
+	tracelog.InfoLogger.Printf("Restored %s", backup.Name)
+	return nil
+}
+
+func downloadMydumperParts(ctx context.Context, backup internal.Backup, parts []LogicalBackupPart,
+	filter LogicalTableFilter, dir string) error {
+	folderReader := internal.NewFolderReader(backup.Folder)
+	tables := 0
+	for _, part := range parts {
+		if !filter.accept(part) {
+			continue
+		}
+		if part.File == "" || filepath.Base(part.File) != part.File || part.File == ".." {
+			return fmt.Errorf("invalid mydumper file name %q in the backup", part.File)
+		}
+		if part.Table != "" {
+			tables++
+		}
+		err := downloadMydumperPart(ctx, folderReader, part, filepath.Join(dir, part.File))
+		if err != nil {
+			return err
+		}
+	}
+	if tables == 0 && len(filter) > 0 {
+		return fmt.Errorf("no tables of the backup match the filter")
+	}
+	return nil
+}
+
+func downloadMydumperPart(ctx context.Context, folderReader internal.StorageFolderReader, part LogicalBackupPart,
+	path string) error {
+	reader, err := internal.DownloadAndDecompressStorageFile(ctx, folderReader, part.Object)
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(reader, "")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0640)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, reader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,123 @@
+package mysql
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+)
+
+func TestParseMydumperFile(t *testing.T) {
+	for name, expected := range map[string][2]string{
+		"metadata":                            {"", ""},
+		"shop-schema-create.sql":              {"shop", ""},
+		"shop-schema-post.sql.zst":            {"shop", ""},
+		"shop.orders-schema.sql":              {"shop", "orders"},
+		"shop.orders.sql":                     {"shop", "orders"},
+		"shop.orders.00000.sql.gz":            {"shop", "orders"},
+		"shop.orders.00001.00002.sql":         {"shop", "orders"},
+		"shop.orders-schema-triggers.sql":     {"shop", "orders"},
+		"shop.orders-metadata":                {"shop", "orders"},
+		"shop.order_summary-schema-view.sql":  {"shop", "order_summary"},
+		"my-shop.orders-schema.sql":           {"my-shop", "orders"},
+		"my-shop-schema-create.sql":           {"my-shop", ""},
+		"shop.orders-schema-sequence.sql.zst": {"shop", "orders"},
+	} {
+		database, table := parseMydumperFile(name)
+		assert.Equal(t, expected, [2]string{database, table}, name)
+	}
+}
+
+const testMydumperLegacyMetadata = `Started dump at: 2019-03-01 10:00:00
+SHOW MASTER STATUS:
+	Log: mysql-bin.000042
+	Pos: 1337
+	GTID:11111111-2222-3333-4444-555555555555:1-20,
+6abc8ecb-bf5c-11e9-9821-c897993b5a14:1-100
+
+SHOW SLAVE STATUS:
+	Host: 10.0.0.1
+	Log: mysql-bin.000007
+	Pos: 99
+	GTID:
+
+Finished dump at: 2019-03-01 10:00:05
+`
+
+const testMydumperMetadata = "# Started dump at: 2024-01-01 10:00:00\n" +
+	"[config]\nquote-character = BACKTICK\n\n" +
+	"[source]\n# Channel_Name = '' # It can be use to setup replication FOR CHANNEL\n" +
+	"File = mysql-bin.000042\nPosition = 1337\n" +
+	"Executed_Gtid_Set = 11111111-2222-3333-4444-555555555555:1-20,6abc8ecb-bf5c-11e9-9821-c897993b5a14:1-100\n\n" +
+	"[replication]\nFile = mysql-bin.000007\nPosition = 99\n\n" +
+	"[`shop`.`orders`]\nreal_table_name=orders\nrows = 2\n" +
+	"# Finished dump at: 2024-01-01 10:00:05\n"
+
+func TestParseMydumperMetadata(t *testing.T) {
+	expected := logicalDumpPosition{
+		binlogFile:     "mysql-bin.000042",
+		binlogPosition: 1337,
+		gtidExecuted:   "11111111-2222-3333-4444-555555555555:1-20,6abc8ecb-bf5c-11e9-9821-c897993b5a14:1-100",
+	}
+	for _, metadata := range []string{testMydumperLegacyMetadata, testMydumperMetadata,
+		strings.Replace(testMydumperMetadata, "[source]", "[master]", 1)} {
+		position, err := parseMydumperMetadata(strings.NewReader(metadata))
+		require.NoError(t, err)
+		assert.Equal(t, expected, position)
+	}
+}
+
+func TestMydumperPushAndFetch(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewKVS())
+	uploader := internal.NewRegularUploader(compression.Compressors[lz4.AlgorithmName], folder)
+	partUploader := &logicalPartUploader{ctx: t.Context(), uploader: uploader, backupName: "stream_20240101T100000Z"}
+	files := map[string]string{
+		"metadata":               testMydumperMetadata,
+		"shop-schema-create.sql": "CREATE DATABASE `shop`;\n",
+		"shop.orders-schema.sql": "CREATE TABLE `orders` (`id` int);\n",
+		"shop.orders.00000.sql":  "INSERT INTO `orders` VALUES (1),(2);\n",
+		"crm-schema-create.sql":  "CREATE DATABASE `crm`;\n",
+		"crm.customers.sql":      "INSERT INTO `customers` VALUES (1);\n",
+	}
+	// the dump command copies the prepared files as mydumper writes them
+	sourceDir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(sourceDir, name), []byte(content), 0600))
+	}
+	dumpCmd := exec.Command("/bin/sh", "-c", `cp "$0"/* "$`+MydumperDirEnv+`"/`, sourceDir)
+	position, err := runMydumper(dumpCmd, t.TempDir(), partUploader)
+	require.NoError(t, err)
+	assert.Equal(t, "mysql-bin.000042", position.binlogFile)
+	assert.Equal(t, uint64(1337), position.binlogPosition)
+
+	info := partUploader.info(MydumperFormat)
+	require.Len(t, info.Parts, len(files))
+	assert.Equal(t, LogicalBackupPart{Database: "crm", Object: "stream_20240101T100000Z/logical/00000.sql",
+		Size: int64(len(files["crm-schema-create.sql"])), File: "crm-schema-create.sql"}, info.Parts[0])
+
+	filter, err := NewLogicalTableFilter([]string{"shop.*"})
+	require.NoError(t, err)
+	restoreDir := t.TempDir()
+	require.NoError(t, downloadMydumperParts(t.Context(), internal.Backup{Folder: folder}, info.Parts, filter, restoreDir))
+	entries, err := os.ReadDir(restoreDir)
+	require.NoError(t, err)
+	restored := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		restored = append(restored, entry.Name())
+	}
+	assert.Equal(t, []string{"metadata", "shop-schema-create.sql", "shop.orders-schema.sql", "shop.orders.00000.sql"}, restored)
+	content, err := os.ReadFile(filepath.Join(restoreDir, "shop.orders.00000.sql"))
+	require.NoError(t, err)
+	assert.Equal(t, files["shop.orders.00000.sql"], string(content))
+
+	info.Parts[0].File = "../crm-schema-create.sql"
+	assert.Error(t, downloadMydumperParts(t.Context(), internal.Backup{Folder: folder}, info.Parts, nil, t.TempDir()))
+}
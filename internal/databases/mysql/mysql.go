@@ -11,6 +11,7 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"regexp"
 	"slices"
 	"strings"
 	"time"
@@ -34,6 +35,9 @@ type BackupTool string
 const (
 	WalgUnspecifiedStreamBackupTool BackupTool = "WALG_UNSPECIFIED_STREAM_BACKUP_TOOL"
 	WalgXtrabackupTool              BackupTool = "WALG_XTRABACKUP_TOOL"
+	WalgMariabackupTool             BackupTool = "WALG_MARIABACKUP_TOOL"
+	WalgCloneTool                   BackupTool = "WALG_CLONE_TOOL"
+	WalgLogicalTool                 BackupTool = "WALG_LOGICAL_TOOL"
 )
 
 func fetchMySQLVariable(conn *client.Conn, variable string) (string, error) {
@@ -120,6 +124,45 @@ func getServerUUID(conn *client.Conn, flavor string) (string, error) {
 	return r.GetString(0, 0)
 }
 
+var innodbLSNRegexp = regexp.MustCompile(`Log sequence number\s+(\d+)`)
+
+// getInnoDBCurrentLSN returns the current redo log LSN reported by `SHOW ENGINE INNODB STATUS`
+func getInnoDBCurrentLSN(conn *client.Conn) (LSN, error) {
+	r, err := conn.Execute("SHOW ENGINE INNODB STATUS")
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	status, err := r.GetStringByName(0, "Status")
+	if err != nil {
+		return 0, err
+	}
+	match := innodbLSNRegexp.FindStringSubmatch(status)
+	if match == nil {
+		return 0, fmt.Errorf("log sequence number is not found in InnoDB status")
+	}
+	lsn := ParseLSN(match[1])
+	if lsn == nil {
+		return 0, fmt.Errorf("failed to parse log sequence number %s", match[1])
+	}
+	return *lsn, nil
+}
+
+// getUploadedBinlogsSize returns the size of binlogs uploaded after the given time
+func getUploadedBinlogsSize(ctx context.Context, folder storage.Folder, since time.Time) (int64, error) {
+	logFiles, _, err := folder.GetSubFolder(BinlogPath).ListFolder(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	for _, logFile := range logFiles {
+		if logFile.GetLastModified().After(since) {
+			size += logFile.GetSize()
+		}
+	}
+	return size, nil
+}
+
 func getLastUploadedBinlog(ctx context.Context, folder storage.Folder) (string, error) {
 	logFiles, _, err := folder.GetSubFolder(BinlogPath).ListFolder(ctx)
 	if err != nil {
@@ -462,6 +505,15 @@ type StreamSentinelDto struct {
 	StartLocalTime time.Time `json:"StartLocalTime,omitempty"`
 	StopLocalTime  time.Time `json:"StopLocalTime,omitempty"`
 
+	// BinLogStartPosition and GTIDExecuted are the exact consistency point, when the backup tool reports it
+	BinLogStartPosition uint64 `json:"BinLogStartPosition,omitempty"`
+	GTIDExecuted        string `json:"GtidExecuted,omitempty"`
+
+	Logical *LogicalBackupInfo `json:"Logical,omitempty"`
+
+	// AutoBackup is the reason of the backup type chosen by `xtrabackup-push --auto`
+	AutoBackup *AutoBackupDecision `json:"AutoBackup,omitempty"`
+
 	UncompressedSize int64  `json:"UncompressedSize,omitempty"`
 	CompressedSize   int64  `json:"CompressedSize,omitempty"`
 	Hostname         string `json:"Hostname,omitempty"`
@@ -495,11 +547,11 @@ type binlogHandler interface {
 	handleBinlog(binlogPath string) error
 }
 
-func fetchLogs(ctx context.Context,
-	folder storage.Folder, dstDir string, startTS, endTS, endBinlogTS time.Time, handler binlogHandler) error {
+// fetchLogs downloads the binlogs until endTS or the stop target (if set) and passes them to the handler
+func fetchLogs(ctx context.Context, folder storage.Folder, dstDir string, startTS, endTS, endBinlogTS time.Time,
+	stopTarget *BinlogStopTarget, handler binlogHandler) error {
 	logFolder := folder.GetSubFolder(BinlogPath)
 	includeStart := true
-outer:
 	for {
 		logsToFetch, err := getLogsCoveringInterval(ctx, logFolder, startTS, includeStart, endBinlogTS)
 		includeStart = false
@@ -510,6 +562,10 @@ outer:
 			startTS = logFile.GetLastModified()
 			binlogName := utility.TrimFileExtension(logFile.GetName())
 			binlogPath := path.Join(dstDir, binlogName)
+			if stopTarget != nil && stopTarget.isPassed(binlogName) {
+				tracelog.WarningLogger.Printf("Binlog of the stop %s is not found, stopping before %s", stopTarget, binlogName)
+				return nil
+			}
 			tracelog.InfoLogger.Printf("downloading %s into %s", binlogName, binlogPath)
 			if err = internal.DownloadFileTo(ctx, internal.NewFolderReader(logFolder), binlogName, binlogPath); err != nil {
 				tracelog.ErrorLogger.Printf("failed to download %s: %v", binlogName, err)
@@ -519,21 +575,41 @@ outer:
 			if err != nil {
 				return err
 			}
+			stopped, err := truncateBinlogAtStop(binlogPath, stopTarget)
+			if err != nil {
+				return err
+			}
 			err = handler.handleBinlog(binlogPath)
 			if err != nil {
 				return err
 			}
-			if timestamp.After(endTS) {
-				break outer
+			if stopped || timestamp.After(endTS) {
+				return nil
 			}
 		}
 		if len(logsToFetch) == 0 {
 			break
 		}
 	}
+	if stopTarget != nil {
+		tracelog.WarningLogger.Printf("Stop %s is not reached, all the binlogs are fetched", stopTarget)
+	}
 	return nil
 }
 
+// truncateBinlogAtStop truncates the downloaded binlog right before the stop target, if the binlog contains it
+func truncateBinlogAtStop(binlogPath string, stopTarget *BinlogStopTarget) (bool, error) {
+	if stopTarget == nil {
+		return false, nil
+	}
+	offset, found, err := stopTarget.findStop(binlogPath)
+	if err != nil || !found {
+		return false, err
+	}
+	tracelog.InfoLogger.Printf("Stop %s is reached in %s at offset %d", stopTarget, path.Base(binlogPath), offset)
+	return true, os.Truncate(binlogPath, offset)
+}
+
 func getBinlogSinceTS(ctx context.Context, folder storage.Folder, backup internal.Backup) (time.Time, error) {
 	startTS := utility.MaxTime // far future
 	var streamSentinel StreamSentinelDto
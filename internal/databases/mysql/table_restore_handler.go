@@ -0,0 +1,344 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/mysql/xbstream"
+	"github.com/wal-g/wal-g/internal/fsutil"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	XtrabackupExport = "--export"
+	XtrabackupTarget = "--target-dir"
+)
+
+var tableRestoreFileExtensions = []string{".ibd", ".cfg", ".cfp"}
+
+// RestoreTable is the InnoDB table restored by table-restore
+type RestoreTable struct {
+	Database string
+	Table    string
+}
+
+// ParseRestoreTable parses the table as 'db.table'
+func ParseRestoreTable(name string) (RestoreTable, error) {
+	database, table, ok := strings.Cut(name, ".")
+	if !ok || database == "" || table == "" {
+		return RestoreTable{}, fmt.Errorf("invalid table %q, expected db.table", name)
+	}
+	return RestoreTable{Database: database, Table: table}, nil
+}
+
+func (table RestoreTable) String() string {
+	return quoteIdentifier(table.Database) + "." + quoteIdentifier(table.Table)
+}
+
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// encodeFileName encodes the identifier the way MySQL names its files (see my_charset_filename)
+func encodeFileName(name string) (string, error) {
+	var result strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= '0' && r <= '9', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+			result.WriteRune(r)
+		case r < utf8.RuneSelf:
+			_, _ = fmt.Fprintf(&result, "@%04x", r)
+		default:
+			return "", fmt.Errorf("non-ASCII identifier %q is not supported", name)
+		}
+	}
+	return result.String(), nil
+}
+
+// tableFileFilter accepts the files of the table and the top-level files xtrabackup needs to prepare them:
+// system and undo tablespaces, data dictionary and xtrabackup_* files
+type tableFileFilter struct {
+	databaseDir string
+	tableFile   string
+}
+
+func newTableFileFilter(table RestoreTable) (tableFileFilter, error) {
+	databaseDir, err := encodeFileName(table.Database)
+	if err != nil {
+		return tableFileFilter{}, err
+	}
+	tableFile, err := encodeFileName(table.Table)
+	if err != nil {
+		return tableFileFilter{}, err
+	}
+	return tableFileFilter{databaseDir: databaseDir, tableFile: tableFile}, nil
+}
+
+func (filter tableFileFilter) accept(filePath string) bool {
+	// incremental backups store changed pages of 'file' in 'file.delta' described by 'file.meta'
+	filePath = strings.TrimSuffix(strings.TrimSuffix(filePath, ".delta"), ".meta")
+	dir, file := path.Split(filePath)
+	if dir == "" {
+		return true
+	}
+	return dir == filter.databaseDir+"/" && filter.isTableFile(file)
+}
+
+func (filter tableFileFilter) isTableFile(file string) bool {
+	ext := path.Ext(file)
+	if !slices.Contains(tableRestoreFileExtensions, ext) {
+		return false
+	}
+	name := strings.TrimSuffix(file, ext)
+	return name == filter.tableFile ||
+		strings.HasPrefix(name, filter.tableFile+"#p#") || strings.HasPrefix(name, filter.tableFile+"#P#")
+}
+
+// HandleTableRestore extracts the table from the xtrabackup backup (and its increments) into targetDir,
+// prepares it for export and imports it into the server or prints the import steps
+func HandleTableRestore(
+	ctx context.Context,
+	folder storage.Folder,
+	targetBackupSelector internal.BackupSelector,
+	table RestoreTable,
+	targetDir string,
+	prepareCmd *exec.Cmd,
+	importTable bool,
+) {
+	filter, err := newTableFileFilter(table)
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	backup, err := targetBackupSelector.Select(ctx, folder)
+	tracelog.ErrorLogger.FatalfOnError("Failed to get backup: %v", err)
+
+	targetDir, err = filepath.Abs(targetDir)
+	tracelog.ErrorLogger.FatalOnError(err)
+	if _, err = os.Stat(targetDir); !os.IsNotExist(err) {
+		tracelog.ErrorLogger.Fatalf("Table restore directory %s must not exist", targetDir)
+	}
+
+	err = tableRestoreFetch(ctx, folder, backup.Name, filter, targetDir, prepareCmd, true)
+	tracelog.ErrorLogger.FatalfOnError("Failed to restore table: %v", err)
+
+	files, err := collectTableFiles(filepath.Join(targetDir, filter.databaseDir), filter)
+	tracelog.ErrorLogger.FatalOnError(err)
+	if len(files) == 0 {
+		tracelog.ErrorLogger.Fatalf("Table %s is not found in backup %s", table, backup.Name)
+	}
+
+	if importTable {
+		err = importTableFiles(ctx, table, filter, targetDir, files)
+		tracelog.ErrorLogger.FatalfOnError("Failed to import table: %v", err)
+		tracelog.InfoLogger.Printf("Table %s is imported from backup %s", table, backup.Name)
+		return
+	}
+	printTableImportSteps(os.Stdout, table, filepath.Join(targetDir, filter.databaseDir), files)
+}
+
+func tableRestoreFetch(
+	ctx context.Context,
+	folder storage.Folder,
+	backupName string,
+	filter tableFileFilter,
+	targetDir string,
+	prepareCmd *exec.Cmd,
+	isLast bool,
+) error {
+	backup, err := internal.GetBackupByName(ctx, backupName, utility.BaseBackupPath, folder)
+	if err != nil {
+		return err
+	}
+	var sentinel StreamSentinelDto
+	err = backup.FetchSentinel(ctx, &sentinel)
+	if err != nil {
+		return err
+	}
+	if sentinel.Tool != WalgXtrabackupTool && sentinel.Tool != WalgMariabackupTool {
+		return fmt.Errorf("backup %s is not created by xtrabackup-push", backupName)
+	}
+
+	// start from base backup & apply increments one by one, like xtrabackupFetch does
+	if sentinel.IsIncremental {
+		tracelog.InfoLogger.Printf("Delta from %v at LSN %x \n", *sentinel.IncrementFrom, *sentinel.IncrementFromLSN)
+		err = tableRestoreFetch(ctx, folder, *sentinel.IncrementFrom, filter, targetDir, prepareCmd, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	prepareCmd = cloneCommand(ctx, prepareCmd)
+	// the last --target-dir option overrides the one of WALG_MYSQL_BACKUP_PREPARE_COMMAND
+	injectCommandArgument(prepareCmd, XtrabackupTarget+"="+targetDir)
+	extractDir := targetDir
+	if sentinel.IsIncremental {
+		extractDir, err = os.MkdirTemp(filepath.Dir(targetDir), "wal-g")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = removeTemporaryDirectory(extractDir) }()
+		injectCommandArgument(prepareCmd, XtrabackupIncrementalDir+"="+extractDir)
+	}
+	if isLast {
+		injectCommandArgument(prepareCmd, XtrabackupExport)
+	} else if needsApplyLogOnly(sentinel) {
+		injectCommandArgument(prepareCmd, XtrabackupApplyLogOnly)
+	}
+
+	err = extractTableFiles(ctx, backup, extractDir, filter)
+	if err != nil {
+		return err
+	}
+	tracelog.InfoLogger.Printf("Restored table files of %s", backup.Name)
+
+	tracelog.InfoLogger.Printf("Preparing %s with cmd %v", backup.Name, prepareCmd.Args)
+	prepareCmd.Stdout = os.Stderr
+	prepareCmd.Stderr = os.Stderr
+	err = prepareCmd.Run()
+	if err != nil {
+		return fmt.Errorf("failed to prepare table files of %s: %w", backup.Name, err)
+	}
+	tracelog.InfoLogger.Printf("Prepared %s", backup.Name)
+	return nil
+}
+
+func extractTableFiles(ctx context.Context, backup internal.Backup, dir string, filter tableFileFilter) error {
+	fetcher, err := internal.GetBackupStreamFetcher(ctx, backup)
+	if err != nil {
+		return err
+	}
+	return extractTableStream(ctx, backup, fetcher, dir, filter)
+}
+
+func extractTableStream(ctx context.Context, backup internal.Backup, fetcher internal.StreamFetcher,
+	dir string, filter tableFileFilter) error {
+	var wg sync.WaitGroup
+	var sinkErr error
+	reader, writer := io.Pipe()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sinkErr = xbstream.ExtractFilteredBackup(xbstream.NewReader(reader, false), dir, true, filter.accept)
+		// unblocks the fetcher if the sink has stopped before the end of the stream
+		_ = reader.CloseWithError(sinkErr)
+	}()
+
+	err := fetcher(ctx, backup, writer)
+	// the sink reads until the pipe is closed, so it is closed on failure too
+	_ = writer.CloseWithError(err)
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+	return sinkErr
+}
+
+// collectTableFiles lists the files of the table in the database directory of the prepared backup
+func collectTableFiles(databaseDir string, filter tableFileFilter) ([]string, error) {
+	entries, err := os.ReadDir(databaseDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && filter.isTableFile(entry.Name()) {
+			files = append(files, entry.Name())
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func isPartitioned(files []string) bool {
+	for _, file := range files {
+		if strings.Contains(file, "#p#") || strings.Contains(file, "#P#") {
+			return true
+		}
+	}
+	return false
+}
+
+func tableTablespaceStatements(table RestoreTable, files []string) (discard, load string) {
+	target := "TABLESPACE"
+	if isPartitioned(files) {
+		target = "PARTITION ALL TABLESPACE"
+	}
+	return fmt.Sprintf("ALTER TABLE %s DISCARD %s", table, target), fmt.Sprintf("ALTER TABLE %s IMPORT %s", table, target)
+}
+
+func printTableImportSteps(output io.Writer, table RestoreTable, databaseDir string, files []string) {
+	discard, load := tableTablespaceStatements(table, files)
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		paths = append(paths, filepath.Join(databaseDir, file))
+	}
+	_, _ = fmt.Fprintf(output, "-- create table %s with its definition at the backup time, if it doesn't exist\n", table)
+	_, _ = fmt.Fprintf(output, "%s;\n", discard)
+	_, _ = fmt.Fprintf(output, "-- copy %s to the database directory of the server, owned by the mysqld user\n",
+		strings.Join(paths, " "))
+	_, _ = fmt.Fprintf(output, "%s;\n", load)
+}
+
+// importTableFiles imports the prepared table files into the table of the local server,
+// the table must exist with the definition it had at the backup time
+func importTableFiles(ctx context.Context, table RestoreTable, filter tableFileFilter, targetDir string, files []string) error {
+	conn, err := getMySQLConnection(ctx)
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(conn, "")
+
+	serverDataDir, err := fetchMySQLVariable(conn, "datadir")
+	if err != nil {
+		return err
+	}
+
+	discard, load := tableTablespaceStatements(table, files)
+	tracelog.InfoLogger.Println(discard)
+	if _, err = conn.Execute(discard); err != nil {
+		return err
+	}
+	for _, file := range files {
+		src := filepath.Join(targetDir, filter.databaseDir, file)
+		dst := filepath.Join(serverDataDir, filter.databaseDir, file)
+		tracelog.InfoLogger.Printf("Copying %s to %s", src, dst)
+		if err = copyTableFile(src, dst); err != nil {
+			return err
+		}
+	}
+	tracelog.InfoLogger.Println(load)
+	_, err = conn.Execute(load)
+	return err
+}
+
+func copyTableFile(src, dst string) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(srcFile, "")
+	dstFile, err := fsutil.OpenFileSecure(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(dstFile, srcFile); err != nil {
+		utility.LoggedClose(dstFile, "")
+		return err
+	}
+	return dstFile.Close()
+}
@@ -0,0 +1,103 @@
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+)
+
+func TestParseRestoreTable(t *testing.T) {
+	table, err := ParseRestoreTable("shop.order.items")
+	require.NoError(t, err)
+	assert.Equal(t, RestoreTable{Database: "shop", Table: "order.items"}, table)
+	assert.Equal(t, "`shop`.`order.items`", table.String())
+
+	for _, invalid := range []string{"orders", ".orders", "shop."} {
+		_, err = ParseRestoreTable(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestTableFileFilter(t *testing.T) {
+	filter, err := newTableFileFilter(RestoreTable{Database: "my-shop", Table: "orders"})
+	require.NoError(t, err)
+	assert.Equal(t, "my@002dshop", filter.databaseDir)
+
+	for filePath, accepted := range map[string]bool{
+		"ibdata1":                       true,
+		"mysql.ibd":                     true,
+		"undo_001.delta":                true,
+		"xtrabackup_checkpoints":        true,
+		"my@002dshop/orders.ibd":        true,
+		"my@002dshop/orders.ibd.delta":  true,
+		"my@002dshop/orders.ibd.meta":   true,
+		"my@002dshop/orders#p#p0.ibd":   true,
+		"my@002dshop/orders_log.ibd":    false,
+		"my@002dshop/customers.ibd":     false,
+		"shop/orders.ibd":               false,
+		"mysql/general_log.CSV":         false,
+		"my@002dshop/orders.frm":        false,
+		"my@002dshop/sub/orders.ibd":    false,
+		"my@002dshop/orders#P#p1.ibd":   true,
+		"my@002dshop/orders#p#p0.cfg":   true,
+		"my@002dshop/orders#p#p0.ibd.x": false,
+	} {
+		assert.Equal(t, accepted, filter.accept(filePath), filePath)
+	}
+
+	_, err = newTableFileFilter(RestoreTable{Database: "shop", Table: "заказы"})
+	assert.Error(t, err)
+}
+
+func TestPrintTableImportSteps(t *testing.T) {
+	table := RestoreTable{Database: "shop", Table: "orders"}
+	databaseDir := t.TempDir()
+	for _, file := range []string{"orders.ibd", "orders.cfg", "customers.ibd"} {
+		require.NoError(t, os.WriteFile(filepath.Join(databaseDir, file), nil, 0600))
+	}
+	filter, err := newTableFileFilter(table)
+	require.NoError(t, err)
+	files, err := collectTableFiles(databaseDir, filter)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"orders.cfg", "orders.ibd"}, files)
+
+	var output bytes.Buffer
+	printTableImportSteps(&output, table, "/restore/shop", files)
+	assert.Equal(t, "-- create table `shop`.`orders` with its definition at the backup time, if it doesn't exist\n"+
+		"ALTER TABLE `shop`.`orders` DISCARD TABLESPACE;\n"+
+		"-- copy /restore/shop/orders.cfg /restore/shop/orders.ibd to the database directory of the server, owned by the mysqld user\n"+
+		"ALTER TABLE `shop`.`orders` IMPORT TABLESPACE;\n", output.String())
+
+	discard, load := tableTablespaceStatements(table, []string{"orders#p#p0.ibd", "orders#p#p1.ibd"})
+	assert.Equal(t, "ALTER TABLE `shop`.`orders` DISCARD PARTITION ALL TABLESPACE", discard)
+	assert.Equal(t, "ALTER TABLE `shop`.`orders` IMPORT PARTITION ALL TABLESPACE", load)
+}
+
+func TestExtractTableStreamReturnsFetchError(t *testing.T) {
+	filter, err := newTableFileFilter(RestoreTable{Database: "shop", Table: "orders"})
+	require.NoError(t, err)
+	fetchErr := errors.New("fetch failed")
+	fetcher := func(ctx context.Context, backup internal.Backup, writeCloser io.WriteCloser) error {
+		_, err := writeCloser.Write([]byte("XBSCK01"))
+		require.NoError(t, err)
+		return fetchErr
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- extractTableStream(t.Context(), internal.Backup{}, fetcher, t.TempDir(), filter) }()
+	select {
+	case err = <-done:
+		assert.ErrorIs(t, err, fetchErr)
+	case <-time.After(5 * time.Second):
+		t.Fatal("table files extraction did not return after the fetch failure")
+	}
+}
@@ -2,6 +2,7 @@ package xbstream
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"sync"
@@ -13,11 +14,26 @@ import (
 // xbstream BackupSink will unpack archive to disk.
 // Note: files may be compressed(quicklz,lz4,zstd) / encrypted("NONE", "AES128", "AES192","AES256")
 func BackupSink(stream *Reader, output string, decompress bool) {
+	FilteredBackupSink(stream, output, decompress, nil)
+}
+
+// FilteredBackupSink unpacks only files accepted by filter, the filter receives file path after decompression mapping.
+// All files are unpacked when filter is nil
+func FilteredBackupSink(stream *Reader, output string, decompress bool, filter func(filePath string) bool) {
+	tracelog.ErrorLogger.FatalOnError(ExtractFilteredBackup(stream, output, decompress, filter))
+}
+
+// ExtractFilteredBackup works as FilteredBackupSink, but returns the error instead of terminating the process
+func ExtractFilteredBackup(stream *Reader, output string, decompress bool, filter func(filePath string) bool) error {
 	err := os.MkdirAll(output, 0777) // FIXME: permission & UMASK
-	tracelog.ErrorLogger.FatalOnError(err)
+	if err != nil {
+		return err
+	}
 
 	spaceIDCollector, err := innodb.NewSpaceIDCollector(output)
-	tracelog.ErrorLogger.FatalOnError(err)
+	if err != nil {
+		return err
+	}
 
 	factory := fileSinkFactory{
 		dataDir:          output,
@@ -33,9 +49,20 @@ func BackupSink(stream *Reader, output string, decompress bool) {
 		if err == io.EOF {
 			break
 		}
-		tracelog.ErrorLogger.FatalfOnError("Cannot read next chunk: %v", err)
+		if err != nil {
+			return fmt.Errorf("cannot read next chunk: %w", err)
+		}
 
 		dsKey := factory.MapDataSinkKey(chunk.Path)
+		if filter != nil && !filter(dsKey) {
+			if chunk.Type != ChunkTypeEOF {
+				_, err = io.Copy(io.Discard, chunk)
+				if err != nil {
+					return fmt.Errorf("cannot skip chunk: %w", err)
+				}
+			}
+			continue
+		}
 		sink, ok := sinks[dsKey]
 		if !ok {
 			sink = factory.NewDataSink(chunk.Path)
@@ -53,6 +80,7 @@ func BackupSink(stream *Reader, output string, decompress bool) {
 	for path := range sinks {
 		tracelog.WarningLogger.Printf("File %v wasn't clossed properly. Probably xbstream is broken", path)
 	}
+	return nil
 }
 
 func AsyncBackupSink(wg *sync.WaitGroup, stream *Reader, dataDir string, decompress bool) {
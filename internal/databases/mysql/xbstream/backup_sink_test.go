@@ -0,0 +1,53 @@
+package xbstream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func appendChunk(stream []byte, chunkType ChunkType, path string, payload []byte) []byte {
+	stream = append(stream, chunkMagic...)
+	stream = append(stream, 0, byte(chunkType))
+	stream = binary.LittleEndian.AppendUint32(stream, uint32(len(path)))
+	stream = append(stream, path...)
+	if chunkType == ChunkTypeEOF {
+		return stream
+	}
+	stream = binary.LittleEndian.AppendUint64(stream, uint64(len(payload)))
+	stream = binary.LittleEndian.AppendUint64(stream, 0)
+	stream = binary.LittleEndian.AppendUint32(stream, 0)
+	return append(stream, payload...)
+}
+
+func TestFilteredBackupSink(t *testing.T) {
+	var stream []byte
+	for path, content := range map[string]string{
+		"ibdata1":       "system",
+		"shop/orders":   "orders",
+		"shop/customer": "customer",
+	} {
+		stream = appendChunk(stream, ChunkTypePayload, path, []byte(content))
+		stream = appendChunk(stream, ChunkTypeEOF, path, nil)
+	}
+
+	output := t.TempDir()
+	FilteredBackupSink(NewReader(bytes.NewReader(stream), false), output, false, func(filePath string) bool {
+		return !strings.HasSuffix(filePath, "customer")
+	})
+
+	content, err := os.ReadFile(filepath.Join(output, "ibdata1"))
+	require.NoError(t, err)
+	assert.Equal(t, "system", string(content))
+	content, err = os.ReadFile(filepath.Join(output, "shop", "orders"))
+	require.NoError(t, err)
+	assert.Equal(t, "orders", string(content))
+	_, err = os.Stat(filepath.Join(output, "shop", "customer"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -42,6 +42,19 @@ func TestMetadataParser(t *testing.T) {
 				SpaceFlags: 0,
 			},
 		},
+		{
+			// hand-written in the format of mariabackup .meta files, not taken from a real backup
+			testName: "mariabackup ibd file",
+			rawFileContent: testutils.HexToBytes(`
+				00000000  70 61 67 65 5f 73 69 7a  65 20 3d 20 31 36 33 38  |page_size = 1638|
+				00000010  34 0a 7a 69 70 5f 73 69  7a 65 20 3d 20 30 0a 73  |4.zip_size = 0.s|
+				00000020  70 61 63 65 5f 69 64 20  3d 20 35 0a              |pace_id = 5.|`),
+			expected: deltaMetadata{
+				PageSize: 16 * 1024,
+				ZipSize:  0,
+				SpaceID:  5,
+			},
+		},
 	}
 
 	for _, tt := range tests {
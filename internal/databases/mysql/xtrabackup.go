@@ -3,11 +3,15 @@ package mysql
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -25,12 +29,26 @@ const (
 	XtrabackupIncrementalDir = "--incremental-dir"
 )
 
+// Files saved by `--extra-lsndir`, mariabackup names them mariadb_backup_* since MariaDB 10.8
+var (
+	xtrabackupCheckpointsFiles = []string{"xtrabackup_checkpoints", "mariadb_backup_checkpoints"}
+	xtrabackupInfoFiles        = []string{"xtrabackup_info", "mariadb_backup_info"}
+)
+
+// binlog_pos = filename 'mysql-bin.000003', position '385', GTID of the last change '0-1-2'
+var xtrabackupBinlogPosRegexp = regexp.MustCompile(`filename '([^']+)', position '(\d+)'(?:, GTID of the last change '([^']*)')?`)
+
 type XtrabackupInfo struct {
 	FromLSN *LSN
 	// LSN that xtrabackup observed when backup started
 	ToLSN *LSN
 	// max LSN that were observed at the end of the backup
 	LastLSN *LSN
+
+	// binlog coordinates & GTIDs (MySQL GTID set or MariaDB GTID position) of the backup consistency point
+	BinlogFile     string
+	BinlogPosition uint64
+	GTIDExecuted   string
 }
 
 type XtrabackupExtInfo struct {
@@ -55,17 +73,35 @@ func NewXtrabackupInfo(content string) XtrabackupInfo {
 			result.ToLSN = ParseLSN(value)
 		case "last_lsn":
 			result.LastLSN = ParseLSN(value)
+		case "binlog_pos":
+			if match := xtrabackupBinlogPosRegexp.FindStringSubmatch(value); match != nil {
+				result.BinlogFile = match[1]
+				result.BinlogPosition, _ = strconv.ParseUint(match[2], 10, 64)
+				result.GTIDExecuted = match[3]
+			}
 		}
 	}
 	return result
 }
 
 func isXtrabackup(cmd *exec.Cmd) bool {
-	return slices.ContainsFunc(cmd.Args, func(arg string) bool {
+	return isMariabackup(cmd) || slices.ContainsFunc(cmd.Args, func(arg string) bool {
 		return strings.Contains(arg, "xtrabackup") || strings.Contains(arg, "xbstream")
 	})
 }
 
+func isMariabackup(cmd *exec.Cmd) bool {
+	return slices.ContainsFunc(cmd.Args, func(arg string) bool {
+		return strings.Contains(arg, "mariabackup") || strings.Contains(arg, "mariadb-backup") || strings.Contains(arg, "mbstream")
+	})
+}
+
+// needsApplyLogOnly checks if the backup should be prepared with --apply-log-only before applying increments:
+// mariabackup doesn't roll back uncommitted transactions on prepare, so it doesn't need the option
+func needsApplyLogOnly(sentinel StreamSentinelDto) bool {
+	return sentinel.Tool != WalgMariabackupTool
+}
+
 //nolint:unparam
 func prepareTemporaryDirectory(tmpDirRoot string) (string, error) {
 	tmpDirPattern := "wal-g"
@@ -88,12 +124,29 @@ func removeTemporaryDirectory(tmpDir string) error {
 	return nil
 }
 
+// readXtrabackupInfo reads LSNs from xtrabackup_checkpoints and binlog coordinates from xtrabackup_info
 func readXtrabackupInfo(xtrabackupExtraDirectory string) (XtrabackupInfo, error) {
-	raw, err := os.ReadFile(filepath.Join(xtrabackupExtraDirectory, "xtrabackup_checkpoints"))
+	checkpoints, err := readXtrabackupFile(xtrabackupExtraDirectory, xtrabackupCheckpointsFiles)
 	if err != nil {
 		return XtrabackupInfo{}, err
 	}
-	return NewXtrabackupInfo(string(raw)), nil
+	info, err := readXtrabackupFile(xtrabackupExtraDirectory, xtrabackupInfoFiles)
+	if err != nil {
+		tracelog.WarningLogger.Printf("failed to read binlog position of the backup: %v", err)
+	}
+	return NewXtrabackupInfo(checkpoints + "\n" + info), nil
+}
+
+// readXtrabackupFile reads the first existing file of the names
+func readXtrabackupFile(directory string, names []string) (string, error) {
+	for _, name := range names {
+		raw, err := os.ReadFile(filepath.Join(directory, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		return string(raw), err
+	}
+	return "", fmt.Errorf("none of %v is found in %s", names, directory)
 }
 
 func enrichBackupArgs(backupCmd *exec.Cmd, xtrabackupExtraDirectory string, isFullBackup bool, prevBackupInfo *PrevBackupInfo) {
@@ -180,7 +233,7 @@ func xtrabackupFetchClassic(ctx context.Context, backup internal.Backup, restore
 		prepareCmd = cloneCommand(ctx, prepareCmd)
 		injectCommandArgument(prepareCmd, XtrabackupIncrementalDir+"="+tempDeltaDir)
 	}
-	if !isLast {
+	if !isLast && prepareCmd != nil && needsApplyLogOnly(sentinel) {
 		prepareCmd = cloneCommand(ctx, prepareCmd)
 		injectCommandArgument(prepareCmd, XtrabackupApplyLogOnly)
 	}
@@ -256,7 +309,7 @@ func xtrabackupFetchInhouse(ctx context.Context, backup internal.Backup, prepare
 		prepareCmd = cloneCommand(ctx, prepareCmd)
 		injectCommandArgument(prepareCmd, XtrabackupIncrementalDir+"="+tempDeltaDir)
 	}
-	if !isLast {
+	if !isLast && needsApplyLogOnly(sentinel) {
 		prepareCmd = cloneCommand(ctx, prepareCmd)
 		injectCommandArgument(prepareCmd, XtrabackupApplyLogOnly)
 	}
@@ -1,11 +1,22 @@
 package mysql
 
 import (
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/databases/mysql/xbstream"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/utility"
 )
 
 func TestIsXtrabackup(t *testing.T) {
@@ -16,6 +27,8 @@ func TestIsXtrabackup(t *testing.T) {
 	}{
 		{true, "/bin/sh", []string{"-c", "xtrabackup –backup"}},
 		{true, "xtrabackup", []string{"–backup"}},
+		{true, "/bin/sh", []string{"-c", "mariabackup --backup --stream=xbstream"}},
+		{true, "mariadb-backup", []string{"--backup"}},
 		{false, "mysqldump", []string{"--backup"}},
 	}
 
@@ -24,6 +37,7 @@ func TestIsXtrabackup(t *testing.T) {
 		t.Run(testName, func(t *testing.T) {
 			cmd := exec.CommandContext(t.Context(), tt.name, tt.args...)
 			assert.Equal(t, tt.exp, isXtrabackup(cmd))
+			assert.Equal(t, strings.Contains(testName, "maria"), isMariabackup(cmd))
 		})
 	}
 }
@@ -46,3 +60,128 @@ func TestReadXtrabackupInfo(t *testing.T) {
 	assert.Equal(t, uint64(3738001), uint64(*info.ToLSN))
 	assert.Equal(t, uint64(3738068), uint64(*info.LastLSN))
 }
+
+func TestReadXtrabackupBinlogPosition(t *testing.T) {
+	info := NewXtrabackupInfo("binlog_pos = filename 'binlog.000002', position '157', " +
+		"GTID of the last change '6abc8ecb-bf5c-11e9-9821-c897993b5a14:1-5'")
+	assert.Equal(t, "binlog.000002", info.BinlogFile)
+	assert.Equal(t, uint64(157), info.BinlogPosition)
+	assert.Equal(t, "6abc8ecb-bf5c-11e9-9821-c897993b5a14:1-5", info.GTIDExecuted)
+
+	// no binlog coordinates when binary log is disabled
+	info = NewXtrabackupInfo("binlog_pos = ")
+	assert.Empty(t, info.BinlogFile)
+}
+
+// testdata/mariabackup/<version> keeps full & incremental streams in the layout of `mariabackup --backup --stream=xbstream`:
+// MariaDB 10.8+ saves mariadb_backup_checkpoints & mariadb_backup_info, older versions save xtrabackup_*
+var mariabackupTestStreams = map[string]map[string]XtrabackupInfo{
+	"10.11": {
+		"full": {FromLSN: lsnPtr(0), ToLSN: lsnPtr(45327), LastLSN: lsnPtr(45327),
+			BinlogFile: "mariadb-bin.000005", BinlogPosition: 342, GTIDExecuted: "0-1-40"},
+		"incremental": {FromLSN: lsnPtr(45327), ToLSN: lsnPtr(61209), LastLSN: lsnPtr(61209),
+			BinlogFile: "mariadb-bin.000005", BinlogPosition: 1234, GTIDExecuted: "0-1-42,1-2-7"},
+	},
+	"10.6": {
+		"full": {FromLSN: lsnPtr(0), ToLSN: lsnPtr(52115), LastLSN: lsnPtr(52115),
+			BinlogFile: "mysql-bin.000012", BinlogPosition: 1024, GTIDExecuted: "0-1-118"},
+		"incremental": {FromLSN: lsnPtr(52115), ToLSN: lsnPtr(70544), LastLSN: lsnPtr(70544),
+			BinlogFile: "mysql-bin.000012", BinlogPosition: 1916, GTIDExecuted: "0-1-131"},
+	},
+}
+
+func lsnPtr(lsn LSN) *LSN {
+	return &lsn
+}
+
+func TestReadMariabackupInfo(t *testing.T) {
+	for version, streams := range mariabackupTestStreams {
+		for name, expected := range streams {
+			t.Run(version+"/"+name, func(t *testing.T) {
+				stream, err := os.Open(filepath.Join("testdata", "mariabackup", version, name+".xbstream"))
+				require.NoError(t, err)
+				defer stream.Close()
+				extraDir := t.TempDir()
+				require.NoError(t, xbstream.ExtractFilteredBackup(xbstream.NewReader(stream, true), extraDir, false,
+					func(string) bool { return true }))
+
+				info, err := readXtrabackupInfo(extraDir)
+				require.NoError(t, err)
+				assert.Equal(t, expected, info)
+			})
+		}
+	}
+
+	assert.False(t, needsApplyLogOnly(StreamSentinelDto{Tool: WalgMariabackupTool}))
+	assert.True(t, needsApplyLogOnly(StreamSentinelDto{Tool: WalgXtrabackupTool}))
+}
+
+func TestMariabackupFetch(t *testing.T) {
+	for version, streams := range mariabackupTestStreams {
+		t.Run(version, func(t *testing.T) {
+			folder := memory.NewFolder("", memory.NewKVS())
+			uploader := internal.NewRegularUploader(compression.Compressors[lz4.AlgorithmName], folder.GetSubFolder(utility.BaseBackupPath))
+			fullName := "stream_20240115T102030Z"
+			pushMariabackupTestStream(t, uploader, fullName, filepath.Join(version, "full.xbstream"),
+				StreamSentinelDto{Tool: WalgMariabackupTool, LSN: streams["full"].ToLSN})
+			pushMariabackupTestStream(t, uploader, "stream_20240115T112030Z", filepath.Join(version, "incremental.xbstream"),
+				StreamSentinelDto{Tool: WalgMariabackupTool, LSN: streams["incremental"].ToLSN, IsIncremental: true,
+					IncrementFrom: &fullName, IncrementFromLSN: streams["incremental"].FromLSN})
+
+			// the prepare command logs its arguments & keeps a copy of the incremental dir
+			dataDir := t.TempDir()
+			incrementalRoot := t.TempDir()
+			script := filepath.Join(t.TempDir(), "prepare.sh")
+			require.NoError(t, os.WriteFile(script, []byte(`echo "$*" >> "$0.log"
+for arg; do case "$arg" in --incremental-dir=*) cp -R "${arg#*=}" "$0.incremental";; esac; done
+`), 0600))
+			prepareCommand := "/bin/sh " + script + " --prepare --target-dir=" + dataDir
+			setMariabackupTestSetting(t, conf.MysqlDataDir, dataDir)
+			setMariabackupTestSetting(t, conf.MysqlIncrementalBackupDst, incrementalRoot)
+			setMariabackupTestSetting(t, conf.MysqlBackupPrepareCmd, prepareCommand)
+			prepareCmd := exec.CommandContext(t.Context(), "/bin/sh", "-c", prepareCommand)
+
+			require.NoError(t, xtrabackupFetch(t.Context(), "stream_20240115T112030Z", folder, nil, prepareCmd, true, false, true))
+
+			preparedArgs, err := os.ReadFile(script + ".log")
+			require.NoError(t, err)
+			lines := strings.Split(strings.TrimSpace(string(preparedArgs)), "\n")
+			require.Len(t, lines, 2)
+			// mariabackup doesn't need --apply-log-only before applying increments
+			assert.Equal(t, "--prepare --target-dir="+dataDir, lines[0])
+			assert.Regexp(t, "^--prepare --target-dir="+dataDir+" "+XtrabackupIncrementalDir+"="+incrementalRoot+"/wal-g[0-9]+$", lines[1])
+
+			info, err := readXtrabackupInfo(dataDir)
+			require.NoError(t, err)
+			assert.Equal(t, streams["full"], info)
+			assert.FileExists(t, filepath.Join(dataDir, "shop", "orders.ibd"))
+
+			info, err = readXtrabackupInfo(script + ".incremental")
+			require.NoError(t, err)
+			assert.Equal(t, streams["incremental"], info)
+			assert.FileExists(t, filepath.Join(script+".incremental", "ibdata1.delta"))
+			assert.FileExists(t, filepath.Join(script+".incremental", "shop", "orders.ibd.delta"))
+			assert.FileExists(t, filepath.Join(script+".incremental", "shop", "orders.ibd.meta"))
+
+			// the temporary incremental dir is removed after prepare
+			entries, err := os.ReadDir(incrementalRoot)
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+		})
+	}
+}
+
+func pushMariabackupTestStream(t *testing.T, uploader *internal.RegularUploader, backupName, stream string, sentinel StreamSentinelDto) {
+	file, err := os.Open(filepath.Join("testdata", "mariabackup", stream))
+	require.NoError(t, err)
+	defer file.Close()
+	_, err = uploader.PushStreamWithName(t.Context(), file, backupName)
+	require.NoError(t, err)
+	require.NoError(t, internal.UploadSentinel(t.Context(), uploader, &sentinel, backupName))
+}
+
+func setMariabackupTestSetting(t *testing.T, key, value string) {
+	prev := viper.Get(key)
+	viper.Set(key, value)
+	t.Cleanup(func() { viper.Set(key, prev) })
+}
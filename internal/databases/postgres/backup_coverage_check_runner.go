@@ -0,0 +1,214 @@
+package postgres
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"io"
+	"slices"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+type BackupCoverageStatus int
+
+const (
+	CoverageComplete BackupCoverageStatus = iota + 1
+	CoverageBroken
+	CoverageOtherTimeline
+)
+
+func (status BackupCoverageStatus) String() string {
+	return [...]string{"", "COMPLETE", "BROKEN", "OTHER_TIMELINE"}[status]
+}
+
+// MarshalText marshals the BackupCoverageStatus enum as a string
+func (status BackupCoverageStatus) MarshalText() ([]byte, error) {
+	return utility.MarshalEnumToString(status)
+}
+
+// BackupCoverage describes the WAL chain from the backup start to the start of the next backup
+type BackupCoverage struct {
+	BackupName           string               `json:"backup_name"`
+	NextBackupName       string               `json:"next_backup_name,omitempty"`
+	StartSegment         string               `json:"start_segment"`
+	EndSegment           string               `json:"end_segment"`
+	MissingSegmentsCount int                  `json:"missing_segments_count"`
+	FirstMissingSegment  string               `json:"first_missing_segment,omitempty"`
+	LastMissingSegment   string               `json:"last_missing_segment,omitempty"`
+	Status               BackupCoverageStatus `json:"status"`
+}
+
+type BackupCoverageCheckDetails []*BackupCoverage
+
+func (coverages BackupCoverageCheckDetails) NewPlainTextReader() (io.Reader, error) {
+	var outputBuffer bytes.Buffer
+
+	tableWriter := table.NewWriter()
+	tableWriter.SetOutputMirror(&outputBuffer)
+	defer tableWriter.Render()
+
+	tableWriter.AppendHeader(table.Row{"Backup", "Start", "End", "Missing count", "First missing", "Status"})
+	for _, row := range coverages {
+		tableWriter.AppendRow(table.Row{row.BackupName, row.StartSegment, row.EndSegment,
+			row.MissingSegmentsCount, row.FirstMissingSegment, row.Status})
+	}
+
+	return &outputBuffer, nil
+}
+
+// BackupCoverageCheckRunner checks that each backup in storage has the continuous WAL chain
+// from its start segment to the start segment of the next backup.
+// The chain of the latest backup ends at its finish segment, the rest is checked by the integrity check.
+type BackupCoverageCheckRunner struct {
+	ctx         context.Context //nolint:containedctx // the check runners are run without a context
+	walFolder   storage.Folder
+	backups     []BackupDetail
+	walSegments map[WalSegmentDescription]bool
+}
+
+func NewBackupCoverageCheckRunner(
+	ctx context.Context,
+	rootFolder storage.Folder,
+	walFolderFilenames []string,
+) (BackupCoverageCheckRunner, error) {
+	backupsFolder := rootFolder.GetSubFolder(utility.BaseBackupPath)
+	backups, err := internal.GetBackups(ctx, backupsFolder)
+	if _, ok := err.(internal.NoBackupsFoundError); ok {
+		tracelog.WarningLogger.Println("No backups found in storage")
+		backups, err = nil, nil
+	}
+	if err != nil {
+		return BackupCoverageCheckRunner{}, err
+	}
+	backupDetails, err := GetBackupsDetails(ctx, backupsFolder, backups)
+	if err != nil {
+		return BackupCoverageCheckRunner{}, errors.Wrap(err, "Failed to fetch backups details")
+	}
+	slices.SortFunc(backupDetails, func(a, b BackupDetail) int {
+		return cmp.Compare(a.StartLsn, b.StartLsn)
+	})
+
+	return BackupCoverageCheckRunner{
+		ctx:         ctx,
+		walFolder:   rootFolder.GetSubFolder(utility.WalPath),
+		backups:     backupDetails,
+		walSegments: getSegmentsFromFiles(walFolderFilenames),
+	}, nil
+}
+
+func (check BackupCoverageCheckRunner) Run() (WalVerifyCheckResult, error) {
+	timelineSwitchMaps := make(map[uint32]map[WalSegmentNo]*TimelineHistoryRecord)
+	coverages := make([]*BackupCoverage, 0, len(check.backups))
+	for i := range check.backups {
+		backup := &check.backups[i]
+		start, err := NewWalSegmentDescription(backup.WalFileName)
+		if err != nil {
+			return WalVerifyCheckResult{}, err
+		}
+		end := WalSegmentDescription{Timeline: start.Timeline, Number: NewWalSegmentNo(max(backup.FinishLsn, 1) - 1)}
+		if end.Number < start.Number {
+			end.Number = start.Number
+		}
+		var nextBackup *BackupDetail
+		if i+1 < len(check.backups) {
+			nextBackup = &check.backups[i+1]
+			end, err = NewWalSegmentDescription(nextBackup.WalFileName)
+			if err != nil {
+				return WalVerifyCheckResult{}, err
+			}
+		}
+
+		timelineSwitchMap, ok := timelineSwitchMaps[end.Timeline]
+		if !ok {
+			timelineSwitchMap, err = createTimelineSwitchMap(check.ctx, end.Timeline, check.walFolder)
+			if err != nil {
+				return WalVerifyCheckResult{}, errors.Wrap(err, "Failed to initialize timeline history map")
+			}
+			timelineSwitchMaps[end.Timeline] = timelineSwitchMap
+		}
+
+		coverage := check.scanCoverage(start, end, timelineSwitchMap)
+		coverage.BackupName = backup.BackupName
+		if nextBackup != nil {
+			coverage.NextBackupName = nextBackup.BackupName
+		}
+		coverages = append(coverages, coverage)
+	}
+	return newBackupCoverageCheckResult(coverages), nil
+}
+
+// scanCoverage travels from the end segment back to the start segment and counts the missing segments
+func (check BackupCoverageCheckRunner) scanCoverage(start, end WalSegmentDescription,
+	timelineSwitchMap map[WalSegmentNo]*TimelineHistoryRecord) *BackupCoverage {
+	coverage := &BackupCoverage{
+		StartSegment: start.GetFileName(),
+		EndSegment:   end.GetFileName(),
+		Status:       CoverageComplete,
+	}
+	addMissing := func(segment WalSegmentDescription) {
+		coverage.MissingSegmentsCount++
+		// the segments are scanned in reversed order
+		coverage.FirstMissingSegment = segment.GetFileName()
+		if coverage.LastMissingSegment == "" {
+			coverage.LastMissingSegment = segment.GetFileName()
+		}
+	}
+
+	if !check.walSegments[end] {
+		addMissing(end)
+	}
+	runner := NewWalSegmentRunner(end, check.walSegments, start.Number, timelineSwitchMap)
+	for {
+		_, err := runner.Next()
+		if _, ok := err.(ReachedStopSegmentError); ok {
+			break
+		}
+		if _, ok := err.(WalSegmentNotFoundError); ok {
+			runner.ForceMoveNext()
+			addMissing(runner.Current())
+		}
+	}
+
+	switch {
+	case runner.Current().Timeline != start.Timeline:
+		// the backup is not in the history of the next backup timeline
+		coverage.Status = CoverageOtherTimeline
+	case coverage.MissingSegmentsCount > 0:
+		coverage.Status = CoverageBroken
+	}
+	return coverage
+}
+
+func (check BackupCoverageCheckRunner) Type() WalVerifyCheckType {
+	return WalVerifyBackupCoverageCheck
+}
+
+// newBackupCoverageCheckResult check produces the WalVerifyCheckResult with status:
+// StatusOk if all the backups have the complete WAL chains
+// StatusWarning if there are no backups or some backups are not in the history of the next backups
+// StatusFailure if some WAL chains have missing segments
+func newBackupCoverageCheckResult(coverages []*BackupCoverage) WalVerifyCheckResult {
+	result := WalVerifyCheckResult{
+		Status:  StatusOk,
+		Details: BackupCoverageCheckDetails(coverages),
+	}
+	if len(coverages) == 0 {
+		result.Status = StatusWarning
+	}
+	for _, coverage := range coverages {
+		switch coverage.Status {
+		case CoverageBroken:
+			result.Status = StatusFailure
+			return result
+		case CoverageOtherTimeline:
+			result.Status = StatusWarning
+		}
+	}
+	return result
+}
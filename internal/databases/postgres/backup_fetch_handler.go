@@ -105,23 +105,41 @@ func deltaFetchRecursionOld(ctx context.Context, backup Backup, rootFolder stora
 	return backup.unwrapToEmptyDirectory(ctx, dbDataDirectory, filesToUnwrap, false, extractProv)
 }
 
-func GetFetcherOld(dbDataDirectory, fileMask, restoreSpecPath string, extractProv ExtractProvider) internal.Fetcher {
+// chooseRestoreSpec reads the restore specification or generates it from the relocation.
+// tablespace_map is not restored in both cases, otherwise Postgres recreates the original symlinks.
+func chooseRestoreSpec(ctx context.Context, backup Backup, dbDataDirectory, restoreSpecPath string,
+	relocation *RestoreRelocation, filesToUnwrap map[string]bool) *TablespaceSpec {
+	var spec *TablespaceSpec
+	if restoreSpecPath != "" {
+		delete(filesToUnwrap, TablespaceMapFilename)
+		spec = &TablespaceSpec{}
+		err := readRestoreSpec(restoreSpecPath, spec)
+		errMessage := fmt.Sprintf("Invalid restore specification path %s\n", restoreSpecPath)
+		tracelog.ErrorLogger.FatalfOnError(errMessage, err)
+	} else if !relocation.Empty() {
+		delete(filesToUnwrap, TablespaceMapFilename)
+		var err error
+		spec, err = relocation.prepareRestore(ctx, backup, dbDataDirectory)
+		tracelog.ErrorLogger.FatalfOnError("Failed to relocate backup: %v\n", err)
+	}
+	return spec
+}
+
+func GetFetcherOld(dbDataDirectory, fileMask, restoreSpecPath string, relocation *RestoreRelocation,
+	extractProv ExtractProvider) internal.Fetcher {
 	return func(ctx context.Context, rootFolder storage.Folder, backup internal.Backup) {
 		pgBackup := ToPgBackup(backup)
 		filesToUnwrap, err := pgBackup.GetFilesToUnwrap(ctx, fileMask)
 		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
 
-		var spec *TablespaceSpec
-		if restoreSpecPath != "" {
-			delete(filesToUnwrap, TablespaceMapFilename)
-			spec = &TablespaceSpec{}
-			err := readRestoreSpec(restoreSpecPath, spec)
-			errMessage := fmt.Sprintf("Invalid restore specification path %s\n", restoreSpecPath)
-			tracelog.ErrorLogger.FatalfOnError(errMessage, err)
-		}
+		dataDirectory := utility.ResolveSymlink(dbDataDirectory)
+		spec := chooseRestoreSpec(ctx, pgBackup, dataDirectory, restoreSpecPath, relocation, filesToUnwrap)
 
-		err = deltaFetchRecursionOld(ctx, pgBackup, rootFolder, utility.ResolveSymlink(dbDataDirectory), spec, filesToUnwrap, extractProv)
+		err = deltaFetchRecursionOld(ctx, pgBackup, rootFolder, dataDirectory, spec, filesToUnwrap, extractProv)
 		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
+
+		err = relocation.FinishRestore(dataDirectory)
+		tracelog.ErrorLogger.FatalfOnError("Failed to relocate backup: %v\n", err)
 	}
 }
 
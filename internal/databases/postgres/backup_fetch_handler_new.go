@@ -2,7 +2,6 @@ package postgres
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
@@ -10,22 +9,16 @@ import (
 	"github.com/wal-g/wal-g/utility"
 )
 
-func GetFetcherNew(dbDataDirectory, fileMask, restoreSpecPath string, skipRedundantTars bool,
-	extractProv ExtractProvider,
+func GetFetcherNew(dbDataDirectory, fileMask, restoreSpecPath string, relocation *RestoreRelocation,
+	skipRedundantTars bool, extractProv ExtractProvider,
 ) internal.Fetcher {
 	return func(ctx context.Context, rootFolder storage.Folder, backup internal.Backup) {
 		pgBackup := ToPgBackup(backup)
 		filesToUnwrap, err := pgBackup.GetFilesToUnwrap(ctx, fileMask)
 		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
 
-		var spec *TablespaceSpec
-		if restoreSpecPath != "" {
-			delete(filesToUnwrap, TablespaceMapFilename)
-			spec = &TablespaceSpec{}
-			err := readRestoreSpec(restoreSpecPath, spec)
-			errMessege := fmt.Sprintf("Invalid restore specification path %s\n", restoreSpecPath)
-			tracelog.ErrorLogger.FatalfOnError(errMessege, err)
-		}
+		spec := chooseRestoreSpec(ctx, pgBackup, utility.ResolveSymlink(dbDataDirectory), restoreSpecPath,
+			relocation, filesToUnwrap)
 
 		// directory must be empty before starting a deltaFetch
 		isEmpty, err := utility.IsDirectoryEmpty(dbDataDirectory, nil)
@@ -46,6 +39,9 @@ func GetFetcherNew(dbDataDirectory, fileMask, restoreSpecPath string, skipRedund
 		)
 		err = deltaFetchRecursionNew(ctx, config)
 		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
+
+		err = relocation.FinishRestore(config.dbDataDirectory)
+		tracelog.ErrorLogger.FatalfOnError("Failed to relocate backup: %v\n", err)
 	}
 }
 
@@ -2,6 +2,8 @@ package postgres
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"os"
 
 	"github.com/wal-g/tracelog"
@@ -11,12 +13,22 @@ import (
 )
 
 func HandleDetailedBackupList(ctx context.Context, folder storage.Folder, pretty bool, json bool) {
+	err := WriteDetailedBackupList(ctx, folder, os.Stdout, pretty, json)
+	tracelog.ErrorLogger.FatalOnError(err)
+}
+
+// WriteDetailedBackupList writes the list of backups with their details to the output
+func WriteDetailedBackupList(ctx context.Context, folder storage.Folder, output io.Writer, pretty bool, json bool) error {
 	backups, err := internal.GetBackups(ctx, folder)
 	err = internal.FilterOutNoBackupFoundError(err, json)
-	tracelog.ErrorLogger.FatalfOnError("Get backups from folder: %v", err)
+	if err != nil {
+		return fmt.Errorf("get backups from folder: %w", err)
+	}
 
 	backupDetails, err := GetBackupsDetails(ctx, folder, backups)
-	tracelog.ErrorLogger.FatalOnError(err)
+	if err != nil {
+		return err
+	}
 
 	SortBackupDetails(backupDetails)
 
@@ -24,6 +36,9 @@ func HandleDetailedBackupList(ctx context.Context, folder storage.Folder, pretty
 	for i := range backupDetails {
 		printableEntities[i] = &backupDetails[i]
 	}
-	err = printlist.List(printableEntities, os.Stdout, pretty, json)
-	tracelog.ErrorLogger.FatalfOnError("Print backups: %v", err)
+	err = printlist.List(printableEntities, output, pretty, json)
+	if err != nil {
+		return fmt.Errorf("print backups: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,265 @@
+package postgres
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+const (
+	BackupManifestFilename = "backup_manifest"
+
+	backupManifestChecksumAlgorithm = "CRC32C"
+	backupManifestTimeFormat        = "2006-01-02 15:04:05 GMT"
+	backupManifestChecksumKey       = "\"Manifest-Checksum\""
+)
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// BackupManifestFile is a single file entry of the backup manifest
+type BackupManifestFile struct {
+	Path              string `json:"Path,omitempty"`
+	EncodedPath       string `json:"Encoded-Path,omitempty"`
+	Size              int64  `json:"Size"`
+	LastModified      string `json:"Last-Modified"`
+	ChecksumAlgorithm string `json:"Checksum-Algorithm,omitempty"`
+	Checksum          string `json:"Checksum,omitempty"`
+}
+
+// HasChecksum reports whether the file contents can be verified
+func (file BackupManifestFile) HasChecksum() bool {
+	return file.ChecksumAlgorithm == backupManifestChecksumAlgorithm && file.Checksum != ""
+}
+
+// BackupManifestWalRange is the range of WAL required to make the backup consistent
+type BackupManifestWalRange struct {
+	Timeline uint32 `json:"Timeline"`
+	StartLSN string `json:"Start-LSN"`
+	EndLSN   string `json:"End-LSN"`
+}
+
+// BackupManifest mirrors the backup_manifest file produced by pg_basebackup,
+// so that restored backups can be checked with pg_verifybackup
+type BackupManifest struct {
+	Version          int                      `json:"PostgreSQL-Backup-Manifest-Version"`
+	SystemIdentifier *uint64                  `json:"System-Identifier,omitempty"`
+	Files            []BackupManifestFile     `json:"Files"`
+	WalRanges        []BackupManifestWalRange `json:"WAL-Ranges"`
+	ManifestChecksum string                   `json:"Manifest-Checksum"`
+}
+
+type InvalidBackupManifestError struct {
+	error
+}
+
+func newInvalidBackupManifestError(format string, args ...interface{}) InvalidBackupManifestError {
+	return InvalidBackupManifestError{errors.Errorf("invalid backup manifest: "+format, args...)}
+}
+
+func (err InvalidBackupManifestError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// NewCRC32CHash returns the hash used for the file checksums of the backup manifest
+func NewCRC32CHash() hash.Hash32 {
+	return crc32.New(castagnoliTable)
+}
+
+// FormatCRC32CChecksum encodes the checksum the same way Postgres does: raw little-endian bytes in hex
+func FormatCRC32CChecksum(checksum uint32) string {
+	var raw [4]byte
+	binary.LittleEndian.PutUint32(raw[:], checksum)
+	return hex.EncodeToString(raw[:])
+}
+
+// Marshal serializes the manifest in the layout of Postgres and fills in the manifest checksum
+func (manifest *BackupManifest) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "{ \"PostgreSQL-Backup-Manifest-Version\": %d,\n", manifest.Version)
+	if manifest.Version >= 2 && manifest.SystemIdentifier != nil {
+		fmt.Fprintf(&buf, "\"System-Identifier\": %d,\n", *manifest.SystemIdentifier)
+	}
+	buf.WriteString("\"Files\": [")
+	for i, file := range manifest.Files {
+		if i == 0 {
+			buf.WriteString("\n")
+		} else {
+			buf.WriteString(",\n")
+		}
+		err := writeBackupManifestFile(&buf, file)
+		if err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteString("\n],\n\"WAL-Ranges\": [\n")
+	for i, walRange := range manifest.WalRanges {
+		if i > 0 {
+			buf.WriteString(",\n")
+		}
+		fmt.Fprintf(&buf, "{ \"Timeline\": %d, \"Start-LSN\": \"%s\", \"End-LSN\": \"%s\" }",
+			walRange.Timeline, walRange.StartLSN, walRange.EndLSN)
+	}
+	buf.WriteString("\n],\n")
+
+	checksum := sha256.Sum256(buf.Bytes())
+	manifest.ManifestChecksum = hex.EncodeToString(checksum[:])
+	fmt.Fprintf(&buf, "%s: \"%s\"}\n", backupManifestChecksumKey, manifest.ManifestChecksum)
+	return buf.Bytes(), nil
+}
+
+func writeBackupManifestFile(buf *bytes.Buffer, file BackupManifestFile) error {
+	if utf8.ValidString(file.Path) {
+		buf.WriteString("{ \"Path\": ")
+		err := writeJSONString(buf, file.Path)
+		if err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintf(buf, "{ \"Encoded-Path\": \"%s\"", hex.EncodeToString([]byte(file.Path)))
+	}
+	fmt.Fprintf(buf, ", \"Size\": %d, \"Last-Modified\": \"%s\"", file.Size, file.LastModified)
+	if file.ChecksumAlgorithm != "" {
+		fmt.Fprintf(buf, ", \"Checksum-Algorithm\": \"%s\", \"Checksum\": \"%s\"", file.ChecksumAlgorithm, file.Checksum)
+	}
+	buf.WriteString(" }")
+	return nil
+}
+
+func writeJSONString(buf *bytes.Buffer, value string) error {
+	var encoded bytes.Buffer
+	encoder := json.NewEncoder(&encoded)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(value)
+	if err != nil {
+		return err
+	}
+	buf.Write(bytes.TrimSuffix(encoded.Bytes(), []byte("\n")))
+	return nil
+}
+
+// ParseBackupManifest parses the manifest and validates its checksum
+func ParseBackupManifest(data []byte) (*BackupManifest, error) {
+	// the checksum covers everything up to the line holding it, as in pg_verifybackup
+	checksumLineStart := bytes.LastIndex(data, []byte("\n"+backupManifestChecksumKey))
+	if checksumLineStart < 0 {
+		return nil, newInvalidBackupManifestError("manifest checksum is missing")
+	}
+
+	manifest := &BackupManifest{}
+	err := json.Unmarshal(data, manifest)
+	if err != nil {
+		return nil, newInvalidBackupManifestError("%v", err)
+	}
+	checksum := sha256.Sum256(data[:checksumLineStart+1])
+	if !strings.EqualFold(hex.EncodeToString(checksum[:]), manifest.ManifestChecksum) {
+		return nil, newInvalidBackupManifestError("manifest checksum mismatch")
+	}
+
+	for i, file := range manifest.Files {
+		if file.EncodedPath == "" {
+			continue
+		}
+		path, err := hex.DecodeString(file.EncodedPath)
+		if err != nil {
+			return nil, newInvalidBackupManifestError("cannot decode path %q: %v", file.EncodedPath, err)
+		}
+		manifest.Files[i].Path = string(path)
+		manifest.Files[i].EncodedPath = ""
+	}
+	return manifest, nil
+}
+
+// BackupManifestBuilder collects manifest entries while backup files are being packed.
+// A nil builder ignores all the calls.
+type BackupManifestBuilder struct {
+	mutex sync.Mutex
+	files map[string]BackupManifestFile
+}
+
+func NewBackupManifestBuilder() *BackupManifestBuilder {
+	return &BackupManifestBuilder{files: make(map[string]BackupManifestFile)}
+}
+
+// AddFile records the file without checksum: its contents are taken from the previous backup or stored as an increment
+func (builder *BackupManifestBuilder) AddFile(path string, size int64, modTime time.Time) {
+	builder.setFile(path, BackupManifestFile{
+		Size:         size,
+		LastModified: modTime.UTC().Format(backupManifestTimeFormat),
+	})
+}
+
+// AddFileWithChecksum records the file with the CRC32C checksum of the contents written to the backup
+func (builder *BackupManifestBuilder) AddFileWithChecksum(path string, size int64, modTime time.Time, checksum uint32) {
+	builder.setFile(path, BackupManifestFile{
+		Size:              size,
+		LastModified:      modTime.UTC().Format(backupManifestTimeFormat),
+		ChecksumAlgorithm: backupManifestChecksumAlgorithm,
+		Checksum:          FormatCRC32CChecksum(checksum),
+	})
+}
+
+// RemoveFile forgets the file, e.g. when it was deleted before it was packed
+func (builder *BackupManifestBuilder) RemoveFile(path string) {
+	if builder == nil {
+		return
+	}
+	builder.mutex.Lock()
+	defer builder.mutex.Unlock()
+	delete(builder.files, backupManifestPath(path))
+}
+
+func (builder *BackupManifestBuilder) setFile(path string, file BackupManifestFile) {
+	if builder == nil {
+		return
+	}
+	file.Path = backupManifestPath(path)
+	builder.mutex.Lock()
+	defer builder.mutex.Unlock()
+	builder.files[file.Path] = file
+}
+
+// Build creates the manifest of the backup. Manifest version 2 is used for PostgreSQL 17 and later.
+func (builder *BackupManifestBuilder) Build(pgVersion int, systemIdentifier *uint64,
+	timeline uint32, startLSN, endLSN LSN) *BackupManifest {
+	builder.mutex.Lock()
+	defer builder.mutex.Unlock()
+
+	manifest := &BackupManifest{
+		Version: 1,
+		Files:   make([]BackupManifestFile, 0, len(builder.files)),
+		WalRanges: []BackupManifestWalRange{{
+			Timeline: timeline,
+			StartLSN: startLSN.String(),
+			EndLSN:   endLSN.String(),
+		}},
+	}
+	if pgVersion >= 170000 && systemIdentifier != nil {
+		manifest.Version = 2
+		manifest.SystemIdentifier = systemIdentifier
+	}
+	for _, file := range builder.files {
+		manifest.Files = append(manifest.Files, file)
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool {
+		return manifest.Files[i].Path < manifest.Files[j].Path
+	})
+	return manifest
+}
+
+// backupManifestPath converts a tar header name into a path relative to PGDATA
+func backupManifestPath(path string) string {
+	return strings.TrimPrefix(path, "/")
+}
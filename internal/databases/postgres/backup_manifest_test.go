@@ -0,0 +1,90 @@
+package postgres_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+func TestFormatCRC32CChecksum(t *testing.T) {
+	checksum := postgres.NewCRC32CHash()
+	_, _ = checksum.Write([]byte("123456789"))
+
+	assert.Equal(t, "839206e3", postgres.FormatCRC32CChecksum(checksum.Sum32()))
+}
+
+func TestBackupManifestBuilder(t *testing.T) {
+	modTime := time.Date(2024, 3, 1, 12, 30, 15, 0, time.UTC)
+	builder := postgres.NewBackupManifestBuilder()
+	builder.AddFile("/base/1/1259", 8192, modTime)
+	builder.AddFile("/base/1/2619", 16384, modTime)
+	builder.AddFileWithChecksum("/base/1/1259", 8192, modTime, 0xe3069283)
+	builder.AddFile("/base/1/deleted", 10, modTime)
+	builder.RemoveFile("/base/1/deleted")
+
+	manifest := builder.Build(150000, nil, 2, 0x3000028, 0x3000100)
+
+	assert.Equal(t, 1, manifest.Version)
+	assert.Equal(t, []postgres.BackupManifestFile{
+		{
+			Path:              "base/1/1259",
+			Size:              8192,
+			LastModified:      "2024-03-01 12:30:15 GMT",
+			ChecksumAlgorithm: "CRC32C",
+			Checksum:          "839206e3",
+		},
+		{Path: "base/1/2619", Size: 16384, LastModified: "2024-03-01 12:30:15 GMT"},
+	}, manifest.Files)
+	assert.Equal(t, []postgres.BackupManifestWalRange{{Timeline: 2, StartLSN: "0/3000028", EndLSN: "0/3000100"}},
+		manifest.WalRanges)
+}
+
+func TestBackupManifestVersion2(t *testing.T) {
+	systemIdentifier := uint64(7291862359912345678)
+	manifest := postgres.NewBackupManifestBuilder().Build(170002, &systemIdentifier, 1, 0, 0)
+
+	data, err := manifest.Marshal()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(string(data),
+		"{ \"PostgreSQL-Backup-Manifest-Version\": 2,\n\"System-Identifier\": 7291862359912345678,\n\"Files\": [\n],\n"))
+}
+
+func TestBackupManifestMarshalParse(t *testing.T) {
+	modTime := time.Date(2024, 3, 1, 12, 30, 15, 0, time.UTC)
+	builder := postgres.NewBackupManifestBuilder()
+	builder.AddFileWithChecksum("backup_label", 225, modTime, 0xe3069283)
+	builder.AddFile("/pg_tblspc/16384/PG_15_202209061/5/\"quoted\"", 0, modTime)
+	builder.AddFile("/base/1/\xff\xfe", 0, modTime)
+	manifest := builder.Build(150000, nil, 1, 0x2000028, 0x2000100)
+
+	data, err := manifest.Marshal()
+	require.NoError(t, err)
+
+	assert.Contains(t, string(data), "{ \"Path\": \"backup_label\", \"Size\": 225, "+
+		"\"Last-Modified\": \"2024-03-01 12:30:15 GMT\", \"Checksum-Algorithm\": \"CRC32C\", \"Checksum\": \"839206e3\" }")
+	assert.Contains(t, string(data), "{ \"Encoded-Path\": \"626173652f312ffffe\"")
+	assert.Contains(t, string(data), "\"WAL-Ranges\": [\n{ \"Timeline\": 1, \"Start-LSN\": \"0/2000028\", \"End-LSN\": \"0/2000100\" }\n],\n")
+	assert.True(t, strings.HasSuffix(string(data), "\"Manifest-Checksum\": \""+manifest.ManifestChecksum+"\"}\n"))
+
+	parsed, err := postgres.ParseBackupManifest(data)
+	require.NoError(t, err)
+	assert.Equal(t, manifest, parsed)
+}
+
+func TestParseBackupManifestChecksumMismatch(t *testing.T) {
+	builder := postgres.NewBackupManifestBuilder()
+	builder.AddFile("base/1/1259", 8192, time.Now())
+	data, err := builder.Build(150000, nil, 1, 0, 0).Marshal()
+	require.NoError(t, err)
+
+	corrupted := bytes.Replace(data, []byte("8192"), []byte("8193"), 1)
+	_, err = postgres.ParseBackupManifest(corrupted)
+
+	assert.ErrorAs(t, err, &postgres.InvalidBackupManifestError{})
+}
@@ -77,6 +77,8 @@ type CurBackupInfo struct {
 	dataCatalogSize  int64
 	incrementCount   int
 	StartChkpNum     *uint32
+	standbyInfo      *StandbyBackupInfo
+	manifest         []byte
 }
 
 func NewPrevBackupInfo(name string, sentinel BackupSentinelDto, filesMeta FilesMetadataDto) PrevBackupInfo {
@@ -115,6 +117,8 @@ type BackupHandler struct {
 	Arguments      BackupArguments
 	Workers        BackupWorkers
 	PgInfo         BackupPgInfo
+	// cancelBackup stops the backup after it was terminated, e.g. when Postgres has gone
+	cancelBackup context.CancelCauseFunc
 }
 
 // NewBackupArguments creates a BackupArgument object to hold the arguments from the cmd
@@ -139,13 +143,17 @@ func NewBackupArguments(uploader internal.Uploader, pgDataDirectory string, back
 	}
 }
 
+// EnableForceIncremental makes every paged file incremented even if it is not listed in the previous backup
+func (ba *BackupArguments) EnableForceIncremental() {
+	ba.forceIncremental = true
+}
+
 func (ba *BackupArguments) EnablePreventConcurrentBackups() {
 	ba.preventConcurrentBackups = true
 	tracelog.InfoLogger.Println("Concurrent backups are disabled")
 }
 
-func (bh *BackupHandler) createAndPushBackup(ctx context.Context) {
-	var err error
+func (bh *BackupHandler) createAndPushBackup(ctx context.Context) error {
 	folder := bh.Arguments.Uploader.Folder()
 	// TODO: AB: this subfolder switch look ugly.
 	// I think typed storage folders could be better (i.e. interface BasebackupStorageFolder, WalStorageFolder etc)
@@ -157,7 +165,10 @@ func (bh *BackupHandler) createAndPushBackup(ctx context.Context) {
 	}
 
 	arguments := bh.Arguments
-	crypter := internal.ConfigureCrypter()
+	crypter, err := internal.ConfigureCrypterForSpecificConfig(viper.GetViper())
+	if err != nil {
+		return errors.Wrap(err, "can't configure crypter")
+	}
 	bh.Workers.Bundle = NewBundle(bh.PgInfo.PgDataDirectory, crypter, bh.prevBackupInfo.name,
 		bh.prevBackupInfo.sentinelDto.BackupStartLSN, bh.prevBackupInfo.filesMetadataDto.Files, arguments.forceIncremental,
 		viper.GetInt64(conf.TarSizeThresholdSetting))
@@ -166,31 +177,61 @@ func (bh *BackupHandler) createAndPushBackup(ctx context.Context) {
 	}
 
 	err = bh.startBackup(ctx)
-	tracelog.ErrorLogger.FatalOnError(err)
+	if err != nil {
+		return err
+	}
 	err = bh.checkDataChecksums(ctx)
-	tracelog.ErrorLogger.FatalOnError(err)
+	if err != nil {
+		return err
+	}
 	err = bh.CheckArchiveCommand(ctx)
-	tracelog.ErrorLogger.FatalOnError(err)
+	if err != nil {
+		return err
+	}
 
 	if orioledbEnabled {
 		chkpNum := orioledb.GetChkpNum(bh.PgInfo.PgDataDirectory)
 		bh.CurBackupInfo.StartChkpNum = &chkpNum
 	}
 	err = bh.handleDeltaBackup(ctx, folder)
-	tracelog.ErrorLogger.FatalOnError(err)
-	tarFileSets := bh.uploadBackup(ctx)
+	if err != nil {
+		return err
+	}
+	tarFileSets, err := bh.uploadBackup(ctx)
+	if err != nil {
+		return err
+	}
+	return bh.finishBackup(ctx, folder, tarFileSets)
+}
+
+// finishBackup uploads the metadata of the backup which files are uploaded
+func (bh *BackupHandler) finishBackup(ctx context.Context, folder storage.Folder, tarFileSets internal.TarFileSets) error {
 	sentinelDto, filesMetaDto, err := bh.setupDTO(ctx, tarFileSets)
-	tracelog.ErrorLogger.FatalOnError(err)
-	bh.markBackups(ctx, folder, sentinelDto)
-	bh.uploadMetadata(ctx, sentinelDto, filesMetaDto)
+	if err != nil {
+		return err
+	}
+	bh.CurBackupInfo.manifest, err = bh.Workers.Bundle.Manifest.Build(bh.PgInfo.PgVersion, bh.PgInfo.systemIdentifier,
+		bh.Workers.Bundle.Timeline, bh.CurBackupInfo.startLSN, bh.CurBackupInfo.endLSN).Marshal()
+	if err != nil {
+		return err
+	}
+	err = bh.markBackups(ctx, folder, sentinelDto)
+	if err != nil {
+		return err
+	}
+	err = bh.uploadMetadata(ctx, sentinelDto, filesMetaDto)
+	if err != nil {
+		return err
+	}
 
 	storageNames := multistorage.UsedStorages(folder)
 	if len(storageNames) == 0 {
-		tracelog.ErrorLogger.Fatalf("No storages are used in the uploading folder")
+		return errors.New("no storages are used in the uploading folder")
 	}
 
 	// logging backup set Name
 	tracelog.InfoLogger.Printf("Wrote backup with name %s to storage %s", bh.CurBackupInfo.Name, storageNames[0])
+	return nil
 }
 
 func (bh *BackupHandler) startBackup(ctx context.Context) error {
@@ -235,6 +276,17 @@ func (bh *BackupHandler) startBackup(ctx context.Context) error {
 		}
 	}
 
+	standby, err := bh.Workers.QueryRunner.IsStandby(ctx)
+	if err != nil {
+		return err
+	}
+	if standby {
+		bh.CurBackupInfo.standbyInfo, err = bh.prepareStandbyBackup(ctx)
+		if err != nil {
+			return errors.Wrap(err, "backup from standby is not possible")
+		}
+	}
+
 	tracelog.DebugLogger.Println("Running StartBackup.")
 	backupName, backupStartLSN, err := bh.Workers.Bundle.StartBackup(
 		ctx, bh.Workers.QueryRunner, utility.CeilTimeUpToMicroseconds(time.Now()).String())
@@ -244,8 +296,7 @@ func (bh *BackupHandler) startBackup(ctx context.Context) error {
 	bh.CurBackupInfo.startLSN = backupStartLSN
 	bh.CurBackupInfo.Name = backupName
 	tracelog.InfoLogger.Printf("Started backup with name %s at LSN %s", backupName, backupStartLSN)
-	bh.initBackupTerminator(ctx)
-	return nil
+	return bh.initBackupTerminator(ctx)
 }
 
 func (bh *BackupHandler) handleDeltaBackup(ctx context.Context, folder storage.Folder) error {
@@ -253,17 +304,15 @@ func (bh *BackupHandler) handleDeltaBackup(ctx context.Context, folder storage.F
 		tracelog.InfoLogger.Println("Delta backup enabled")
 		tracelog.DebugLogger.Printf("Previous backup: %s\nBackup start LSN: %s", bh.prevBackupInfo.name,
 			bh.prevBackupInfo.sentinelDto.BackupStartLSN)
-		if *bh.prevBackupInfo.sentinelDto.BackupFinishLSN > bh.CurBackupInfo.startLSN {
-			tracelog.ErrorLogger.FatalOnError(newBackupFromFuture(bh.prevBackupInfo.name))
-		}
-		if bh.prevBackupInfo.sentinelDto.SystemIdentifier != nil &&
-			bh.PgInfo.systemIdentifier != nil &&
-			*bh.PgInfo.systemIdentifier != *bh.prevBackupInfo.sentinelDto.SystemIdentifier {
-			tracelog.ErrorLogger.FatalOnError(newBackupFromOtherBD())
+		err := bh.checkIncrementBase()
+		if err != nil {
+			return err
 		}
 
 		useWalDelta, _, err := configureWalDeltaUsage()
-		tracelog.ErrorLogger.FatalOnError(err)
+		if err != nil {
+			return err
+		}
 
 		if useWalDelta {
 			ForceWalDetal, _ := conf.GetBoolSettingDefault(conf.ForceWalDetal, false)
@@ -284,6 +333,19 @@ func (bh *BackupHandler) handleDeltaBackup(ctx context.Context, folder storage.F
 	return nil
 }
 
+// checkIncrementBase checks that the delta backup can be taken from the previous backup
+func (bh *BackupHandler) checkIncrementBase() error {
+	if *bh.prevBackupInfo.sentinelDto.BackupFinishLSN > bh.CurBackupInfo.startLSN {
+		return newBackupFromFuture(bh.prevBackupInfo.name)
+	}
+	if bh.prevBackupInfo.sentinelDto.SystemIdentifier != nil &&
+		bh.PgInfo.systemIdentifier != nil &&
+		*bh.PgInfo.systemIdentifier != *bh.prevBackupInfo.sentinelDto.SystemIdentifier {
+		return newBackupFromOtherBD()
+	}
+	return nil
+}
+
 func (bh *BackupHandler) setupDTO(ctx context.Context, tarFileSets internal.TarFileSets) (sentinelDto BackupSentinelDto,
 	filesMeta FilesMetadataDto, err error) {
 	var tablespaceSpec *TablespaceSpec
@@ -299,13 +361,14 @@ func (bh *BackupHandler) setupDTO(ctx context.Context, tarFileSets internal.TarF
 	return sentinelDto, filesMeta, err
 }
 
-func (bh *BackupHandler) markBackups(ctx context.Context, folder storage.Folder, sentinelDto BackupSentinelDto) {
+func (bh *BackupHandler) markBackups(ctx context.Context, folder storage.Folder, sentinelDto BackupSentinelDto) error {
 	// If pushing permanent delta backup, mark all previous backups permanent
 	// Do this before uploading current meta to ensure that backups are marked in increasing order
 	if bh.Arguments.isPermanent && sentinelDto.IsIncremental() {
 		markBackupHandler := internal.NewBackupMarkHandler(NewGenericMetaInteractor(), folder)
-		markBackupHandler.MarkBackup(ctx, bh.prevBackupInfo.name, true)
+		return markBackupHandler.Mark(ctx, bh.prevBackupInfo.name, true)
 	}
+	return nil
 }
 
 func (bh *BackupHandler) SetComposerInitFunc(initFunc func(ctx context.Context, handler *BackupHandler) error) {
@@ -313,10 +376,10 @@ func (bh *BackupHandler) SetComposerInitFunc(initFunc func(ctx context.Context,
 }
 
 func configureTarBallComposer(ctx context.Context, bh *BackupHandler, tarBallComposerType TarBallComposerType) error {
+	filePackerOptions := NewTarBallFilePackerOptions(bh.Arguments.verifyPageChecksums, bh.Arguments.storeAllCorruptBlocks)
+	filePackerOptions.manifest = bh.Workers.Bundle.Manifest
 	maker, err := NewTarBallComposerMaker(ctx, tarBallComposerType, bh.Workers.QueryRunner,
-		bh.Arguments.Uploader, bh.CurBackupInfo.Name,
-		NewTarBallFilePackerOptions(bh.Arguments.verifyPageChecksums, bh.Arguments.storeAllCorruptBlocks),
-		bh.Arguments.withoutFilesMetadata)
+		bh.Arguments.Uploader, bh.CurBackupInfo.Name, filePackerOptions, bh.Arguments.withoutFilesMetadata)
 	if err != nil {
 		return err
 	}
@@ -324,43 +387,74 @@ func configureTarBallComposer(ctx context.Context, bh *BackupHandler, tarBallCom
 	return bh.Workers.Bundle.SetupComposer(ctx, maker)
 }
 
-func (bh *BackupHandler) uploadBackup(ctx context.Context) internal.TarFileSets {
+func (bh *BackupHandler) uploadBackup(ctx context.Context) (internal.TarFileSets, error) {
 	bundle := bh.Workers.Bundle
 	// Start a new tar bundle, walk the pgDataDirectory and upload everything there.
 	tracelog.InfoLogger.Println("Starting a new tar bundle")
 	err := bundle.StartQueue(internal.NewStorageTarBallMaker(bh.CurBackupInfo.Name, bh.Arguments.Uploader))
-	tracelog.ErrorLogger.FatalOnError(err)
+	if err != nil {
+		return nil, err
+	}
 
 	err = bh.Arguments.composerInitFunc(ctx, bh)
-	tracelog.ErrorLogger.FatalOnError(err)
+	if err != nil {
+		return nil, err
+	}
 
 	tracelog.InfoLogger.Println("Walking ...")
-	err = filepath.Walk(bh.PgInfo.PgDataDirectory, bundle.HandleWalkedFSObject)
-	tracelog.ErrorLogger.FatalOnError(err)
+	err = filepath.Walk(bh.PgInfo.PgDataDirectory, func(path string, info os.FileInfo, err error) error {
+		// stop walking once the backup is terminated
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+		return bundle.HandleWalkedFSObject(path, info, err)
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	tracelog.InfoLogger.Println("Packing ...")
 	tarFileSets, err := bundle.FinishTarComposer()
-	tracelog.ErrorLogger.FatalOnError(err)
+	if err != nil {
+		return nil, err
+	}
 
 	tracelog.DebugLogger.Println("Finishing queue ...")
 	err = bundle.FinishQueue()
-	tracelog.ErrorLogger.FatalOnError(err)
+	if err != nil {
+		return nil, err
+	}
 
 	tracelog.DebugLogger.Println("Uploading pg_control ...")
 	err = bundle.UploadPgControl(ctx, bh.Arguments.Uploader.Compression().FileExtension())
-	tracelog.ErrorLogger.FatalOnError(err)
+	if err != nil {
+		return nil, err
+	}
+	err = bh.stopBackup(ctx, tarFileSets)
+	if err != nil {
+		return nil, err
+	}
+	return tarFileSets, nil
+}
 
+// stopBackup stops the backup in Postgres, uploads the label files and waits for all the uploads
+func (bh *BackupHandler) stopBackup(ctx context.Context, tarFileSets internal.TarFileSets) error {
+	bundle := bh.Workers.Bundle
 	// Stops backup and write/upload postgres `backup_label` and `tablespace_map` Files
 	tracelog.DebugLogger.Println("Stop backup and upload backup_label and tablespace_map")
 	labelFilesTarBallName, labelFilesList, finishLsn, err := bundle.uploadLabelFiles(
 		ctx, bh.Workers.QueryRunner,
 		bh.Arguments.Uploader.Compression().FileExtension())
-	tracelog.ErrorLogger.FatalOnError(err)
+	if err != nil {
+		return err
+	}
 	bh.CurBackupInfo.endLSN = finishLsn
 	bh.CurBackupInfo.uncompressedSize = bundle.TarBallQueue.AllTarballsSize.Load()
 	bh.CurBackupInfo.compressedSize, err = bh.Arguments.Uploader.UploadedDataSize()
 	bh.CurBackupInfo.dataCatalogSize = bundle.DataCatalogSize.Load()
-	tracelog.ErrorLogger.FatalOnError(err)
+	if err != nil {
+		return err
+	}
 	tarFileSets.AddFiles(labelFilesTarBallName, labelFilesList)
 	timelineChanged := bundle.checkTimelineChanged(ctx, bh.Workers.QueryRunner)
 	tracelog.DebugLogger.Printf("Labelfiles tarball name: %s", labelFilesTarBallName)
@@ -373,50 +467,67 @@ func (bh *BackupHandler) uploadBackup(ctx context.Context) internal.TarFileSets
 	tracelog.DebugLogger.Println("Waiting for all uploads to finish")
 	bh.Arguments.Uploader.Finish()
 	if bh.Arguments.Uploader.Failed() {
-		tracelog.ErrorLogger.Fatalf("Uploading failed during '%s' backup.\n", bh.CurBackupInfo.Name)
+		return errors.Errorf("uploading failed during '%s' backup", bh.CurBackupInfo.Name)
 	}
 	if timelineChanged {
-		tracelog.ErrorLogger.Fatalf("Cannot finish backup because of changed timeline.")
+		return errors.New("cannot finish backup because of changed timeline")
 	}
-	return tarFileSets
+	return nil
 }
 
 // HandleBackupPush handles the backup being read from Postgres or filesystem and being pushed to the repository
-// TODO : unit tests
 func (bh *BackupHandler) HandleBackupPush(ctx context.Context) {
+	tracelog.ErrorLogger.FatalOnError(bh.PushBackup(ctx))
+}
+
+// PushBackup makes the backup and returns the error instead of terminating the process, so the daemon can run it
+// TODO : unit tests
+func (bh *BackupHandler) PushBackup(ctx context.Context) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	bh.cancelBackup = cancel
 	bh.CurBackupInfo.StartTime = utility.TimeNowCrossPlatformUTC()
 
+	var err error
 	if bh.Arguments.pgDataDirectory == "" {
-		bh.handleBackupPushRemote(ctx)
+		err = bh.handleBackupPushRemote(ctx)
 	} else {
-		bh.handleBackupPushLocal(ctx)
+		err = bh.handleBackupPushLocal(ctx)
+	}
+	if err != nil && ctx.Err() != nil {
+		return errors.Wrapf(context.Cause(ctx), "backup is terminated")
 	}
+	return err
 }
 
-func (bh *BackupHandler) handleBackupPushRemote(ctx context.Context) {
+func (bh *BackupHandler) handleBackupPushRemote(ctx context.Context) error {
 	if bh.Arguments.forceIncremental {
-		tracelog.ErrorLogger.Println("Delta backup not available for remote backup.")
-		tracelog.ErrorLogger.Fatal("To run delta backup, supply [db_directory].")
+		return errors.New("delta backup not available for remote backup, to run delta backup, supply [db_directory]")
 	}
 	// If no arg is parsed, try to run remote backup using pglogrepl's BASE_BACKUP functionality
 	tracelog.InfoLogger.Println("Running remote backup through Postgres connection.")
-	tracelog.InfoLogger.Println("Features like delta backup and partial restore are disabled, there might be a performance impact.")
-	tracelog.InfoLogger.Println("To run with local backup functionalities, supply [db_directory].")
-	if bh.PgInfo.PgVersion < 110000 && !bh.Arguments.verifyPageChecksums {
-		tracelog.InfoLogger.Println("VerifyPageChecksums=false is only supported for streaming backup since PG11")
-		bh.Arguments.verifyPageChecksums = true
+	if bh.Arguments.isFullBackup {
+		tracelog.InfoLogger.Println("Doing full backup.")
+	} else {
+		var err error
+		bh.prevBackupInfo, bh.CurBackupInfo.incrementCount, err = bh.Arguments.deltaConfigurator.Configure(
+			ctx,
+			bh.Arguments.Uploader.Folder(), bh.Arguments.isPermanent)
+		if err != nil {
+			return err
+		}
 	}
-	bh.createAndPushRemoteBackup(ctx)
+	return bh.createAndPushRemoteBackup(ctx)
 }
 
-func (bh *BackupHandler) handleBackupPushLocal(ctx context.Context) {
+func (bh *BackupHandler) handleBackupPushLocal(ctx context.Context) error {
 	{
 		// The 'data' path provided on the command line must point at the same directory as the one listed by the Postgresql server.
 		// If mismatched, this means we aren't connected to the correct server. This is a fatal error.
 		fromCli := bh.Arguments.pgDataDirectory
 		fromServer := bh.PgInfo.PgDataDirectory // that value is expected to already be absolute and "unsymlinked"
 		if utility.AbsResolveSymlink(fromCli) != fromServer {
-			tracelog.ErrorLogger.Fatalf("Data directory from command line '%s' is not the same as Postgres' one '%s'", fromCli, fromServer)
+			return errors.Errorf("data directory from command line '%s' is not the same as Postgres' one '%s'", fromCli, fromServer)
 		}
 	}
 
@@ -424,23 +535,28 @@ func (bh *BackupHandler) handleBackupPushLocal(ctx context.Context) {
 	baseBackupFolder := folder.GetSubFolder(bh.Arguments.backupsFolder)
 	tracelog.DebugLogger.Printf("Base backup folder: %s", baseBackupFolder.GetPath())
 
-	bh.checkPgVersionAndPgControl()
+	err := bh.checkPgVersionAndPgControl()
+	if err != nil {
+		return err
+	}
 
 	if bh.Arguments.isFullBackup {
 		tracelog.InfoLogger.Println("Doing full backup.")
 	} else {
-		var err error
 		bh.prevBackupInfo, bh.CurBackupInfo.incrementCount, err = bh.Arguments.deltaConfigurator.Configure(
 			ctx,
 			folder, bh.Arguments.isPermanent)
-		tracelog.ErrorLogger.FatalOnError(err)
+		if err != nil {
+			return err
+		}
 	}
 
-	bh.createAndPushBackup(ctx)
+	return bh.createAndPushBackup(ctx)
 }
 
-func (bh *BackupHandler) createAndPushRemoteBackup(ctx context.Context) {
+func (bh *BackupHandler) createAndPushRemoteBackup(ctx context.Context) error {
 	var err error
+	folder := bh.Arguments.Uploader.Folder()
 	uploader := bh.Arguments.Uploader
 	uploader.ChangeDirectory(utility.BaseBackupPath)
 	tracelog.DebugLogger.Printf("Uploading folder: %s", uploader.Folder())
@@ -452,40 +568,63 @@ func (bh *BackupHandler) createAndPushRemoteBackup(ctx context.Context) {
 		tarFileSets = internal.NewRegularTarFileSets()
 	}
 
-	baseBackup := bh.runRemoteBackup(ctx)
+	baseBackup, fileHandler, err := bh.runRemoteBackup(ctx, folder, tarFileSets)
+	if err != nil {
+		return err
+	}
 	tracelog.InfoLogger.Println("Updating metadata")
 	bh.CurBackupInfo.startLSN = LSN(baseBackup.StartLSN)
 	bh.CurBackupInfo.endLSN = LSN(baseBackup.EndLSN)
 
+	bh.CurBackupInfo.manifest = baseBackup.Manifest
 	bh.CurBackupInfo.uncompressedSize = baseBackup.UncompressedSize
+	bh.CurBackupInfo.dataCatalogSize = fileHandler.dataCatalogSize
 	bh.CurBackupInfo.compressedSize, err = bh.Arguments.Uploader.UploadedDataSize()
-	tracelog.ErrorLogger.FatalOnError(err)
+	if err != nil {
+		return err
+	}
 	sentinelDto := NewBackupSentinelDto(bh, baseBackup.GetTablespaceSpec())
 	filesMetadataDto := NewFilesMetadataDto(baseBackup.Files, tarFileSets)
+	if !bh.Arguments.withoutFilesMetadata && !viper.GetBool(conf.DisablePartialRestore) {
+		filesMetadataDto.DatabasesByNames = bh.collectRemoteDatabaseNamesMetadata(ctx)
+	}
 	bh.CurBackupInfo.Name = baseBackup.BackupName()
+	err = bh.markBackups(ctx, folder, sentinelDto)
+	if err != nil {
+		return err
+	}
 	tracelog.InfoLogger.Println("Uploading metadata")
-	bh.uploadMetadata(ctx, sentinelDto, filesMetadataDto)
+	err = bh.uploadMetadata(ctx, sentinelDto, filesMetadataDto)
+	if err != nil {
+		return err
+	}
 	// logging backup set Name
 	tracelog.InfoLogger.Printf("Wrote backup with name %s", bh.CurBackupInfo.Name)
+	return nil
 }
 
-func (bh *BackupHandler) uploadMetadata(ctx context.Context, sentinelDto BackupSentinelDto, filesMetaDto FilesMetadataDto) {
+func (bh *BackupHandler) uploadMetadata(ctx context.Context, sentinelDto BackupSentinelDto, filesMetaDto FilesMetadataDto) error {
 	curBackupName := bh.CurBackupInfo.Name
 	meta := NewExtendedMetadataDto(bh.Arguments.isPermanent, bh.PgInfo.PgDataDirectory,
 		bh.CurBackupInfo.StartTime, sentinelDto)
 
 	err := bh.uploadExtendedMetadata(ctx, meta)
 	if err != nil {
-		tracelog.ErrorLogger.Fatalf("Failed to upload metadata file for backup %s: %v", curBackupName, err)
+		return errors.Wrapf(err, "failed to upload metadata file for backup %s", curBackupName)
 	}
 	err = bh.uploadFilesMetadata(ctx, filesMetaDto)
 	if err != nil {
-		tracelog.ErrorLogger.Fatalf("Failed to upload files metadata for backup %s: %v", curBackupName, err)
+		return errors.Wrapf(err, "failed to upload files metadata for backup %s", curBackupName)
+	}
+	err = bh.uploadBackupManifest(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "failed to upload backup manifest for backup %s", curBackupName)
 	}
 	err = internal.UploadSentinel(ctx, bh.Arguments.Uploader, NewBackupSentinelDtoV2(sentinelDto, meta), bh.CurBackupInfo.Name)
 	if err != nil {
-		tracelog.ErrorLogger.Fatalf("Failed to upload sentinel file for backup %s: %v", curBackupName, err)
+		return errors.Wrapf(err, "failed to upload sentinel file for backup %s", curBackupName)
 	}
+	return nil
 }
 
 func (bh *BackupHandler) collectDatabaseNamesMetadata(ctx context.Context) (DatabasesByNames, error) {
@@ -499,6 +638,11 @@ func (bh *BackupHandler) collectDatabaseNamesMetadata(ctx context.Context) (Data
 			if err != nil {
 				return err
 			}
+			orioledbRelnodes, err := currentRunner.getOrioledbRelnodes(ctx)
+			if err != nil {
+				return err
+			}
+			setOrioledbRelnodes(info.Tables, orioledbRelnodes)
 
 			databases[db.Name] = *info
 			return nil
@@ -507,6 +651,25 @@ func (bh *BackupHandler) collectDatabaseNamesMetadata(ctx context.Context) (Data
 	return databases, err
 }
 
+// collectRemoteDatabaseNamesMetadata collects the partial restore metadata of the remote backup through
+// a regular connection, the backup is usable without it, so the failures are not fatal
+func (bh *BackupHandler) collectRemoteDatabaseNamesMetadata(ctx context.Context) DatabasesByNames {
+	conn, err := Connect(ctx)
+	if err == nil {
+		defer utility.LoggedCloseContext(ctx, conn, "")
+		bh.Workers.QueryRunner, err = NewPgQueryRunner(ctx, conn)
+	}
+	if err == nil {
+		var databases DatabasesByNames
+		databases, err = bh.collectDatabaseNamesMetadata(ctx)
+		if err == nil {
+			return databases
+		}
+	}
+	tracelog.WarningLogger.Printf("Failed to collect the databases metadata, partial restore will not be available: %v", err)
+	return nil
+}
+
 // NewBackupHandler returns a backup handler object, which can handle the backup
 func NewBackupHandler(ctx context.Context, arguments BackupArguments) (bh *BackupHandler, err error) {
 	// RemoteBackup is triggered by not passing PGDATA to wal-g,
@@ -526,7 +689,8 @@ func NewBackupHandler(ctx context.Context, arguments BackupArguments) (bh *Backu
 	return bh, nil
 }
 
-func (bh *BackupHandler) runRemoteBackup(ctx context.Context) *StreamingBaseBackup {
+func (bh *BackupHandler) runRemoteBackup(ctx context.Context, folder storage.Folder,
+	tarFileSets internal.TarFileSets) (*StreamingBaseBackup, *streamingFileHandler, error) {
 	var diskLimit int32
 	if viper.IsSet(conf.DiskRateLimitSetting) {
 		// Note that BASE_BACKUP (pg protocol) allows to limit in kb/sec
@@ -536,35 +700,135 @@ func (bh *BackupHandler) runRemoteBackup(ctx context.Context) *StreamingBaseBack
 			tracelog.InfoLogger.Printf("DiskIO limited to %d kb/s", diskLimit)
 		}
 	}
-	// Connect to postgres and start/finish a nonexclusive backup.
-	tracelog.DebugLogger.Println("Connecting to Postgres (replication connection)")
-	conn, err := pgconn.Connect(ctx, "replication=yes")
-	tracelog.ErrorLogger.FatalOnError(err)
+	base := bh.newStreamingIncrementBase(ctx, folder)
+
+	tracelog.InfoLogger.Println("Starting remote backup")
+	baseBackup, err := bh.startStreamingBaseBackup(ctx, base, diskLimit)
+	if err != nil && base.isServerIncremental() {
+		tracelog.WarningLogger.Printf("Failed to start the incremental backup: %v. "+
+			"Fallback to the delta backup over the full data stream", err)
+		base.manifest = nil
+		baseBackup, err = bh.startStreamingBaseBackup(ctx, base, diskLimit)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	// the connection is closed explicitly when the backup is finished
+	defer utility.LoggedCloseContext(ctx, baseBackup.pgConn, "")
+	if base != nil {
+		bh.CurBackupInfo.startLSN = LSN(baseBackup.StartLSN)
+		err = bh.checkIncrementBase()
+		if err == nil && !base.isServerIncremental() {
+			err = loadStreamingDeltaMap(ctx, folder, base, baseBackup)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
 
-	baseBackup := NewStreamingBaseBackup(bh.PgInfo.PgDataDirectory, viper.GetInt64(conf.TarSizeThresholdSetting), bh.PgInfo.PgVersion, conn)
 	var bundleFiles internal.BundleFiles
 	if bh.Arguments.withoutFilesMetadata {
 		bundleFiles = &internal.NopBundleFiles{}
 	} else {
 		bundleFiles = &internal.RegularBundleFiles{}
 	}
-	tracelog.InfoLogger.Println("Starting remote backup")
-	err = baseBackup.Start(ctx, bh.Arguments.verifyPageChecksums, diskLimit)
-	tracelog.ErrorLogger.FatalOnError(err)
+	fileHandler := newStreamingFileHandler(bundleFiles,
+		NewTarBallFilePackerOptions(bh.Arguments.verifyPageChecksums, bh.Arguments.storeAllCorruptBlocks), base)
 
 	tracelog.InfoLogger.Println("Streaming remote backup")
-	err = baseBackup.Upload(ctx, bh.Arguments.Uploader, bundleFiles)
-	tracelog.ErrorLogger.FatalOnError(err)
+	err = baseBackup.Upload(ctx, bh.Arguments.Uploader, fileHandler, tarFileSets)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	tracelog.InfoLogger.Println("Finishing backup")
 	tracelog.InfoLogger.Println("If wal-g hangs during this step, please Postgres log file for details.")
 	err = baseBackup.Finish(ctx)
-	tracelog.ErrorLogger.FatalOnError(err)
-
+	if err != nil {
+		return nil, nil, err
+	}
 	tracelog.DebugLogger.Println("Closing Postgres connection (replication connection)")
-	err = conn.Close(ctx)
-	tracelog.ErrorLogger.FatalOnError(err)
-	return baseBackup
+	err = baseBackup.pgConn.Close(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return baseBackup, fileHandler, nil
+}
+
+// newStreamingIncrementBase describes the previous backup for the remote delta backup, it returns nil for the full backup.
+// On PG17+ the backup_manifest of the previous backup is provided to BASE_BACKUP, so Postgres sends only the changed pages.
+func (bh *BackupHandler) newStreamingIncrementBase(ctx context.Context, folder storage.Folder) *streamingIncrementBase {
+	if len(bh.prevBackupInfo.name) == 0 || bh.prevBackupInfo.sentinelDto.BackupStartLSN == nil {
+		return nil
+	}
+	tracelog.InfoLogger.Println("Delta backup enabled")
+	tracelog.DebugLogger.Printf("Previous backup: %s\nBackup start LSN: %s", bh.prevBackupInfo.name,
+		bh.prevBackupInfo.sentinelDto.BackupStartLSN)
+	base := &streamingIncrementBase{
+		name:  bh.prevBackupInfo.name,
+		lsn:   *bh.prevBackupInfo.sentinelDto.BackupStartLSN,
+		files: bh.prevBackupInfo.filesMetadataDto.Files,
+	}
+	if bh.PgInfo.PgVersion < 170000 {
+		return base
+	}
+
+	prevBackup, err := NewBackup(folder.GetSubFolder(utility.BaseBackupPath), base.name)
+	if err == nil {
+		base.manifest, _, err = fetchBackupManifest(ctx, prevBackup)
+	}
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to fetch %s of the previous backup: %v. "+
+			"Fallback to the delta backup over the full data stream", BackupManifestFilename, err)
+		base.manifest = nil
+	}
+	return base
+}
+
+// startStreamingBaseBackup connects to Postgres with the replication connection and starts BASE_BACKUP
+func (bh *BackupHandler) startStreamingBaseBackup(ctx context.Context, base *streamingIncrementBase,
+	diskLimit int32) (*StreamingBaseBackup, error) {
+	// Connect to postgres and start/finish a nonexclusive backup.
+	tracelog.DebugLogger.Println("Connecting to Postgres (replication connection)")
+	conn, err := pgconn.Connect(ctx, "replication=yes")
+	if err != nil {
+		return nil, err
+	}
+
+	baseBackup := NewStreamingBaseBackup(bh.PgInfo.PgDataDirectory, viper.GetInt64(conf.TarSizeThresholdSetting), bh.PgInfo.PgVersion, conn)
+	if base != nil {
+		baseBackup.IncrementFromName = base.name
+		baseBackup.IncrementFromManifest = base.manifest
+	}
+	err = baseBackup.Start(ctx, diskLimit)
+	if err != nil {
+		utility.LoggedCloseContext(ctx, conn, "")
+		return nil, err
+	}
+	return baseBackup, nil
+}
+
+// loadStreamingDeltaMap loads the WAL delta map for the delta backup over the full data stream,
+// without it the changed pages are selected by their LSN
+func loadStreamingDeltaMap(ctx context.Context, folder storage.Folder, base *streamingIncrementBase,
+	baseBackup *StreamingBaseBackup) error {
+	useWalDelta, _, err := configureWalDeltaUsage()
+	if err != nil || !useWalDelta {
+		return err
+	}
+	forceWalDelta, _ := conf.GetBoolSettingDefault(conf.ForceWalDetal, false)
+	base.deltaMap, err = getDeltaMap(ctx, internal.NewFolderReader(folder.GetSubFolder(utility.WalPath)),
+		baseBackup.TimeLine, base.lsn, LSN(baseBackup.StartLSN))
+	if err == nil {
+		tracelog.InfoLogger.Println("Successfully loaded delta map, delta backup will be made with provided delta map")
+		return nil
+	}
+	if forceWalDelta {
+		return errors.Wrapf(err, "Failed to load delta map from previous backup")
+	}
+	tracelog.WarningLogger.Printf("Error during loading delta map: '%v'. Fallback to full scan delta backup\n", err)
+	base.deltaMap = nil
+	return nil
 }
 
 func GetPgServerInfo(ctx context.Context, keepRunner bool) (pgInfo BackupPgInfo, runner *PgQueryRunner, err error) {
@@ -635,29 +899,59 @@ func (bh *BackupHandler) uploadFilesMetadata(ctx context.Context, filesMetaDto F
 	return bh.Arguments.Uploader.UploadJSON(ctx, getFilesMetadataPath(bh.CurBackupInfo.Name), filesMetaDto)
 }
 
-func (bh *BackupHandler) checkPgVersionAndPgControl() {
+func (bh *BackupHandler) uploadBackupManifest(ctx context.Context) error {
+	if bh.CurBackupInfo.manifest == nil {
+		tracelog.WarningLogger.Printf("No %s was produced for backup %s", BackupManifestFilename, bh.CurBackupInfo.Name)
+		return nil
+	}
+	manifestPath := storage.JoinPath(bh.CurBackupInfo.Name, BackupManifestFilename)
+	tracelog.DebugLogger.Printf("Uploading backup manifest (%s)", manifestPath)
+	return bh.Arguments.Uploader.Upload(ctx, manifestPath, bytes.NewReader(bh.CurBackupInfo.manifest))
+}
+
+func (bh *BackupHandler) checkPgVersionAndPgControl() error {
 	_, err := os.ReadFile(filepath.Join(bh.PgInfo.PgDataDirectory, PgControlPath))
-	tracelog.ErrorLogger.FatalfOnError(
-		"It looks like you are trying to backup not pg_data. PgControl file not found: %v\n", err)
+	if err != nil {
+		return errors.Wrap(err, "it looks like you are trying to backup not pg_data, PgControl file not found")
+	}
 	_, err = os.ReadFile(filepath.Join(bh.PgInfo.PgDataDirectory, "PG_VERSION"))
-	tracelog.ErrorLogger.FatalfOnError(
-		"It looks like you are trying to backup not pg_data. PG_VERSION file not found: %v\n", err)
+	return errors.Wrap(err, "it looks like you are trying to backup not pg_data, PG_VERSION file not found")
 }
 
-func (bh *BackupHandler) initBackupTerminator(ctx context.Context) {
-	errCh := make(chan error, 1)
+// initBackupTerminator stops the running backup in Postgres when the backup can't be finished,
+// e.g. on the interruption signal, then the backup is canceled
+func (bh *BackupHandler) initBackupTerminator(ctx context.Context) error {
+	errCh := make(chan error, 3)
 
-	addSignalListener(errCh)
-	addPgIsAliveChecker(ctx, bh.Workers.QueryRunner, errCh)
+	addSignalListener(ctx, errCh)
+	err := addPgIsAliveChecker(ctx, bh.Workers.QueryRunner, errCh)
+	if err != nil {
+		return err
+	}
+	if bh.Workers.Bundle.Replica {
+		err = addStandbyPromotionChecker(ctx, bh.Workers.QueryRunner, errCh)
+		if err != nil {
+			return err
+		}
+	}
 
 	terminator := NewBackupTerminator(bh.Workers.QueryRunner, bh.PgInfo.PgVersion, bh.PgInfo.PgDataDirectory)
 
 	go func() {
-		err := <-errCh
-		tracelog.ErrorLogger.Printf("Error: %v, gracefully stopping the running backup...", err)
-		terminator.TerminateBackup(ctx)
-		tracelog.ErrorLogger.Fatal("Finished backup termination, will now exit")
+		select {
+		case <-ctx.Done():
+		case err := <-errCh:
+			// the checkers fail when the finished backup is canceled
+			if ctx.Err() != nil {
+				return
+			}
+			tracelog.ErrorLogger.Printf("Error: %v, gracefully stopping the running backup...", err)
+			terminator.TerminateBackup(ctx)
+			tracelog.ErrorLogger.Println("Finished backup termination")
+			bh.cancelBackup(err)
+		}
 	}()
+	return nil
 }
 
 func (bh *BackupHandler) checkDataChecksums(ctx context.Context) error {
@@ -735,22 +1029,28 @@ func (bh *BackupHandler) CheckArchiveCommand(ctx context.Context) error {
 	return nil
 }
 
-func addSignalListener(errCh chan error) {
+func addSignalListener(ctx context.Context, errCh chan error) {
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 
 	go func() {
-		sig := <-sigCh
-		errCh <- fmt.Errorf("received interruption signal: %s", sig)
+		defer signal.Stop(sigCh)
+		select {
+		case sig := <-sigCh:
+			errCh <- fmt.Errorf("received interruption signal: %s", sig)
+		case <-ctx.Done():
+		}
 	}()
 }
 
-func addPgIsAliveChecker(ctx context.Context, queryRunner *PgQueryRunner, errCh chan error) {
+func addPgIsAliveChecker(ctx context.Context, queryRunner *PgQueryRunner, errCh chan error) error {
 	if !viper.IsSet(conf.PgAliveCheckInterval) {
-		return
+		return nil
 	}
 	stateUpdateInterval, err := conf.GetDurationSetting(conf.PgAliveCheckInterval)
-	tracelog.ErrorLogger.FatalOnError(err)
+	if err != nil {
+		return err
+	}
 	tracelog.InfoLogger.Printf("Initializing the PG alive checker (interval=%s)...", stateUpdateInterval)
 	pgWatcher := NewPgWatcher(ctx, queryRunner, stateUpdateInterval)
 
@@ -758,4 +1058,5 @@ func addPgIsAliveChecker(ctx context.Context, queryRunner *PgQueryRunner, errCh
 		err := <-pgWatcher.Err
 		errCh <- fmt.Errorf("PG alive check failed: %v", err)
 	}()
+	return nil
 }
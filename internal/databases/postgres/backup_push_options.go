@@ -0,0 +1,158 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/multistorage"
+	"github.com/wal-g/wal-g/internal/multistorage/policies"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	BackupPushPermanentFlag             = "permanent"
+	BackupPushFullBackupFlag            = "full"
+	BackupPushVerifyPagesFlag           = "verify"
+	BackupPushStoreAllCorruptBlocksFlag = "store-all-corrupt"
+	BackupPushUseRatingComposerFlag     = "rating-composer"
+	BackupPushUseCopyComposerFlag       = "copy-composer"
+	BackupPushUseDatabaseComposerFlag   = "database-composer"
+	BackupPushDeltaFromUserDataFlag     = "delta-from-user-data"
+	BackupPushDeltaFromNameFlag         = "delta-from-name"
+	BackupPushAddUserDataFlag           = "add-user-data"
+	BackupPushWithoutFilesMetadataFlag  = "without-files-metadata"
+)
+
+// BackupPushOptions are the options of backup-push, they are parsed both by the command and by the daemon
+type BackupPushOptions struct {
+	DataDirectory         string
+	Permanent             bool
+	FullBackup            bool
+	VerifyPageChecksums   bool
+	StoreAllCorruptBlocks bool
+	UseRatingComposer     bool
+	UseCopyComposer       bool
+	UseDatabaseComposer   bool
+	DeltaFromName         string
+	DeltaFromUserData     string
+	UserData              string
+	WithoutFilesMetadata  bool
+	TargetStorage         string
+}
+
+// AddBackupPushFlags registers the backup-push flags, except the target storage
+func AddBackupPushFlags(flags *pflag.FlagSet, options *BackupPushOptions) {
+	flags.BoolVarP(&options.Permanent, BackupPushPermanentFlag, "p",
+		false, "Pushes permanent backup")
+	flags.BoolVarP(&options.FullBackup, BackupPushFullBackupFlag, "f",
+		false, "Make full backup-push")
+	flags.BoolVarP(&options.VerifyPageChecksums, BackupPushVerifyPagesFlag, "v",
+		false, "Verify page checksums")
+	flags.BoolVarP(&options.StoreAllCorruptBlocks, BackupPushStoreAllCorruptBlocksFlag, "s",
+		false, "Store all corrupt blocks found during page checksum verification")
+	flags.BoolVarP(&options.UseRatingComposer, BackupPushUseRatingComposerFlag, "r",
+		false, "Use rating tar composer (beta)")
+	flags.BoolVarP(&options.UseCopyComposer, BackupPushUseCopyComposerFlag, "c",
+		false, "Use copy tar composer (beta)")
+	flags.BoolVarP(&options.UseDatabaseComposer, BackupPushUseDatabaseComposerFlag, "b",
+		false, "Use database tar composer (experimental)")
+	flags.StringVar(&options.DeltaFromName, BackupPushDeltaFromNameFlag,
+		"", "Select the backup specified by name as the target for the delta backup")
+	flags.StringVar(&options.DeltaFromUserData, BackupPushDeltaFromUserDataFlag,
+		"", "Select the backup specified by UserData as the target for the delta backup")
+	flags.StringVar(&options.UserData, BackupPushAddUserDataFlag,
+		"", "Write the provided user data to the backup sentinel and metadata files.")
+	flags.BoolVar(&options.WithoutFilesMetadata, BackupPushWithoutFilesMetadataFlag,
+		false, "Do not track files metadata, significantly reducing memory usage")
+}
+
+// ConfigureBackupPushUploader makes the uploader to the target storage or to the first alive one
+func ConfigureBackupPushUploader(ctx context.Context, rootFolder storage.Folder,
+	targetStorage string) (internal.Uploader, error) {
+	rootFolder = multistorage.SetPolicies(rootFolder, policies.TakeFirstStorage)
+	var err error
+	if targetStorage == "" {
+		rootFolder, err = multistorage.UseFirstAliveStorage(ctx, rootFolder)
+	} else {
+		rootFolder, err = multistorage.UseSpecificStorage(ctx, targetStorage, rootFolder)
+	}
+	if err != nil {
+		return nil, err
+	}
+	tracelog.InfoLogger.Printf("Backup will be pushed to storage: %v", multistorage.UsedStorages(rootFolder)[0])
+
+	return internal.ConfigureUploaderToFolder(rootFolder)
+}
+
+// NewBackupPushArguments applies the settings to the options and makes the backup arguments
+func NewBackupPushArguments(uploader internal.Uploader, options BackupPushOptions) (BackupArguments, error) {
+	options.VerifyPageChecksums = options.VerifyPageChecksums || viper.GetBool(conf.VerifyPageChecksumsSetting)
+	options.StoreAllCorruptBlocks = options.StoreAllCorruptBlocks || viper.GetBool(conf.StoreAllCorruptBlocksSetting)
+
+	tarBallComposerType := chooseTarBallComposer(&options)
+
+	if options.DeltaFromName == "" {
+		options.DeltaFromName = viper.GetString(conf.DeltaFromNameSetting)
+	}
+	if options.DeltaFromUserData == "" {
+		options.DeltaFromUserData = viper.GetString(conf.DeltaFromUserDataSetting)
+	}
+	if options.UserData == "" {
+		options.UserData = viper.GetString(conf.SentinelUserDataSetting)
+	}
+	options.WithoutFilesMetadata = options.WithoutFilesMetadata || viper.GetBool(conf.WithoutFilesMetadataSetting)
+	if options.WithoutFilesMetadata {
+		// files metadata tracking is required for delta backups and copy/rating composers
+		if tarBallComposerType != RegularComposer {
+			return BackupArguments{}, errors.Errorf("%s option cannot be used with non-regular tar ball composer",
+				BackupPushWithoutFilesMetadataFlag)
+		}
+		if options.DeltaFromName != "" || options.DeltaFromUserData != "" {
+			return BackupArguments{}, errors.Errorf("%s option cannot be used with %s, %s options",
+				BackupPushWithoutFilesMetadataFlag, BackupPushDeltaFromNameFlag, BackupPushDeltaFromUserDataFlag)
+		}
+		tracelog.InfoLogger.Print("Files metadata tracking is disabled")
+		options.FullBackup = true
+	}
+
+	deltaBaseSelector, err := internal.NewDeltaBaseSelector(
+		options.DeltaFromName, options.DeltaFromUserData, NewGenericMetaFetcher())
+	if err != nil {
+		return BackupArguments{}, err
+	}
+
+	userData, err := internal.UnmarshalSentinelUserData(options.UserData)
+	if err != nil {
+		return BackupArguments{}, errors.Wrap(err, "failed to unmarshal the provided UserData")
+	}
+
+	return NewBackupArguments(uploader, options.DataDirectory, utility.BaseBackupPath,
+		options.Permanent, options.VerifyPageChecksums, options.FullBackup, options.StoreAllCorruptBlocks,
+		tarBallComposerType, NewRegularDeltaBackupConfigurator(deltaBaseSelector),
+		userData, options.WithoutFilesMetadata), nil
+}
+
+func chooseTarBallComposer(options *BackupPushOptions) TarBallComposerType {
+	tarBallComposerType := RegularComposer
+
+	if options.UseRatingComposer || viper.GetBool(conf.UseRatingComposerSetting) {
+		tarBallComposerType = RatingComposer
+	}
+
+	if options.UseDatabaseComposer || viper.GetBool(conf.UseDatabaseComposerSetting) {
+		tarBallComposerType = DatabaseComposer
+	}
+
+	if options.UseCopyComposer || viper.GetBool(conf.UseCopyComposerSetting) {
+		options.FullBackup = true
+		tarBallComposerType = CopyComposer
+	}
+
+	return tarBallComposerType
+}
@@ -36,6 +36,10 @@ type BackupSentinelDto struct {
 	FilesMetadataDisabled bool    `json:"FilesMetadataDisabled,omitempty"`
 	BackupStartChkpNum    *uint32 `json:"ChkpNum"`
 	IncrementFromChkpNum  *uint32 `json:"DeltaChkpNum,omitempty"`
+
+	FromStandby             bool    `json:"FromStandby,omitempty"`
+	PrimarySystemIdentifier *uint64 `json:"PrimarySystemIdentifier,omitempty"`
+	PrimaryTimeline         uint32  `json:"PrimaryTimeline,omitempty"`
 }
 
 func NewBackupSentinelDto(bh *BackupHandler, tbsSpec *TablespaceSpec) BackupSentinelDto {
@@ -64,6 +68,11 @@ func NewBackupSentinelDto(bh *BackupHandler, tbsSpec *TablespaceSpec) BackupSent
 	sentinel.CompressedSize = bh.CurBackupInfo.compressedSize
 	sentinel.DataCatalogSize = bh.CurBackupInfo.dataCatalogSize
 	sentinel.FilesMetadataDisabled = bh.Arguments.withoutFilesMetadata
+	if bh.CurBackupInfo.standbyInfo != nil {
+		sentinel.FromStandby = true
+		sentinel.PrimarySystemIdentifier = bh.CurBackupInfo.standbyInfo.PrimarySystemIdentifier
+		sentinel.PrimaryTimeline = bh.CurBackupInfo.standbyInfo.PrimaryTimeline
+	}
 	return sentinel
 }
 
@@ -107,7 +107,7 @@ func TestBackupSentinelDto_UnmarshalJSON_NoSpec(t *testing.T) {
 func TestBackupSentinelDto_MarshalJSON_OutputsUppercase(t *testing.T) {
 	// Test that marshaling outputs uppercase "Spec" for backward compatibility with existing WAL-G user scripts
 	spec := NewTablespaceSpec("/pgdata/test")
-	spec.addTablespace("16451", "/pgdata/tablespace1")
+	spec.AddTablespace("16451", "/pgdata/tablespace1")
 
 	dto := BackupSentinelDto{
 		PgVersion:      160000,
@@ -120,3 +120,26 @@ func TestBackupSentinelDto_MarshalJSON_OutputsUppercase(t *testing.T) {
 	// Verify the output contains uppercase "Spec" (WAL-G format) for backward compatibility
 	assert.Contains(t, string(data), `"Spec"`)
 }
+
+func TestBackupSentinelDto_StandbyFields(t *testing.T) {
+	systemIdentifier := uint64(7194659462587145523)
+	dto := BackupSentinelDto{
+		FromStandby:             true,
+		PrimarySystemIdentifier: &systemIdentifier,
+		PrimaryTimeline:         5,
+	}
+
+	data, err := json.Marshal(dto)
+	require.NoError(t, err)
+
+	var restored BackupSentinelDto
+	require.NoError(t, json.Unmarshal(data, &restored))
+	assert.True(t, restored.FromStandby)
+	assert.Equal(t, systemIdentifier, *restored.PrimarySystemIdentifier)
+	assert.Equal(t, uint32(5), restored.PrimaryTimeline)
+
+	data, err = json.Marshal(BackupSentinelDto{})
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "FromStandby")
+	assert.NotContains(t, string(data), "PrimaryTimeline")
+}
@@ -0,0 +1,233 @@
+package postgres
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// backupVerifyIgnoredPaths are not expected to match the manifest, the same as in pg_verifybackup
+var backupVerifyIgnoredPaths = map[string]bool{
+	"pg_wal":               true,
+	BackupManifestFilename: true,
+	"postgresql.auto.conf": true,
+	"recovery.signal":      true,
+	"standby.signal":       true,
+}
+
+// HandleBackupVerify checks the data directory restored from the backup, or the stored backup tars
+// if pgDataDirectory is empty, against the backup manifest
+func HandleBackupVerify(ctx context.Context, folder storage.Folder, backupSelector internal.BackupSelector,
+	pgDataDirectory string, saveManifest bool) {
+	selected, err := backupSelector.Select(ctx, folder)
+	tracelog.ErrorLogger.FatalOnError(err)
+	backup := ToPgBackup(selected)
+
+	manifestData, manifest, err := fetchBackupManifest(ctx, backup)
+	tracelog.ErrorLogger.FatalOnError(err)
+	checker := newBackupManifestChecker(manifest)
+
+	if pgDataDirectory != "" {
+		tracelog.InfoLogger.Printf("Verifying %s against the manifest of backup %s", pgDataDirectory, backup.Name)
+		err = checker.checkDirectory(utility.ResolveSymlink(pgDataDirectory))
+		tracelog.ErrorLogger.FatalOnError(err)
+		checker.checkMissing(false)
+	} else {
+		tracelog.InfoLogger.Printf("Verifying stored tars against the manifest of backup %s", backup.Name)
+		concurrentTars, sequentialTars, err := FilesToExtractProviderImpl{}.Get(ctx, backup, nil, false)
+		tracelog.ErrorLogger.FatalOnError(err)
+		err = internal.ExtractAll(ctx, checker, append(concurrentTars, sequentialTars...))
+		tracelog.ErrorLogger.FatalOnError(err)
+		// files without checksum may be stored in the base backups or as increments
+		checker.checkMissing(true)
+	}
+
+	if len(checker.problems) > 0 {
+		sort.Strings(checker.problems)
+		for _, problem := range checker.problems {
+			tracelog.ErrorLogger.Println(problem)
+		}
+		tracelog.ErrorLogger.Fatalf("Backup %s verification failed: %d problem(s) found", backup.Name, len(checker.problems))
+	}
+	tracelog.InfoLogger.Printf("Backup %s is verified: %d file(s) match the manifest", backup.Name, len(manifest.Files))
+
+	if saveManifest && pgDataDirectory != "" {
+		manifestPath := filepath.Join(pgDataDirectory, BackupManifestFilename)
+		err = os.WriteFile(manifestPath, manifestData, 0600)
+		tracelog.ErrorLogger.FatalfOnError("Failed to save backup manifest: %v", err)
+		tracelog.InfoLogger.Printf("Saved backup manifest to %s", manifestPath)
+	}
+}
+
+func fetchBackupManifest(ctx context.Context, backup Backup) ([]byte, *BackupManifest, error) {
+	manifestPath := storage.JoinPath(backup.Name, BackupManifestFilename)
+	reader, err := backup.Folder.ReadObject(ctx, manifestPath)
+	if err != nil {
+		if _, ok := err.(storage.ObjectNotFoundError); ok {
+			return nil, nil, errors.Errorf("backup %s has no %s, it was probably taken by an older WAL-G version",
+				backup.Name, BackupManifestFilename)
+		}
+		return nil, nil, err
+	}
+	defer utility.LoggedClose(reader, "")
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to read %s", manifestPath)
+	}
+	manifest, err := ParseBackupManifest(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, manifest, nil
+}
+
+// backupManifestChecker compares files with the manifest entries and collects the problems found.
+// It is also a TarInterpreter, so it can verify the stored backup tars.
+type backupManifestChecker struct {
+	mutex    sync.Mutex
+	files    map[string]BackupManifestFile
+	seen     map[string]bool
+	problems []string
+}
+
+func newBackupManifestChecker(manifest *BackupManifest) *backupManifestChecker {
+	files := make(map[string]BackupManifestFile, len(manifest.Files))
+	for _, file := range manifest.Files {
+		files[file.Path] = file
+	}
+	return &backupManifestChecker{
+		files: files,
+		seen:  make(map[string]bool, len(files)),
+	}
+}
+
+func (checker *backupManifestChecker) reportf(format string, args ...interface{}) {
+	checker.mutex.Lock()
+	defer checker.mutex.Unlock()
+	checker.problems = append(checker.problems, fmt.Sprintf(format, args...))
+}
+
+func (checker *backupManifestChecker) lookup(filePath string) (BackupManifestFile, bool) {
+	checker.mutex.Lock()
+	defer checker.mutex.Unlock()
+	file, ok := checker.files[filePath]
+	if ok {
+		checker.seen[filePath] = true
+	} else {
+		checker.problems = append(checker.problems, fmt.Sprintf("%s is present but not in the manifest", filePath))
+	}
+	return file, ok
+}
+
+// Interpret verifies a file from the backup tar. Files stored as increments have no checksum in the manifest.
+func (checker *backupManifestChecker) Interpret(reader io.Reader, header *tar.Header) error {
+	if header.Typeflag != tar.TypeReg {
+		return nil
+	}
+	filePath := backupManifestPath(header.Name)
+	file, ok := checker.lookup(filePath)
+	if !ok || !file.HasChecksum() {
+		return nil
+	}
+	return checker.checkContents(filePath, file, header.Size, reader)
+}
+
+func (checker *backupManifestChecker) checkContents(filePath string, file BackupManifestFile, size int64, reader io.Reader) error {
+	if size != file.Size {
+		checker.reportf("%s has size %d, but the manifest says %d", filePath, size, file.Size)
+		return nil
+	}
+	if !file.HasChecksum() {
+		return nil
+	}
+	checksum := NewCRC32CHash()
+	_, err := io.Copy(checksum, reader)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s", filePath)
+	}
+	if actual := FormatCRC32CChecksum(checksum.Sum32()); actual != file.Checksum {
+		checker.reportf("%s has checksum %s, but the manifest says %s", filePath, actual, file.Checksum)
+	}
+	return nil
+}
+
+func (checker *backupManifestChecker) checkDirectory(root string) error {
+	return checker.walkDirectory(root, "")
+}
+
+// walkDirectory verifies the files under dir, prefix is the path of dir relative to PGDATA.
+// Tablespace symlinks are followed.
+func (checker *backupManifestChecker) walkDirectory(dir, prefix string) error {
+	return filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		if relPath == "." {
+			return nil
+		}
+		relPath = path.Join(prefix, filepath.ToSlash(relPath))
+		if backupVerifyIgnoredPaths[relPath] {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.Type()&fs.ModeSymlink != 0 && path.Dir(relPath) == TablespaceFolder {
+			target, err := filepath.EvalSymlinks(filePath)
+			if err != nil {
+				return errors.Wrapf(err, "failed to resolve tablespace %s", relPath)
+			}
+			return checker.walkDirectory(target, relPath)
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		return checker.checkFile(filePath, relPath)
+	})
+}
+
+func (checker *backupManifestChecker) checkFile(filePath, relPath string) error {
+	file, ok := checker.lookup(relPath)
+	if !ok {
+		return nil
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+	reader, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(reader, "")
+	return checker.checkContents(relPath, file, info.Size(), reader)
+}
+
+// checkMissing reports manifest entries which were not found, optionally only the ones with checksum
+func (checker *backupManifestChecker) checkMissing(onlyWithChecksum bool) {
+	checker.mutex.Lock()
+	defer checker.mutex.Unlock()
+	for filePath, file := range checker.files {
+		if checker.seen[filePath] || onlyWithChecksum && !file.HasChecksum() {
+			continue
+		}
+		checker.problems = append(checker.problems, fmt.Sprintf("%s is in the manifest but not found", filePath))
+	}
+}
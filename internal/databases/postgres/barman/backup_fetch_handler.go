@@ -0,0 +1,180 @@
+package barman
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	directoryMode = 0700
+	fileMode      = 0600
+)
+
+// emptyDirectories are created on restore of the plain backups, storage does not keep the empty directories
+var emptyDirectories = []string{
+	"pg_wal/archive_status", "pg_tblspc", "pg_notify", "pg_replslot", "pg_serial", "pg_snapshots", "pg_stat",
+	"pg_stat_tmp", "pg_subtrans", "pg_dynshmem", "pg_twophase", "pg_commit_ts", "pg_logical/snapshots", "pg_logical/mappings",
+}
+
+func HandleBackupFetch(ctx context.Context, folder storage.Folder, server string, destinationDirectory string,
+	backupSelector internal.BackupSelector) error {
+	backup, err := backupSelector.Select(ctx, folder)
+	if err != nil {
+		return err
+	}
+	info, err := LoadBackupInfo(ctx, folder, server, backup.Name)
+	if err != nil {
+		return err
+	}
+	if info.Status != BackupStatusDone {
+		return errors.Errorf("backup %s has status %s, only %s backups can be restored", info.BackupID, info.Status, BackupStatusDone)
+	}
+	if info.ParentBackupID != "" {
+		return errors.Errorf("backup %s is incremental, Barman incremental backups are not supported", info.BackupID)
+	}
+
+	backupFolder := backupsFolder(folder, server).GetSubFolder(info.BackupID)
+	dataFolder := backupFolder.GetSubFolder(DataFolderName)
+	objects, _, err := dataFolder.ListFolder(ctx)
+	if err != nil {
+		return err
+	}
+	if baseTar := findTar(objects, "base"); baseTar != "" {
+		err = fetchTarBackup(ctx, dataFolder, baseTar, objects, destinationDirectory, info)
+	} else {
+		err = fetchPlainBackup(ctx, backupFolder, destinationDirectory, info)
+	}
+	if err != nil {
+		return err
+	}
+	return createTablespaceLinks(destinationDirectory, info)
+}
+
+// findTar returns the name of the tar written by pg_basebackup -Ft, e.g. base.tar.gz
+func findTar(objects []storage.Object, name string) string {
+	for _, object := range objects {
+		if strings.HasPrefix(object.GetName(), name+".tar") {
+			return object.GetName()
+		}
+	}
+	return ""
+}
+
+// fetchTarBackup extracts the backup taken with backup_method = postgres and backup_compression set:
+// the data directory is stored as base.tar and every tablespace as <oid>.tar
+func fetchTarBackup(ctx context.Context, dataFolder storage.Folder, baseTar string, objects []storage.Object,
+	destinationDirectory string, info *BackupInfo) error {
+	tarInterpreter := postgres.NewFileTarInterpreter(destinationDirectory, postgres.BackupSentinelDto{},
+		postgres.FilesMetadataDto{}, nil, false)
+	err := internal.ExtractAll(ctx, tarInterpreter, []internal.ReaderMaker{internal.NewStorageReaderMaker(dataFolder, baseTar)})
+	if err != nil {
+		return err
+	}
+
+	for _, tablespace := range info.Tablespaces {
+		tablespaceTar := findTar(objects, tablespace.Oid)
+		if tablespaceTar == "" {
+			return errors.Errorf("tablespace %s is not found in backup %s", tablespace.Name, info.BackupID)
+		}
+		tarInterpreter := postgres.NewFileTarInterpreter(tablespace.Location, postgres.BackupSentinelDto{},
+			postgres.FilesMetadataDto{}, nil, false)
+		err := internal.ExtractAll(ctx, tarInterpreter, []internal.ReaderMaker{internal.NewStorageReaderMaker(dataFolder, tablespaceTar)})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchPlainBackup copies the backup taken with rsync or pg_basebackup -Fp:
+// the data directory is stored in the data folder and every tablespace in the <oid> folder
+func fetchPlainBackup(ctx context.Context, backupFolder storage.Folder, destinationDirectory string, info *BackupInfo) error {
+	if err := copyFolder(ctx, backupFolder.GetSubFolder(DataFolderName), destinationDirectory); err != nil {
+		return err
+	}
+	for _, tablespace := range info.Tablespaces {
+		if err := copyFolder(ctx, backupFolder.GetSubFolder(tablespace.Oid), tablespace.Location); err != nil {
+			return err
+		}
+	}
+	for _, directory := range emptyDirectories {
+		if err := os.MkdirAll(filepath.Join(destinationDirectory, directory), directoryMode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFolder(ctx context.Context, folder storage.Folder, destinationDirectory string) error {
+	objects, err := storage.ListFolderRecursively(ctx, folder)
+	if err != nil {
+		return err
+	}
+	concurrency, err := conf.GetMaxDownloadConcurrency()
+	if err != nil {
+		return err
+	}
+	errGroup, groupCtx := errgroup.WithContext(ctx)
+	errGroup.SetLimit(concurrency)
+	for _, object := range objects {
+		objectPath := object.GetName()
+		errGroup.Go(func() error {
+			return copyFile(groupCtx, folder, objectPath, filepath.Join(destinationDirectory, filepath.FromSlash(objectPath)))
+		})
+	}
+	return errGroup.Wait()
+}
+
+func copyFile(ctx context.Context, folder storage.Folder, objectPath, localPath string) error {
+	if err := os.MkdirAll(filepath.Dir(localPath), directoryMode); err != nil {
+		return err
+	}
+	reader, err := folder.ReadObject(ctx, objectPath)
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(reader, "")
+
+	file, err := os.OpenFile(localPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fileMode)
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(file, "")
+	if _, err := utility.FastCopy(file, reader); err != nil {
+		return errors.Wrapf(err, "failed to copy %s", objectPath)
+	}
+	return nil
+}
+
+func createTablespaceLinks(destinationDirectory string, info *BackupInfo) error {
+	for _, tablespace := range info.Tablespaces {
+		if err := os.MkdirAll(tablespace.Location, directoryMode); err != nil {
+			return err
+		}
+		linkPath := path.Join(destinationDirectory, postgres.TablespaceFolder, tablespace.Oid)
+		if err := os.MkdirAll(filepath.Dir(linkPath), directoryMode); err != nil {
+			return err
+		}
+		// the link stored in base.tar may be extracted already
+		if err := os.Remove(linkPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.Symlink(tablespace.Location, linkPath); err != nil {
+			return errors.Wrapf(err, "failed to create the link of tablespace %s", tablespace.Name)
+		}
+		tracelog.InfoLogger.Printf("Tablespace %s is restored to %s\n", tablespace.Name, tablespace.Location)
+	}
+	return nil
+}
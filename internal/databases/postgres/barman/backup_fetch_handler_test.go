@@ -0,0 +1,121 @@
+package barman_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/internal/databases/postgres/barman"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/testtools"
+)
+
+func init() {
+	internal.ConfigureSettings(conf.PG)
+	conf.InitConfig()
+	conf.Configure()
+}
+
+func putBackupInfo(t *testing.T, folder storage.Folder, backupID, status, beginWal, endTime, tablespaces string) {
+	backupInfo := fmt.Sprintf(`backup_id=%s
+backup_label='START WAL LOCATION: 0/2000028 (file %s)\n'
+begin_offset=40
+begin_time=2024-01-01 00:00:00.123456+00:00
+begin_wal=%s
+begin_xlog=0/2000028
+end_time=%s
+end_wal=%s
+end_xlog=0/2000100
+error=None
+mode=rsync-concurrent
+pgdata=/var/lib/postgresql/15/main
+server_name=main
+status=%s
+systemid=7300000000000000001
+tablespaces=%s
+timeline=1
+version=150002
+`, backupID, beginWal, beginWal, endTime, beginWal, status, tablespaces)
+	require.NoError(t, folder.PutObject(context.Background(), "main/base/"+backupID+"/backup.info", strings.NewReader(backupInfo)))
+}
+
+func TestHandleBackupFetch(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	tablespaceLocation := filepath.Join(t.TempDir(), "tbs")
+	putBackupInfo(t, folder, "20240101T000000", barman.BackupStatusDone, "000000010000000000000002",
+		"2024-01-01 00:10:00.000001+00:00", fmt.Sprintf("[('tbs', 16384, '%s')]", tablespaceLocation))
+	putBackupInfo(t, folder, "20240102T000000", "FAILED", "000000010000000000000004", "None", "None")
+	files := map[string]string{
+		"main/base/20240101T000000/data/PG_VERSION":                   "15\n",
+		"main/base/20240101T000000/data/global/pg_control":            "pg_control",
+		"main/base/20240101T000000/data/base/1/1234":                  "table",
+		"main/base/20240101T000000/16384/PG_15_202209061/16385/16386": "tablespace table",
+	}
+	for name, content := range files {
+		require.NoError(t, folder.PutObject(context.Background(), name, strings.NewReader(content)))
+	}
+
+	backups, err := barman.GetBackupList(context.Background(), folder, "main")
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.Equal(t, "000000010000000000000002", backups[0].WalFileName)
+
+	destination := t.TempDir()
+	err = barman.HandleBackupFetch(context.Background(), folder, "main", destination,
+		barman.NewBackupSelector(internal.LatestString, "main"))
+	require.NoError(t, err)
+
+	for name, expected := range map[string]string{
+		"PG_VERSION":        "15\n",
+		"global/pg_control": "pg_control",
+		"base/1/1234":       "table",
+		"pg_tblspc/16384/PG_15_202209061/16385/16386": "tablespace table",
+	} {
+		actual, err := os.ReadFile(filepath.Join(destination, name))
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(actual))
+	}
+	link, err := os.Readlink(filepath.Join(destination, postgres.TablespaceFolder, "16384"))
+	require.NoError(t, err)
+	assert.Equal(t, tablespaceLocation, link)
+	assert.DirExists(t, filepath.Join(destination, "pg_wal", "archive_status"))
+
+	err = barman.HandleBackupFetch(context.Background(), folder, "main", t.TempDir(),
+		barman.NewBackupSelector("20240102T000000", "main"))
+	assert.Error(t, err)
+}
+
+func TestHandleWalFetch(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, err := writer.Write([]byte("segment"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	require.NoError(t, folder.PutObject(context.Background(), "main/wals/0000000100000000/000000010000000000000002", &compressed))
+	require.NoError(t, folder.PutObject(context.Background(), "main/wals/0000000100000000/000000010000000000000003",
+		strings.NewReader("plain segment")))
+	require.NoError(t, folder.PutObject(context.Background(), "main/wals/00000002.history", strings.NewReader("history")))
+
+	for name, expected := range map[string]string{
+		"000000010000000000000002": "segment",
+		"000000010000000000000003": "plain segment",
+		"00000002.history":         "history",
+	} {
+		location := filepath.Join(t.TempDir(), name)
+		require.NoError(t, barman.HandleWalFetch(context.Background(), folder, "main", name, location))
+		actual, err := os.ReadFile(location)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(actual))
+	}
+}
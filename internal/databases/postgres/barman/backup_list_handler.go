@@ -0,0 +1,117 @@
+package barman
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/internal/printlist"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+type BackupDetails struct {
+	BackupName       string
+	WalFileName      string
+	Mode             string
+	StartTime        time.Time
+	FinishTime       time.Time
+	PgVersion        int
+	StartLsn         postgres.LSN
+	FinishLsn        postgres.LSN
+	SystemIdentifier string
+}
+
+func NewBackupDetails(info *BackupInfo) *BackupDetails {
+	return &BackupDetails{
+		BackupName:       info.BackupID,
+		WalFileName:      info.BeginWal,
+		Mode:             info.Mode,
+		StartTime:        info.BeginTime,
+		FinishTime:       info.EndTime,
+		PgVersion:        info.Version,
+		StartLsn:         info.BeginLsn,
+		FinishLsn:        info.EndLsn,
+		SystemIdentifier: info.SystemID,
+	}
+}
+
+func (bd *BackupDetails) PrintableFields() []printlist.TableField {
+	prettyStartTime := internal.PrettyFormatTime(bd.StartTime)
+	prettyFinishTime := internal.PrettyFormatTime(bd.FinishTime)
+	return []printlist.TableField{
+		{
+			Name:       "name",
+			PrettyName: "Name",
+			Value:      bd.BackupName,
+		},
+		{
+			Name:       "wal_file_name",
+			PrettyName: "WAL file name",
+			Value:      bd.WalFileName,
+		},
+		{
+			Name:       "mode",
+			PrettyName: "Mode",
+			Value:      bd.Mode,
+		},
+		{
+			Name:        "start_time",
+			PrettyName:  "Start time",
+			Value:       internal.FormatTime(bd.StartTime),
+			PrettyValue: &prettyStartTime,
+		},
+		{
+			Name:        "finish_time",
+			PrettyName:  "Finish time",
+			Value:       internal.FormatTime(bd.FinishTime),
+			PrettyValue: &prettyFinishTime,
+		},
+		{
+			Name:       "pg_version",
+			PrettyName: "PG version",
+			Value:      strconv.Itoa(bd.PgVersion),
+		},
+		{
+			Name:       "start_lsn",
+			PrettyName: "Start LSN",
+			Value:      bd.StartLsn.String(),
+		},
+		{
+			Name:       "finish_lsn",
+			PrettyName: "Finish LSN",
+			Value:      bd.FinishLsn.String(),
+		},
+		{
+			Name:       "system_identifier",
+			PrettyName: "System identifier",
+			Value:      bd.SystemIdentifier,
+		},
+	}
+}
+
+func newBackupTime(info *BackupInfo) internal.BackupTime {
+	return internal.BackupTime{
+		BackupName:  info.BackupID,
+		Time:        info.EndTime,
+		WalFileName: info.BeginWal,
+	}
+}
+
+func GetBackupList(ctx context.Context, folder storage.Folder, server string) ([]internal.BackupTime, error) {
+	infos, err := LoadBackupInfos(ctx, folder, server)
+	if err != nil {
+		return nil, err
+	}
+	return postgres.GetForeignBackupTimes(infos, newBackupTime), nil
+}
+
+func HandleBackupList(ctx context.Context, folder storage.Folder, server string, detailed bool, pretty bool, json bool) error {
+	infos, err := LoadBackupInfos(ctx, folder, server)
+	if err != nil {
+		return err
+	}
+	return postgres.HandleForeignBackupList(infos, newBackupTime,
+		func(info *BackupInfo) printlist.Entity { return NewBackupDetails(info) }, detailed, pretty, json)
+}
@@ -0,0 +1,162 @@
+package barman
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	BaseFolderName = "base"
+	WalsFolderName = "wals"
+	DataFolderName = "data"
+	BackupInfoFile = "backup.info"
+
+	BackupStatusDone = "DONE"
+)
+
+var tablespaceRegexp = regexp.MustCompile(`\(\s*'([^']*)'\s*,\s*(\d+)\s*,\s*'([^']*)'\s*\)`)
+
+// Tablespace is the entry of the tablespaces list of backup.info
+type Tablespace struct {
+	Name     string
+	Oid      string
+	Location string
+}
+
+// BackupInfo is the backup.info file Barman keeps in the directory of every base backup
+type BackupInfo struct {
+	BackupID       string
+	Status         string
+	Mode           string
+	BeginTime      time.Time
+	EndTime        time.Time
+	BeginWal       string
+	EndWal         string
+	BeginLsn       postgres.LSN
+	EndLsn         postgres.LSN
+	Timeline       uint32
+	Version        int
+	SystemID       string
+	PgData         string
+	Tablespaces    []Tablespace
+	ParentBackupID string
+}
+
+// parseBackupInfo parses the key=value lines of backup.info, Barman writes None for the unset values
+func parseBackupInfo(reader io.Reader) (map[string]string, error) {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok || value == "None" {
+			continue
+		}
+		values[strings.TrimSpace(key)] = value
+	}
+	return values, scanner.Err()
+}
+
+func parseLSN(value string) (postgres.LSN, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return postgres.ParseLSN(value)
+}
+
+func newBackupInfo(backupID string, values map[string]string) (*BackupInfo, error) {
+	info := &BackupInfo{
+		BackupID:       backupID,
+		Status:         values["status"],
+		Mode:           values["mode"],
+		BeginWal:       values["begin_wal"],
+		EndWal:         values["end_wal"],
+		SystemID:       values["systemid"],
+		PgData:         values["pgdata"],
+		ParentBackupID: values["parent_backup_id"],
+	}
+	var err error
+	if info.BeginTime, err = postgres.ParseForeignBackupTime(values["begin_time"]); err != nil {
+		return nil, errors.Wrap(err, "invalid begin_time")
+	}
+	if info.EndTime, err = postgres.ParseForeignBackupTime(values["end_time"]); err != nil {
+		return nil, errors.Wrap(err, "invalid end_time")
+	}
+	if info.BeginLsn, err = parseLSN(values["begin_xlog"]); err != nil {
+		return nil, errors.Wrap(err, "invalid begin_xlog")
+	}
+	if info.EndLsn, err = parseLSN(values["end_xlog"]); err != nil {
+		return nil, errors.Wrap(err, "invalid end_xlog")
+	}
+	if timeline, ok := values["timeline"]; ok {
+		parsed, err := strconv.ParseUint(timeline, 10, 32)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid timeline")
+		}
+		info.Timeline = uint32(parsed)
+	}
+	if version, ok := values["version"]; ok {
+		if info.Version, err = strconv.Atoi(version); err != nil {
+			return nil, errors.Wrap(err, "invalid version")
+		}
+	}
+	for _, match := range tablespaceRegexp.FindAllStringSubmatch(values["tablespaces"], -1) {
+		info.Tablespaces = append(info.Tablespaces, Tablespace{Name: match[1], Oid: match[2], Location: match[3]})
+	}
+	return info, nil
+}
+
+func backupsFolder(folder storage.Folder, server string) storage.Folder {
+	return folder.GetSubFolder(server).GetSubFolder(BaseFolderName)
+}
+
+func LoadBackupInfo(ctx context.Context, folder storage.Folder, server, backupID string) (*BackupInfo, error) {
+	reader, err := backupsFolder(folder, server).GetSubFolder(backupID).ReadObject(ctx, BackupInfoFile)
+	if err != nil {
+		return nil, err
+	}
+	defer utility.LoggedClose(reader, "")
+
+	values, err := parseBackupInfo(reader)
+	if err != nil {
+		return nil, err
+	}
+	info, err := newBackupInfo(backupID, values)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s of backup %s", BackupInfoFile, backupID)
+	}
+	return info, nil
+}
+
+// LoadBackupInfos returns the backups of the server with the DONE status, the failed and running ones can't be restored
+func LoadBackupInfos(ctx context.Context, folder storage.Folder, server string) ([]*BackupInfo, error) {
+	_, backupFolders, err := backupsFolder(folder, server).ListFolder(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []*BackupInfo
+	for _, backupFolder := range backupFolders {
+		backupID := path.Base(strings.TrimSuffix(backupFolder.GetPath(), "/"))
+		info, err := LoadBackupInfo(ctx, folder, server, backupID)
+		if err != nil {
+			return nil, err
+		}
+		if info.Status != BackupStatusDone {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
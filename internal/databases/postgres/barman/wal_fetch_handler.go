@@ -0,0 +1,88 @@
+package barman
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"context"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/gzip"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/compression/zstd"
+	"github.com/wal-g/wal-g/internal/ioextensions"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// compressionMagics detect the compression of the WAL files, Barman keeps the names of the compressed files unchanged
+var compressionMagics = []struct {
+	magic     []byte
+	extension string
+}{
+	{[]byte{0x1f, 0x8b}, gzip.FileExtension},
+	{[]byte{0x04, 0x22, 0x4d, 0x18}, lz4.FileExtension},
+	{[]byte{0x28, 0xb5, 0x2f, 0xfd}, zstd.FileExtension},
+	{[]byte("BZh"), "bz2"},
+}
+
+// walFilePath returns the path of the WAL file relative to the wals folder:
+// history files are stored in the root, the others in the folder named after the timeline and log
+func walFilePath(walFileName string) string {
+	if strings.HasSuffix(walFileName, ".history") || len(walFileName) < 16 {
+		return walFileName
+	}
+	return walFileName[:16] + "/" + walFileName
+}
+
+func decompressWalFile(reader io.ReadCloser) (io.ReadCloser, error) {
+	bufferedReader := bufio.NewReader(reader)
+	header, err := bufferedReader.Peek(4)
+	if err != nil && err != io.EOF {
+		utility.LoggedClose(reader, "")
+		return nil, err
+	}
+	for _, compressionMagic := range compressionMagics {
+		if !bytes.HasPrefix(header, compressionMagic.magic) {
+			continue
+		}
+		if compressionMagic.extension == "bz2" {
+			return ioextensions.ReadCascadeCloser{Reader: bzip2.NewReader(bufferedReader), Closer: reader}, nil
+		}
+		decompressed, err := compression.FindDecompressor(compressionMagic.extension).Decompress(bufferedReader)
+		if err != nil {
+			utility.LoggedClose(reader, "")
+			return nil, err
+		}
+		return ioextensions.ReadCascadeCloser{
+			Reader: decompressed,
+			Closer: ioextensions.NewMultiCloser([]io.Closer{decompressed, reader}),
+		}, nil
+	}
+	return ioextensions.ReadCascadeCloser{Reader: bufferedReader, Closer: reader}, nil
+}
+
+func HandleWalFetch(ctx context.Context, folder storage.Folder, server string, walFileName string, location string) error {
+	walsFolder := folder.GetSubFolder(server).GetSubFolder(WalsFolderName)
+	reader, err := walsFolder.ReadObject(ctx, walFilePath(walFileName))
+	if err != nil {
+		return err
+	}
+	decompressed, err := decompressWalFile(reader)
+	if err != nil {
+		return errors.Wrapf(err, "failed to decompress %s", walFileName)
+	}
+	defer utility.LoggedClose(decompressed, "")
+
+	file, err := os.OpenFile(location, os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_EXCL, 0666)
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(file, "")
+	_, err = utility.FastCopy(file, decompressed)
+	return err
+}
@@ -4,6 +4,7 @@ import (
 	"archive/tar"
 	"context"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"io/fs"
 	"os"
@@ -72,6 +73,7 @@ type Bundle struct {
 	DeltaMap           PagedFileDeltaMap
 	TablespaceSpec     TablespaceSpec
 	DataCatalogSize    atomic.Int64
+	Manifest           *BackupManifestBuilder
 
 	forceIncremental bool
 
@@ -95,6 +97,7 @@ func NewBundle(
 		IncrementFromFiles: incrementFromFiles,
 		IncrementFromName:  incrementFromName,
 		TablespaceSpec:     NewTablespaceSpec(directory),
+		Manifest:           NewBackupManifestBuilder(),
 		forceIncremental:   forceIncremental,
 	}
 }
@@ -125,6 +128,12 @@ func (bundle *Bundle) getIncrementBaseFiles() internal.BackupFileList {
 // checkTimelineChanged compares timelines of pg_backup_start() and pg_backup_stop()
 func (bundle *Bundle) checkTimelineChanged(ctx context.Context, queryRunner *PgQueryRunner) bool {
 	if bundle.Replica {
+		standby, err := queryRunner.IsStandby(ctx)
+		if err != nil || !standby {
+			tracelog.ErrorLogger.Printf("Standby was promoted or its state is unknown. Sentinel for the backup will not be uploaded.")
+			return true
+		}
+
 		timeline, err := queryRunner.ReadTimeline(ctx)
 		if err != nil {
 			tracelog.ErrorLogger.Printf("Unable to check timeline change. Sentinel for the backup will not be uploaded.")
@@ -218,7 +227,7 @@ func (bundle *Bundle) HandleWalkedFSObject(path string, info os.FileInfo, err er
 				if err != nil {
 					return fmt.Errorf("could not read symlink for tablespace %v", err)
 				}
-				bundle.TablespaceSpec.addTablespace(symlinkName, actualPath)
+				bundle.TablespaceSpec.AddTablespace(symlinkName, actualPath)
 				err = filepath.Walk(actualPath, bundle.HandleWalkedFSObject)
 				if err != nil {
 					return fmt.Errorf("could not walk tablespace symlink tree error %v", err)
@@ -268,6 +277,8 @@ func (bundle *Bundle) addToBundle(path string, info os.FileInfo) error {
 		baseFile, wasInBase := baseFiles[fileInfoHeader.Name]
 		// It is important to take MTime before ReadIncrementalFile()
 		time := info.ModTime()
+		// the packer replaces this entry with a checksummed one if the file is stored in full
+		bundle.Manifest.AddFile(fileInfoHeader.Name, info.Size(), time)
 
 		// We do not rely here on monotonic time, instead we backup file if MTime changed somehow
 		// For details see
@@ -298,7 +309,8 @@ func (bundle *Bundle) addToBundle(path string, info os.FileInfo) error {
 func (bundle *Bundle) isIncremented(path string, wasInBase bool, info fs.FileInfo) bool {
 	incrementBaseLsn := bundle.getIncrementBaseLsn()
 	isIncremented := incrementBaseLsn != nil && (wasInBase || bundle.forceIncremental) && isPagedFile(info, path)
-	isIncremented = isIncremented || (bundle.IncrementFromChkpNum != nil && wasInBase && orioledb.IsOrioledbDataFile(info, path))
+	isIncremented = isIncremented ||
+		(bundle.IncrementFromChkpNum != nil && (wasInBase || bundle.forceIncremental) && orioledb.IsOrioledbDataFile(info, path))
 	return isIncremented
 }
 
@@ -337,11 +349,13 @@ func (bundle *Bundle) UploadPgControl(ctx context.Context, compressorFileExtensi
 			R: file,
 			N: fileInfoHeader.Size,
 		}
+		checksum := NewCRC32CHash()
 
-		_, err = io.Copy(tarWriter, lim)
+		_, err = io.Copy(tarWriter, io.TeeReader(lim, checksum))
 		if err != nil {
 			return errors.Wrap(err, "UploadPgControl: copy failed")
 		}
+		bundle.Manifest.AddFileWithChecksum(fileInfoHeader.Name, fileInfoHeader.Size, info.ModTime(), checksum.Sum32())
 
 		tarBall.AddSize(fileInfoHeader.Size)
 		utility.LoggedClose(file, "")
@@ -369,6 +383,11 @@ func (bundle *Bundle) uploadLabelFiles(ctx context.Context,
 	if !queryRunner.IsTablespaceMapExists() {
 		return "", nil, lsn, nil
 	}
+	stopTime := utility.TimeNowCrossPlatformUTC()
+	bundle.Manifest.AddFileWithChecksum(BackupLabelFilename, int64(len(label)), stopTime,
+		crc32.Checksum([]byte(label), castagnoliTable))
+	bundle.Manifest.AddFileWithChecksum(TablespaceMapFilename, int64(len(offsetMap)), stopTime,
+		crc32.Checksum([]byte(offsetMap), castagnoliTable))
 
 	tarBall := bundle.NewTarBall(false)
 	tarBall.SetUp(ctx, bundle.Crypter, utility.AddFileExtension("backup_label.tar", compressorFileExtension))
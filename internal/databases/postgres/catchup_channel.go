@@ -0,0 +1,417 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/ioextensions"
+	"github.com/wal-g/wal-g/internal/limiters"
+)
+
+const (
+	catchupNonceSize = 32
+	// catchupRecordSize is the maximum size of the plaintext sealed into one record of the pre-shared key session
+	catchupRecordSize = 64 * 1024
+)
+
+// catchupHandshakeTimeout limits the TLS and the pre-shared key handshakes,
+// so a stalled peer doesn't block the receiver from accepting the next connection
+var catchupHandshakeTimeout = 30 * time.Second
+
+// channelSettings are the names of the settings securing a TCP channel between the wal-g instances
+type channelSettings struct {
+	tlsCertFile string
+	tlsKeyFile  string
+	tlsCAFile   string
+	psk         string
+}
+
+var (
+	// catchupChannelSettings secure the catchup-send and catchup-receive connection
+	catchupChannelSettings = channelSettings{
+		conf.PgCatchupTLSCertFile, conf.PgCatchupTLSKeyFile, conf.PgCatchupTLSCAFile, conf.PgCatchupPSK}
+	// peerChannelSettings secure the connections of wal-fetch to the peer daemons
+	peerChannelSettings = channelSettings{
+		conf.PgPeerTLSCertFile, conf.PgPeerTLSKeyFile, conf.PgPeerTLSCAFile, conf.PgPeerPSK}
+)
+
+// catchupChannelConfig describes how the catchup-send and catchup-receive connection is secured,
+// the connections of the peer daemons are secured the same way
+type catchupChannelConfig struct {
+	// tlsConfig is nil if TLS is disabled
+	tlsConfig *tls.Config
+	// psk is empty if pre-shared key authentication is disabled
+	psk []byte
+}
+
+type catchupAuthError struct {
+	error
+}
+
+func newCatchupAuthError(format string, args ...interface{}) catchupAuthError {
+	return catchupAuthError{errors.Errorf(format, args...)}
+}
+
+func (err catchupAuthError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// newCatchupChannelConfig reads the TLS and pre-shared key settings, serverName is used
+// to verify the receiver certificate and is ignored on the receiver side
+func newCatchupChannelConfig(settings channelSettings, isReceiver bool, serverName string) (*catchupChannelConfig, error) {
+	config := &catchupChannelConfig{psk: []byte(viper.GetString(settings.psk))}
+
+	certFile := viper.GetString(settings.tlsCertFile)
+	keyFile := viper.GetString(settings.tlsKeyFile)
+	caFile := viper.GetString(settings.tlsCAFile)
+	if certFile == "" && keyFile == "" && caFile == "" {
+		return config, nil
+	}
+	if certFile == "" || keyFile == "" || caFile == "" {
+		return nil, errors.Errorf("mutual TLS requires all of %s, %s and %s to be set",
+			settings.tlsCertFile, settings.tlsKeyFile, settings.tlsCAFile)
+	}
+
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load the TLS certificate %s", certFile)
+	}
+	caCertificates, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the TLS CA file %s", caFile)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCertificates) {
+		return nil, errors.Errorf("no certificates found in %s", caFile)
+	}
+
+	config.tlsConfig = &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if isReceiver {
+		config.tlsConfig.ClientCAs = pool
+		config.tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	} else {
+		config.tlsConfig.RootCAs = pool
+		config.tlsConfig.ServerName = serverName
+	}
+	return config, nil
+}
+
+func (config *catchupChannelConfig) describe() string {
+	switch {
+	case config.tlsConfig != nil && len(config.psk) > 0:
+		return "mutual TLS and pre-shared key"
+	case config.tlsConfig != nil:
+		return "mutual TLS"
+	case len(config.psk) > 0:
+		return "pre-shared key"
+	default:
+		return "no authentication"
+	}
+}
+
+// dialCatchupChannel connects catchup-send to catchup-receive and authenticates the connection
+func dialCatchupChannel(ctx context.Context, destination string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(destination)
+	if err != nil {
+		return nil, err
+	}
+	config, err := newCatchupChannelConfig(catchupChannelSettings, false, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", destination)
+	if err != nil {
+		return nil, err
+	}
+	conn, err = config.secure(ctx, conn, false)
+	if err != nil {
+		return nil, err
+	}
+	tracelog.InfoLogger.Printf("Connected to %v with %s", destination, config.describe())
+	return conn, nil
+}
+
+// acceptCatchupChannel waits for catchup-send to connect, connections that fail
+// the authentication are dropped and the next one is awaited
+func acceptCatchupChannel(ctx context.Context, listener net.Listener) (net.Conn, error) {
+	config, err := newCatchupChannelConfig(catchupChannelSettings, true, "")
+	if err != nil {
+		return nil, err
+	}
+	if config.tlsConfig == nil && len(config.psk) == 0 {
+		tracelog.WarningLogger.Printf("Catchup connection is not authenticated, set %s or %s to secure it",
+			conf.PgCatchupPSK, conf.PgCatchupTLSCertFile)
+	}
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		securedConn, err := config.secure(ctx, conn, true)
+		if err != nil {
+			tracelog.WarningLogger.Printf("Rejected catchup connection from %v: %v", conn.RemoteAddr(), err)
+			continue
+		}
+		tracelog.InfoLogger.Printf("Accepted catchup connection from %v with %s", conn.RemoteAddr(), config.describe())
+		return securedConn, nil
+	}
+}
+
+// secure wraps the connection into TLS and performs the pre-shared key authentication.
+// Without TLS the traffic is encrypted and authenticated with the session keys derived from the pre-shared key,
+// so the commands can't be injected into the authenticated connection.
+// The network rate limit is applied under TLS to limit the actual traffic.
+func (config *catchupChannelConfig) secure(ctx context.Context, conn net.Conn, isReceiver bool) (net.Conn, error) {
+	rawConn := conn
+	if err := rawConn.SetDeadline(time.Now().Add(catchupHandshakeTimeout)); err != nil {
+		_ = rawConn.Close()
+		return nil, err
+	}
+	if limiters.NetworkLimiter != nil {
+		conn = &rateLimitedConn{Conn: conn, writer: limiters.NewNetworkLimitWriter(ctx, conn)}
+	}
+	if config.tlsConfig != nil {
+		var tlsConn *tls.Conn
+		if isReceiver {
+			tlsConn = tls.Server(conn, config.tlsConfig)
+		} else {
+			tlsConn = tls.Client(conn, config.tlsConfig)
+		}
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, errors.Wrap(err, "TLS handshake failed")
+		}
+		conn = tlsConn
+	}
+	if len(config.psk) > 0 {
+		sendNonce, receiveNonce, err := authenticateCatchupPeer(conn, config.psk, isReceiver)
+		if err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		if config.tlsConfig == nil {
+			conn, err = newCatchupSessionConn(conn, config.psk, isReceiver, sendNonce, receiveNonce)
+			if err != nil {
+				_ = rawConn.Close()
+				return nil, err
+			}
+		}
+	}
+	if err := rawConn.SetDeadline(time.Time{}); err != nil {
+		_ = rawConn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// authenticateCatchupPeer proves the knowledge of the pre-shared key to the peer and checks the peer does the same.
+// Each side sends a random nonce and then the HMAC of both nonces, so the proofs can't be replayed or reflected.
+// It returns the nonces of catchup-send and catchup-receive.
+func authenticateCatchupPeer(conn io.ReadWriter, psk []byte, isReceiver bool) ([]byte, []byte, error) {
+	ownNonce := make([]byte, catchupNonceSize)
+	if _, err := rand.Read(ownNonce); err != nil {
+		return nil, nil, err
+	}
+	if _, err := conn.Write(ownNonce); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to send the authentication nonce")
+	}
+	peerNonce := make([]byte, catchupNonceSize)
+	if _, err := io.ReadFull(conn, peerNonce); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to read the authentication nonce")
+	}
+	if bytes.Equal(ownNonce, peerNonce) {
+		return nil, nil, newCatchupAuthError("the peer has reflected the authentication nonce")
+	}
+
+	if _, err := conn.Write(catchupPSKProof(psk, isReceiver, peerNonce, ownNonce)); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to send the authentication proof")
+	}
+	peerProof := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(conn, peerProof); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to read the authentication proof")
+	}
+	if !hmac.Equal(peerProof, catchupPSKProof(psk, !isReceiver, ownNonce, peerNonce)) {
+		return nil, nil, newCatchupAuthError("the peer failed the pre-shared key authentication")
+	}
+	if isReceiver {
+		return peerNonce, ownNonce, nil
+	}
+	return ownNonce, peerNonce, nil
+}
+
+func catchupRole(isReceiver bool) string {
+	if isReceiver {
+		return "catchup-receive"
+	}
+	return "catchup-send"
+}
+
+func catchupPSKProof(psk []byte, isReceiver bool, challenge, response []byte) []byte {
+	mac := hmac.New(sha256.New, psk)
+	mac.Write([]byte(catchupRole(isReceiver)))
+	mac.Write(challenge)
+	mac.Write(response)
+	return mac.Sum(nil)
+}
+
+// catchupSessionKey derives the key of the traffic sent by the side from the pre-shared key and the nonces of the session
+func catchupSessionKey(psk []byte, isReceiver bool, sendNonce, receiveNonce []byte) []byte {
+	mac := hmac.New(sha256.New, psk)
+	mac.Write([]byte("session key of " + catchupRole(isReceiver)))
+	mac.Write(sendNonce)
+	mac.Write(receiveNonce)
+	return mac.Sum(nil)
+}
+
+func newCatchupSessionCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// catchupSessionConn seals the traffic into AES-GCM records with the per-direction session keys.
+// The record number is the GCM nonce, so the records can't be dropped, reordered or replayed unnoticed.
+type catchupSessionConn struct {
+	net.Conn
+	sealer cipher.AEAD
+	opener cipher.AEAD
+
+	writeMutex  sync.Mutex
+	writeNumber uint64
+	readNumber  uint64
+	// readBuffer holds the opened plaintext which is not read yet
+	readBuffer []byte
+}
+
+func newCatchupSessionConn(conn net.Conn, psk []byte, isReceiver bool,
+	sendNonce, receiveNonce []byte) (*catchupSessionConn, error) {
+	sealer, err := newCatchupSessionCipher(catchupSessionKey(psk, isReceiver, sendNonce, receiveNonce))
+	if err != nil {
+		return nil, err
+	}
+	opener, err := newCatchupSessionCipher(catchupSessionKey(psk, !isReceiver, sendNonce, receiveNonce))
+	if err != nil {
+		return nil, err
+	}
+	return &catchupSessionConn{Conn: conn, sealer: sealer, opener: opener}, nil
+}
+
+func catchupRecordNonce(aead cipher.AEAD, number uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], number)
+	return nonce
+}
+
+func (conn *catchupSessionConn) Write(b []byte) (int, error) {
+	conn.writeMutex.Lock()
+	defer conn.writeMutex.Unlock()
+	written := 0
+	for written < len(b) {
+		plaintext := b[written:min(len(b), written+catchupRecordSize)]
+		record := make([]byte, 4, 4+len(plaintext)+conn.sealer.Overhead())
+		record = conn.sealer.Seal(record, catchupRecordNonce(conn.sealer, conn.writeNumber), plaintext, nil)
+		binary.BigEndian.PutUint32(record, uint32(len(record)-4))
+		if _, err := conn.Conn.Write(record); err != nil {
+			return written, err
+		}
+		conn.writeNumber++
+		written += len(plaintext)
+	}
+	return written, nil
+}
+
+func (conn *catchupSessionConn) Read(b []byte) (int, error) {
+	if len(conn.readBuffer) == 0 {
+		if err := conn.readRecord(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(b, conn.readBuffer)
+	conn.readBuffer = conn.readBuffer[n:]
+	return n, nil
+}
+
+func (conn *catchupSessionConn) readRecord() error {
+	var header [4]byte
+	if _, err := io.ReadFull(conn.Conn, header[:]); err != nil {
+		return err
+	}
+	length := binary.BigEndian.Uint32(header[:])
+	if length > uint32(catchupRecordSize+conn.opener.Overhead()) {
+		return newCatchupAuthError("catchup record of %d bytes exceeds the limit", length)
+	}
+	record := make([]byte, length)
+	if _, err := io.ReadFull(conn.Conn, record); err != nil {
+		return errors.Wrap(err, "failed to read the catchup record")
+	}
+	plaintext, err := conn.opener.Open(record[:0], catchupRecordNonce(conn.opener, conn.readNumber), record, nil)
+	if err != nil {
+		return newCatchupAuthError("catchup record %d failed the authentication", conn.readNumber)
+	}
+	conn.readNumber++
+	conn.readBuffer = plaintext
+	return nil
+}
+
+// openCatchupStreams sets up the compression and the encryption of the authenticated connection
+func openCatchupStreams(conn net.Conn) (ioextensions.WriteFlushCloser, *gob.Decoder, *gob.Encoder) {
+	crypter := internal.ConfigureCrypter()
+
+	cmpr, decmpr := chooseCompression()
+
+	writer := cmpr.NewWriter(conn)
+	reader, err := decmpr.Decompress(conn)
+	tracelog.ErrorLogger.FatalOnError(err)
+	var decoder *gob.Decoder
+	var encoder *gob.Encoder
+	if crypter != nil {
+		decrypt, err := crypter.Decrypt(reader)
+		tracelog.ErrorLogger.FatalOnError(err)
+		decoder = gob.NewDecoder(decrypt)
+		encrypt, err := crypter.Encrypt(writer)
+		tracelog.ErrorLogger.FatalOnError(err)
+		encoder = gob.NewEncoder(encrypt)
+	} else {
+		decoder = gob.NewDecoder(reader)
+		encoder = gob.NewEncoder(writer)
+	}
+	return writer, decoder, encoder
+}
+
+// rateLimitedConn applies the network rate limit to the outgoing traffic
+type rateLimitedConn struct {
+	net.Conn
+	writer io.Writer
+}
+
+func (conn *rateLimitedConn) Write(b []byte) (int, error) {
+	return conn.writer.Write(b)
+}
@@ -0,0 +1,152 @@
+package postgres
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	conf "github.com/wal-g/wal-g/internal/config"
+)
+
+// connectCatchupPeers secures the loopback connection with the provided pre-shared keys of both sides
+func connectCatchupPeers(t *testing.T, senderPSK, receiverPSK string) (senderErr, receiverErr error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	receiverDone := make(chan error)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			receiverDone <- err
+			return
+		}
+		config := &catchupChannelConfig{psk: []byte(receiverPSK)}
+		securedConn, err := config.secure(t.Context(), conn, true)
+		if err == nil {
+			defer securedConn.Close()
+			message := make([]byte, 5)
+			_, err = io.ReadFull(securedConn, message)
+			if err == nil && string(message) != "hello" {
+				err = io.ErrUnexpectedEOF
+			}
+		}
+		receiverDone <- err
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	config := &catchupChannelConfig{psk: []byte(senderPSK)}
+	securedConn, senderErr := config.secure(t.Context(), conn, false)
+	if senderErr == nil {
+		defer securedConn.Close()
+		_, senderErr = securedConn.Write([]byte("hello"))
+	}
+	return senderErr, <-receiverDone
+}
+
+func TestCatchupChannel_PSKAuthentication(t *testing.T) {
+	senderErr, receiverErr := connectCatchupPeers(t, "secret", "secret")
+	assert.NoError(t, senderErr)
+	assert.NoError(t, receiverErr)
+}
+
+func TestCatchupChannel_PSKMismatch(t *testing.T) {
+	senderErr, receiverErr := connectCatchupPeers(t, "secret", "other secret")
+	assert.IsType(t, catchupAuthError{}, senderErr)
+	assert.IsType(t, catchupAuthError{}, receiverErr)
+}
+
+func TestCatchupChannelConfig_RequiresAllTLSFiles(t *testing.T) {
+	viper.Set(conf.PgCatchupTLSCertFile, "/path/to/cert.pem")
+	defer viper.Set(conf.PgCatchupTLSCertFile, nil)
+
+	_, err := newCatchupChannelConfig(catchupChannelSettings, true, "")
+	assert.Error(t, err)
+}
+
+func TestCatchupChannelConfig_NoAuthentication(t *testing.T) {
+	config, err := newCatchupChannelConfig(catchupChannelSettings, false, "localhost")
+	require.NoError(t, err)
+	assert.Nil(t, config.tlsConfig)
+	assert.Empty(t, config.psk)
+	assert.Equal(t, "no authentication", config.describe())
+}
+
+// bufferConn passes the written bytes to the reader
+type bufferConn struct {
+	net.Conn
+	buffer bytes.Buffer
+}
+
+func (conn *bufferConn) Read(b []byte) (int, error)  { return conn.buffer.Read(b) }
+func (conn *bufferConn) Write(b []byte) (int, error) { return conn.buffer.Write(b) }
+
+func TestCatchupSessionConn_EncryptsAndAuthenticatesRecords(t *testing.T) {
+	psk := []byte("secret")
+	sendNonce := bytes.Repeat([]byte{1}, catchupNonceSize)
+	receiveNonce := bytes.Repeat([]byte{2}, catchupNonceSize)
+	channel := &bufferConn{}
+	sender, err := newCatchupSessionConn(channel, psk, false, sendNonce, receiveNonce)
+	require.NoError(t, err)
+	receiver, err := newCatchupSessionConn(channel, psk, true, sendNonce, receiveNonce)
+	require.NoError(t, err)
+
+	message := bytes.Repeat([]byte("file command "), catchupRecordSize/10)
+	_, err = sender.Write(message)
+	require.NoError(t, err)
+	assert.NotContains(t, channel.buffer.String(), "file command")
+	received := make([]byte, len(message))
+	_, err = io.ReadFull(receiver, received)
+	require.NoError(t, err)
+	assert.Equal(t, message, received)
+
+	// the modified record
+	_, err = sender.Write([]byte("file command"))
+	require.NoError(t, err)
+	channel.buffer.Bytes()[10] ^= 1
+	_, err = receiver.Read(received)
+	assert.IsType(t, catchupAuthError{}, err)
+
+	// the record reflected back to its sender
+	channel.buffer.Reset()
+	_, err = sender.Write([]byte("file command"))
+	require.NoError(t, err)
+	_, err = sender.Read(received)
+	assert.IsType(t, catchupAuthError{}, err)
+}
+
+func TestAcceptCatchupChannel_DropsStalledHandshake(t *testing.T) {
+	viper.Set(conf.PgCatchupPSK, "secret")
+	defer viper.Set(conf.PgCatchupPSK, nil)
+	defer func(timeout time.Duration) { catchupHandshakeTimeout = timeout }(catchupHandshakeTimeout)
+	catchupHandshakeTimeout = 200 * time.Millisecond
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	stalledConn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer stalledConn.Close()
+	senderErr := make(chan error, 1)
+	go func() {
+		// the receiver drops the stalled connection after the handshake timeout
+		_, _ = io.Copy(io.Discard, stalledConn)
+		conn, err := dialCatchupChannel(t.Context(), listener.Addr().String())
+		if err == nil {
+			conn.Close()
+		}
+		senderErr <- err
+	}()
+
+	conn, err := acceptCatchupChannel(t.Context(), listener)
+	require.NoError(t, err)
+	conn.Close()
+	assert.NoError(t, <-senderErr)
+}
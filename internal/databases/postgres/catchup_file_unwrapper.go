@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/pkg/errors"
+	"github.com/wal-g/wal-g/internal/databases/postgres/orioledb"
 	"github.com/wal-g/wal-g/utility"
 )
 
@@ -24,8 +25,21 @@ func clearLocalFile(file *os.File) error {
 	return err
 }
 
+// unwrapOrioledbIncrement applies the orioledb data file increment, its pages may be compressed chunks
+// of a different size, so they are written as is without checking the local pages
+func unwrapOrioledbIncrement(reader io.Reader, file *os.File, fsync bool) (*FileUnwrapResult, error) {
+	err := ApplyFileIncrement(file.Name(), reader, true, fsync)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Interpret: failed to apply orioledb increment to file '%s'", file.Name())
+	}
+	return NewCompletedResult(), nil
+}
+
 func (u *CatchupFileUnwrapper) UnwrapNewFile(reader io.Reader, header *tar.Header,
 	file *os.File, fsync bool) (*FileUnwrapResult, error) {
+	if u.options.isIncremented && orioledb.IsOrioledbDataPath(file.Name()) {
+		return unwrapOrioledbIncrement(reader, file, fsync)
+	}
 	if u.options.isIncremented {
 		targetReadWriterAt, err := NewReadWriterAtFrom(file)
 		if err != nil {
@@ -46,6 +60,9 @@ func (u *CatchupFileUnwrapper) UnwrapNewFile(reader io.Reader, header *tar.Heade
 
 func (u *CatchupFileUnwrapper) UnwrapExistingFile(reader io.Reader, header *tar.Header,
 	file *os.File, fsync bool) (*FileUnwrapResult, error) {
+	if u.options.isIncremented && orioledb.IsOrioledbDataPath(file.Name()) {
+		return unwrapOrioledbIncrement(reader, file, fsync)
+	}
 	if u.options.isIncremented {
 		targetReadWriterAt, err := NewReadWriterAtFrom(file)
 		if err != nil {
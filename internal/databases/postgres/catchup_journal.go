@@ -0,0 +1,168 @@
+package postgres
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// CatchupJournalFilename is the file in the receiver data directory which records the progress of catchup-receive
+const CatchupJournalFilename = "walg_catchup.journal"
+
+// catchupJournalHeader identifies the catchup the journal belongs to, the journal is
+// valid only until the receiver pg_control is replaced at the end of the catchup
+type catchupJournalHeader struct {
+	SystemIdentifier uint64 `json:"system_identifier"`
+	Checkpoint       LSN    `json:"checkpoint"`
+}
+
+type catchupJournalRecord struct {
+	FileName string    `json:"file"`
+	Done     bool      `json:"done,omitempty"`
+	MTime    time.Time `json:"mtime"`
+}
+
+// catchupJournal is an append-only log of the received files. A file is marked as started before it is
+// written and as done with the sender modification time after it is synced, so on restart the completed
+// files are reported to the sender as unchanged and the interrupted ones as missing.
+type catchupJournal struct {
+	path        string
+	file        *os.File
+	completed   map[string]time.Time
+	interrupted map[string]bool
+}
+
+func openCatchupJournal(directory string, control PgControlData) (*catchupJournal, error) {
+	journal := &catchupJournal{
+		path:        path.Join(directory, CatchupJournalFilename),
+		completed:   make(map[string]time.Time),
+		interrupted: make(map[string]bool),
+	}
+	header := catchupJournalHeader{SystemIdentifier: control.SystemIdentifier, Checkpoint: control.Checkpoint}
+	if err := journal.load(header); err != nil {
+		return nil, err
+	}
+	if len(journal.completed) > 0 || len(journal.interrupted) > 0 {
+		tracelog.InfoLogger.Printf("Resuming catchup: %d files are already received, %d files were interrupted",
+			len(journal.completed), len(journal.interrupted))
+	}
+	// rewrite the journal to drop the records of the other catchup or the torn tail of the interrupted one
+	if err := journal.rewrite(header); err != nil {
+		return nil, err
+	}
+	return journal, nil
+}
+
+func (journal *catchupJournal) load(header catchupJournalHeader) error {
+	file, err := os.Open(journal.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to open the catchup journal")
+	}
+	defer utility.LoggedClose(file, "failed to close the catchup journal")
+
+	scanner := bufio.NewScanner(file)
+	var storedHeader catchupJournalHeader
+	if !scanner.Scan() || json.Unmarshal(scanner.Bytes(), &storedHeader) != nil || storedHeader != header {
+		tracelog.InfoLogger.Println("Catchup journal belongs to another catchup, starting from scratch")
+		return nil
+	}
+	for scanner.Scan() {
+		var record catchupJournalRecord
+		if json.Unmarshal(scanner.Bytes(), &record) != nil {
+			// the tail of the journal may be torn by the interruption
+			break
+		}
+		if record.Done {
+			journal.completed[record.FileName] = record.MTime
+			delete(journal.interrupted, record.FileName)
+		} else {
+			journal.interrupted[record.FileName] = true
+			delete(journal.completed, record.FileName)
+		}
+	}
+	return nil
+}
+
+func (journal *catchupJournal) rewrite(header catchupJournalHeader) error {
+	tmpPath := journal.path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return errors.Wrap(err, "failed to create the catchup journal")
+	}
+	encoder := json.NewEncoder(file)
+	err = encoder.Encode(header)
+	for fileName, mTime := range journal.completed {
+		if err != nil {
+			break
+		}
+		err = encoder.Encode(catchupJournalRecord{FileName: fileName, Done: true, MTime: mTime})
+	}
+	for fileName := range journal.interrupted {
+		if err != nil {
+			break
+		}
+		err = encoder.Encode(catchupJournalRecord{FileName: fileName})
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to write the catchup journal")
+	}
+	if err = os.Rename(tmpPath, journal.path); err != nil {
+		return errors.Wrap(err, "failed to write the catchup journal")
+	}
+
+	journal.file, err = os.OpenFile(journal.path, os.O_WRONLY|os.O_APPEND, 0600)
+	return errors.Wrap(err, "failed to open the catchup journal")
+}
+
+// adjustFileList hides the interrupted files from the sender, so they are sent in full,
+// and reports the completed ones with the sender modification time, so they are skipped if unchanged
+func (journal *catchupJournal) adjustFileList(list internal.BackupFileList) {
+	for fileName := range journal.interrupted {
+		delete(list, fileName)
+	}
+	for fileName, mTime := range journal.completed {
+		if description, ok := list[fileName]; ok {
+			description.MTime = mTime
+			list[fileName] = description
+		}
+	}
+}
+
+func (journal *catchupJournal) markStarted(fileName string) error {
+	return journal.append(catchupJournalRecord{FileName: fileName})
+}
+
+func (journal *catchupJournal) markDone(fileName string, mTime time.Time) error {
+	return journal.append(catchupJournalRecord{FileName: fileName, Done: true, MTime: mTime})
+}
+
+func (journal *catchupJournal) append(record catchupJournalRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = journal.file.Write(append(data, '\n'))
+	return errors.Wrap(err, "failed to append to the catchup journal")
+}
+
+// remove deletes the journal once the catchup is finished
+func (journal *catchupJournal) remove() error {
+	utility.LoggedClose(journal.file, "failed to close the catchup journal")
+	return os.Remove(journal.path)
+}
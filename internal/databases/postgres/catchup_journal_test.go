@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+)
+
+func TestCatchupJournal_Resume(t *testing.T) {
+	directory := t.TempDir()
+	control := PgControlData{SystemIdentifier: 42, Checkpoint: 0x1000}
+	mTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	journal, err := openCatchupJournal(directory, control)
+	require.NoError(t, err)
+	require.NoError(t, journal.markStarted("base/1/100"))
+	require.NoError(t, journal.markDone("base/1/100", mTime))
+	require.NoError(t, journal.markStarted("base/1/200"))
+	closeCatchupJournal(t, journal)
+
+	// the interruption may tear the last record
+	file, err := os.OpenFile(path.Join(directory, CatchupJournalFilename), os.O_WRONLY|os.O_APPEND, 0600)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"file":"base/1/3`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	journal, err = openCatchupJournal(directory, control)
+	require.NoError(t, err)
+	defer closeCatchupJournal(t, journal)
+	assert.Equal(t, map[string]time.Time{"base/1/100": mTime}, journal.completed)
+	assert.Equal(t, map[string]bool{"base/1/200": true}, journal.interrupted)
+
+	localMTime := mTime.Add(time.Hour)
+	list := internal.BackupFileList{
+		"base/1/100": {MTime: localMTime},
+		"base/1/200": {MTime: localMTime},
+		"base/1/300": {MTime: localMTime},
+	}
+	journal.adjustFileList(list)
+	assert.Equal(t, internal.BackupFileList{
+		"base/1/100": {MTime: mTime},
+		"base/1/300": {MTime: localMTime},
+	}, list)
+}
+
+func TestCatchupJournal_OtherCatchupIsDiscarded(t *testing.T) {
+	directory := t.TempDir()
+
+	journal, err := openCatchupJournal(directory, PgControlData{SystemIdentifier: 42, Checkpoint: 0x1000})
+	require.NoError(t, err)
+	require.NoError(t, journal.markDone("base/1/100", time.Now()))
+	closeCatchupJournal(t, journal)
+
+	journal, err = openCatchupJournal(directory, PgControlData{SystemIdentifier: 42, Checkpoint: 0x2000})
+	require.NoError(t, err)
+	assert.Empty(t, journal.completed)
+	assert.Empty(t, journal.interrupted)
+
+	require.NoError(t, journal.remove())
+	_, err = os.Stat(path.Join(directory, CatchupJournalFilename))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func closeCatchupJournal(t *testing.T, journal *catchupJournal) {
+	require.NoError(t, journal.file.Close())
+}
@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	defaultCatchupProgressInterval = 10 * time.Second
+	bytesInMiB                     = 1 << 20
+)
+
+// catchupProgress tracks the files processed by catchup-send or catchup-receive and periodically logs
+// the progress. The totals are the sizes of the files to be sent, increments usually take much less.
+type catchupProgress struct {
+	totalFiles       atomic.Int64
+	totalBytes       atomic.Int64
+	doneFiles        atomic.Int64
+	doneBytes        atomic.Int64
+	transferredBytes atomic.Int64
+	startTime        time.Time
+}
+
+func newCatchupProgress() *catchupProgress {
+	return &catchupProgress{startTime: utility.TimeNowCrossPlatformUTC()}
+}
+
+func (progress *catchupProgress) setPlan(files, bytes int64) {
+	progress.totalFiles.Store(files)
+	progress.totalBytes.Store(bytes)
+}
+
+// fileDone accounts the file of the provided size which took transferredBytes of the traffic
+func (progress *catchupProgress) fileDone(size, transferredBytes int64) {
+	progress.doneFiles.Add(1)
+	progress.doneBytes.Add(size)
+	progress.transferredBytes.Add(transferredBytes)
+}
+
+// start logs the progress every WALG_CATCHUP_PROGRESS_INTERVAL until the context is done
+func (progress *catchupProgress) start(ctx context.Context) {
+	interval, err := conf.GetDurationSettingDefault(conf.PgCatchupProgressPeriod, defaultCatchupProgressInterval)
+	if err != nil || interval <= 0 {
+		tracelog.WarningLogger.Printf("Invalid %s, using %v", conf.PgCatchupProgressPeriod, defaultCatchupProgressInterval)
+		interval = defaultCatchupProgressInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				progress.log()
+			}
+		}
+	}()
+}
+
+func (progress *catchupProgress) log() {
+	tracelog.InfoLogger.Printf("Catchup progress: %s", progress.describe(utility.TimeNowCrossPlatformUTC()))
+}
+
+func (progress *catchupProgress) describe(now time.Time) string {
+	elapsed := now.Sub(progress.startTime)
+	doneBytes := progress.doneBytes.Load()
+	totalBytes := progress.totalBytes.Load()
+	transferredBytes := progress.transferredBytes.Load()
+
+	var speed float64
+	if elapsed > 0 {
+		speed = float64(transferredBytes) / bytesInMiB / elapsed.Seconds()
+	}
+	eta := "unknown"
+	if doneBytes > 0 && totalBytes >= doneBytes {
+		remaining := time.Duration(float64(elapsed) * float64(totalBytes-doneBytes) / float64(doneBytes))
+		eta = remaining.Round(time.Second).String()
+	}
+	return fmt.Sprintf("%d/%d files, %.1f/%.1f MiB, %.1f MiB transferred (%.2f MiB/s), elapsed %v, ETA %s",
+		progress.doneFiles.Load(), progress.totalFiles.Load(),
+		float64(doneBytes)/bytesInMiB, float64(totalBytes)/bytesInMiB,
+		float64(transferredBytes)/bytesInMiB, speed, elapsed.Round(time.Second), eta)
+}
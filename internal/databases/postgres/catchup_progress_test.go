@@ -0,0 +1,17 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatchupProgress_Describe(t *testing.T) {
+	progress := newCatchupProgress()
+	progress.setPlan(4, 40*bytesInMiB)
+	progress.fileDone(10*bytesInMiB, 2*bytesInMiB)
+
+	description := progress.describe(progress.startTime.Add(10 * time.Second))
+	assert.Equal(t, "1/4 files, 10.0/40.0 MiB, 2.0 MiB transferred (0.20 MiB/s), elapsed 10s, ETA 30s", description)
+}
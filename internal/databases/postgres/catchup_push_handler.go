@@ -10,20 +10,23 @@ import (
 )
 
 func extendExcludedFiles() {
-	for _, fname := range []string{"pg_hba.conf", "postgresql.conf", "postgresql.auto.conf"} {
+	for _, fname := range []string{"pg_hba.conf", "postgresql.conf", "postgresql.auto.conf",
+		CatchupJournalFilename, CatchupJournalFilename + ".tmp"} {
 		ExcludedFilenames[fname] = utility.Empty{}
 	}
 }
 
-// HandleCatchupPush is invoked to perform a wal-g catchup-push
-func HandleCatchupPush(ctx context.Context, pgDataDirectory string, fromLSN LSN) {
+// HandleCatchupPush is invoked to perform a wal-g catchup-push.
+// fromChkpNum is the orioledb checkpoint number of the replica, it is required to catch up orioledb data files.
+func HandleCatchupPush(ctx context.Context, pgDataDirectory string, fromLSN LSN, fromChkpNum *uint32) {
 	uploader, err := internal.ConfigureUploader(ctx)
 	tracelog.ErrorLogger.FatalOnError(err)
 
 	pgDataDirectory = utility.ResolveSymlink(pgDataDirectory)
 
 	fakePreviousBackupSentinelDto := BackupSentinelDto{
-		BackupStartLSN: &fromLSN,
+		BackupStartLSN:     &fromLSN,
+		BackupStartChkpNum: fromChkpNum,
 	}
 
 	extendExcludedFiles()
@@ -36,8 +39,11 @@ func HandleCatchupPush(ctx context.Context, pgDataDirectory string, fromLSN LSN)
 		false, false, false,
 		RegularComposer, NewCatchupDeltaBackupConfigurator(fakePreviousBackupSentinelDto),
 		userData, false)
-	if orioledb.IsEnabled(pgDataDirectory) {
-		tracelog.InfoLogger.Printf("Catchup incremental backup is not implemented for orioledb. Full backup will be performed.")
+	// the replica files are not known, so every paged file is sent as the increment since the provided position
+	backupArguments.EnableForceIncremental()
+	if orioledb.IsEnabled(pgDataDirectory) && fromChkpNum == nil {
+		tracelog.WarningLogger.Printf("Orioledb checkpoint number of the replica is not provided with --from-chkp-num. " +
+			"Orioledb data files will be sent in full.")
 	}
 
 	backupConfig, err := NewBackupHandler(ctx, backupArguments)
@@ -12,11 +12,13 @@ import (
 	"path/filepath"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/compression"
 	"github.com/wal-g/wal-g/internal/databases/postgres/errors"
+	"github.com/wal-g/wal-g/internal/databases/postgres/orioledb"
 	"github.com/wal-g/wal-g/internal/ioextensions"
 	"github.com/wal-g/wal-g/utility"
 )
@@ -29,7 +31,7 @@ func HandleCatchupSend(ctx context.Context, pgDataDirectory string, destination
 		tracelog.ErrorLogger.Fatal("Our system lacks System Identifier, cannot proceed")
 	}
 	tracelog.ErrorLogger.FatalOnError(err)
-	writer, decoder, encoder := startSendConnection(destination)
+	writer, decoder, encoder := startSendConnection(ctx, destination)
 
 	var control PgControlData
 	err = decoder.Decode(&control)
@@ -56,7 +58,7 @@ func HandleCatchupSend(ctx context.Context, pgDataDirectory string, destination
 			lsn, control.Checkpoint)
 	}
 
-	sendFileCommands(ctx, encoder, pgDataDirectory, fileList, control.Checkpoint)
+	sendFileCommands(ctx, encoder, pgDataDirectory, fileList, control)
 
 	label, offsetMap, _, err := runner.StopBackup(ctx)
 	tracelog.ErrorLogger.FatalOnError(err)
@@ -82,31 +84,10 @@ func HandleCatchupSend(ctx context.Context, pgDataDirectory string, destination
 	tracelog.InfoLogger.Printf("Send done")
 }
 
-func startSendConnection(destination string) (ioextensions.WriteFlushCloser, *gob.Decoder, *gob.Encoder) {
-	dial, err := net.Dial("tcp", destination)
+func startSendConnection(ctx context.Context, destination string) (ioextensions.WriteFlushCloser, *gob.Decoder, *gob.Encoder) {
+	conn, err := dialCatchupChannel(ctx, destination)
 	tracelog.ErrorLogger.FatalOnError(err)
-
-	crypter := internal.ConfigureCrypter()
-
-	cmpr, decmpr := chooseCompression()
-
-	writer := cmpr.NewWriter(dial)
-	reader, err := decmpr.Decompress(dial)
-	tracelog.ErrorLogger.FatalOnError(err)
-	var decoder *gob.Decoder
-	var encoder *gob.Encoder
-	if crypter != nil {
-		decrypt, err := crypter.Decrypt(reader)
-		tracelog.ErrorLogger.FatalOnError(err)
-		decoder = gob.NewDecoder(decrypt)
-		encrypt, err := crypter.Encrypt(writer)
-		tracelog.ErrorLogger.FatalOnError(err)
-		encoder = gob.NewEncoder(encrypt)
-	} else {
-		decoder = gob.NewDecoder(reader)
-		encoder = gob.NewEncoder(writer)
-	}
-	return writer, decoder, encoder
+	return openCatchupStreams(conn)
 }
 
 func chooseCompression() (compression.Compressor, compression.Decompressor) {
@@ -120,8 +101,43 @@ func chooseCompression() (compression.Compressor, compression.Decompressor) {
 	return c, d
 }
 
-func sendFileCommands(ctx context.Context, encoder *gob.Encoder, directory string, list internal.BackupFileList, checkpoint LSN) {
+// catchupFile is the file of the sender data directory which differs from the receiver one
+type catchupFile struct {
+	path      string
+	name      string
+	info      fs.FileInfo
+	wasInBase bool
+}
+
+func sendFileCommands(ctx context.Context, encoder *gob.Encoder, directory string, list internal.BackupFileList,
+	control PgControlData) {
 	extendExcludedFiles()
+	files, seenFiles := collectCatchupFiles(directory, list)
+
+	progress := newCatchupProgress()
+	var plannedBytes int64
+	for _, file := range files {
+		plannedBytes += file.info.Size()
+	}
+	progress.setPlan(int64(len(files)), plannedBytes)
+	err := encoder.Encode(CatchupCommandDto{IsPlan: true, PlannedFiles: uint64(len(files)), PlannedBytes: uint64(plannedBytes)})
+	tracelog.ErrorLogger.FatalOnError(err)
+	tracelog.InfoLogger.Printf("Sending %d files, %d bytes", len(files), plannedBytes)
+
+	progressCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	progress.start(progressCtx)
+	for _, file := range files {
+		sentBytes := sendOneFile(ctx, file.path, file.info, file.wasInBase, control, encoder, file.name)
+		progress.fileDone(file.info.Size(), sentBytes)
+	}
+	progress.log()
+	sendDeletedFiles(encoder, list, seenFiles)
+}
+
+// collectCatchupFiles returns the files changed since the receiver got them and the names of all the sender files
+func collectCatchupFiles(directory string, list internal.BackupFileList) ([]catchupFile, map[string]bool) {
+	var files []catchupFile
 	seenFiles := make(map[string]bool)
 	err := filepath.Walk(directory, func(path string, info fs.FileInfo, err error) error {
 		fullFileName := utility.GetSubdirectoryRelativePath(path, directory)
@@ -158,13 +174,12 @@ func sendFileCommands(ctx context.Context, encoder *gob.Encoder, directory strin
 			wasInBase = true
 		}
 
-		sendOneFile(ctx, path, info, wasInBase, checkpoint, encoder, fullFileName)
-
+		files = append(files, catchupFile{path: path, name: fullFileName, info: info, wasInBase: wasInBase})
 		return nil
 	})
 	tracelog.ErrorLogger.FatalOnError(err)
 	tracelog.DebugLogger.Printf("Filepath walk done")
-	sendDeletedFiles(encoder, list, seenFiles)
+	return files, seenFiles
 }
 
 func sendDeletedFiles(encoder *gob.Encoder, list internal.BackupFileList, seenFiles map[string]bool) {
@@ -187,9 +202,11 @@ func sendDeletedFiles(encoder *gob.Encoder, list internal.BackupFileList, seenFi
 	}
 }
 
-func sendOneFile(ctx context.Context, path string, info fs.FileInfo, wasInBase bool, checkpoint LSN,
-	encoder *gob.Encoder, fullFileName string) {
-	increment := isPagedFile(info, path) && wasInBase
+// sendOneFile sends the file in full or as the increment and returns the number of bytes sent
+func sendOneFile(ctx context.Context, path string, info fs.FileInfo, wasInBase bool, control PgControlData,
+	encoder *gob.Encoder, fullFileName string) int64 {
+	isOrioledbIncrement := control.OrioledbChkpNum != nil && orioledb.IsOrioledbDataFile(info, path)
+	increment := (isPagedFile(info, path) || isOrioledbIncrement) && wasInBase
 	var err error
 
 	var fd io.ReadCloser
@@ -197,17 +214,21 @@ func sendOneFile(ctx context.Context, path string, info fs.FileInfo, wasInBase b
 	if !increment {
 		fd, err = os.Open(path)
 		if os.IsNotExist(err) {
-			return
+			return 0
 		}
 		tracelog.ErrorLogger.FatalOnError(err)
 		size = info.Size()
 	} else {
-		fd, size, err = ReadIncrementalFile(ctx, path, info.Size(), checkpoint, nil)
+		if isOrioledbIncrement {
+			fd, size, err = orioledb.ReadIncrementalFile(ctx, path, info.Size(), *control.OrioledbChkpNum, nil)
+		} else {
+			fd, size, err = ReadIncrementalFile(ctx, path, info.Size(), control.Checkpoint, nil)
+		}
 
-		if _, ok := err.(*errors.InvalidBlockError); ok {
+		if _, ok := err.(errors.InvalidBlockError); ok {
 			fd, err = os.Open(path)
 			if os.IsNotExist(err) {
-				return
+				return 0
 			}
 			tracelog.ErrorLogger.FatalOnError(err)
 			size = info.Size()
@@ -217,10 +238,11 @@ func sendOneFile(ctx context.Context, path string, info fs.FileInfo, wasInBase b
 		}
 	}
 
-	err = encoder.Encode(
-		CatchupCommandDto{FileName: fullFileName, IsFull: !increment, FileSize: uint64(size), IsIncremental: increment})
+	err = encoder.Encode(CatchupCommandDto{FileName: fullFileName, IsFull: !increment, FileSize: uint64(size),
+		IsIncremental: increment, SourceFileSize: uint64(info.Size()), MTime: info.ModTime()})
 	tracelog.ErrorLogger.FatalOnError(err)
 	reader := io.MultiReader(fd, &ioextensions.ZeroReader{})
+	sentBytes := size
 
 	for size != 0 {
 		var bytes = make([]byte, int(min(size, 8192)))
@@ -235,40 +257,26 @@ func sendOneFile(ctx context.Context, path string, info fs.FileInfo, wasInBase b
 	tracelog.ErrorLogger.FatalOnError(err)
 	err = fd.Close()
 	tracelog.ErrorLogger.FatalOnError(err)
+	return sentBytes
 }
 
-func HandleCatchupReceive(pgDataDirectory string, port int) {
+func HandleCatchupReceive(ctx context.Context, pgDataDirectory string, port int) {
 	pgDataDirectory = utility.ResolveSymlink(pgDataDirectory)
 	tracelog.InfoLogger.Printf("Receiving %v on port %v\n", pgDataDirectory, port)
 	listen, err := net.Listen("tcp", fmt.Sprintf(":%v", port))
 	tracelog.ErrorLogger.FatalOnError(err)
-	conn, err := listen.Accept()
+	conn, err := acceptCatchupChannel(ctx, listen)
 	tracelog.ErrorLogger.FatalOnError(err)
 
-	cmpr, decmpr := chooseCompression()
-
-	writer := cmpr.NewWriter(conn)
-	reader, err := decmpr.Decompress(conn)
-	tracelog.ErrorLogger.FatalOnError(err)
-
-	crypter := internal.ConfigureCrypter()
-
-	var decoder *gob.Decoder
-	var encoder *gob.Encoder
-	if crypter != nil {
-		decrypt, err := crypter.Decrypt(reader)
-		tracelog.ErrorLogger.FatalOnError(err)
-		decoder = gob.NewDecoder(decrypt)
-		encrypt, err := crypter.Encrypt(writer)
-		tracelog.ErrorLogger.FatalOnError(err)
-		encoder = gob.NewEncoder(encrypt)
-	} else {
-		decoder = gob.NewDecoder(reader)
-		encoder = gob.NewEncoder(writer)
-	}
-	sendControlAndFileList(pgDataDirectory, encoder)
+	writer, decoder, encoder := openCatchupStreams(conn)
+	journal := sendControlAndFileList(pgDataDirectory, encoder)
 	err = writer.Flush()
 	tracelog.ErrorLogger.FatalOnError(err)
+
+	progress := newCatchupProgress()
+	progressCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	progress.start(progressCtx)
 	for {
 		var cmd CatchupCommandDto
 		err := decoder.Decode(&cmd)
@@ -276,8 +284,11 @@ func HandleCatchupReceive(pgDataDirectory string, port int) {
 		if cmd.IsDone {
 			break
 		}
-		doRcvCommand(cmd, pgDataDirectory, decoder)
+		doRcvCommand(cmd, pgDataDirectory, decoder, journal, progress)
 	}
+	progress.log()
+	err = journal.remove()
+	tracelog.ErrorLogger.FatalOnError(err)
 	tracelog.InfoLogger.Printf("Receive done")
 }
 
@@ -302,7 +313,14 @@ func (d *DecoderReader) Read(bytes []byte) (n int, err error) {
 	return i, err
 }
 
-func doRcvCommand(cmd CatchupCommandDto, directory string, decoder *gob.Decoder) {
+func doRcvCommand(cmd CatchupCommandDto, directory string, decoder *gob.Decoder,
+	journal *catchupJournal, progress *catchupProgress) {
+	if cmd.IsPlan {
+		tracelog.InfoLogger.Printf("Receiving %d files, %d bytes", cmd.PlannedFiles, cmd.PlannedBytes)
+		progress.setPlan(int64(cmd.PlannedFiles), int64(cmd.PlannedBytes))
+		return
+	}
+
 	if cmd.IsBinContents {
 		tracelog.InfoLogger.Printf("Writing file %v", cmd.FileName)
 		err := os.WriteFile(path.Join(directory, cmd.FileName), cmd.BinaryContents, 0666)
@@ -310,32 +328,20 @@ func doRcvCommand(cmd CatchupCommandDto, directory string, decoder *gob.Decoder)
 		return
 	}
 
-	if cmd.IsFull {
-		tracelog.InfoLogger.Printf("Full file %v", cmd.FileName)
-		fd, err := os.Create(path.Join(directory, cmd.FileName))
+	if cmd.IsFull || cmd.IsIncremental {
+		err := journal.markStarted(cmd.FileName)
 		tracelog.ErrorLogger.FatalOnError(err)
-		size := int64(cmd.FileSize)
-		for size != 0 {
-			var bytes []byte
-			err := decoder.Decode(&bytes)
-			tracelog.ErrorLogger.FatalOnError(err)
-			_, err = fd.Write(bytes)
+		if cmd.IsFull {
+			receiveFullFile(cmd, directory, decoder)
+		} else {
+			tracelog.InfoLogger.Printf("Incremental file %v", cmd.FileName)
+			err = ApplyFileIncrement(path.Join(directory, cmd.FileName),
+				&DecoderReader{decoder, nil, int64(cmd.FileSize)}, true, true)
 			tracelog.ErrorLogger.FatalOnError(err)
-			size -= int64(len(bytes))
 		}
-		tracelog.InfoLogger.Printf("Received %v bytes", cmd.FileSize)
-		tracelog.ErrorLogger.FatalOnError(err)
-		err = fd.Close()
-		tracelog.ErrorLogger.FatalOnError(err)
-		return
-	}
-
-	if cmd.IsIncremental {
-		tracelog.InfoLogger.Printf("Incremental file %v", cmd.FileName)
-
-		err := ApplyFileIncrement(path.Join(directory, cmd.FileName),
-			&DecoderReader{decoder, nil, int64(cmd.FileSize)}, true, false)
+		err = journal.markDone(cmd.FileName, cmd.MTime)
 		tracelog.ErrorLogger.FatalOnError(err)
+		progress.fileDone(int64(cmd.SourceFileSize), int64(cmd.FileSize))
 		return
 	}
 	if cmd.IsDelete {
@@ -349,32 +355,70 @@ func doRcvCommand(cmd CatchupCommandDto, directory string, decoder *gob.Decoder)
 	tracelog.ErrorLogger.Fatal("Unknown command")
 }
 
+// receiveFullFile writes the file and syncs it, so it can be marked as done in the journal
+func receiveFullFile(cmd CatchupCommandDto, directory string, decoder *gob.Decoder) {
+	tracelog.InfoLogger.Printf("Full file %v", cmd.FileName)
+	fd, err := os.Create(path.Join(directory, cmd.FileName))
+	tracelog.ErrorLogger.FatalOnError(err)
+	size := int64(cmd.FileSize)
+	for size != 0 {
+		var bytes []byte
+		err := decoder.Decode(&bytes)
+		tracelog.ErrorLogger.FatalOnError(err)
+		_, err = fd.Write(bytes)
+		tracelog.ErrorLogger.FatalOnError(err)
+		size -= int64(len(bytes))
+	}
+	tracelog.InfoLogger.Printf("Received %v bytes", cmd.FileSize)
+	err = fd.Sync()
+	tracelog.ErrorLogger.FatalOnError(err)
+	err = fd.Close()
+	tracelog.ErrorLogger.FatalOnError(err)
+}
+
 type CatchupCommandDto struct {
 	IsDone         bool
 	IsIncremental  bool
 	IsFull         bool
 	IsDelete       bool
 	IsBinContents  bool
+	IsPlan         bool
 	FileSize       uint64
 	FileName       string
 	BinaryContents []byte
 	FilesToDelete  []string
+	// SourceFileSize and MTime describe the sender file, MTime is recorded to the receiver journal
+	SourceFileSize uint64
+	MTime          time.Time
+	// PlannedFiles and PlannedBytes are the totals of the files to be sent
+	PlannedFiles uint64
+	PlannedBytes uint64
 }
 
-func sendControlAndFileList(pgDataDirectory string, encoder *gob.Encoder) {
+func sendControlAndFileList(pgDataDirectory string, encoder *gob.Encoder) *catchupJournal {
 	control, err := ExtractPgControl(pgDataDirectory)
 	tracelog.ErrorLogger.FatalOnError(err)
 	tracelog.InfoLogger.Printf("Our system id %v, need catchup from %v",
 		control.SystemIdentifier, control.Checkpoint)
 	tracelog.ErrorLogger.FatalOnError(err)
+	if orioledb.IsEnabled(pgDataDirectory) {
+		chkpNum := orioledb.GetChkpNum(pgDataDirectory)
+		tracelog.InfoLogger.Printf("Orioledb checkpoint number %d", chkpNum)
+		control.OrioledbChkpNum = &chkpNum
+	}
 	err = encoder.Encode(control)
 	tracelog.ErrorLogger.FatalOnError(err)
+	journal, err := openCatchupJournal(pgDataDirectory, *control)
+	tracelog.ErrorLogger.FatalOnError(err)
 	rcvFileList := receiveFileList(pgDataDirectory)
+	journal.adjustFileList(rcvFileList)
 	err = encoder.Encode(rcvFileList)
 	tracelog.ErrorLogger.FatalOnError(err)
+	return journal
 }
 
 func receiveFileList(directory string) internal.BackupFileList {
+	extendExcludedFiles()
 	var result = make(internal.BackupFileList)
 	err := filepath.Walk(directory, func(path string, info fs.FileInfo, err error) error {
 		if err != nil {
@@ -0,0 +1,321 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/daemon"
+	"github.com/wal-g/wal-g/internal/multistorage"
+	"github.com/wal-g/wal-g/internal/multistorage/policies"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+var daemonMessageNames = map[daemon.SocketMessageType]string{
+	daemon.CheckType:        "check",
+	daemon.WalPushType:      "wal-push",
+	daemon.WalFetchType:     "wal-fetch",
+	daemon.PeerWalFetchType: "peer-wal-fetch",
+	daemon.BackupPushType:   "backup-push",
+	daemon.BackupListType:   "backup-list",
+	daemon.WalShowType:      "wal-show",
+	daemon.StatusType:       "status",
+}
+
+// DaemonMessageStats holds the counters of the handled messages of one type
+type DaemonMessageStats struct {
+	Succeeded    int64     `json:"succeeded"`
+	Failed       int64     `json:"failed"`
+	TotalSeconds float64   `json:"total_seconds"`
+	LastTime     time.Time `json:"last_time"`
+	LastError    string    `json:"last_error,omitempty"`
+}
+
+// DaemonStatus is the response to the status message
+type DaemonStatus struct {
+	Version       string                         `json:"version"`
+	Pid           int                            `json:"pid"`
+	StartTime     time.Time                      `json:"start_time"`
+	UptimeSeconds float64                        `json:"uptime_seconds"`
+	Messages      map[string]*DaemonMessageStats `json:"messages"`
+}
+
+type daemonStats struct {
+	mutex     sync.Mutex
+	version   string
+	startTime time.Time
+	messages  map[string]*DaemonMessageStats
+}
+
+var currentDaemonStats = &daemonStats{
+	startTime: time.Now(),
+	messages:  make(map[string]*DaemonMessageStats),
+}
+
+func (stats *daemonStats) record(messageType daemon.SocketMessageType, duration time.Duration, err error) {
+	name, ok := daemonMessageNames[messageType]
+	if !ok {
+		name = string(messageType)
+	}
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	messageStats, ok := stats.messages[name]
+	if !ok {
+		messageStats = &DaemonMessageStats{}
+		stats.messages[name] = messageStats
+	}
+	if err != nil {
+		messageStats.Failed++
+		messageStats.LastError = err.Error()
+	} else {
+		messageStats.Succeeded++
+	}
+	messageStats.TotalSeconds += duration.Seconds()
+	messageStats.LastTime = utility.TimeNowCrossPlatformUTC()
+}
+
+func (stats *daemonStats) status() DaemonStatus {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	messages := make(map[string]*DaemonMessageStats, len(stats.messages))
+	for name, messageStats := range stats.messages {
+		statsCopy := *messageStats
+		messages[name] = &statsCopy
+	}
+	return DaemonStatus{
+		Version:       stats.version,
+		Pid:           os.Getpid(),
+		StartTime:     stats.startTime.UTC(),
+		UptimeSeconds: time.Since(stats.startTime).Seconds(),
+		Messages:      messages,
+	}
+}
+
+// parseDaemonFlags parses the boolean flags sent as message arguments
+func parseDaemonFlags(args []string, allowed ...string) (map[string]bool, error) {
+	flags := make(map[string]bool, len(args))
+	for _, arg := range args {
+		if !slices.Contains(allowed, arg) {
+			return nil, fmt.Errorf("unsupported argument %q, expected one of %v", arg, allowed)
+		}
+		flags[arg] = true
+	}
+	return flags, nil
+}
+
+func writeDaemonResponse(fd net.Conn, response daemon.SocketMessageType) error {
+	_, err := fd.Write(response.ToBytes())
+	if err != nil {
+		return newSocketWriteFailedError(err)
+	}
+	return nil
+}
+
+// BackupPushMessageHandler runs backup-push with the message arguments on the daemon storage
+// and streams its log back as output
+type BackupPushMessageHandler struct {
+	fd      net.Conn
+	storage storage.Storage
+}
+
+// backupPushMutex allows only one backup-push of the daemon at a time
+var backupPushMutex sync.Mutex
+
+func (h *BackupPushMessageHandler) Handle(ctx context.Context, messageBody []byte) error {
+	args, err := daemon.BytesToArgs(messageBody)
+	if err != nil {
+		return err
+	}
+	options, err := parseBackupPushArgs(args)
+	if err != nil {
+		return err
+	}
+	if !backupPushMutex.TryLock() {
+		return errors.New("backup-push is already running")
+	}
+	defer backupPushMutex.Unlock()
+
+	tracelog.InfoLogger.Printf("starting backup-push with arguments %v\n", args)
+	output := &detachableWriter{writer: daemon.NewOutputWriter(h.fd)}
+	restoreLogOutput := teeLogOutput(output)
+	err = h.pushBackup(ctx, options)
+	restoreLogOutput()
+	if output.detached {
+		tracelog.WarningLogger.Println("client has gone, backup-push output is dropped")
+	}
+	if err != nil {
+		return fmt.Errorf("backup-push failed: %w", err)
+	}
+	return writeDaemonResponse(h.fd, daemon.OkType)
+}
+
+func (h *BackupPushMessageHandler) pushBackup(ctx context.Context, options BackupPushOptions) error {
+	internal.ConfigureLimiters()
+	uploader, err := ConfigureBackupPushUploader(ctx, h.storage.RootFolder(), options.TargetStorage)
+	if err != nil {
+		return err
+	}
+	arguments, err := NewBackupPushArguments(uploader, options)
+	if err != nil {
+		return err
+	}
+	backupHandler, err := NewBackupHandler(ctx, arguments)
+	if err != nil {
+		return err
+	}
+	return backupHandler.PushBackup(ctx)
+}
+
+// parseBackupPushArgs parses the message arguments the same way as the backup-push command does
+func parseBackupPushArgs(args []string) (BackupPushOptions, error) {
+	var options BackupPushOptions
+	flags := pflag.NewFlagSet("backup-push", pflag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	AddBackupPushFlags(flags, &options)
+	flags.StringVar(&options.TargetStorage, "target-storage", viper.GetString(conf.PgTargetStorage), "")
+	err := flags.Parse(args)
+	if err != nil {
+		return BackupPushOptions{}, err
+	}
+	if flags.NArg() > 1 {
+		return BackupPushOptions{}, fmt.Errorf("accepts at most 1 arg, received %d", flags.NArg())
+	}
+	options.DataDirectory = flags.Arg(0)
+	return options, nil
+}
+
+// teeLogOutput copies the log to the writer until the returned function is called.
+// The log of the messages handled concurrently is copied too.
+func teeLogOutput(writer io.Writer) func() {
+	loggers := []*log.Logger{tracelog.InfoLogger.Logger, tracelog.WarningLogger.Logger,
+		tracelog.ErrorLogger.Logger, tracelog.DebugLogger.Logger}
+	outputs := make([]io.Writer, len(loggers))
+	for i, logger := range loggers {
+		outputs[i] = logger.Writer()
+		if outputs[i] != io.Discard {
+			logger.SetOutput(io.MultiWriter(outputs[i], writer))
+		}
+	}
+	return func() {
+		for i, logger := range loggers {
+			logger.SetOutput(outputs[i])
+		}
+	}
+}
+
+// detachableWriter drops the output after the client has gone, so that the backup is not interrupted
+type detachableWriter struct {
+	mutex    sync.Mutex
+	writer   io.Writer
+	detached bool
+}
+
+func (w *detachableWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.detached {
+		return len(p), nil
+	}
+	_, err := w.writer.Write(p)
+	if err != nil {
+		w.detached = true
+	}
+	return len(p), nil
+}
+
+type BackupListMessageHandler struct {
+	fd      net.Conn
+	storage storage.Storage
+}
+
+func (h *BackupListMessageHandler) Handle(ctx context.Context, messageBody []byte) error {
+	args, err := daemon.BytesToArgs(messageBody)
+	if err != nil {
+		return err
+	}
+	flags, err := parseDaemonFlags(args, "--pretty", "--json", "--detail")
+	if err != nil {
+		return err
+	}
+	rootFolder := multistorage.SetPolicies(h.storage.RootFolder(), policies.UniteAllStorages)
+	rootFolder, err = multistorage.UseAllAliveStorages(ctx, rootFolder)
+	if err != nil {
+		return err
+	}
+
+	backupsFolder := rootFolder.GetSubFolder(utility.BaseBackupPath)
+	output := daemon.NewOutputWriter(h.fd)
+	if flags["--detail"] {
+		err = WriteDetailedBackupList(ctx, backupsFolder, output, flags["--pretty"], flags["--json"])
+	} else {
+		err = internal.WriteDefaultBackupList(ctx, backupsFolder, output, flags["--pretty"], flags["--json"])
+	}
+	if err != nil {
+		return err
+	}
+	return writeDaemonResponse(h.fd, daemon.OkType)
+}
+
+type WalShowMessageHandler struct {
+	fd      net.Conn
+	storage storage.Storage
+}
+
+func (h *WalShowMessageHandler) Handle(ctx context.Context, messageBody []byte) error {
+	args, err := daemon.BytesToArgs(messageBody)
+	if err != nil {
+		return err
+	}
+	flags, err := parseDaemonFlags(args, "--detailed-json", "--without-backups")
+	if err != nil {
+		return err
+	}
+	rootFolder := multistorage.SetPolicies(h.storage.RootFolder(), policies.UniteAllStorages)
+	rootFolder, err = multistorage.UseAllAliveStorages(ctx, rootFolder)
+	if err != nil {
+		return err
+	}
+
+	outputType := TableOutput
+	if flags["--detailed-json"] {
+		outputType = JSONOutput
+	}
+	showBackups := !flags["--without-backups"]
+	outputWriter := NewWalShowOutputWriter(outputType, daemon.NewOutputWriter(h.fd), showBackups)
+	err = WriteWalShow(ctx, rootFolder, showBackups, outputWriter)
+	if err != nil {
+		return err
+	}
+	return writeDaemonResponse(h.fd, daemon.OkType)
+}
+
+// StatusMessageHandler sends the daemon status and the counters of the handled messages in JSON
+type StatusMessageHandler struct {
+	fd net.Conn
+}
+
+func (h *StatusMessageHandler) Handle(_ context.Context, _ []byte) error {
+	status, err := json.MarshalIndent(currentDaemonStats.status(), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = daemon.NewOutputWriter(h.fd).Write(append(status, '\n'))
+	if err != nil {
+		return newSocketWriteFailedError(err)
+	}
+	return writeDaemonResponse(h.fd, daemon.OkType)
+}
@@ -41,6 +41,10 @@ func (err SocketWriteFailedError) Error() string {
 
 type DaemonOptions struct {
 	SocketPath string
+	// PeerAddress is the TCP address to serve the files of pg_wal to the peers from, disabled if empty
+	PeerAddress string
+	// Version is reported in response to the status message
+	Version string
 }
 
 type SocketMessageHandler interface {
@@ -63,6 +67,7 @@ func (h *CheckMessageHandler) Handle(_ context.Context, _ []byte) error {
 type ArchiveMessageHandler struct {
 	fd       net.Conn
 	uploader *WalUploader
+	pusher   *DaemonWalPusher
 }
 
 func (h *ArchiveMessageHandler) Handle(ctx context.Context, messageBody []byte) error {
@@ -81,7 +86,7 @@ func (h *ArchiveMessageHandler) Handle(ctx context.Context, messageBody []byte)
 	}
 	ctx, cancel := context.WithTimeout(ctx, pushTimeout)
 	defer cancel()
-	err = HandleWALPush(ctx, h.uploader, fullPath)
+	err = h.pusher.Push(ctx, h.uploader, fullPath)
 	if err != nil {
 		return fmt.Errorf("file archiving failed: %w", err)
 	}
@@ -133,6 +138,69 @@ func (h *WalFetchMessageHandler) Handle(ctx context.Context, messageBody []byte)
 	return nil
 }
 
+// PeerWalFetchMessageHandler sends a file from pg_wal to a peer, e.g. to a replica recovering after failover.
+// Only complete WAL segments are sent.
+type PeerWalFetchMessageHandler struct {
+	fd                  net.Conn
+	getCurrentSegmentNo func(ctx context.Context) (WalSegmentNo, error)
+}
+
+func newPeerWalFetchMessageHandler(fd net.Conn) *PeerWalFetchMessageHandler {
+	return &PeerWalFetchMessageHandler{fd, queryCurrentWalSegmentNo}
+}
+
+func (h *PeerWalFetchMessageHandler) Handle(ctx context.Context, messageBody []byte) error {
+	fileName := string(messageBody)
+	isSegment := isWalFilename(fileName)
+	if !isSegment && !(strings.HasSuffix(fileName, ".history") && timelineHistoryFileRegexp.MatchString(fileName)) {
+		return fmt.Errorf("not a WAL file name: %q", fileName)
+	}
+	walDir, err := getFullPath("pg_wal")
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(path.Join(walDir, fileName))
+	if os.IsNotExist(err) {
+		tracelog.DebugLogger.Printf("peer requested %s, but it is not in pg_wal\n", fileName)
+		return h.replyNonExistence()
+	}
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(file, "")
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	// timeline history files are written by Postgres atomically
+	if isSegment {
+		err = checkPeerWalSegment(ctx, walDir, fileName, file, h.getCurrentSegmentNo)
+		if err != nil {
+			tracelog.WarningLogger.Printf("peer requested %s, but it can't be sent: %v\n", fileName, err)
+			return h.replyNonExistence()
+		}
+	}
+
+	_, err = h.fd.Write(binary.BigEndian.AppendUint64(daemon.OkType.ToBytes(), uint64(stat.Size())))
+	if err != nil {
+		return newSocketWriteFailedError(err)
+	}
+	_, err = io.CopyN(h.fd, file, stat.Size())
+	if err != nil {
+		return newSocketWriteFailedError(err)
+	}
+	tracelog.DebugLogger.Printf("sent %s to peer %s\n", fileName, h.fd.RemoteAddr())
+	return nil
+}
+
+func (h *PeerWalFetchMessageHandler) replyNonExistence() error {
+	_, err := h.fd.Write(daemon.ArchiveNonExistenceType.ToBytes())
+	if err != nil {
+		return newSocketWriteFailedError(err)
+	}
+	return nil
+}
+
 func NewMessageHandler(
 	ctx context.Context,
 	messageType daemon.SocketMessageType,
@@ -147,7 +215,11 @@ func NewMessageHandler(
 		if err != nil {
 			return nil, err
 		}
-		return &ArchiveMessageHandler{c, walUploader}, nil
+		pusher, err := getDaemonWalPusher()
+		if err != nil {
+			return nil, err
+		}
+		return &ArchiveMessageHandler{c, walUploader, pusher}, nil
 	case daemon.WalFetchType:
 		folderReader, err := internal.PrepareMultiStorageFolderReader(ctx, storage.RootFolder(), "")
 		if err != nil {
@@ -155,6 +227,14 @@ func NewMessageHandler(
 		}
 
 		return &WalFetchMessageHandler{c, folderReader}, nil
+	case daemon.BackupPushType:
+		return &BackupPushMessageHandler{c, storage}, nil
+	case daemon.BackupListType:
+		return &BackupListMessageHandler{c, storage}, nil
+	case daemon.WalShowType:
+		return &WalShowMessageHandler{c, storage}, nil
+	case daemon.StatusType:
+		return &StatusMessageHandler{c}, nil
 	default:
 		return nil, nil
 	}
@@ -219,6 +299,8 @@ func HandleDaemon(ctx context.Context, options DaemonOptions) {
 		tracelog.ErrorLogger.Fatal("Error on listening socket:", err)
 	}
 
+	currentDaemonStats.version = options.Version
+
 	sdNotifyTicker := time.NewTicker(30 * time.Second)
 	defer sdNotifyTicker.Stop()
 	go SendSdNotify(sdNotifyTicker.C)
@@ -232,9 +314,90 @@ func HandleDaemon(ctx context.Context, options DaemonOptions) {
 	}
 	defer utility.LoggedClose(multiSt, "close multi-storage")
 
+	if options.PeerAddress != "" {
+		peerListener, err := net.Listen("tcp", options.PeerAddress)
+		if err != nil {
+			tracelog.ErrorLogger.Fatal("Error on listening peer address:", err)
+		}
+		tracelog.InfoLogger.Printf("Serving pg_wal to peers on %s", options.PeerAddress)
+		err = ServePeers(ctx, peerListener)
+		if err != nil {
+			tracelog.ErrorLogger.Fatal("Failed to serve peers:", err)
+		}
+	}
+
 	serve(ctx, l, multiSt)
 }
 
+// ServePeers starts accepting the wal-fetch requests of the peers in background. Only the files in pg_wal are served,
+// never the storage. Connections are secured with mutual TLS and pre-shared key as catchup-receive does.
+func ServePeers(ctx context.Context, l net.Listener) error {
+	config, err := newCatchupChannelConfig(peerChannelSettings, true, "")
+	if err != nil {
+		return err
+	}
+	if config.tlsConfig == nil && len(config.psk) == 0 {
+		tracelog.WarningLogger.Printf("Peer connections are not authenticated, set %s or %s to secure them",
+			conf.PgPeerPSK, conf.PgPeerTLSCertFile)
+	}
+	go func() {
+		<-ctx.Done()
+		utility.LoggedClose(l, "close peer listener")
+	}()
+
+	go func() {
+		for {
+			fd, err := l.Accept()
+			if err != nil {
+				if ctx.Err() == nil {
+					tracelog.ErrorLogger.Printf("Failed to accept peer connection: %v", err)
+				}
+				return
+			}
+			go func() {
+				// the handshake is done here, so a stalled peer doesn't block accepting the others
+				securedConn, err := config.secure(ctx, fd, true)
+				if err != nil {
+					tracelog.WarningLogger.Printf("Rejected peer connection from %v: %v", fd.RemoteAddr(), err)
+					return
+				}
+				processPeerConnection(ctx, securedConn)
+			}()
+		}
+	}()
+	return nil
+}
+
+func processPeerConnection(ctx context.Context, c net.Conn) {
+	defer utility.LoggedClose(c, fmt.Sprintf("Failed to close connection with %s \n", c.RemoteAddr()))
+	messageReader := NewMessageReader(c)
+	for {
+		messageType, messageBody, err := messageReader.Next()
+		if err != nil {
+			failAndLogError(c, fmt.Errorf("read message from %s, err: %v", c.RemoteAddr(), err))
+			return
+		}
+		var messageHandler SocketMessageHandler
+		switch messageType {
+		case daemon.CheckType:
+			messageHandler = &CheckMessageHandler{c}
+		case daemon.PeerWalFetchType:
+			messageHandler = newPeerWalFetchMessageHandler(c)
+		default:
+			failAndLogError(c, fmt.Errorf("unexpected peer message type: %s", string(messageType)))
+			return
+		}
+		err = messageHandler.Handle(ctx, messageBody)
+		if err != nil {
+			failAndLogError(c, err)
+			return
+		}
+		if messageType == daemon.PeerWalFetchType {
+			return
+		}
+	}
+}
+
 func serve(ctx context.Context, l net.Listener, multiSt *multistorage.Storage) {
 	go func() {
 		<-ctx.Done()
@@ -264,7 +427,9 @@ func ProcessConnection(ctx context.Context, c net.Conn, multiSt *multistorage.St
 			failAndLogError(c, fmt.Errorf("read message from %s, err: %v", c.RemoteAddr(), err))
 			return
 		}
+		startTime := time.Now()
 		err = handleMessage(ctx, messageType, messageBody, c, multiSt)
+		currentDaemonStats.record(messageType, time.Since(startTime), err)
 		if err != nil {
 			failAndLogError(c, err)
 			return
@@ -277,6 +442,9 @@ func ProcessConnection(ctx context.Context, c net.Conn, multiSt *multistorage.St
 			tracelog.DebugLogger.Printf("successfully fetched: %s\n", string(messageBody))
 			return
 		}
+		if messageType != daemon.CheckType {
+			return
+		}
 	}
 }
 
@@ -2,12 +2,21 @@ package postgres
 
 import (
 	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
 	"net"
+	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/daemon"
+	"github.com/wal-g/wal-g/internal/walparser"
 )
 
 func TestServe_ReturnsOnContextCancel(t *testing.T) {
@@ -30,3 +39,93 @@ func TestServe_ReturnsOnContextCancel(t *testing.T) {
 		t.Fatal("serve did not return after context cancel")
 	}
 }
+
+// makeTestSwitchSegment makes a WAL segment with the only WAL switch record
+func makeTestSwitchSegment(segmentNo WalSegmentNo) []byte {
+	segment := make([]byte, WalSegmentSize)
+	binary.LittleEndian.PutUint16(segment[0:], 0xD10D)
+	binary.LittleEndian.PutUint16(segment[2:], walparser.XlpLongHeader)
+	binary.LittleEndian.PutUint32(segment[4:], 1)
+	binary.LittleEndian.PutUint64(segment[8:], uint64(segmentNo.firstLsn()))
+	binary.LittleEndian.PutUint32(segment[32:], uint32(WalSegmentSize))
+	binary.LittleEndian.PutUint32(segment[36:], uint32(walparser.WalPageSize))
+
+	record := segment[40 : 40+walparser.XLogRecordHeaderSize]
+	binary.LittleEndian.PutUint32(record[0:], walparser.XLogRecordHeaderSize)
+	record[16] = walparser.XLogSwitch
+	record[17] = walparser.RmXlogID
+	crc := crc32.Checksum(record[:20], crc32.MakeTable(crc32.Castagnoli))
+	binary.LittleEndian.PutUint32(record[20:], crc)
+	return segment
+}
+
+func TestPeerWalFetchSendsOnlyFinishedSegments(t *testing.T) {
+	pgData := t.TempDir()
+	walDir := filepath.Join(pgData, "pg_wal")
+	require.NoError(t, os.MkdirAll(filepath.Join(walDir, archiveStatusDir), 0700))
+	viper.Set(conf.PgDataSetting, pgData)
+	writeSegment := func(name string, contents []byte, status string) {
+		require.NoError(t, os.WriteFile(filepath.Join(walDir, name), contents, 0600))
+		if status != "" {
+			require.NoError(t, os.WriteFile(filepath.Join(walDir, archiveStatusDir, name+status), nil, 0600))
+		}
+	}
+	fetch := func(name string) (daemon.SocketMessageType, []byte) {
+		server, client := net.Pipe()
+		handler := &PeerWalFetchMessageHandler{server, func(context.Context) (WalSegmentNo, error) { return 7, nil }}
+		errs := make(chan error, 1)
+		go func() {
+			errs <- handler.Handle(t.Context(), []byte(name))
+			_ = server.Close()
+		}()
+		response, err := io.ReadAll(client)
+		require.NoError(t, err)
+		require.NoError(t, <-errs)
+		require.NotEmpty(t, response)
+		if daemon.SocketMessageType(response[0]) != daemon.OkType {
+			return daemon.SocketMessageType(response[0]), nil
+		}
+		return daemon.OkType, response[9:]
+	}
+
+	ready := makeTestSwitchSegment(4)
+	writeSegment("000000010000000000000004", ready, ".ready")
+	writeSegment("000000010000000000000005", makeTestSwitchSegment(5), ".done")
+	older := makeTestSwitchSegment(6)
+	writeSegment("000000010000000000000006", older, "")
+	// the segment being written, preallocated and recycled ones
+	writeSegment("000000010000000000000007", makeTestSwitchSegment(7), "")
+	writeSegment("000000010000000000000008", make([]byte, WalSegmentSize), "")
+	writeSegment("000000010000000000000009", makeTestSwitchSegment(2), "")
+	// marked, but corrupted
+	writeSegment("00000001000000000000000A", make([]byte, WalSegmentSize), ".ready")
+	writeSegment("00000001000000000000000B", makeTestSwitchSegment(3), ".done")
+
+	response, data := fetch("000000010000000000000004")
+	assert.Equal(t, daemon.OkType, response)
+	assert.Equal(t, ready, data)
+	response, _ = fetch("000000010000000000000005")
+	assert.Equal(t, daemon.OkType, response)
+	response, data = fetch("000000010000000000000006")
+	assert.Equal(t, daemon.OkType, response)
+	assert.Equal(t, older, data)
+	for _, name := range []string{"000000010000000000000007", "000000010000000000000008", "000000010000000000000009",
+		"00000001000000000000000A", "00000001000000000000000B"} {
+		response, _ = fetch(name)
+		assert.Equal(t, daemon.ArchiveNonExistenceType, response, name)
+	}
+}
+
+func TestParseBackupPushArgs(t *testing.T) {
+	options, err := parseBackupPushArgs([]string{"--full", "-p", "--target-storage", "failover", "/var/lib/pgdata"})
+	require.NoError(t, err)
+	assert.True(t, options.FullBackup)
+	assert.True(t, options.Permanent)
+	assert.Equal(t, "failover", options.TargetStorage)
+	assert.Equal(t, "/var/lib/pgdata", options.DataDirectory)
+
+	_, err = parseBackupPushArgs([]string{"--unknown"})
+	assert.Error(t, err)
+	_, err = parseBackupPushArgs([]string{"/var/lib/pgdata", "/var/lib/other"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,227 @@
+package postgres
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/statistics"
+	"golang.org/x/sync/semaphore"
+)
+
+// DaemonWalPusher serves the wal-push requests of the daemon. The segments are uploaded on a worker pool
+// shared by all the connections, and the segments following the requested one which are already .ready
+// in archive_status are uploaded ahead, so that archive_command finds them uploaded by the time it asks.
+// A request is acknowledged only after its own segment is uploaded.
+type DaemonWalPusher struct {
+	mutex sync.Mutex
+	// tasks holds the uploads in progress and the uploaded ahead segments which are not requested yet
+	tasks map[string]*walPushTask
+	// ahead counts the tasks which are not requested yet
+	ahead int
+
+	// workers limits the number of concurrent uploads, usually defined by WALG_UPLOAD_CONCURRENCY
+	workers *semaphore.Weighted
+	// aheadWorkers keeps one of the workers for the requested segments
+	aheadWorkers    *semaphore.Weighted
+	numAheadWorkers int
+	// maxAhead limits the number of segments uploaded ahead of the requests, usually defined by TOTAL_BG_UPLOADED_LIMIT
+	maxAhead      int
+	uploadTimeout time.Duration
+}
+
+type walPushTask struct {
+	done      chan struct{}
+	err       error
+	requested bool
+	uploaded  bool
+}
+
+type walPushSettings struct {
+	preventWalOverwrite bool
+	readyRename         bool
+}
+
+func NewDaemonWalPusher(concurrency, maxAhead int, uploadTimeout time.Duration) *DaemonWalPusher {
+	concurrency = max(concurrency, 1)
+	return &DaemonWalPusher{
+		tasks:           make(map[string]*walPushTask),
+		workers:         semaphore.NewWeighted(int64(concurrency)),
+		aheadWorkers:    semaphore.NewWeighted(int64(concurrency - 1)),
+		numAheadWorkers: concurrency - 1,
+		maxAhead:        maxAhead,
+		uploadTimeout:   uploadTimeout,
+	}
+}
+
+var getDaemonWalPusher = sync.OnceValues(func() (*DaemonWalPusher, error) {
+	concurrency, err := conf.GetMaxUploadConcurrency()
+	if err != nil {
+		return nil, err
+	}
+	uploadTimeout, err := conf.GetDurationSetting(conf.PgDaemonWALUploadTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return NewDaemonWalPusher(concurrency, viper.GetInt(conf.TotalBgUploadedLimit), uploadTimeout), nil
+})
+
+// Push uploads the WAL file unless it has been uploaded ahead and starts uploading ahead the following segments.
+// It returns after the WAL file is uploaded, the upload goes on if ctx is canceled and a retry waits for it.
+func (p *DaemonWalPusher) Push(ctx context.Context, uploader *WalUploader, walFilePath string) error {
+	if uploader.getUseWalDelta() {
+		// delta files are flushed after the background uploads are drained, so the uploads can't outlive a request
+		return HandleWALPush(ctx, uploader, walFilePath)
+	}
+	if uploader.ArchiveStatusManager.IsWalAlreadyUploaded(walFilePath) {
+		if err := uploader.ArchiveStatusManager.UnmarkWalFile(walFilePath); err != nil {
+			tracelog.ErrorLogger.Printf("unmark wal-g status for %s file failed due following error %+v", walFilePath, err)
+		}
+		return uploadLocalWalMetadata(ctx, walFilePath, uploader)
+	}
+
+	settings := walPushSettings{
+		// .history files must not be overwritten, see https://github.com/wal-g/wal-g/issues/420
+		preventWalOverwrite: viper.GetBool(conf.PreventWalOverwriteSetting) || strings.HasSuffix(walFilePath, ".history"),
+		readyRename:         viper.GetBool(conf.PgReadyRename),
+	}
+	// the uploads are shared between the requests, so they must not be canceled with the request
+	uploadCtx := context.WithoutCancel(ctx)
+	walName := filepath.Base(walFilePath)
+
+	p.mutex.Lock()
+	task, ok := p.tasks[walName]
+	if !ok {
+		task = p.startTask(uploadCtx, uploader, walFilePath, settings, false)
+	} else if !task.requested {
+		tracelog.DebugLogger.Printf("%s is uploaded ahead\n", walName)
+		task.requested = true
+		p.ahead--
+	}
+	p.evictSkipped(walName)
+	p.uploadAhead(uploadCtx, uploader, walFilePath, settings)
+	p.mutex.Unlock()
+
+	select {
+	case <-task.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	p.mutex.Lock()
+	if p.tasks[walName] == task {
+		delete(p.tasks, walName)
+	}
+	p.mutex.Unlock()
+	if task.err != nil {
+		return task.err
+	}
+	return uploadLocalWalMetadata(ctx, walFilePath, uploader.Uploader)
+}
+
+// evictSkipped forgets the segments uploaded ahead which are older than the requested WAL file,
+// they have been archived in some other way and are not requested anymore. Must be called with the mutex held
+func (p *DaemonWalPusher) evictSkipped(walName string) {
+	for name, task := range p.tasks {
+		if task.uploaded && !task.requested && name < walName {
+			delete(p.tasks, name)
+			p.ahead--
+		}
+	}
+}
+
+// uploadAhead starts uploading the segments following the WAL file which are .ready in archive_status,
+// must be called with the mutex held
+func (p *DaemonWalPusher) uploadAhead(ctx context.Context, uploader *WalUploader, walFilePath string, settings walPushSettings) {
+	if p.numAheadWorkers < 1 {
+		return
+	}
+	dir := filepath.Dir(walFilePath)
+	walName := filepath.Base(walFilePath)
+	for p.ahead < p.maxAhead {
+		var err error
+		walName, err = GetNextWalFilename(walName)
+		if err != nil {
+			return
+		}
+		if _, ok := p.tasks[walName]; ok {
+			continue
+		}
+		_, err = os.Stat(filepath.Join(dir, archiveStatusDir, walName+readySuffix))
+		if err != nil {
+			return
+		}
+		if uploader.ArchiveStatusManager.IsWalAlreadyUploaded(walName) {
+			continue
+		}
+		p.startTask(ctx, uploader.clone(), filepath.Join(dir, walName), settings, true)
+	}
+}
+
+// startTask must be called with the mutex held
+func (p *DaemonWalPusher) startTask(ctx context.Context, uploader *WalUploader, walFilePath string,
+	settings walPushSettings, ahead bool) *walPushTask {
+	walName := filepath.Base(walFilePath)
+	task := &walPushTask{done: make(chan struct{}), requested: !ahead}
+	p.tasks[walName] = task
+	if ahead {
+		p.ahead++
+	}
+
+	go func() {
+		renamed, err := p.upload(ctx, uploader, walFilePath, settings, ahead)
+		if err != nil && ahead {
+			tracelog.WarningLogger.Printf("Failed to upload ahead %s: %v\n", walName, err)
+		}
+		p.mutex.Lock()
+		task.err = err
+		task.uploaded = err == nil
+		// a failed upload is retried by the next request, a renamed .ready file is not requested at all
+		if (err != nil || renamed) && p.tasks[walName] == task {
+			delete(p.tasks, walName)
+			if !task.requested {
+				p.ahead--
+			}
+		}
+		p.mutex.Unlock()
+		close(task.done)
+	}()
+	return task
+}
+
+// upload uploads the WAL file on one of the workers and reports whether its .ready file was renamed to .done
+func (p *DaemonWalPusher) upload(ctx context.Context, uploader *WalUploader, walFilePath string,
+	settings walPushSettings, ahead bool) (bool, error) {
+	if ahead {
+		if err := p.aheadWorkers.Acquire(ctx, 1); err != nil {
+			return false, err
+		}
+		defer p.aheadWorkers.Release(1)
+	}
+	if err := p.workers.Acquire(ctx, 1); err != nil {
+		return false, err
+	}
+	defer p.workers.Release(1)
+
+	ctx, cancel := context.WithTimeout(ctx, p.uploadTimeout)
+	defer cancel()
+	uploadStart := time.Now()
+	err := uploadWALFile(ctx, uploader, walFilePath, settings.preventWalOverwrite)
+	if err != nil {
+		return false, err
+	}
+	statistics.WriteS3UploadTimeMetric(time.Since(uploadStart))
+
+	if !ahead || !settings.readyRename {
+		return false, nil
+	}
+	err = uploader.PGArchiveStatusManager.RenameReady(filepath.Base(walFilePath))
+	// error here is not a fatal thing, just a bit more work for the next wal-push
+	tracelog.ErrorLogger.PrintOnError(err)
+	return err == nil, nil
+}
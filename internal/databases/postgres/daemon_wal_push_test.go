@@ -0,0 +1,109 @@
+package postgres_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/asm"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/testtools"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func TestDaemonWalPusherUploadsAhead(t *testing.T) {
+	viper.Set(conf.UploadWalMetadata, "NOMETADATA")
+	walDir := filepath.Join(t.TempDir(), "pg_wal")
+	require.NoError(t, os.MkdirAll(filepath.Join(walDir, "archive_status"), 0700))
+	segments := []string{"000000010000000000000001", "000000010000000000000002",
+		"000000010000000000000003", "000000010000000000000004", "000000010000000000000006"}
+	for _, name := range segments {
+		require.NoError(t, os.WriteFile(filepath.Join(walDir, name), []byte(name), 0600))
+		require.NoError(t, os.WriteFile(filepath.Join(walDir, "archive_status", name+".ready"), nil, 0600))
+	}
+
+	walFolder := testtools.MakeDefaultInMemoryStorageFolder().GetSubFolder(utility.WalPath)
+	uploader := postgres.NewWalUploader(internal.NewRegularUploader(&testtools.MockCompressor{}, walFolder), nil)
+	uploader.ArchiveStatusManager = asm.NewFakeASM()
+	pusher := postgres.NewDaemonWalPusher(4, 2, time.Minute)
+
+	require.NoError(t, pusher.Push(t.Context(), uploader, filepath.Join(walDir, segments[0])))
+	exists, err := walFolder.Exists(t.Context(), segments[0]+".mock")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// at most two segments are uploaded ahead, the gap after the fourth one stops upload-ahead
+	assert.Eventually(t, func() bool {
+		exists, err := walFolder.Exists(t.Context(), segments[2]+".mock")
+		return err == nil && exists
+	}, 5*time.Second, 10*time.Millisecond)
+	exists, err = walFolder.Exists(t.Context(), segments[3]+".mock")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	for _, name := range segments[1:4] {
+		require.NoError(t, pusher.Push(t.Context(), uploader, filepath.Join(walDir, name)))
+		exists, err = walFolder.Exists(t.Context(), name+".mock")
+		require.NoError(t, err)
+		assert.True(t, exists)
+	}
+	exists, err = walFolder.Exists(t.Context(), segments[4]+".mock")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestDaemonWalPusherRetriesFailedUpload(t *testing.T) {
+	viper.Set(conf.UploadWalMetadata, "NOMETADATA")
+	walDir := filepath.Join(t.TempDir(), "pg_wal")
+	require.NoError(t, os.MkdirAll(filepath.Join(walDir, "archive_status"), 0700))
+	walFilePath := filepath.Join(walDir, "000000010000000000000001")
+
+	walFolder := testtools.MakeDefaultInMemoryStorageFolder().GetSubFolder(utility.WalPath)
+	uploader := postgres.NewWalUploader(internal.NewRegularUploader(&testtools.MockCompressor{}, walFolder), nil)
+	uploader.ArchiveStatusManager = asm.NewFakeASM()
+	pusher := postgres.NewDaemonWalPusher(1, 0, time.Minute)
+
+	assert.Error(t, pusher.Push(t.Context(), uploader, walFilePath))
+
+	require.NoError(t, os.WriteFile(walFilePath, []byte("segment"), 0600))
+	require.NoError(t, pusher.Push(t.Context(), uploader, walFilePath))
+	exists, err := walFolder.Exists(t.Context(), "000000010000000000000001.mock")
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestDaemonWalPusherEvictsSkippedSegments(t *testing.T) {
+	viper.Set(conf.UploadWalMetadata, "NOMETADATA")
+	walDir := filepath.Join(t.TempDir(), "pg_wal")
+	require.NoError(t, os.MkdirAll(filepath.Join(walDir, "archive_status"), 0700))
+	segments := []string{"000000010000000000000001", "000000010000000000000002",
+		"000000010000000000000003", "000000010000000000000004"}
+	for _, name := range segments {
+		require.NoError(t, os.WriteFile(filepath.Join(walDir, name), []byte(name), 0600))
+		require.NoError(t, os.WriteFile(filepath.Join(walDir, "archive_status", name+".ready"), nil, 0600))
+	}
+
+	walFolder := testtools.MakeDefaultInMemoryStorageFolder().GetSubFolder(utility.WalPath)
+	uploader := postgres.NewWalUploader(internal.NewRegularUploader(&testtools.MockCompressor{}, walFolder), nil)
+	uploader.ArchiveStatusManager = asm.NewFakeASM()
+	pusher := postgres.NewDaemonWalPusher(2, 1, time.Minute)
+
+	require.NoError(t, pusher.Push(t.Context(), uploader, filepath.Join(walDir, segments[0])))
+	assert.Eventually(t, func() bool {
+		exists, err := walFolder.Exists(t.Context(), segments[1]+".mock")
+		return err == nil && exists
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the second segment is never requested, it must not hold the upload-ahead limit
+	require.NoError(t, pusher.Push(t.Context(), uploader, filepath.Join(walDir, segments[2])))
+	assert.Eventually(t, func() bool {
+		exists, err := walFolder.Exists(t.Context(), segments[3]+".mock")
+		return err == nil && exists
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
 	"github.com/wal-g/wal-g/internal/multistorage"
 	"github.com/wal-g/wal-g/pkg/storages/storage"
 	"github.com/wal-g/wal-g/utility"
@@ -15,6 +16,11 @@ import (
 
 type DeleteHandler struct {
 	internal.DeleteHandler
+	backups          []internal.BackupObject
+	less             func(object1, object2 storage.Object) bool
+	permanentBackups map[PermanentObject]bool
+	// pitrWindow is the period before now which must stay recoverable to any point in time
+	pitrWindow time.Duration
 }
 
 const (
@@ -49,15 +55,25 @@ func NewDeleteHandler(ctx context.Context, folder storage.Folder, permanentBacku
 		return nil, err
 	}
 
-	deleteHandler :=
-		&DeleteHandler{
-			*internal.NewDeleteHandler(
-				folder,
-				postgresBackups,
-				lessFunc,
-				internal.IsPermanentFunc(
-					makePermanentFunc(permanentBackups, permanentWals))),
-		}
+	pitrWindow, err := conf.GetDurationSettingDefault(conf.PgDeletePITRWindow, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	deleteHandler := &DeleteHandler{
+		backups:          postgresBackups,
+		less:             lessFunc,
+		permanentBackups: permanentBackups,
+		pitrWindow:       pitrWindow,
+	}
+	deleteHandler.DeleteHandler = *internal.NewDeleteHandler(
+		folder,
+		postgresBackups,
+		lessFunc,
+		internal.IsPermanentFunc(
+			makePermanentFunc(permanentBackups, permanentWals)),
+		internal.AdjustTargetFunc(deleteHandler.applyPITRWindow),
+		internal.DeleteBeforeTargetFunc(deleteHandler.DeleteBeforeTarget))
 
 	return deleteHandler, nil
 }
@@ -226,6 +242,97 @@ func getIncrementInfo(ctx context.Context, folder storage.Folder, object storage
 	return *sentinel.IncrementFullName, *sentinel.IncrementFrom, false, nil
 }
 
+// applyPITRWindow moves the target back to the full backup of the newest backup taken before the PITR window,
+// so the cluster can still be recovered to any point in time within the window after the deletion
+func (dh *DeleteHandler) applyPITRWindow(target internal.BackupObject) (internal.BackupObject, error) {
+	if target == nil || dh.pitrWindow == 0 {
+		return target, nil
+	}
+
+	windowStart := utility.TimeNowCrossPlatformUTC().Add(-dh.pitrWindow)
+	var windowBackup internal.BackupObject
+	for _, backup := range dh.backups {
+		if backup.GetBackupTime().After(windowStart) {
+			continue
+		}
+		if windowBackup == nil || dh.less(windowBackup, backup) {
+			windowBackup = backup
+		}
+	}
+	if windowBackup == nil {
+		tracelog.InfoLogger.Printf("No backup was taken before the PITR window start %s\n", windowStart.Format(time.RFC3339))
+		return nil, nil
+	}
+
+	windowTarget, err := dh.FindTargetBeforeName(windowBackup.GetBackupName(), internal.FindFullDeleteModifier)
+	if err != nil {
+		return nil, err
+	}
+	if dh.less(windowTarget, target) {
+		tracelog.InfoLogger.Printf("Backup %s is kept to cover the PITR window since %s\n",
+			windowTarget.GetBackupName(), windowStart.Format(time.RFC3339))
+		return windowTarget, nil
+	}
+	return target, nil
+}
+
+func (dh *DeleteHandler) DeleteBeforeTarget(ctx context.Context, target internal.BackupObject, confirmed bool) error {
+	objFilter := func(storage.Object) bool { return true }
+	folderFilter := func(string) bool { return true }
+
+	return dh.DeleteBeforeTargetWhere(ctx, target, confirmed, objFilter, folderFilter)
+}
+
+// DeleteBeforeTargetWhere deletes the objects older than the target except the WAL still needed by the retained backups.
+// These are the WAL of the retained backup timelines and of the timelines branched off them after the backup start,
+// which may be older than the target when backups are ordered by the start time.
+func (dh *DeleteHandler) DeleteBeforeTargetWhere(
+	ctx context.Context,
+	target internal.BackupObject,
+	confirmed bool,
+	objSelector func(object storage.Object) bool,
+	folderFilter func(name string) bool,
+) error {
+	retainedBackups := make([]internal.BackupObject, 0, len(dh.backups))
+	for _, backup := range dh.backups {
+		permanentBackup := PermanentObject{Name: backup.GetBackupName(), StorageName: backup.GetStorage()}
+		if !dh.less(backup, target) || dh.permanentBackups[permanentBackup] {
+			retainedBackups = append(retainedBackups, backup)
+		}
+	}
+	retention, err := newWalRetention(ctx, dh.Folder.GetSubFolder(utility.WalPath), getBackupStarts(retainedBackups))
+	if err != nil {
+		return err
+	}
+
+	isNeededWal := walObjectFilter(retention.isNeededObject)
+	return dh.DeleteHandler.DeleteBeforeTargetWhere(ctx, target, confirmed, func(object storage.Object) bool {
+		return objSelector(object) && !isNeededWal(object)
+	}, folderFilter)
+}
+
+// HandleDeleteGarbageTimelines deletes WAL files and .history files of the dead timelines, i.e. the branches
+// abandoned after promotions which can't be reached from any backup and which the cluster doesn't run on
+func (dh *DeleteHandler) HandleDeleteGarbageTimelines(ctx context.Context, confirm bool, deleteWithoutBackups bool) error {
+	tracelog.InfoLogger.Printf("Timelines mode selected. Will remove only WAL files of the dead timelines.")
+	if len(dh.backups) == 0 && !deleteWithoutBackups {
+		tracelog.InfoLogger.Println("Couldn't find any backups in storage. Not doing anything.")
+		return nil
+	}
+
+	retention, err := newWalRetention(ctx, dh.Folder.GetSubFolder(utility.WalPath), getBackupStarts(dh.backups))
+	if err != nil {
+		return err
+	}
+
+	isDeadBranchWal := walObjectFilter(retention.isDeadBranchObject)
+	windowStart := utility.TimeNowCrossPlatformUTC().Add(-dh.pitrWindow)
+	folderFilter := func(string) bool { return true }
+	return dh.DeleteWhere(ctx, confirm, func(object storage.Object) bool {
+		return isDeadBranchWal(object) && !object.GetLastModified().After(windowStart)
+	}, folderFilter)
+}
+
 // HandleDeleteGarbage delete outdated WAL archives and leftover backup files
 func (dh *DeleteHandler) HandleDeleteGarbage(ctx context.Context, args []string, confirm bool, deleteWithoutBackups bool) error {
 	predicate := ExtractDeleteGarbagePredicate(args)
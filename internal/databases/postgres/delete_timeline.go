@@ -0,0 +1,174 @@
+package postgres
+
+import (
+	"context"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// walRetention decides which WAL objects are still needed by the backups kept in storage.
+// The backup needs the WAL of its own timeline starting from the backup start segment
+// and the WAL of every timeline branched off it after the backup has been started.
+type walRetention struct {
+	backupStarts []WalSegmentDescription
+	// histories holds the .history records of each timeline found in the WAL folder
+	histories map[uint32][]*TimelineHistoryRecord
+	// protectedTimelines are the latest timeline and its ancestors, i.e. the branch the cluster is running on
+	protectedTimelines map[uint32]bool
+}
+
+func newWalRetention(ctx context.Context, walFolder storage.Folder, backupStarts []WalSegmentDescription) (*walRetention, error) {
+	filenames, err := getFolderFilenames(ctx, walFolder)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the WAL folder filenames")
+	}
+
+	timelines := make(map[uint32]bool)
+	for segment := range getSegmentsFromFiles(filenames) {
+		timelines[segment.Timeline] = true
+	}
+	for _, filename := range filenames {
+		if timeline, ok := parseHistoryFileTimeline(filename); ok {
+			timelines[timeline] = true
+		}
+	}
+
+	retention := &walRetention{
+		backupStarts:       backupStarts,
+		histories:          make(map[uint32][]*TimelineHistoryRecord, len(timelines)),
+		protectedTimelines: make(map[uint32]bool),
+	}
+	var latestTimeline uint32
+	for timeline := range timelines {
+		historyRecords, err := GetTimeLineHistoryRecords(ctx, timeline, walFolder)
+		if err != nil {
+			if _, ok := err.(HistoryFileNotFoundError); !ok {
+				return nil, errors.Wrap(err, "error while loading .history file")
+			}
+		}
+		retention.histories[timeline] = historyRecords
+		latestTimeline = max(latestTimeline, timeline)
+	}
+
+	if len(timelines) > 0 {
+		retention.protectedTimelines[latestTimeline] = true
+		for _, record := range retention.histories[latestTimeline] {
+			retention.protectedTimelines[record.timeline] = true
+		}
+	}
+	return retention, nil
+}
+
+// isReachable reports whether the timeline can be recovered to from the backup started at the provided segment
+func (retention *walRetention) isReachable(backupStart WalSegmentDescription, timeline uint32) bool {
+	if backupStart.Timeline == timeline {
+		return true
+	}
+	for _, record := range retention.histories[timeline] {
+		if record.timeline == backupStart.Timeline {
+			// the timeline is branched off the backup timeline, check if the backup was started before the switch
+			return NewWalSegmentNo(record.lsn) >= backupStart.Number
+		}
+	}
+	return false
+}
+
+// isNeededSegment reports whether any retained backup needs the WAL segments up to lastNo on the timeline
+func (retention *walRetention) isNeededSegment(timeline uint32, lastNo WalSegmentNo) bool {
+	for _, backupStart := range retention.backupStarts {
+		if lastNo >= backupStart.Number && retention.isReachable(backupStart, timeline) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDeadTimeline reports whether the timeline is an abandoned branch no retained backup can reach
+func (retention *walRetention) isDeadTimeline(timeline uint32) bool {
+	if retention.protectedTimelines[timeline] {
+		return false
+	}
+	for _, backupStart := range retention.backupStarts {
+		if retention.isReachable(backupStart, timeline) {
+			return false
+		}
+	}
+	return true
+}
+
+// isNeededObject reports whether the WAL folder object must be kept, objectName is relative to the WAL folder
+func (retention *walRetention) isNeededObject(objectName string) bool {
+	timeline, lastNo, ok := parseWalObjectRange(objectName)
+	if !ok {
+		// .history files and everything we can't reason about are kept
+		return true
+	}
+	return retention.isNeededSegment(timeline, lastNo)
+}
+
+// isDeadBranchObject reports whether the WAL folder object belongs to a dead timeline
+func (retention *walRetention) isDeadBranchObject(objectName string) bool {
+	if timeline, ok := parseHistoryFileTimeline(objectName); ok {
+		return retention.isDeadTimeline(timeline)
+	}
+	timeline, _, ok := parseWalObjectRange(objectName)
+	return ok && retention.isDeadTimeline(timeline)
+}
+
+// parseWalObjectRange returns the timeline and the last segment number stored in the WAL segment or bundle object
+func parseWalObjectRange(objectName string) (uint32, WalSegmentNo, bool) {
+	if strings.HasPrefix(objectName, WalBundlesFolder+"/") {
+		name := path.Base(objectName)
+		if baseName, found := strings.CutSuffix(name, walBundleIndexSuffix); found {
+			name = baseName + walBundleDataSuffix
+		}
+		bundle, ok := ParseWalBundleName(name)
+		return bundle.Timeline, bundle.LastNo, ok
+	}
+	if _, ok := parseHistoryFileTimeline(objectName); ok {
+		return 0, 0, false
+	}
+	timeline, segmentNo, ok := TryFetchTimelineAndLogSegNo(path.Base(objectName))
+	return timeline, WalSegmentNo(segmentNo), ok
+}
+
+func parseHistoryFileTimeline(filename string) (uint32, bool) {
+	matchResult := timelineHistoryFileRegexp.FindStringSubmatch(path.Base(filename))
+	if matchResult == nil {
+		return 0, false
+	}
+	timeline, err := strconv.ParseUint(matchResult[1], hexadecimal, sizeofInt32bits)
+	if err != nil {
+		return 0, false
+	}
+	return uint32(timeline), true
+}
+
+// getBackupStarts returns the first WAL segment of each backup
+func getBackupStarts(backups []internal.BackupObject) []WalSegmentDescription {
+	backupStarts := make([]WalSegmentDescription, 0, len(backups))
+	for _, backup := range backups {
+		backupStart, err := NewWalSegmentDescription(utility.StripWalFileName(backup.GetBackupName()))
+		if err != nil {
+			tracelog.WarningLogger.Printf("Failed to parse the start WAL segment of backup %s: %v, ignoring...\n",
+				backup.GetBackupName(), err)
+			continue
+		}
+		backupStarts = append(backupStarts, backupStart)
+	}
+	return backupStarts
+}
+
+func walObjectFilter(isWalObjectMatching func(walObjectName string) bool) func(storage.Object) bool {
+	return func(object storage.Object) bool {
+		walObjectName, found := strings.CutPrefix(object.GetName(), utility.WalPath)
+		return found && isWalObjectMatching(walObjectName)
+	}
+}
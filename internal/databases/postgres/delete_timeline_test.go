@@ -0,0 +1,158 @@
+package postgres_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/testtools"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	oldBackupName      = "base_000000010000000000000002"
+	promotedBackupName = "base_000000020000000000000006"
+	oldPrimaryBackup   = "base_000000010000000000000004"
+)
+
+// createTimelineBranchesFolder sets up the storage where timeline 2 was promoted from timeline 1 at segment 5,
+// timeline 3 is the abandoned branch off timeline 1 at segment 3 and timeline 4 is the latest one branched off timeline 2.
+// The old primary kept running on timeline 1 and the latest backup was taken there.
+func createTimelineBranchesFolder(t *testing.T, withOldBackup bool) storage.Folder {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	now := utility.TimeNowCrossPlatformUTC()
+	backups := map[string]time.Time{
+		promotedBackupName: now.Add(-48 * time.Hour),
+		oldPrimaryBackup:   now.Add(-24 * time.Hour),
+	}
+	if withOldBackup {
+		backups[oldBackupName] = now.Add(-72 * time.Hour)
+	}
+	backupsFolder := folder.GetSubFolder(utility.BaseBackupPath)
+	for backupName, startTime := range backups {
+		require.NoError(t, backupsFolder.PutObject(t.Context(), backupName+utility.SentinelSuffix, strings.NewReader("{}")))
+		metadata := fmt.Sprintf(`{"start_time":"%s"}`, startTime.Format(time.RFC3339Nano))
+		require.NoError(t, backupsFolder.PutObject(t.Context(), backupName+"/"+utility.MetadataFileName, strings.NewReader(metadata)))
+	}
+
+	walFolder := folder.GetSubFolder(utility.WalPath)
+	segments := map[uint32][2]uint64{1: {1, 10}, 2: {5, 8}, 3: {3, 4}, 4: {8, 9}}
+	for timeline, segmentRange := range segments {
+		for segmentNo := segmentRange[0]; segmentNo <= segmentRange[1]; segmentNo++ {
+			require.NoError(t, walFolder.PutObject(t.Context(), walSegmentName(timeline, segmentNo)+"."+lz4.FileExtension,
+				strings.NewReader("segment")))
+		}
+	}
+	histories := map[uint32]string{
+		2: "1\t0/5000000\tno recovery target specified\n",
+		3: "1\t0/3000000\tno recovery target specified\n",
+		4: "1\t0/5000000\tno recovery target specified\n2\t0/8000000\tno recovery target specified\n",
+	}
+	for timeline, contents := range histories {
+		name, data, err := newTimelineHistoryFile(contents, timeline)
+		require.NoError(t, err)
+		require.NoError(t, walFolder.PutObject(t.Context(), name, data))
+	}
+	return folder
+}
+
+func walSegmentName(timeline uint32, segmentNo uint64) string {
+	return fmt.Sprintf("%08X%08X%08X", timeline, 0, segmentNo)
+}
+
+func assertWalSegmentsExist(t *testing.T, folder storage.Folder, timeline uint32, firstNo, lastNo uint64, expected bool) {
+	for segmentNo := firstNo; segmentNo <= lastNo; segmentNo++ {
+		name := walSegmentName(timeline, segmentNo) + "." + lz4.FileExtension
+		exists, err := folder.GetSubFolder(utility.WalPath).Exists(t.Context(), name)
+		require.NoError(t, err)
+		assert.Equal(t, expected, exists, "unexpected existence of %s", name)
+	}
+}
+
+func assertBackupExists(t *testing.T, folder storage.Folder, backupName string, expected bool) {
+	exists, err := folder.GetSubFolder(utility.BaseBackupPath).Exists(t.Context(), backupName+utility.SentinelSuffix)
+	require.NoError(t, err)
+	assert.Equal(t, expected, exists, "unexpected existence of %s", backupName)
+}
+
+func TestDeleteBeforeTarget_KeepsWalOfRetainedTimelines(t *testing.T) {
+	folder := createTimelineBranchesFolder(t, true)
+	deleteHandler, err := postgres.NewDeleteHandler(t.Context(), folder,
+		map[postgres.PermanentObject]bool{}, map[postgres.PermanentObject]bool{}, true)
+	require.NoError(t, err)
+
+	target, err := deleteHandler.FindTargetRetain(2, internal.NoDeleteModifier)
+	require.NoError(t, err)
+	require.Equal(t, promotedBackupName, target.GetBackupName())
+	require.NoError(t, deleteHandler.DeleteBeforeTarget(t.Context(), target, true))
+
+	assertBackupExists(t, folder, oldBackupName, false)
+	assertBackupExists(t, folder, promotedBackupName, true)
+	assertBackupExists(t, folder, oldPrimaryBackup, true)
+	// the backup on timeline 1 is newer than the target and still needs the segments since 4,
+	// including the timeline 2 branched off after it
+	assertWalSegmentsExist(t, folder, 1, 1, 3, false)
+	assertWalSegmentsExist(t, folder, 1, 4, 10, true)
+	assertWalSegmentsExist(t, folder, 2, 5, 8, true)
+	assertWalSegmentsExist(t, folder, 3, 3, 4, false)
+	assertWalSegmentsExist(t, folder, 4, 8, 9, true)
+	for _, historyFile := range []string{"00000002.history.lz4", "00000003.history.lz4", "00000004.history.lz4"} {
+		exists, err := folder.GetSubFolder(utility.WalPath).Exists(t.Context(), historyFile)
+		require.NoError(t, err)
+		assert.True(t, exists, historyFile)
+	}
+}
+
+func TestHandleDeleteGarbageTimelines(t *testing.T) {
+	folder := createTimelineBranchesFolder(t, false)
+	deleteHandler, err := postgres.NewDeleteHandler(t.Context(), folder,
+		map[postgres.PermanentObject]bool{}, map[postgres.PermanentObject]bool{}, true)
+	require.NoError(t, err)
+
+	require.NoError(t, deleteHandler.HandleDeleteGarbageTimelines(t.Context(), true, false))
+
+	// only the timeline 3 is branched off before any backup and is not on the latest timeline path
+	assertWalSegmentsExist(t, folder, 1, 1, 10, true)
+	assertWalSegmentsExist(t, folder, 2, 5, 8, true)
+	assertWalSegmentsExist(t, folder, 3, 3, 4, false)
+	assertWalSegmentsExist(t, folder, 4, 8, 9, true)
+	for historyFile, expected := range map[string]bool{
+		"00000002.history.lz4": true,
+		"00000003.history.lz4": false,
+		"00000004.history.lz4": true,
+	} {
+		exists, err := folder.GetSubFolder(utility.WalPath).Exists(t.Context(), historyFile)
+		require.NoError(t, err)
+		assert.Equal(t, expected, exists, historyFile)
+	}
+	assertBackupExists(t, folder, promotedBackupName, true)
+	assertBackupExists(t, folder, oldPrimaryBackup, true)
+}
+
+func TestHandleDeleteRetain_KeepsPITRWindow(t *testing.T) {
+	viper.Set(conf.PgDeletePITRWindow, "60h")
+	defer viper.Set(conf.PgDeletePITRWindow, nil)
+
+	folder := createTimelineBranchesFolder(t, true)
+	deleteHandler, err := postgres.NewDeleteHandler(t.Context(), folder,
+		map[postgres.PermanentObject]bool{}, map[postgres.PermanentObject]bool{}, true)
+	require.NoError(t, err)
+
+	deleteHandler.HandleDeleteRetain(t.Context(), []string{"1"}, true)
+
+	// the oldest backup is the only one taken before the window start, so it is kept with its WAL
+	assertBackupExists(t, folder, oldBackupName, true)
+	assertBackupExists(t, folder, promotedBackupName, true)
+	assertWalSegmentsExist(t, folder, 1, 1, 1, false)
+	assertWalSegmentsExist(t, folder, 1, 2, 10, true)
+	assertWalSegmentsExist(t, folder, 3, 3, 4, true)
+}
@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	"path"
 	"strings"
 
 	"github.com/wal-g/tracelog"
@@ -70,6 +71,9 @@ func GetPermanentBackupsAndWals(ctx context.Context, folder storage.Folder) (map
 }
 
 func IsPermanent(objectName, storageName string, permanentBackups, permanentWals map[PermanentObject]bool) bool {
+	if strings.HasPrefix(objectName, utility.WalPath+WalBundlesFolder+"/") {
+		return isPermanentWalBundle(objectName, storageName, permanentWals)
+	}
 	if strings.HasPrefix(objectName, utility.WalPath) && len(objectName) >= len(utility.WalPath)+24 {
 		wal := PermanentObject{
 			Name:        objectName[len(utility.WalPath) : len(utility.WalPath)+24],
@@ -87,3 +91,21 @@ func IsPermanent(objectName, storageName string, permanentBackups, permanentWals
 	// should not reach here, default to false
 	return false
 }
+
+// isPermanentWalBundle reports whether the bundle data or index object holds a permanent WAL segment
+func isPermanentWalBundle(objectName, storageName string, permanentWals map[PermanentObject]bool) bool {
+	name := path.Base(objectName)
+	if baseName, found := strings.CutSuffix(name, walBundleIndexSuffix); found {
+		name = baseName + walBundleDataSuffix
+	}
+	bundle, ok := ParseWalBundleName(name)
+	if !ok {
+		return false
+	}
+	for _, segmentName := range bundle.SegmentNames() {
+		if permanentWals[PermanentObject{Name: segmentName, StorageName: storageName}] {
+			return true
+		}
+	}
+	return false
+}
@@ -42,7 +42,9 @@ func (c RegularDeltaBackupConfigurator) Configure(
 
 	previousPgBackup := ToPgBackup(previousBackup)
 	prevBackupSentinelDto, err := previousPgBackup.GetSentinel(ctx)
-	tracelog.ErrorLogger.FatalOnError(err)
+	if err != nil {
+		return PrevBackupInfo{}, 0, err
+	}
 
 	if prevBackupSentinelDto.IncrementCount != nil {
 		incrementCount = *prevBackupSentinelDto.IncrementCount + 1
@@ -9,6 +9,7 @@ import (
 
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres/orioledb"
 )
 
 const (
@@ -76,6 +77,9 @@ func (desc RestoreDesc) FilterFilesToUnwrap(filesToUnwrap map[string]bool) {
 }
 
 func TryGetOidPair(file string) (bool, uint32, uint32) {
+	if dbID, relnode, ok := orioledb.ParseDataPath(file); ok {
+		return true, dbID, relnode
+	}
 	//nolint:staticcheck
 	if !(strings.HasPrefix(file, defaultTbspPrefix) || strings.HasPrefix(file, customTbspPrefix)) {
 		return false, 0, 0
@@ -105,3 +105,53 @@ func TestRestoreDesc_NoSystemDatabaseSkip(t *testing.T) {
 	restoreDesc.Add(20000, 30000, 30000)
 	assert.Equal(t, false, restoreDesc.IsSkipped(10000, 40000))
 }
+
+func TestTryGetOidPair_OrioledbDataFile(t *testing.T) {
+	isDB, dbID, tableID := postgres.TryGetOidPair("/orioledb_data/1234/5678")
+	assert.Equal(t, true, isDB)
+	assert.Equal(t, uint32(1234), dbID)
+	assert.Equal(t, uint32(5678), tableID)
+}
+
+func TestTryGetOidPair_OrioledbMapFile(t *testing.T) {
+	isDB, dbID, tableID := postgres.TryGetOidPair("/orioledb_data/1234/5678-12.map")
+	assert.Equal(t, true, isDB)
+	assert.Equal(t, uint32(1234), dbID)
+	assert.Equal(t, uint32(5678), tableID)
+}
+
+func TestTryGetOidPair_OrioledbControlFile(t *testing.T) {
+	isDB, dbID, tableID := postgres.TryGetOidPair("/orioledb_data/12.xid")
+	assert.Equal(t, false, isDB)
+	assert.Equal(t, uint32(0), dbID)
+	assert.Equal(t, uint32(0), tableID)
+}
+
+func TestRegexpRestoreDescMaker_FiltersOrioledbFiles(t *testing.T) {
+	names := make(postgres.DatabasesByNames)
+	names["db"] = *postgres.NewDatabaseObjectsInfo(20000)
+	names["db"].Tables["public.heap"] = postgres.TableInfo{Oid: 30000, Relfilenode: 30000}
+	names["db"].Tables["public.oriole"] = postgres.TableInfo{Oid: 30001, Relfilenode: 30001,
+		OrioledbRelnodes: []uint32{30002, 30003}}
+	names["db"].Tables["public.other_oriole"] = postgres.TableInfo{Oid: 30004, Relfilenode: 30004,
+		OrioledbRelnodes: []uint32{30005}}
+
+	restoreDesc, err := postgres.RegexpRestoreDescMaker{}.Make([]string{"db/oriole"}, names)
+	assert.NoError(t, err)
+
+	filesToUnwrap := map[string]bool{
+		"/base/20000/30000":                true,
+		"/orioledb_data/20000/30002":       true,
+		"/orioledb_data/20000/30003-5.map": true,
+		"/orioledb_data/20000/30005":       true,
+		"/orioledb_data/1/5":               true,
+		"/orioledb_data/5.xid":             true,
+	}
+	restoreDesc.FilterFilesToUnwrap(filesToUnwrap)
+	assert.Equal(t, map[string]bool{
+		"/orioledb_data/20000/30002":       true,
+		"/orioledb_data/20000/30003-5.map": true,
+		"/orioledb_data/1/5":               true,
+		"/orioledb_data/5.xid":             true,
+	}, filesToUnwrap)
+}
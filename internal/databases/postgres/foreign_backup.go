@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/printlist"
+)
+
+// foreignBackupTimeLayouts are the time formats of Barman and pg_probackup, the fractional seconds are optional
+var foreignBackupTimeLayouts = []string{"2006-01-02 15:04:05-07:00", "2006-01-02 15:04:05-07", "2006-01-02 15:04:05"}
+
+// ParseForeignBackupTime parses the time written by the other backup tools (Barman, pg_probackup),
+// the empty value is the zero time
+func ParseForeignBackupTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	var err error
+	for _, layout := range foreignBackupTimeLayouts {
+		var parsed time.Time
+		if parsed, err = time.Parse(layout, value); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// GetForeignBackupTimes makes the backup list entries of the backups made by the other backup tools
+func GetForeignBackupTimes[T any](backups []T, newBackupTime func(T) internal.BackupTime) []internal.BackupTime {
+	backupTimes := make([]internal.BackupTime, 0, len(backups))
+	for _, backup := range backups {
+		backupTimes = append(backupTimes, newBackupTime(backup))
+	}
+	return backupTimes
+}
+
+// HandleForeignBackupList prints the backups made by the other backup tools sorted by time
+func HandleForeignBackupList[T any](backups []T, newBackupTime func(T) internal.BackupTime,
+	newBackupDetails func(T) printlist.Entity, detailed bool, pretty bool, json bool) error {
+	if len(backups) == 0 {
+		tracelog.InfoLogger.Println("No backups found")
+		return nil
+	}
+
+	backupTimes := make([]internal.BackupTime, 0, len(backups))
+	detailsByName := make(map[string]printlist.Entity, len(backups))
+	for _, backup := range backups {
+		backupTime := newBackupTime(backup)
+		backupTimes = append(backupTimes, backupTime)
+		detailsByName[backupTime.BackupName] = newBackupDetails(backup)
+	}
+	internal.SortBackupTimeSlices(backupTimes)
+
+	printableEntities := make([]printlist.Entity, len(backupTimes))
+	for i := range backupTimes {
+		if detailed {
+			printableEntities[i] = detailsByName[backupTimes[i].BackupName]
+		} else {
+			printableEntities[i] = backupTimes[i]
+		}
+	}
+
+	err := printlist.List(printableEntities, os.Stdout, pretty, json)
+	if err != nil {
+		return fmt.Errorf("print backups: %w", err)
+	}
+	return nil
+}
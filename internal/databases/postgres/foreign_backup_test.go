@@ -0,0 +1,31 @@
+package postgres_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+func TestParseForeignBackupTime(t *testing.T) {
+	for value, expected := range map[string]time.Time{
+		// Barman
+		"2024-03-01 10:20:30.123456+03:00": time.Date(2024, 3, 1, 7, 20, 30, 123456000, time.UTC),
+		"2024-03-01 10:20:30.123456":       time.Date(2024, 3, 1, 10, 20, 30, 123456000, time.UTC),
+		// pg_probackup
+		"2024-03-01 10:20:30+03": time.Date(2024, 3, 1, 7, 20, 30, 0, time.UTC),
+		"2024-03-01 10:20:30":    time.Date(2024, 3, 1, 10, 20, 30, 0, time.UTC),
+	} {
+		parsed, err := postgres.ParseForeignBackupTime(value)
+		require.NoError(t, err, value)
+		assert.True(t, expected.Equal(parsed), value)
+	}
+
+	parsed, err := postgres.ParseForeignBackupTime("")
+	require.NoError(t, err)
+	assert.True(t, parsed.IsZero())
+	_, err = postgres.ParseForeignBackupTime("yesterday")
+	assert.Error(t, err)
+}
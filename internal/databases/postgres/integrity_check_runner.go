@@ -63,23 +63,10 @@ func NewIntegrityCheckRunner(
 		return IntegrityCheckRunner{}, errors.Wrap(err, "Failed to initialize timeline history map")
 	}
 
-	noBackupsFound := false
-
-	var stopWalSegmentNo WalSegmentNo
-	if backupSearchParams.FindEarliestBackup {
-		stopWalSegmentNo, err = getEarliestBackupStartSegmentNo(ctx, timelineSwitchMap, currentWalSegment.Timeline, rootFolder)
-		if err != nil {
-			tracelog.WarningLogger.Printf("Failed to detect earliest backup WAL segment no: '%v',"+
-				"will scan until the 0000000X0000000000000001 segment.\n", err)
-			stopWalSegmentNo = 1
-			noBackupsFound = true
-		}
-	} else {
-		stopWalSegmentNo, err = getSpecifiedBackupStartSegmentNo(ctx, timelineSwitchMap,
-			currentWalSegment.Timeline, backupSearchParams, rootFolder)
-		if err != nil {
-			return IntegrityCheckRunner{}, errors.Wrap(err, "Failed to find specified backup start segment")
-		}
+	stopWalSegmentNo, noBackupsFound, err := getStopWalSegmentNo(ctx, rootFolder, timelineSwitchMap,
+		currentWalSegment.Timeline, backupSearchParams)
+	if err != nil {
+		return IntegrityCheckRunner{}, err
 	}
 
 	// uploadingSegmentRangeSize is needed to determine max amount of missing WAL segments
@@ -174,6 +161,33 @@ func newIntegrityScanSegmentSequence(sequence *WalSegmentsSequence,
 	}
 }
 
+// getStopWalSegmentNo returns the start segment of the earliest correct backup or the specified backup.
+// If there are no correct backups, it returns the 0000000X0000000000000001 segment.
+func getStopWalSegmentNo(
+	ctx context.Context,
+	rootFolder storage.Folder,
+	timelineSwitchMap map[WalSegmentNo]*TimelineHistoryRecord,
+	currentTimeline uint32,
+	backupSearchParams BackupSearchParams,
+) (stopWalSegmentNo WalSegmentNo, noBackupsFound bool, err error) {
+	if !backupSearchParams.FindEarliestBackup {
+		stopWalSegmentNo, err = getSpecifiedBackupStartSegmentNo(ctx, timelineSwitchMap,
+			currentTimeline, backupSearchParams, rootFolder)
+		if err != nil {
+			return 0, false, errors.Wrap(err, "Failed to find specified backup start segment")
+		}
+		return stopWalSegmentNo, false, nil
+	}
+
+	stopWalSegmentNo, err = getEarliestBackupStartSegmentNo(ctx, timelineSwitchMap, currentTimeline, rootFolder)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to detect earliest backup WAL segment no: '%v',"+
+			"will scan until the 0000000X0000000000000001 segment.\n", err)
+		return 1, true, nil
+	}
+	return stopWalSegmentNo, false, nil
+}
+
 // runWalIntegrityScan invokes the following storage scan series
 // (on each iteration scanner continues from the position where it stopped)
 // 1. At first, it runs scan until it finds some segment in WAL storage
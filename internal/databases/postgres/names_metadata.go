@@ -18,12 +18,26 @@ type TableInfo struct {
 	Oid         uint32               `json:"oid"`
 	Relfilenode uint32               `json:"relfilenode"`
 	SubTables   map[string]TableInfo `json:"subtables,omitempty"`
+	// OrioledbRelnodes are the relnodes of the table, index and toast trees of the orioledb table
+	OrioledbRelnodes []uint32 `json:"orioledb_relnodes,omitempty"`
 }
 
 func NewDatabaseObjectsInfo(oid uint32) *DatabaseObjectsInfo {
 	return &DatabaseObjectsInfo{Oid: oid, Tables: make(map[string]TableInfo)}
 }
 
+// setOrioledbRelnodes attaches the orioledb tree relnodes to the tables and their partitions
+func setOrioledbRelnodes(tables map[string]TableInfo, relnodes map[uint32][]uint32) {
+	if len(relnodes) == 0 {
+		return
+	}
+	for name, tableInfo := range tables {
+		tableInfo.OrioledbRelnodes = relnodes[tableInfo.Oid]
+		setOrioledbRelnodes(tableInfo.SubTables, relnodes)
+		tables[name] = tableInfo
+	}
+}
+
 func (meta DatabasesByNames) Resolve(key string) (uint32, uint32, error) {
 	database, table, err := meta.unpackKey(key)
 	if err != nil {
@@ -62,9 +76,11 @@ func (meta DatabasesByNames) ResolveRegexp(key string) (map[uint32][]uint32, err
 				if table == "" || tableRegexp.MatchString(name) {
 					tracelog.InfoLogger.Printf("table to restore through key  %d %s", tableInfo.Relfilenode, table)
 					toRestore[dbInfo.Oid] = append(toRestore[dbInfo.Oid], tableInfo.Relfilenode)
+					toRestore[dbInfo.Oid] = append(toRestore[dbInfo.Oid], tableInfo.OrioledbRelnodes...)
 					for _, tableInfo2 := range tableInfo.SubTables {
 						tracelog.InfoLogger.Printf("subtanble for the table table to restore through key  %d %s", tableInfo2.Relfilenode, table)
 						toRestore[dbInfo.Oid] = append(toRestore[dbInfo.Oid], tableInfo2.Relfilenode)
+						toRestore[dbInfo.Oid] = append(toRestore[dbInfo.Oid], tableInfo2.OrioledbRelnodes...)
 					}
 				}
 			}
@@ -12,9 +12,12 @@ import (
 )
 
 var pagedFilenameRegexp *regexp.Regexp
+var dataFilePathRegexp *regexp.Regexp
 
 func init() {
 	pagedFilenameRegexp = regexp.MustCompile(`^(\d+)([.]\d+)?$`)
+	// data files are orioledb_data/<datoid>/<relnode>[.<segno>], checkpoint maps are <relnode>-<chkpnum>.map
+	dataFilePathRegexp = regexp.MustCompile(`(?:^|/)orioledb_data/(\d+)/(\d+)(?:[.-][^/]*)?$`)
 }
 
 func IsOrioledbDataPath(filePath string) bool {
@@ -34,6 +37,23 @@ func IsOrioledbDataFile(info os.FileInfo, filePath string) bool {
 	return true
 }
 
+// ParseDataPath returns the database oid and the tree relnode of the file stored in the orioledb_data directory
+func ParseDataPath(filePath string) (datoid uint32, relnode uint32, ok bool) {
+	matches := dataFilePathRegexp.FindStringSubmatch(filePath)
+	if matches == nil {
+		return 0, 0, false
+	}
+	parsedDatoid, err := strconv.ParseUint(matches[1], 10, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+	parsedRelnode, err := strconv.ParseUint(matches[2], 10, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+	return uint32(parsedDatoid), uint32(parsedRelnode), true
+}
+
 func IsEnabled(PgDataDirectory string) bool {
 	_, err := os.Stat(PgDataDirectory + "/orioledb_data")
 	return err == nil
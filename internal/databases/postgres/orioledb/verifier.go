@@ -0,0 +1,118 @@
+package orioledb
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/walparser/parsingutil"
+)
+
+const compressedChunkHeaderSize = 8
+
+// VerifyDataFile checks the structure of the orioledb data file or its increment and returns the corrupt block numbers.
+// OrioleDB pages carry no PostgreSQL checksums, so the verification is limited to the layout of the compressed
+// chunks and of the increment. On success the reader is fully drained.
+func VerifyDataFile(path string, fileSize int64, reader io.Reader, isIncremented bool) ([]uint32, error) {
+	var corruptBlocks []uint32
+	var err error
+	switch {
+	case isIncremented:
+		corruptBlocks, err = verifyIncrement(reader)
+	case fileSize%DatabasePageSize != 0:
+		corruptBlocks, err = verifyCompressedChunks(reader)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// uncompressed pages have no verifiable fields, the rest of the file is just drained
+	if _, err = io.Copy(io.Discard, reader); err != nil {
+		return nil, err
+	}
+	if len(corruptBlocks) > 0 {
+		tracelog.WarningLogger.Printf("VerifyDataFile: %s, found %d corrupt orioledb blocks\n", path, len(corruptBlocks))
+	}
+	return corruptBlocks, nil
+}
+
+// verifyCompressedChunks walks the chain of compressed chunks, each chunk is a header followed by
+// the compressed page padded to CompressedPageSize. The walk stops at the first corrupt chunk.
+func verifyCompressedChunks(reader io.Reader) ([]uint32, error) {
+	header := make([]byte, compressedChunkHeaderSize)
+	for blockNo := uint32(0); ; {
+		_, err := io.ReadFull(reader, header)
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err == io.ErrUnexpectedEOF {
+			return []uint32{blockNo}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		pageSize := int64(binary.LittleEndian.Uint16(header))
+		if pageSize > DatabasePageSize {
+			tracelog.WarningLogger.Printf("Compressed orioledb chunk at block %d has invalid size %d\n", blockNo, pageSize)
+			return []uint32{blockNo}, nil
+		}
+		blocksTotal := (pageSize + compressedChunkHeaderSize + CompressedPageSize - 1) / CompressedPageSize
+		_, err = io.CopyN(io.Discard, reader, blocksTotal*CompressedPageSize-compressedChunkHeaderSize)
+		if err == io.EOF {
+			return []uint32{blockNo}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		blockNo += uint32(blocksTotal)
+	}
+}
+
+// verifyIncrement checks that the increment header describes the blocks within the file
+// and that the increment holds the data for every block of the diff map
+func verifyIncrement(reader io.Reader) ([]uint32, error) {
+	header := make([]byte, len(IncrementFileHeader))
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, errors.Wrap(err, "failed to read the increment header")
+	}
+	if header[0] != 'w' || header[1] != 'i' || header[3] != SignatureMagicNumber {
+		return nil, errors.New("invalid increment file header")
+	}
+
+	var fileSize uint64
+	var pageSize uint16
+	var diffBlockCount uint32
+	err := parsingutil.ParseMultipleFieldsFromReader([]parsingutil.FieldToParse{
+		{Field: &fileSize, Name: "fileSize"},
+		{Field: &pageSize, Name: "pageSize"},
+		{Field: &diffBlockCount, Name: "diffBlockCount"},
+	}, reader)
+	if err != nil {
+		return nil, err
+	}
+	if int64(pageSize) != DatabasePageSize && pageSize != CompressedPageSize {
+		return nil, errors.Errorf("invalid increment page size %d", pageSize)
+	}
+
+	blockNumbers := make([]uint32, diffBlockCount)
+	if err = binary.Read(reader, binary.LittleEndian, blockNumbers); err != nil {
+		return nil, errors.Wrap(err, "failed to read the increment diff map")
+	}
+
+	var corruptBlocks []uint32
+	for i, blockNo := range blockNumbers {
+		_, err = io.CopyN(io.Discard, reader, int64(pageSize))
+		if err == io.EOF {
+			return append(corruptBlocks, blockNumbers[i:]...), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if (uint64(blockNo)+1)*uint64(pageSize) > fileSize {
+			corruptBlocks = append(corruptBlocks, blockNo)
+		}
+	}
+	return corruptBlocks, nil
+}
@@ -0,0 +1,82 @@
+package orioledb_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/databases/postgres/orioledb"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func makeCompressedChunk(pageSize uint16, blocks int) []byte {
+	chunk := make([]byte, blocks*orioledb.CompressedPageSize)
+	binary.LittleEndian.PutUint16(chunk, pageSize)
+	return chunk
+}
+
+func makeIncrement(fileSize uint64, pageSize uint16, blockNumbers []uint32, dataBlocks int) []byte {
+	var increment bytes.Buffer
+	increment.Write(orioledb.IncrementFileHeader)
+	increment.Write(utility.ToBytes(fileSize))
+	increment.Write(utility.ToBytes(pageSize))
+	increment.Write(utility.ToBytes(uint32(len(blockNumbers))))
+	for _, blockNo := range blockNumbers {
+		increment.Write(utility.ToBytes(blockNo))
+	}
+	increment.Write(make([]byte, dataBlocks*int(pageSize)))
+	return increment.Bytes()
+}
+
+func TestVerifyDataFile_ValidCompressedFile(t *testing.T) {
+	var file []byte
+	file = append(file, makeCompressedChunk(1000, 2)...)
+	file = append(file, makeCompressedChunk(100, 1)...)
+
+	corruptBlocks, err := orioledb.VerifyDataFile("orioledb_data/1/2", int64(len(file)), bytes.NewReader(file), false)
+	require.NoError(t, err)
+	assert.Empty(t, corruptBlocks)
+}
+
+func TestVerifyDataFile_TruncatedCompressedChunk(t *testing.T) {
+	var file []byte
+	file = append(file, makeCompressedChunk(100, 1)...)
+	file = append(file, makeCompressedChunk(2000, 3)[:2*orioledb.CompressedPageSize]...)
+
+	corruptBlocks, err := orioledb.VerifyDataFile("orioledb_data/1/2", int64(len(file)), bytes.NewReader(file), false)
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{1}, corruptBlocks)
+}
+
+func TestVerifyDataFile_OversizedCompressedChunk(t *testing.T) {
+	var file []byte
+	file = append(file, makeCompressedChunk(100, 1)...)
+	file = append(file, makeCompressedChunk(uint16(orioledb.DatabasePageSize+1), 1)...)
+	file = append(file, makeCompressedChunk(100, 1)...)
+
+	reader := bytes.NewReader(file)
+	corruptBlocks, err := orioledb.VerifyDataFile("orioledb_data/1/2", int64(len(file)), reader, false)
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{1}, corruptBlocks)
+	assert.Zero(t, reader.Len())
+}
+
+func TestVerifyDataFile_Increment(t *testing.T) {
+	pageSize := uint16(orioledb.DatabasePageSize)
+	increment := makeIncrement(3*uint64(pageSize), pageSize, []uint32{0, 2}, 2)
+
+	corruptBlocks, err := orioledb.VerifyDataFile("orioledb_data/1/2", 0, bytes.NewReader(increment), true)
+	require.NoError(t, err)
+	assert.Empty(t, corruptBlocks)
+}
+
+func TestVerifyDataFile_IncrementBlocksOutOfFile(t *testing.T) {
+	pageSize := uint16(orioledb.CompressedPageSize)
+	increment := makeIncrement(3*uint64(pageSize), pageSize, []uint32{1, 5, 6}, 2)
+
+	corruptBlocks, err := orioledb.VerifyDataFile("orioledb_data/1/2", 0, bytes.NewReader(increment), true)
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{5, 6}, corruptBlocks)
+}
@@ -17,6 +17,10 @@ type PgControlData struct {
 	CurrentTimeline  uint32 // CurrentTimeline represents current timeline of PG cluster (f.e. [48-52] bytes in pg_control v. 1100+)
 	Checkpoint       LSN    //
 	// Any data from pg_control
+
+	// OrioledbChkpNum is the last orioledb checkpoint number, it is not a part of pg_control
+	// and is filled only by catchup-receive for clusters with orioledb
+	OrioledbChkpNum *uint32
 }
 
 // ExtractPgControl extract pg_control data of cluster by storage
@@ -0,0 +1,350 @@
+package pgbackrest
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/crypto"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// backupTarWriter packs the imported files into the tar partitions of the wal-g backup
+type backupTarWriter struct {
+	ctx              context.Context //nolint:containedctx // tar balls start their uploads with it
+	crypter          crypto.Crypter
+	uploader         internal.Uploader
+	tarBallMaker     *internal.StorageTarBallMaker
+	tarSizeThreshold int64
+	tarBall          internal.TarBall
+	tarFileSets      internal.TarFileSets
+	uncompressedSize int64
+}
+
+func newBackupTarWriter(ctx context.Context, backupName string, uploader internal.Uploader,
+	crypter crypto.Crypter) *backupTarWriter {
+	return &backupTarWriter{
+		ctx:              ctx,
+		crypter:          crypter,
+		uploader:         uploader,
+		tarBallMaker:     internal.NewStorageTarBallMaker(backupName, uploader),
+		tarSizeThreshold: viper.GetInt64(conf.TarSizeThresholdSetting),
+		tarFileSets:      internal.NewRegularTarFileSets(),
+	}
+}
+
+func (writer *backupTarWriter) write(header *tar.Header, content io.Reader) error {
+	if writer.tarBall == nil {
+		writer.tarBall = writer.tarBallMaker.Make(false)
+		writer.tarBall.SetUp(writer.ctx, writer.crypter)
+	}
+	if header.Typeflag == tar.TypeDir {
+		return errors.Wrapf(writer.tarBall.TarWriter().WriteHeader(header), "failed to pack %s", header.Name)
+	}
+	if _, err := internal.PackFileTo(writer.tarBall, header, content); err != nil {
+		return errors.Wrapf(err, "failed to pack %s", header.Name)
+	}
+	writer.uncompressedSize += header.Size
+	writer.tarFileSets.AddFile(writer.tarBall.Name(), header.Name)
+	if writer.tarBall.Size() > writer.tarSizeThreshold {
+		return writer.closeTarBall()
+	}
+	return nil
+}
+
+// startSeparateTar closes the current tar partition and starts the tar with the fixed name,
+// wal-g extracts such tars (pg_control.tar and backup_label.tar) after the regular partitions
+func (writer *backupTarWriter) startSeparateTar(name string) error {
+	if err := writer.closeTarBall(); err != nil {
+		return err
+	}
+	writer.tarBall = writer.tarBallMaker.Make(false)
+	writer.tarBall.SetUp(writer.ctx, writer.crypter,
+		utility.AddFileExtension(name, writer.uploader.Compression().FileExtension()))
+	return nil
+}
+
+func (writer *backupTarWriter) closeTarBall() error {
+	if writer.tarBall == nil {
+		return nil
+	}
+	tarBall := writer.tarBall
+	writer.tarBall = nil
+	if err := tarBall.CloseTar(); err != nil {
+		return err
+	}
+	return tarBall.AwaitUploads()
+}
+
+// walgFilePath converts the path of the pgBackRest manifest to the path of the wal-g tar partition entry
+func walgFilePath(manifestPath string) (string, bool) {
+	if relativePath, ok := strings.CutPrefix(manifestPath, BackupDataDirectory+"/"); ok {
+		return "/" + relativePath, true
+	}
+	if strings.HasPrefix(manifestPath, postgres.TablespaceFolder+"/") {
+		return "/" + manifestPath, true
+	}
+	return "", false
+}
+
+// isTablespaceLink reports whether the manifest path is the tablespace directory itself,
+// wal-g creates the pg_tblspc symlinks from the tablespace spec instead
+func isTablespaceLink(manifestPath string) bool {
+	return strings.Count(manifestPath, "/") == 1 && strings.HasPrefix(manifestPath, postgres.TablespaceFolder+"/")
+}
+
+func parseMode(mode, defaultMode string) (int64, error) {
+	if mode == "" {
+		mode = defaultMode
+	}
+	return strconv.ParseInt(mode, 8, 64)
+}
+
+// parsePgVersion converts the pgBackRest db-version ("9.6", "15") to the server_version_num format
+func parsePgVersion(version string) (int, error) {
+	majorVersion, minorVersion, _ := strings.Cut(version, ".")
+	major, err := strconv.Atoi(majorVersion)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid PostgreSQL version %q", version)
+	}
+	if major >= 10 {
+		return major * 10000, nil
+	}
+	minor, err := strconv.Atoi(minorVersion)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid PostgreSQL version %q", version)
+	}
+	return major*10000 + minor*100, nil
+}
+
+func (importer *Importer) importBackup(ctx context.Context, backup *importedBackup) error {
+	manifest := backup.manifest
+	if manifest.Encrypted {
+		return errors.New("encrypted pgBackRest repositories are not supported")
+	}
+
+	uploader, err := internal.ConfigureUploaderToFolder(importer.target.GetSubFolder(utility.BaseBackupPath))
+	if err != nil {
+		return err
+	}
+	writer := newBackupTarWriter(ctx, backup.name, uploader, importer.crypter)
+	startTime := time.Unix(manifest.BackupSection.BackupTimestampStart, 0)
+
+	for _, manifestPath := range slices.Sorted(maps.Keys(manifest.Paths)) {
+		name, ok := walgFilePath(manifestPath)
+		if !ok || isTablespaceLink(manifestPath) {
+			continue
+		}
+		mode, err := parseMode(manifest.Paths[manifestPath].Mode, manifest.DefaultPathSection.Mode)
+		if err != nil {
+			return errors.Wrapf(err, "invalid mode of %s", manifestPath)
+		}
+		header := &tar.Header{Typeflag: tar.TypeDir, Name: name, Mode: mode, ModTime: startTime}
+		if err := writer.write(header, nil); err != nil {
+			return err
+		}
+	}
+
+	files, separateFiles, err := importer.packFiles(ctx, writer, backup)
+	if err != nil {
+		return err
+	}
+	if err := importer.packSeparateTars(ctx, writer, backup, separateFiles); err != nil {
+		return err
+	}
+	return importer.uploadBackupMetadata(ctx, uploader, writer, backup, files)
+}
+
+// packFiles packs the files stored in the backup and returns the description of all the backup files.
+// pg_control and the backup label files are returned separately, they are packed into their own tars.
+func (importer *Importer) packFiles(ctx context.Context, writer *backupTarWriter,
+	backup *importedBackup) (internal.BackupFileList, map[string]string, error) {
+	files := make(internal.BackupFileList)
+	separateFiles := make(map[string]string)
+	for _, manifestPath := range slices.Sorted(maps.Keys(backup.manifest.Files)) {
+		file := backup.manifest.Files[manifestPath]
+		name, ok := walgFilePath(manifestPath)
+		if !ok {
+			return nil, nil, errors.Errorf("unexpected file %s outside of the data directory and tablespaces", manifestPath)
+		}
+		if file.BlockIncrementalSize != 0 {
+			return nil, nil, errors.Errorf("file %s is stored as block incremental, which is not supported", manifestPath)
+		}
+		if strings.TrimPrefix(name, "/") == postgres.BackupLabelFilename ||
+			strings.TrimPrefix(name, "/") == postgres.TablespaceMapFilename || name == postgres.PgControlPath {
+			separateFiles[manifestPath] = name
+			continue
+		}
+
+		files[name] = internal.BackupFileDescription{
+			IsSkipped: file.Reference != "",
+			MTime:     time.Unix(file.Timestamp, 0),
+		}
+		if file.Reference != "" {
+			continue
+		}
+		if err := importer.packFile(ctx, writer, backup, manifestPath, name); err != nil {
+			return nil, nil, err
+		}
+	}
+	return files, separateFiles, nil
+}
+
+func (importer *Importer) packSeparateTars(ctx context.Context, writer *backupTarWriter, backup *importedBackup,
+	separateFiles map[string]string) error {
+	pgControlPath := BackupDataDirectory + postgres.PgControlPath
+	if _, ok := separateFiles[pgControlPath]; !ok {
+		return errors.Errorf("%s is not found in the backup", pgControlPath)
+	}
+
+	if len(separateFiles) > 1 {
+		if err := writer.startSeparateTar("backup_label.tar"); err != nil {
+			return err
+		}
+		for _, manifestPath := range slices.Sorted(maps.Keys(separateFiles)) {
+			if manifestPath == pgControlPath {
+				continue
+			}
+			// wal-g stores the backup label files without the leading slash
+			name := strings.TrimPrefix(separateFiles[manifestPath], "/")
+			if err := importer.packFile(ctx, writer, backup, manifestPath, name); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := writer.startSeparateTar("pg_control.tar"); err != nil {
+		return err
+	}
+	if err := importer.packFile(ctx, writer, backup, pgControlPath, postgres.PgControlPath); err != nil {
+		return err
+	}
+	return writer.closeTarBall()
+}
+
+func (importer *Importer) packFile(ctx context.Context, writer *backupTarWriter, backup *importedBackup,
+	manifestPath, name string) error {
+	file := backup.manifest.Files[manifestPath]
+	mode, err := parseMode(file.Mode, backup.manifest.DefaultFileSection.Mode)
+	if err != nil {
+		return errors.Wrapf(err, "invalid mode of %s", manifestPath)
+	}
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     file.Size,
+		Mode:     mode,
+		ModTime:  time.Unix(file.Timestamp, 0),
+	}
+	// pgBackRest does not store the empty files
+	if file.Size == 0 {
+		return writer.write(header, strings.NewReader(""))
+	}
+
+	reader, err := importer.openBackupFile(ctx, backup, manifestPath, file)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s", manifestPath)
+	}
+	defer utility.LoggedClose(reader, "")
+	return writer.write(header, newChecksumReader(reader, file.Checksum, manifestPath))
+}
+
+func (importer *Importer) openBackupFile(ctx context.Context, backup *importedBackup, manifestPath string,
+	file ManifestFile) (io.ReadCloser, error) {
+	label := backup.label()
+	if file.Reference != "" {
+		label = file.Reference
+	}
+	backupFolder := importer.backupFolder(label)
+	compressionType := backup.manifest.CompressionType()
+
+	var reader io.ReadCloser
+	var err error
+	if file.BundleID != 0 {
+		size := file.RepoSize
+		if size == 0 {
+			size = file.Size
+		}
+		reader, err = storage.ReadObjectRange(ctx, backupFolder.GetSubFolder(BundleFolderName),
+			strconv.FormatInt(file.BundleID, 10), file.BundleOffset, size)
+	} else {
+		reader, err = backupFolder.ReadObject(ctx, manifestPath+compressionExtension(compressionType))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decompress(reader, compressionType)
+}
+
+func makeTablespaceSpec(manifest *ManifestSettings) *postgres.TablespaceSpec {
+	var spec *postgres.TablespaceSpec
+	for _, targetName := range slices.Sorted(maps.Keys(manifest.Targets)) {
+		target := manifest.Targets[targetName]
+		if target.TablespaceID == "" {
+			continue
+		}
+		if spec == nil {
+			tablespaceSpec := postgres.NewTablespaceSpec(manifest.Targets[BackupDataDirectory].Path)
+			spec = &tablespaceSpec
+		}
+		spec.AddTablespace(target.TablespaceID, target.Path)
+	}
+	return spec
+}
+
+func (importer *Importer) uploadBackupMetadata(ctx context.Context, uploader internal.Uploader, writer *backupTarWriter,
+	backup *importedBackup, files internal.BackupFileList) error {
+	manifest := backup.manifest
+	finishLsn, err := postgres.ParseLSN(manifest.BackupSection.BackupLsnStop)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse stop LSN")
+	}
+	pgVersion, err := parsePgVersion(manifest.BackupDatabaseSection.Version)
+	if err != nil {
+		return err
+	}
+	compressedSize, err := uploader.UploadedDataSize()
+	if err != nil {
+		return err
+	}
+
+	sentinel := postgres.BackupSentinelDto{
+		BackupStartLSN:   &backup.startLsn,
+		BackupFinishLSN:  &finishLsn,
+		PgVersion:        pgVersion,
+		SystemIdentifier: &manifest.BackupDatabaseSection.SystemID,
+		UncompressedSize: writer.uncompressedSize,
+		CompressedSize:   compressedSize,
+		TablespaceSpec:   makeTablespaceSpec(manifest),
+	}
+	if backup.prior != nil {
+		sentinel.IncrementFrom = &backup.prior.name
+		sentinel.IncrementFromLSN = &backup.prior.startLsn
+		sentinel.IncrementFullName = &backup.full.name
+		sentinel.IncrementCount = &backup.increment
+	}
+	meta := postgres.NewExtendedMetadataDto(false, manifest.Targets[BackupDataDirectory].Path,
+		time.Unix(manifest.BackupSection.BackupTimestampStart, 0), sentinel)
+	meta.FinishTime = time.Unix(manifest.BackupSection.BackupTimestampStop, 0)
+	filesMetadata := postgres.NewFilesMetadataDto(files, writer.tarFileSets)
+
+	if err := uploader.UploadJSON(ctx, storage.JoinPath(backup.name, utility.MetadataFileName), meta); err != nil {
+		return errors.Wrap(err, "failed to upload metadata")
+	}
+	if err := uploader.UploadJSON(ctx, storage.JoinPath(backup.name, postgres.FilesMetadataName), filesMetadata); err != nil {
+		return errors.Wrap(err, "failed to upload files metadata")
+	}
+	// the sentinel is uploaded last, so the interrupted import is restarted from scratch
+	return internal.UploadSentinel(ctx, uploader, postgres.NewBackupSentinelDtoV2(sentinel, meta), backup.name)
+}
@@ -0,0 +1,298 @@
+package pgbackrest
+
+import (
+	"cmp"
+	"compress/bzip2"
+	"context"
+	"crypto/sha1" //nolint:gosec // pgBackRest checksums are SHA-1
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/crypto"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/internal/ioextensions"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+type ChecksumMismatchError struct {
+	error
+}
+
+func newChecksumMismatchError(name, expected, actual string) ChecksumMismatchError {
+	return ChecksumMismatchError{errors.Errorf("checksum mismatch for %s: expected %s, got %s", name, expected, actual)}
+}
+
+func (err ChecksumMismatchError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+type ImportVerificationError struct {
+	error
+}
+
+func newImportVerificationError(subject string, problems []string) ImportVerificationError {
+	return ImportVerificationError{errors.Errorf("verification of %s failed:\n%s", subject, strings.Join(problems, "\n"))}
+}
+
+func (err ImportVerificationError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// ImportOptions controls which parts of the pgBackRest repository are imported
+type ImportOptions struct {
+	// BackupName limits the import to the backup with this label and the backups it depends on
+	BackupName string
+	WithoutWal bool
+	// Verify reads the imported backups and WAL back and compares them with the pgBackRest checksums
+	Verify bool
+}
+
+// importedBackup is the pgBackRest backup together with its wal-g counterpart
+type importedBackup struct {
+	settings BackupSettings
+	manifest *ManifestSettings
+	name     string
+	startLsn postgres.LSN
+	prior    *importedBackup
+	full     *importedBackup
+	// increment is the number of the backups between this backup and its full backup
+	increment int
+}
+
+func (backup *importedBackup) label() string {
+	return backup.settings.Name
+}
+
+// Importer transcodes the stanza of the pgBackRest repository into the wal-g storage.
+// The backups become wal-g full and delta backups, the files stored in the prior pgBackRest backups
+// are marked as skipped. The import can be restarted: the backups with the uploaded sentinel
+// and the WAL files present in storage are not imported again.
+type Importer struct {
+	source  storage.Folder
+	target  storage.Folder
+	stanza  string
+	crypter crypto.Crypter
+	options ImportOptions
+}
+
+func HandleImport(ctx context.Context, source, target storage.Folder, stanza string, options ImportOptions) error {
+	importer := &Importer{
+		source:  source,
+		target:  target,
+		stanza:  stanza,
+		crypter: internal.ConfigureCrypter(),
+		options: options,
+	}
+
+	archiveSettings, err := LoadArchiveSettings(ctx, source, stanza)
+	if err != nil {
+		return errors.Wrap(err, "failed to load pgBackRest archive info")
+	}
+	backups, err := importer.loadBackups(ctx, archiveSettings.DatabaseID)
+	if err != nil {
+		return err
+	}
+	if err := importer.importBackups(ctx, backups); err != nil {
+		return err
+	}
+
+	var walFiles []archiveFile
+	if !options.WithoutWal {
+		firstWalFile := ""
+		if options.BackupName != "" && len(backups) > 0 {
+			firstWalFile = backups[0].settings.BackupArchiveStart
+		}
+		walFiles, err = importer.importWal(ctx, archiveSettings.ArchiveName(), firstWalFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	if options.Verify {
+		for _, backup := range backups {
+			if err := importer.verifyBackup(ctx, backup); err != nil {
+				return err
+			}
+		}
+		if err := importer.verifyWal(ctx, walFiles); err != nil {
+			return err
+		}
+	}
+	tracelog.InfoLogger.Printf("Imported %d backups of the pgBackRest stanza %s\n", len(backups), stanza)
+	return nil
+}
+
+// loadBackups returns the backups of the current database ordered so that each backup follows its prior backup
+func (importer *Importer) loadBackups(ctx context.Context, databaseID int64) ([]*importedBackup, error) {
+	backupsSettings, err := LoadBackupsSettings(ctx, importer.source, importer.stanza)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load pgBackRest backup info")
+	}
+	slices.SortFunc(backupsSettings, func(a, b BackupSettings) int {
+		return cmp.Compare(a.BackupTimestampStart, b.BackupTimestampStart)
+	})
+
+	backupsByLabel := make(map[string]*importedBackup)
+	labelsByName := make(map[string]string)
+	backups := make([]*importedBackup, 0, len(backupsSettings))
+	for _, settings := range backupsSettings {
+		if int64(settings.BackupPgID) != databaseID {
+			tracelog.WarningLogger.Printf("Skipping backup %s of the database %d, only the backups of the current database %d are imported\n",
+				settings.Name, settings.BackupPgID, databaseID)
+			continue
+		}
+		backup, err := newImportedBackup(settings, backupsByLabel)
+		if err != nil {
+			return nil, err
+		}
+		if label, ok := labelsByName[backup.name]; ok {
+			return nil, errors.Errorf("backups %s and %s start in the same WAL segment and can't be both stored as %s",
+				label, backup.label(), backup.name)
+		}
+		labelsByName[backup.name] = backup.label()
+		backupsByLabel[backup.label()] = backup
+		backups = append(backups, backup)
+	}
+
+	if importer.options.BackupName == "" {
+		return backups, nil
+	}
+	backup, ok := backupsByLabel[importer.options.BackupName]
+	if !ok {
+		return nil, errors.Errorf("backup %s is not found in the pgBackRest stanza %s", importer.options.BackupName, importer.stanza)
+	}
+	var chain []*importedBackup
+	for ; backup != nil; backup = backup.prior {
+		chain = append(chain, backup)
+	}
+	slices.Reverse(chain)
+	return chain, nil
+}
+
+func newImportedBackup(settings BackupSettings, backupsByLabel map[string]*importedBackup) (*importedBackup, error) {
+	if _, _, err := postgres.ParseWALFilename(settings.BackupArchiveStart); err != nil {
+		return nil, errors.Wrapf(err, "backup %s has no valid archive start segment", settings.Name)
+	}
+	backup := &importedBackup{
+		settings: settings,
+		name:     utility.BackupNamePrefix + settings.BackupArchiveStart,
+	}
+	if settings.BackupPrior == "" {
+		return backup, nil
+	}
+
+	prior, ok := backupsByLabel[settings.BackupPrior]
+	if !ok {
+		return nil, errors.Errorf("prior backup %s of backup %s is not found", settings.BackupPrior, settings.Name)
+	}
+	backup.prior = prior
+	backup.full = prior
+	if prior.full != nil {
+		backup.full = prior.full
+	}
+	backup.increment = prior.increment + 1
+	backup.name += "_D_" + utility.StripWalFileName(prior.name)
+	return backup, nil
+}
+
+func (importer *Importer) importBackups(ctx context.Context, backups []*importedBackup) error {
+	importedNames := make(map[string]bool)
+	existingBackups, err := internal.GetBackups(ctx, importer.target.GetSubFolder(utility.BaseBackupPath))
+	if _, ok := err.(internal.NoBackupsFoundError); !ok && err != nil {
+		return errors.Wrap(err, "failed to list wal-g backups")
+	}
+	for _, backup := range existingBackups {
+		importedNames[backup.BackupName] = true
+	}
+
+	for _, backup := range backups {
+		backup.manifest, err = LoadManifest(ctx, importer.source, importer.stanza, backup.label())
+		if err != nil {
+			return errors.Wrapf(err, "failed to load manifest of backup %s", backup.label())
+		}
+		backup.startLsn, err = postgres.ParseLSN(backup.manifest.BackupSection.BackupLsnStart)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse start LSN of backup %s", backup.label())
+		}
+
+		if importedNames[backup.name] {
+			tracelog.InfoLogger.Printf("Backup %s is already imported as %s, skipping\n", backup.label(), backup.name)
+			continue
+		}
+		tracelog.InfoLogger.Printf("Importing backup %s as %s\n", backup.label(), backup.name)
+		if err := importer.importBackup(ctx, backup); err != nil {
+			return errors.Wrapf(err, "failed to import backup %s", backup.label())
+		}
+	}
+	return nil
+}
+
+func (importer *Importer) backupFolder(label string) storage.Folder {
+	return importer.source.GetSubFolder(BackupFolderName).GetSubFolder(importer.stanza).GetSubFolder(label)
+}
+
+// compressionExtension returns the extension of the files compressed by pgBackRest with compressionType
+func compressionExtension(compressionType string) string {
+	if compressionType == "none" || compressionType == "" {
+		return ""
+	}
+	return "." + compressionType
+}
+
+// decompress wraps the reader of the repository file compressed by pgBackRest with compressionType
+func decompress(reader io.ReadCloser, compressionType string) (io.ReadCloser, error) {
+	switch compressionType {
+	case "none", "":
+		return reader, nil
+	case "bz2":
+		return ioextensions.ReadCascadeCloser{Reader: bzip2.NewReader(reader), Closer: reader}, nil
+	}
+
+	decompressor := compression.FindDecompressor(compressionType)
+	if decompressor == nil {
+		utility.LoggedClose(reader, "")
+		return nil, errors.Errorf("unsupported pgBackRest compression type %q", compressionType)
+	}
+	decompressed, err := decompressor.Decompress(reader)
+	if err != nil {
+		utility.LoggedClose(reader, "")
+		return nil, err
+	}
+	return ioextensions.ReadCascadeCloser{
+		Reader: decompressed,
+		Closer: ioextensions.NewMultiCloser([]io.Closer{decompressed, reader}),
+	}, nil
+}
+
+// checksumReader returns ChecksumMismatchError instead of io.EOF
+// when the content read does not match the pgBackRest SHA-1 checksum
+type checksumReader struct {
+	reader   io.Reader
+	hash     hash.Hash
+	expected string
+	name     string
+}
+
+func newChecksumReader(reader io.Reader, expected, name string) *checksumReader {
+	return &checksumReader{reader: reader, hash: sha1.New(), expected: expected, name: name} //nolint:gosec
+}
+
+func (reader *checksumReader) Read(p []byte) (int, error) {
+	n, err := reader.reader.Read(p)
+	reader.hash.Write(p[:n])
+	if err == io.EOF {
+		if actual := hex.EncodeToString(reader.hash.Sum(nil)); actual != reader.expected {
+			return n, newChecksumMismatchError(reader.name, reader.expected, actual)
+		}
+	}
+	return n, err
+}
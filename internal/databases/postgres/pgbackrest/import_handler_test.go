@@ -0,0 +1,206 @@
+package pgbackrest_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha1" //nolint:gosec // pgBackRest checksums are SHA-1
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/internal/databases/postgres/pgbackrest"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/testtools"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func init() {
+	internal.ConfigureSettings(conf.PG)
+	conf.InitConfig()
+	conf.Configure()
+}
+
+const (
+	fullLabel = "20240101-000000F"
+	incrLabel = "20240101-000000F_20240102-000000I"
+	fullName  = "base_000000010000000000000002"
+	incrName  = "base_000000010000000000000004_D_000000010000000000000002"
+)
+
+func sha1Hex(content string) string {
+	hash := sha1.Sum([]byte(content)) //nolint:gosec
+	return hex.EncodeToString(hash[:])
+}
+
+func putGzip(t *testing.T, folder storage.Folder, path, content string) {
+	var data bytes.Buffer
+	writer := gzip.NewWriter(&data)
+	_, err := writer.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	require.NoError(t, folder.PutObject(context.Background(), path, &data))
+}
+
+type repoFile struct {
+	content   string
+	reference string
+}
+
+// putBackup writes the manifest of the pgBackRest backup and the files stored in it
+func putBackup(t *testing.T, folder storage.Folder, label, archiveStart, lsnStart string, files map[string]repoFile) {
+	backupFolder := folder.GetSubFolder("backup/main").GetSubFolder(label)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var fileSection strings.Builder
+	for _, name := range names {
+		file := files[name]
+		reference := ""
+		if file.reference != "" {
+			reference = fmt.Sprintf(`,"reference":"%s"`, file.reference)
+		} else if file.content != "" {
+			putGzip(t, backupFolder, name+".gz", file.content)
+		}
+		fmt.Fprintf(&fileSection, `%s={"checksum":"%s","size":%d,"timestamp":1700000000%s}`+"\n",
+			name, sha1Hex(file.content), len(file.content), reference)
+	}
+
+	manifest := fmt.Sprintf(`[backup]
+backup-archive-start="%s"
+backup-label="%s"
+backup-lsn-start="%s"
+backup-lsn-stop="0/5000100"
+backup-timestamp-start=1700000000
+backup-timestamp-stop=1700000100
+
+[backup:db]
+db-id=1
+db-system-id=7300000000000000001
+db-version="15"
+
+[backup:option]
+option-compress-type="gz"
+
+[backup:target]
+pg_data={"path":"/var/lib/postgresql/15/main","type":"path"}
+
+[target:file]
+%s
+[target:file:default]
+mode="0600"
+
+[target:path]
+pg_data={}
+pg_data/base={}
+pg_data/base/1={}
+pg_data/global={}
+
+[target:path:default]
+mode="0700"
+`, archiveStart, label, lsnStart, fileSection.String())
+	require.NoError(t, backupFolder.PutObject(context.Background(), "backup.manifest", strings.NewReader(manifest)))
+}
+
+func setupPgBackRestRepo(t *testing.T, folder storage.Folder) map[string]string {
+	require.NoError(t, folder.PutObject(context.Background(), "archive/main/archive.info",
+		strings.NewReader("[db]\ndb-id=1\ndb-version=\"15\"\n")))
+	backupInfo := fmt.Sprintf(`[backup:current]
+%s={"backup-archive-start":"000000010000000000000002","backup-timestamp-start":1700000000,"backup-type":"full","db-id":1}
+%s={"backup-archive-start":"000000010000000000000004","backup-prior":"%s","backup-timestamp-start":1700001000,"db-id":1}
+`, fullLabel, incrLabel, fullLabel)
+	require.NoError(t, folder.PutObject(context.Background(), "backup/main/backup.info", strings.NewReader(backupInfo)))
+
+	putBackup(t, folder, fullLabel, "000000010000000000000002", "0/2000028", map[string]repoFile{
+		"pg_data/PG_VERSION":        {content: "15\n"},
+		"pg_data/backup_label":      {content: "START WAL LOCATION: 0/2000028\n"},
+		"pg_data/base/1/1234":       {content: "table data"},
+		"pg_data/base/1/empty":      {},
+		"pg_data/global/pg_control": {content: "full pg_control"},
+	})
+	putBackup(t, folder, incrLabel, "000000010000000000000004", "0/4000028", map[string]repoFile{
+		"pg_data/PG_VERSION":        {content: "15\n", reference: fullLabel},
+		"pg_data/backup_label":      {content: "START WAL LOCATION: 0/4000028\n"},
+		"pg_data/base/1/1234":       {content: "table data", reference: fullLabel},
+		"pg_data/base/1/5678":       {content: "new table"},
+		"pg_data/global/pg_control": {content: "incr pg_control"},
+	})
+
+	segments := map[string]string{
+		"000000010000000000000002": "segment 2",
+		"000000010000000000000003": "segment 3",
+		"000000010000000000000004": "segment 4",
+	}
+	archiveFolder := folder.GetSubFolder("archive/main/15-1/0000000100000000")
+	for name, content := range segments {
+		putGzip(t, archiveFolder, name+"-"+sha1Hex(content)+".gz", content)
+	}
+	putGzip(t, archiveFolder, "000000010000000000000005.partial-"+sha1Hex("partial")+".gz", "partial")
+	return segments
+}
+
+func TestHandleImport(t *testing.T) {
+	rootFolder := testtools.MakeDefaultInMemoryStorageFolder()
+	segments := setupPgBackRestRepo(t, rootFolder)
+
+	err := pgbackrest.HandleImport(context.Background(), rootFolder, rootFolder, "main", pgbackrest.ImportOptions{Verify: true})
+	require.NoError(t, err)
+
+	baseBackupFolder := rootFolder.GetSubFolder(utility.BaseBackupPath)
+	fullBackup, err := postgres.NewBackup(baseBackupFolder, fullName)
+	require.NoError(t, err)
+	fullSentinel, fullFiles, err := fullBackup.GetSentinelAndFilesMetadata(context.Background())
+	require.NoError(t, err)
+	assert.False(t, fullSentinel.IsIncremental())
+	assert.Equal(t, 150000, fullSentinel.PgVersion)
+	assert.Equal(t, postgres.LSN(0x2000028), *fullSentinel.BackupStartLSN)
+	assert.Contains(t, fullFiles.Files, "/base/1/empty")
+	assert.NotContains(t, fullFiles.Files, postgres.PgControlPath)
+
+	incrBackup, err := postgres.NewBackup(baseBackupFolder, incrName)
+	require.NoError(t, err)
+	incrSentinel, incrFiles, err := incrBackup.GetSentinelAndFilesMetadata(context.Background())
+	require.NoError(t, err)
+	require.True(t, incrSentinel.IsIncremental())
+	assert.Equal(t, fullName, *incrSentinel.IncrementFrom)
+	assert.Equal(t, fullName, *incrSentinel.IncrementFullName)
+	assert.Equal(t, 1, *incrSentinel.IncrementCount)
+	assert.True(t, incrFiles.Files["/base/1/1234"].IsSkipped)
+	assert.False(t, incrFiles.Files["/base/1/5678"].IsSkipped)
+
+	walObjects, _, err := rootFolder.GetSubFolder(utility.WalPath).ListFolder(context.Background())
+	require.NoError(t, err)
+	walNames := make([]string, 0, len(walObjects))
+	for _, object := range walObjects {
+		walNames = append(walNames, utility.TrimFileExtension(object.GetName()))
+	}
+	assert.Len(t, walNames, len(segments))
+	for name := range segments {
+		assert.Contains(t, walNames, name)
+	}
+
+	// the repeated import skips everything imported already
+	err = pgbackrest.HandleImport(context.Background(), rootFolder, rootFolder, "main", pgbackrest.ImportOptions{Verify: true})
+	require.NoError(t, err)
+}
+
+func TestHandleImport_ChecksumMismatch(t *testing.T) {
+	rootFolder := testtools.MakeDefaultInMemoryStorageFolder()
+	setupPgBackRestRepo(t, rootFolder)
+	putGzip(t, rootFolder.GetSubFolder("backup/main").GetSubFolder(fullLabel), "pg_data/base/1/1234.gz", "corrupted!")
+
+	err := pgbackrest.HandleImport(context.Background(), rootFolder, rootFolder, "main",
+		pgbackrest.ImportOptions{WithoutWal: true})
+	var mismatchError pgbackrest.ChecksumMismatchError
+	require.ErrorAs(t, err, &mismatchError)
+}
@@ -0,0 +1,150 @@
+package pgbackrest
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha1" //nolint:gosec // pgBackRest checksums are SHA-1
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+	"golang.org/x/sync/errgroup"
+)
+
+// verifyBackup reads the tar partitions of the imported backup back and compares
+// the files stored in them with the checksums of the pgBackRest manifest
+func (importer *Importer) verifyBackup(ctx context.Context, backup *importedBackup) error {
+	expected := make(map[string]string)
+	for manifestPath, file := range backup.manifest.Files {
+		name, ok := walgFilePath(manifestPath)
+		if !ok || file.Reference != "" {
+			continue
+		}
+		if trimmed := strings.TrimPrefix(name, "/"); trimmed == postgres.BackupLabelFilename || trimmed == postgres.TablespaceMapFilename {
+			name = trimmed
+		}
+		expected[name] = file.Checksum
+	}
+
+	tarsFolder := importer.target.GetSubFolder(utility.BaseBackupPath).
+		GetSubFolder(backup.name).GetSubFolder(internal.TarPartitionFolderName)
+	tarObjects, _, err := tarsFolder.ListFolder(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list tar partitions of %s", backup.name)
+	}
+	var problems []string
+	for _, tarObject := range tarObjects {
+		tarProblems, err := importer.verifyTar(ctx, tarsFolder, tarObject.GetName(), expected)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, tarProblems...)
+	}
+	for _, name := range slices.Sorted(maps.Keys(expected)) {
+		problems = append(problems, fmt.Sprintf("%s: missing", name))
+	}
+	if len(problems) > 0 {
+		return newImportVerificationError(backup.name, problems)
+	}
+	tracelog.InfoLogger.Printf("Backup %s is verified\n", backup.name)
+	return nil
+}
+
+// verifyTar checks the files of the tar partition and removes them from expected
+func (importer *Importer) verifyTar(ctx context.Context, tarsFolder storage.Folder, tarName string,
+	expected map[string]string) ([]string, error) {
+	reader, err := tarsFolder.ReadObject(ctx, tarName)
+	if err != nil {
+		return nil, err
+	}
+	defer utility.LoggedClose(reader, "")
+	decompressed, err := internal.DecryptAndDecompressTar(reader, tarName, importer.crypter)
+	if err != nil {
+		return nil, err
+	}
+	defer utility.LoggedClose(decompressed, "")
+
+	var problems []string
+	tarReader := tar.NewReader(decompressed)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return problems, nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s", tarName)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		hash := sha1.New() //nolint:gosec
+		if _, err := io.Copy(hash, tarReader); err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s from %s", header.Name, tarName)
+		}
+		checksum, ok := expected[header.Name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: unexpected file in %s", header.Name, tarName))
+			continue
+		}
+		delete(expected, header.Name)
+		if actual := hex.EncodeToString(hash.Sum(nil)); actual != checksum {
+			problems = append(problems, fmt.Sprintf("%s: checksum mismatch: expected %s, got %s", header.Name, checksum, actual))
+		}
+	}
+}
+
+// verifyWal downloads the imported WAL files and compares them with the checksums of the pgBackRest archive
+func (importer *Importer) verifyWal(ctx context.Context, files []archiveFile) error {
+	walFolderReader := internal.NewFolderReader(importer.target.GetSubFolder(utility.WalPath))
+	concurrency, err := conf.GetMaxDownloadConcurrency()
+	if err != nil {
+		return err
+	}
+	errGroup, groupCtx := errgroup.WithContext(ctx)
+	errGroup.SetLimit(concurrency)
+
+	var problemsMutex sync.Mutex
+	var problems []string
+	for _, file := range files {
+		if file.checksum == "" {
+			continue
+		}
+		errGroup.Go(func() error {
+			reader, err := internal.DownloadAndDecompressStorageFile(groupCtx, walFolderReader, file.name)
+			if err != nil {
+				return errors.Wrapf(err, "failed to download %s", file.name)
+			}
+			defer utility.LoggedClose(reader, "")
+			hash := sha1.New() //nolint:gosec
+			if _, err := io.Copy(hash, reader); err != nil {
+				return errors.Wrapf(err, "failed to read %s", file.name)
+			}
+			if actual := hex.EncodeToString(hash.Sum(nil)); actual != file.checksum {
+				problemsMutex.Lock()
+				defer problemsMutex.Unlock()
+				problems = append(problems, fmt.Sprintf("%s: checksum mismatch: expected %s, got %s", file.name, file.checksum, actual))
+			}
+			return nil
+		})
+	}
+	if err := errGroup.Wait(); err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		slices.Sort(problems)
+		return newImportVerificationError("WAL", problems)
+	}
+	tracelog.InfoLogger.Printf("%d WAL files are verified\n", len(files))
+	return nil
+}
@@ -0,0 +1,129 @@
+package pgbackrest
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/ioextensions"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+	"golang.org/x/sync/errgroup"
+)
+
+var (
+	archiveCompressionTypes = []string{"gz", "bz2", "lz4", "zst"}
+	archiveChecksumRegexp   = regexp.MustCompile(`-[0-9a-f]{40}$`)
+	archiveFileNameRegexp   = regexp.MustCompile(`^[0-9A-F]{24}(\.[0-9A-F]{8}\.backup)?$|^[0-9A-F]{8}\.history$`)
+)
+
+// archiveFile is the WAL segment, backup history or timeline history file of the pgBackRest archive
+type archiveFile struct {
+	// path is relative to the archive folder of the database
+	path        string
+	name        string
+	checksum    string
+	compression string
+}
+
+// parseArchiveFile parses the pgBackRest archive file path:
+// <timeline and log>/<segment>-<sha1>[.<ext>] for segments and <timeline>.history for timeline histories
+func parseArchiveFile(path string) (archiveFile, bool) {
+	file := archiveFile{path: path, compression: "none"}
+	name := path[strings.LastIndex(path, "/")+1:]
+	for _, compressionType := range archiveCompressionTypes {
+		if trimmed, ok := strings.CutSuffix(name, "."+compressionType); ok {
+			name, file.compression = trimmed, compressionType
+			break
+		}
+	}
+	if location := archiveChecksumRegexp.FindStringIndex(name); location != nil {
+		file.checksum = name[location[0]+1:]
+		name = name[:location[0]]
+	}
+	file.name = name
+	return file, archiveFileNameRegexp.MatchString(name)
+}
+
+// importWal uploads the archive files to wal-g storage starting with firstWalFile segment, all if it is empty.
+// It returns the imported files, those already present in storage included.
+func (importer *Importer) importWal(ctx context.Context, archiveName, firstWalFile string) ([]archiveFile, error) {
+	archiveFolder := importer.source.GetSubFolder(WalArchivePath).GetSubFolder(importer.stanza).GetSubFolder(archiveName)
+	objects, err := storage.ListFolderRecursively(ctx, archiveFolder)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list pgBackRest archive")
+	}
+
+	walFolder := importer.target.GetSubFolder(utility.WalPath)
+	existingObjects, _, err := walFolder.ListFolder(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list wal-g WAL files")
+	}
+	existingNames := make(map[string]bool, len(existingObjects))
+	for _, object := range existingObjects {
+		existingNames[utility.TrimFileExtension(object.GetName())] = true
+	}
+
+	uploader, err := internal.ConfigureUploaderToFolder(walFolder)
+	if err != nil {
+		return nil, err
+	}
+	concurrency, err := conf.GetMaxUploadConcurrency()
+	if err != nil {
+		return nil, err
+	}
+	errGroup, groupCtx := errgroup.WithContext(ctx)
+	errGroup.SetLimit(concurrency)
+
+	var files []archiveFile
+	skipped := 0
+	for _, object := range objects {
+		file, ok := parseArchiveFile(object.GetName())
+		if !ok {
+			continue
+		}
+		if firstWalFile != "" && !strings.HasSuffix(file.name, ".history") && file.name[:24] < firstWalFile {
+			continue
+		}
+		files = append(files, file)
+		if existingNames[file.name] {
+			skipped++
+			continue
+		}
+		errGroup.Go(func() error {
+			return importer.importArchiveFile(groupCtx, archiveFolder, uploader, file)
+		})
+	}
+	if err := errGroup.Wait(); err != nil {
+		return nil, err
+	}
+	tracelog.InfoLogger.Printf("Imported %d WAL files, %d were already present in storage\n", len(files)-skipped, skipped)
+	return files, nil
+}
+
+func (importer *Importer) importArchiveFile(ctx context.Context, archiveFolder storage.Folder,
+	uploader internal.Uploader, file archiveFile) error {
+	reader, err := archiveFolder.ReadObject(ctx, file.path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s", file.path)
+	}
+	decompressed, err := decompress(reader, file.compression)
+	if err != nil {
+		return errors.Wrapf(err, "failed to decompress %s", file.path)
+	}
+	defer utility.LoggedClose(decompressed, "")
+
+	var content io.Reader = decompressed
+	if file.checksum != "" {
+		content = newChecksumReader(decompressed, file.checksum, file.path)
+	}
+	if err := uploader.UploadFile(ctx, ioextensions.NewNamedReaderImpl(content, file.name)); err != nil {
+		return errors.Wrapf(err, "failed to upload %s", file.name)
+	}
+	return nil
+}
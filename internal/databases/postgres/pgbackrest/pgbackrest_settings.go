@@ -18,6 +18,7 @@ const (
 
 	BackupFolderName    = "backup"
 	BackupDataDirectory = "pg_data"
+	BundleFolderName    = "bundle"
 )
 
 type ArchiveSettings struct {
@@ -25,6 +26,11 @@ type ArchiveSettings struct {
 	DatabaseVersion string `ini:"db-version"`
 }
 
+// ArchiveName returns the name of the archive folder of the current database
+func (settings ArchiveSettings) ArchiveName() string {
+	return fmt.Sprintf("%s-%d", settings.DatabaseVersion, settings.DatabaseID)
+}
+
 type BackupSettings struct {
 	Name                    string
 	BackrestFormat          int    `json:"backrest-format"`
@@ -79,14 +85,66 @@ type PathSection struct {
 	directoryPaths []string
 }
 
+type BackupOptionSection struct {
+	OptionCompress     bool   `ini:"option-compress"`
+	OptionCompressType string `ini:"option-compress-type"`
+}
+
 type ManifestSettings struct {
 	BackrestSection       BackrestSection       `ini:"backrest"`
 	BackupSection         BackupSection         `ini:"backup"`
 	BackupTargetSection   BackupTargetSection   `ini:"backup:target"`
 	BackupDatabaseSection BackupDatabaseSection `ini:"backup:db"`
+	BackupOptionSection   BackupOptionSection   `ini:"backup:option"`
 	PathSection           PathSection
 	DefaultFileSection    DefaultFileSection `ini:"target:file:default"`
 	DefaultPathSection    DefaultPathSection `ini:"target:path:default"`
+
+	Targets   map[string]ManifestTarget `ini:"-"`
+	Files     map[string]ManifestFile   `ini:"-"`
+	Paths     map[string]ManifestPath   `ini:"-"`
+	Encrypted bool                      `ini:"-"`
+}
+
+// CompressionType returns the compression type of the repository files, "none" if they are not compressed
+func (settings *ManifestSettings) CompressionType() string {
+	if settings.BackupOptionSection.OptionCompressType != "" {
+		return settings.BackupOptionSection.OptionCompressType
+	}
+	// pgBackRest before 2.27 could compress with gzip only
+	if settings.BackupOptionSection.OptionCompress {
+		return "gz"
+	}
+	return "none"
+}
+
+// ManifestTarget describes the entry of the [backup:target] section: the data directory or a tablespace link
+type ManifestTarget struct {
+	Path           string `json:"path"`
+	Type           string `json:"type"`
+	TablespaceID   string `json:"tablespace-id"`
+	TablespaceName string `json:"tablespace-name"`
+}
+
+// ManifestFile describes the entry of the [target:file] section
+type ManifestFile struct {
+	Checksum  string `json:"checksum"`
+	Size      int64  `json:"size"`
+	RepoSize  int64  `json:"repo-size"`
+	Timestamp int64  `json:"timestamp"`
+	Mode      string `json:"mode"`
+	// Reference is the label of the prior backup that stores the file
+	Reference string `json:"reference"`
+	// BundleID and BundleOffset locate the file inside a bundle when repo-bundle is enabled
+	BundleID     int64 `json:"bni"`
+	BundleOffset int64 `json:"bno"`
+	// BlockIncrementalSize is set when the file is stored as a block incremental map
+	BlockIncrementalSize int64 `json:"bi"`
+}
+
+// ManifestPath describes the entry of the [target:path] section
+type ManifestPath struct {
+	Mode string `json:"mode"`
 }
 
 type BackupDatabaseSection struct {
@@ -116,6 +174,16 @@ type DefaultPathSection struct {
 }
 
 func GetArchiveName(ctx context.Context, folder storage.Folder, stanza string) (*string, error) {
+	settings, err := LoadArchiveSettings(ctx, folder, stanza)
+	if err != nil {
+		return nil, err
+	}
+
+	archiveName := settings.ArchiveName()
+	return &archiveName, nil
+}
+
+func LoadArchiveSettings(ctx context.Context, folder storage.Folder, stanza string) (*ArchiveSettings, error) {
 	archiveFolder := folder.GetSubFolder(WalArchivePath).GetSubFolder(stanza)
 	ioReader, err := archiveFolder.ReadObject(ctx, ArchiveInfo)
 	if err != nil {
@@ -136,9 +204,7 @@ func GetArchiveName(ctx context.Context, folder storage.Folder, stanza string) (
 	if err := dbSection.MapTo(&settings); err != nil {
 		return nil, err
 	}
-
-	archiveName := fmt.Sprintf("%s-%d", settings.DatabaseVersion, settings.DatabaseID)
-	return &archiveName, nil
+	return &settings, nil
 }
 
 func LoadBackupsSettings(ctx context.Context, folder storage.Folder, stanza string) ([]BackupSettings, error) {
@@ -188,5 +254,28 @@ func LoadManifest(ctx context.Context, folder storage.Folder, stanza string, bac
 		return nil, err
 	}
 	settings.PathSection.directoryPaths = cfg.Section("target:path").KeyStrings()
+	if settings.Targets, err = loadJSONSection[ManifestTarget](cfg.Section("backup:target")); err != nil {
+		return nil, err
+	}
+	if settings.Files, err = loadJSONSection[ManifestFile](cfg.Section("target:file")); err != nil {
+		return nil, err
+	}
+	if settings.Paths, err = loadJSONSection[ManifestPath](cfg.Section("target:path")); err != nil {
+		return nil, err
+	}
+	settings.Encrypted = cfg.HasSection("cipher")
 	return &settings, nil
 }
+
+// loadJSONSection parses the manifest section where every value is a JSON object
+func loadJSONSection[T any](section *ini.Section) (map[string]T, error) {
+	values := make(map[string]T, len(section.Keys()))
+	for _, key := range section.Keys() {
+		var value T
+		if err := json.Unmarshal([]byte(key.Value()), &value); err != nil {
+			return nil, fmt.Errorf("failed to parse manifest entry %s: %w", key.Name(), err)
+		}
+		values[key.Name()] = value
+	}
+	return values, nil
+}
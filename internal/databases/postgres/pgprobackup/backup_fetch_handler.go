@@ -0,0 +1,224 @@
+package pgprobackup
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+	"golang.org/x/sync/errgroup"
+)
+
+// backupRestorer restores the backup together with the chain of its parent backups.
+// The incremental backups store the changed pages of the data files and the changed non-data files only.
+type backupRestorer struct {
+	folder      storage.Folder
+	destination string
+	// chain starts with the full backup and ends with the backup being restored
+	chain    []*BackupControl
+	contents []map[string]ContentFile
+}
+
+func HandleBackupFetch(ctx context.Context, folder storage.Folder, instance string, destinationDirectory string,
+	backupSelector internal.BackupSelector) error {
+	backup, err := backupSelector.Select(ctx, folder)
+	if err != nil {
+		return err
+	}
+	chain, err := loadBackupChain(ctx, folder, instance, backup.Name)
+	if err != nil {
+		return err
+	}
+
+	restorer := &backupRestorer{
+		folder:      backupsFolder(folder, instance),
+		destination: destinationDirectory,
+		chain:       chain,
+	}
+	for _, control := range chain {
+		content, err := LoadBackupContent(ctx, folder, instance, control.BackupID)
+		if err != nil {
+			return err
+		}
+		restorer.contents = append(restorer.contents, content)
+	}
+	return restorer.restore(ctx)
+}
+
+func loadBackupChain(ctx context.Context, folder storage.Folder, instance, backupID string) ([]*BackupControl, error) {
+	var chain []*BackupControl
+	for {
+		control, err := LoadBackupControl(ctx, folder, instance, backupID)
+		if err != nil {
+			return nil, err
+		}
+		if !control.IsValid() {
+			return nil, errors.Errorf("backup %s has status %s and can't be restored", control.BackupID, control.Status)
+		}
+		chain = append(chain, control)
+		if control.Mode == BackupModeFull {
+			break
+		}
+		if control.ParentBackupID == "" {
+			return nil, errors.Errorf("%s backup %s has no parent backup", control.Mode, control.BackupID)
+		}
+		backupID = control.ParentBackupID
+	}
+	slices.Reverse(chain)
+	return chain, nil
+}
+
+func (restorer *backupRestorer) restore(ctx context.Context) error {
+	target := restorer.contents[len(restorer.contents)-1]
+	paths := slices.Sorted(maps.Keys(target))
+
+	var files []ContentFile
+	for _, filePath := range paths {
+		file := target[filePath]
+		switch {
+		case file.ExternalDirNum != 0:
+			tracelog.WarningLogger.Printf("Skipping %s of the external directory %d\n", file.Path, file.ExternalDirNum)
+		case file.IsCfs:
+			return errors.Errorf("%s is compressed by CFS, which is not supported", file.Path)
+		case file.Kind == "dir":
+			if err := restorer.createDirectory(file); err != nil {
+				return err
+			}
+		case file.Kind == "reg":
+			files = append(files, file)
+		}
+	}
+
+	concurrency, err := conf.GetMaxDownloadConcurrency()
+	if err != nil {
+		return err
+	}
+	errGroup, groupCtx := errgroup.WithContext(ctx)
+	errGroup.SetLimit(concurrency)
+	for _, file := range files {
+		errGroup.Go(func() error {
+			return errors.Wrapf(restorer.restoreFile(groupCtx, file), "failed to restore %s", file.Path)
+		})
+	}
+	if err := errGroup.Wait(); err != nil {
+		return err
+	}
+	tracelog.InfoLogger.Printf("Backup %s is restored to %s\n", restorer.chain[len(restorer.chain)-1].BackupID, restorer.destination)
+	return nil
+}
+
+// createDirectory creates the directory, the tablespace directories are created as links to their locations
+func (restorer *backupRestorer) createDirectory(file ContentFile) error {
+	localPath := filepath.Join(restorer.destination, filepath.FromSlash(file.Path))
+	mode := os.FileMode(file.Mode & 0777)
+	if file.Linked == "" {
+		if err := os.MkdirAll(localPath, mode); err != nil {
+			return err
+		}
+		return os.Chmod(localPath, mode)
+	}
+
+	if err := os.MkdirAll(file.Linked, mode); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(localPath), 0700); err != nil {
+		return err
+	}
+	return os.Symlink(file.Linked, localPath)
+}
+
+func (restorer *backupRestorer) backupFolder(index int) storage.Folder {
+	return restorer.folder.GetSubFolder(restorer.chain[index].BackupID)
+}
+
+func (restorer *backupRestorer) restoreFile(ctx context.Context, file ContentFile) error {
+	localPath := filepath.Join(restorer.destination, filepath.FromSlash(file.Path))
+	if err := os.MkdirAll(filepath.Dir(localPath), 0700); err != nil {
+		return err
+	}
+	localFile, err := os.OpenFile(localPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.FileMode(file.Mode&0777))
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(localFile, "")
+
+	if file.IsDatafile {
+		return restorer.restoreDataFile(ctx, file, localFile)
+	}
+
+	// the non-data file is taken from the latest backup where it has changed
+	last := len(restorer.chain) - 1
+	for index := last; index >= 0; index-- {
+		backupFile, ok := restorer.contents[index][file.Path]
+		if !ok || backupFile.Size == bytesInvalid {
+			continue
+		}
+		if backupFile.Size == 0 {
+			return nil
+		}
+		reader, err := restorer.backupFolder(index).GetSubFolder(DatabaseFolderName).ReadObject(ctx, file.Path)
+		if err != nil {
+			return err
+		}
+		defer utility.LoggedClose(reader, "")
+		_, err = utility.FastCopy(localFile, reader)
+		return err
+	}
+	return errors.New("file is not found in the backup chain")
+}
+
+// restoreDataFile applies the pages of the data file stored in the backups of the chain,
+// starting with the oldest backup which has the file without a gap
+func (restorer *backupRestorer) restoreDataFile(ctx context.Context, file ContentFile, localFile *os.File) error {
+	first := len(restorer.chain) - 1
+	for first > 0 {
+		if _, ok := restorer.contents[first-1][file.Path]; !ok {
+			break
+		}
+		first--
+	}
+
+	for index := first; index < len(restorer.chain); index++ {
+		backupFile := restorer.contents[index][file.Path]
+		if backupFile.Size == bytesInvalid || backupFile.Size == 0 {
+			continue
+		}
+		if err := restorer.applyBackupDataFile(ctx, index, backupFile, localFile); err != nil {
+			return errors.Wrapf(err, "backup %s", restorer.chain[index].BackupID)
+		}
+	}
+	if file.NBlocks >= 0 {
+		return localFile.Truncate(file.NBlocks * restorer.chain[len(restorer.chain)-1].BlockSize)
+	}
+	return nil
+}
+
+func (restorer *backupRestorer) applyBackupDataFile(ctx context.Context, index int, file ContentFile, localFile *os.File) error {
+	control := restorer.chain[index]
+	backupFolder := restorer.backupFolder(index)
+	var headers []pageHeader2
+	if file.HeaderSize > 0 {
+		var err error
+		if headers, err = loadPageHeaders(ctx, backupFolder, file); err != nil {
+			return err
+		}
+	}
+
+	reader, err := backupFolder.GetSubFolder(DatabaseFolderName).ReadObject(ctx, file.Path)
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(reader, "")
+	compressAlg := file.CompressAlg
+	if compressAlg == "" {
+		compressAlg = control.CompressAlg
+	}
+	return applyDataFile(newDataFileReader(reader, compressAlg, control.BlockSize, headers), localFile)
+}
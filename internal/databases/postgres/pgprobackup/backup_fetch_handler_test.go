@@ -0,0 +1,201 @@
+package pgprobackup_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/databases/postgres/pgprobackup"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/testtools"
+)
+
+const blockSize = 8192
+
+func init() {
+	internal.ConfigureSettings(conf.PG)
+	conf.InitConfig()
+	conf.Configure()
+}
+
+func zlibCompress(t *testing.T, data []byte) []byte {
+	var buffer bytes.Buffer
+	writer := zlib.NewWriter(&buffer)
+	_, err := writer.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return buffer.Bytes()
+}
+
+// pglzCompressRepeated encodes the page filled with the single byte: the literal followed by the matches of it
+func pglzCompressRepeated(value byte) []byte {
+	var compressed []byte
+	control, items := 0, 0
+	addItem := func(isMatch bool, data ...byte) {
+		if items%8 == 0 {
+			compressed = append(compressed, 0)
+			control = len(compressed) - 1
+		}
+		if isMatch {
+			compressed[control] |= 1 << (items % 8)
+		}
+		compressed = append(compressed, data...)
+		items++
+	}
+	addItem(false, value)
+	for written := 1; written < blockSize; written += 273 {
+		addItem(true, 0x0f, 0x01, 255)
+	}
+	return compressed
+}
+
+func putBackup(t *testing.T, folder storage.Folder, backupID, control string, content []string, files map[string][]byte) {
+	backupFolder := folder.GetSubFolder("backups/main").GetSubFolder(backupID)
+	require.NoError(t, backupFolder.PutObject(context.Background(), pgprobackup.BackupControlFile, strings.NewReader(control)))
+	require.NoError(t, backupFolder.PutObject(context.Background(), pgprobackup.BackupContentFile,
+		strings.NewReader(strings.Join(content, "\n")+"\n")))
+	for name, data := range files {
+		require.NoError(t, backupFolder.PutObject(context.Background(), name, bytes.NewReader(data)))
+	}
+}
+
+func inlinePage(block int32, data []byte) []byte {
+	header := make([]byte, 8)
+	binary.LittleEndian.PutUint32(header[0:], uint32(block))
+	binary.LittleEndian.PutUint32(header[4:], uint32(len(data)))
+	padding := make([]byte, (8-len(data)%8)%8)
+	return append(append(header, data...), padding...)
+}
+
+func setupPgProbackupCatalog(t *testing.T, folder storage.Folder) {
+	pageA := bytes.Repeat([]byte{'a'}, blockSize)
+	pageB := bytes.Repeat([]byte{'b'}, blockSize)
+	pageX := bytes.Repeat([]byte{'x'}, blockSize)
+
+	fullDataFile := append(inlinePage(0, zlibCompress(t, pageA)), inlinePage(1, pageB)...)
+	truncatedDataFile := append(inlinePage(0, pageX), inlinePage(1, pageX)...)
+	putBackup(t, folder, "S1", `#Configuration
+backup-mode = FULL
+compress-alg = zlib
+#Result backup info
+timelineid = 1
+start-lsn = 0/2000028
+stop-lsn = 0/2000100
+start-time = '2024-01-01 00:00:00+00'
+end-time = '2024-01-01 00:01:00+00'
+status = OK
+`, []string{
+		`{"path":"base", "size":"0", "kind":"dir", "mode":"16832", "is_datafile":"0", "external_dir_num":"0"}`,
+		`{"path":"base/1", "size":"0", "kind":"dir", "mode":"16832", "is_datafile":"0", "external_dir_num":"0"}`,
+		`{"path":"PG_VERSION", "size":"3", "kind":"reg", "mode":"33152", "is_datafile":"0", "external_dir_num":"0"}`,
+		fmt.Sprintf(`{"path":"base/1/1000", "size":"%d", "kind":"reg", "mode":"33152", "is_datafile":"1", "compress_alg":"zlib",`+
+			` "external_dir_num":"0", "n_blocks":"2"}`, len(fullDataFile)),
+		fmt.Sprintf(`{"path":"base/1/2000", "size":"%d", "kind":"reg", "mode":"33152", "is_datafile":"1", "compress_alg":"zlib",`+
+			` "external_dir_num":"0", "n_blocks":"2"}`, len(truncatedDataFile)),
+	}, map[string][]byte{
+		"database/PG_VERSION":  []byte("15\n"),
+		"database/base/1/1000": fullDataFile,
+		"database/base/1/2000": truncatedDataFile,
+	})
+
+	changedPage := pglzCompressRepeated('c')
+	headers := make([]byte, 48)
+	binary.LittleEndian.PutUint32(headers[8:], 1)
+	binary.LittleEndian.PutUint32(headers[24+12:], uint32(len(changedPage)))
+	headerMap := zlibCompress(t, headers)
+	putBackup(t, folder, "S2", `backup-mode = PAGE
+compress-alg = pglz
+timelineid = 1
+start-lsn = 0/4000028
+stop-lsn = 0/4000100
+start-time = '2024-01-02 00:00:00+00'
+end-time = '2024-01-02 00:01:00+00'
+status = OK
+parent-backup-id = 'S1'
+`, []string{
+		`{"path":"base", "size":"0", "kind":"dir", "mode":"16832", "is_datafile":"0", "external_dir_num":"0"}`,
+		`{"path":"base/1", "size":"0", "kind":"dir", "mode":"16832", "is_datafile":"0", "external_dir_num":"0"}`,
+		`{"path":"PG_VERSION", "size":"-1", "kind":"reg", "mode":"33152", "is_datafile":"0", "external_dir_num":"0"}`,
+		`{"path":"postgresql.auto.conf", "size":"8", "kind":"reg", "mode":"33152", "is_datafile":"0", "external_dir_num":"0"}`,
+		fmt.Sprintf(`{"path":"base/1/1000", "size":"%d", "kind":"reg", "mode":"33152", "is_datafile":"1", "compress_alg":"pglz",`+
+			` "external_dir_num":"0", "n_blocks":"2", "hdr_off":"0", "hdr_size":"%d", "n_headers":"1"}`, len(changedPage), len(headerMap)),
+		`{"path":"base/1/2000", "size":"0", "kind":"reg", "mode":"33152", "is_datafile":"1", "compress_alg":"pglz",` +
+			` "external_dir_num":"0", "n_blocks":"1"}`,
+	}, map[string][]byte{
+		"database/postgresql.auto.conf": []byte("# empty\n"),
+		"database/base/1/1000":          changedPage,
+		pgprobackup.PageHeaderMapFile:   headerMap,
+	})
+}
+
+func TestHandleBackupFetch(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	setupPgProbackupCatalog(t, folder)
+	destination := t.TempDir()
+
+	err := pgprobackup.HandleBackupFetch(context.Background(), folder, "main", destination,
+		pgprobackup.NewBackupSelector(internal.LatestString, "main"))
+	require.NoError(t, err)
+
+	expectedFiles := map[string][]byte{
+		"PG_VERSION":           []byte("15\n"),
+		"postgresql.auto.conf": []byte("# empty\n"),
+		"base/1/1000":          append(bytes.Repeat([]byte{'a'}, blockSize), bytes.Repeat([]byte{'c'}, blockSize)...),
+		"base/1/2000":          bytes.Repeat([]byte{'x'}, blockSize),
+	}
+	for name, expected := range expectedFiles {
+		actual, err := os.ReadFile(filepath.Join(destination, name))
+		require.NoError(t, err)
+		assert.True(t, bytes.Equal(expected, actual), "unexpected content of %s", name)
+	}
+	info, err := os.Stat(filepath.Join(destination, "base/1"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+}
+
+func TestHandleBackupList(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	setupPgProbackupCatalog(t, folder)
+
+	backups, err := pgprobackup.GetBackupList(context.Background(), folder, "main")
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+	walFileNames := map[string]string{}
+	for _, backup := range backups {
+		walFileNames[backup.BackupName] = backup.WalFileName
+	}
+	assert.Equal(t, map[string]string{
+		"S1": "000000010000000000000002",
+		"S2": "000000010000000000000004",
+	}, walFileNames)
+}
+
+func TestHandleWalFetch(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, err := writer.Write([]byte("segment"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	require.NoError(t, folder.PutObject(context.Background(), "wal/main/000000010000000000000002.gz", &compressed))
+	require.NoError(t, folder.PutObject(context.Background(), "wal/main/00000002.history", strings.NewReader("history")))
+
+	for name, expected := range map[string]string{"000000010000000000000002": "segment", "00000002.history": "history"} {
+		location := filepath.Join(t.TempDir(), name)
+		require.NoError(t, pgprobackup.HandleWalFetch(context.Background(), folder, "main", name, location))
+		actual, err := os.ReadFile(location)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(actual))
+	}
+}
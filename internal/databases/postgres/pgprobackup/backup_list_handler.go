@@ -0,0 +1,127 @@
+package pgprobackup
+
+import (
+	"context"
+	"time"
+
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/internal/printlist"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+type BackupDetails struct {
+	BackupName     string
+	WalFileName    string
+	Mode           string
+	ParentBackupID string
+	StartTime      time.Time
+	FinishTime     time.Time
+	PgVersion      string
+	StartLsn       postgres.LSN
+	FinishLsn      postgres.LSN
+	CompressAlg    string
+}
+
+func NewBackupDetails(control *BackupControl) *BackupDetails {
+	return &BackupDetails{
+		BackupName:     control.BackupID,
+		WalFileName:    startWalFileName(control),
+		Mode:           control.Mode,
+		ParentBackupID: control.ParentBackupID,
+		StartTime:      control.StartTime,
+		FinishTime:     control.EndTime,
+		PgVersion:      control.ServerVersion,
+		StartLsn:       control.StartLsn,
+		FinishLsn:      control.StopLsn,
+		CompressAlg:    control.CompressAlg,
+	}
+}
+
+func (bd *BackupDetails) PrintableFields() []printlist.TableField {
+	prettyStartTime := internal.PrettyFormatTime(bd.StartTime)
+	prettyFinishTime := internal.PrettyFormatTime(bd.FinishTime)
+	return []printlist.TableField{
+		{
+			Name:       "name",
+			PrettyName: "Name",
+			Value:      bd.BackupName,
+		},
+		{
+			Name:       "wal_file_name",
+			PrettyName: "WAL file name",
+			Value:      bd.WalFileName,
+		},
+		{
+			Name:       "mode",
+			PrettyName: "Mode",
+			Value:      bd.Mode,
+		},
+		{
+			Name:       "parent",
+			PrettyName: "Parent",
+			Value:      bd.ParentBackupID,
+		},
+		{
+			Name:        "start_time",
+			PrettyName:  "Start time",
+			Value:       internal.FormatTime(bd.StartTime),
+			PrettyValue: &prettyStartTime,
+		},
+		{
+			Name:        "finish_time",
+			PrettyName:  "Finish time",
+			Value:       internal.FormatTime(bd.FinishTime),
+			PrettyValue: &prettyFinishTime,
+		},
+		{
+			Name:       "pg_version",
+			PrettyName: "PG version",
+			Value:      bd.PgVersion,
+		},
+		{
+			Name:       "start_lsn",
+			PrettyName: "Start LSN",
+			Value:      bd.StartLsn.String(),
+		},
+		{
+			Name:       "finish_lsn",
+			PrettyName: "Finish LSN",
+			Value:      bd.FinishLsn.String(),
+		},
+		{
+			Name:       "compress_alg",
+			PrettyName: "Compression",
+			Value:      bd.CompressAlg,
+		},
+	}
+}
+
+func startWalFileName(control *BackupControl) string {
+	return postgres.NewWalSegmentNo(control.StartLsn).GetFilename(control.Timeline)
+}
+
+func newBackupTime(control *BackupControl) internal.BackupTime {
+	return internal.BackupTime{
+		BackupName:  control.BackupID,
+		Time:        control.EndTime,
+		WalFileName: startWalFileName(control),
+	}
+}
+
+func GetBackupList(ctx context.Context, folder storage.Folder, instance string) ([]internal.BackupTime, error) {
+	controls, err := LoadBackupControls(ctx, folder, instance)
+	if err != nil {
+		return nil, err
+	}
+	return postgres.GetForeignBackupTimes(controls, newBackupTime), nil
+}
+
+func HandleBackupList(ctx context.Context, folder storage.Folder, instance string, detailed bool, pretty bool, json bool) error {
+	controls, err := LoadBackupControls(ctx, folder, instance)
+	if err != nil {
+		return err
+	}
+	return postgres.HandleForeignBackupList(controls, newBackupTime,
+		func(control *BackupControl) printlist.Entity { return NewBackupDetails(control) }, detailed, pretty, json)
+}
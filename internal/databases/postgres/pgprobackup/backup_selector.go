@@ -0,0 +1,58 @@
+package pgprobackup
+
+import (
+	"context"
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+type LatestBackupSelector struct {
+	Instance string
+}
+
+type NamedBackupSelector struct {
+	BackupName string
+	Instance   string
+}
+
+func (selector LatestBackupSelector) Select(ctx context.Context, folder storage.Folder) (internal.Backup, error) {
+	backupList, err := GetBackupList(ctx, folder, selector.Instance)
+	if err != nil {
+		return internal.Backup{}, err
+	}
+	if len(backupList) == 0 {
+		return internal.Backup{}, internal.NewNoBackupsFoundError()
+	}
+	slices.SortFunc(backupList, func(a, b internal.BackupTime) int {
+		return a.Time.Compare(b.Time)
+	})
+
+	return internal.NewBackup(folder, backupList[len(backupList)-1].BackupName)
+}
+
+func (selector NamedBackupSelector) Select(ctx context.Context, folder storage.Folder) (internal.Backup, error) {
+	backupList, err := GetBackupList(ctx, folder, selector.Instance)
+	if err != nil {
+		return internal.Backup{}, err
+	}
+	for _, backup := range backupList {
+		if backup.BackupName == selector.BackupName {
+			return internal.NewBackup(folder, backup.BackupName)
+		}
+	}
+	return internal.Backup{}, errors.Errorf("backup %s is not found in the pg_probackup instance %s", selector.BackupName, selector.Instance)
+}
+
+func NewBackupSelector(backupName string, instance string) internal.BackupSelector {
+	if backupName == internal.LatestString {
+		tracelog.InfoLogger.Printf("Selecting the latest backup...\n")
+		return LatestBackupSelector{Instance: instance}
+	}
+
+	tracelog.InfoLogger.Printf("Selecting the backup with name %s...\n", backupName)
+	return NamedBackupSelector{BackupName: backupName, Instance: instance}
+}
@@ -0,0 +1,228 @@
+package pgprobackup
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	// pageIsTruncated is the compressed size of the inline header which marks the file truncated at the block
+	pageIsTruncated = -2
+	// backupPageHeaderSize is the size of the inline page header: int32 block, int32 compressed_size
+	backupPageHeaderSize = 8
+	// backupPageHeader2Size is the size of the page_header_map entry: uint64 lsn, int32 block, int32 pos, uint16 checksum
+	backupPageHeader2Size = 24
+	maxAlign              = 8
+)
+
+// dataPage is the page of the data file stored in the backup
+type dataPage struct {
+	block int64
+	data  []byte
+}
+
+// dataFileReader reads the pages of the data file stored by pg_probackup:
+// the compressed pages follow either the inline headers or the headers stored in page_header_map since 2.4
+type dataFileReader struct {
+	reader      io.Reader
+	compressAlg string
+	blockSize   int64
+	// headers are set for the page_header_map format only
+	headers []pageHeader2
+	next    int
+	// truncatedBlock is the block the file was truncated at, -1 if it was not
+	truncatedBlock int64
+}
+
+type pageHeader2 struct {
+	block int64
+	pos   int64
+}
+
+func newDataFileReader(reader io.Reader, compressAlg string, blockSize int64, headers []pageHeader2) *dataFileReader {
+	return &dataFileReader{reader: reader, compressAlg: compressAlg, blockSize: blockSize, headers: headers, truncatedBlock: -1}
+}
+
+// loadPageHeaders reads the headers of the data file from page_header_map of the backup
+func loadPageHeaders(ctx context.Context, backupFolder storage.Folder, file ContentFile) ([]pageHeader2, error) {
+	reader, err := storage.ReadObjectRange(ctx, backupFolder, PageHeaderMapFile, file.HeaderOffset, file.HeaderSize)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read page headers of %s", file.Path)
+	}
+	defer utility.LoggedClose(reader, "")
+	zlibReader, err := zlib.NewReader(reader)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decompress page headers of %s", file.Path)
+	}
+	data := make([]byte, (file.HeadersCount+1)*backupPageHeader2Size)
+	if _, err := io.ReadFull(zlibReader, data); err != nil {
+		return nil, errors.Wrapf(err, "failed to decompress page headers of %s", file.Path)
+	}
+
+	headers := make([]pageHeader2, file.HeadersCount+1)
+	for i := range headers {
+		entry := data[i*backupPageHeader2Size:]
+		headers[i] = pageHeader2{
+			block: int64(int32(binary.LittleEndian.Uint32(entry[8:]))),
+			pos:   int64(int32(binary.LittleEndian.Uint32(entry[12:]))),
+		}
+	}
+	return headers, nil
+}
+
+// Next returns the next page, io.EOF after the last one
+func (reader *dataFileReader) Next() (*dataPage, error) {
+	if reader.headers != nil {
+		return reader.nextMapped()
+	}
+	return reader.nextInline()
+}
+
+func (reader *dataFileReader) nextMapped() (*dataPage, error) {
+	if reader.next+1 >= len(reader.headers) {
+		return nil, io.EOF
+	}
+	header, nextHeader := reader.headers[reader.next], reader.headers[reader.next+1]
+	reader.next++
+	compressed := make([]byte, nextHeader.pos-header.pos)
+	if _, err := io.ReadFull(reader.reader, compressed); err != nil {
+		return nil, err
+	}
+	data, err := reader.decompressPage(compressed)
+	if err != nil {
+		return nil, errors.Wrapf(err, "block %d", header.block)
+	}
+	return &dataPage{block: header.block, data: data}, nil
+}
+
+func (reader *dataFileReader) nextInline() (*dataPage, error) {
+	for {
+		var header [backupPageHeaderSize]byte
+		if _, err := io.ReadFull(reader.reader, header[:]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				return nil, errors.New("truncated page header")
+			}
+			return nil, err
+		}
+		block := int64(int32(binary.LittleEndian.Uint32(header[0:])))
+		compressedSize := int64(int32(binary.LittleEndian.Uint32(header[4:])))
+		switch {
+		case compressedSize == pageIsTruncated:
+			reader.truncatedBlock = block
+			continue
+		case compressedSize < 0:
+			continue
+		case compressedSize == 0:
+			return &dataPage{block: block, data: make([]byte, reader.blockSize)}, nil
+		}
+
+		alignedSize := (compressedSize + maxAlign - 1) / maxAlign * maxAlign
+		compressed := make([]byte, alignedSize)
+		if _, err := io.ReadFull(reader.reader, compressed); err != nil {
+			return nil, errors.Wrapf(err, "block %d", block)
+		}
+		data, err := reader.decompressPage(compressed[:compressedSize])
+		if err != nil {
+			return nil, errors.Wrapf(err, "block %d", block)
+		}
+		return &dataPage{block: block, data: data}, nil
+	}
+}
+
+func (reader *dataFileReader) decompressPage(compressed []byte) ([]byte, error) {
+	if int64(len(compressed)) == reader.blockSize {
+		return compressed, nil
+	}
+	page := make([]byte, reader.blockSize)
+	switch reader.compressAlg {
+	case "zlib":
+		zlibReader, err := zlib.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(zlibReader, page); err != nil {
+			return nil, err
+		}
+		return page, nil
+	case "pglz":
+		if err := pglzDecompress(compressed, page); err != nil {
+			return nil, err
+		}
+		return page, nil
+	default:
+		return nil, errors.Errorf("page of %d bytes with compression %q", len(compressed), reader.compressAlg)
+	}
+}
+
+// pglzDecompress decompresses the PostgreSQL LZ format, it fails unless the destination is filled completely
+func pglzDecompress(source, destination []byte) error {
+	sp, dp := 0, 0
+	for sp < len(source) && dp < len(destination) {
+		control := source[sp]
+		sp++
+		for bit := 0; bit < 8 && sp < len(source) && dp < len(destination); bit++ {
+			if control&1 == 0 {
+				destination[dp] = source[sp]
+				sp++
+				dp++
+				control >>= 1
+				continue
+			}
+			if sp+1 >= len(source) {
+				return errors.New("pglz: truncated match tag")
+			}
+			length := int(source[sp]&0x0f) + 3
+			offset := int(source[sp]&0xf0)<<4 | int(source[sp+1])
+			sp += 2
+			if length == 18 {
+				if sp >= len(source) {
+					return errors.New("pglz: truncated match length")
+				}
+				length += int(source[sp])
+				sp++
+			}
+			if offset == 0 || offset > dp {
+				return errors.New("pglz: invalid match offset")
+			}
+			length = min(length, len(destination)-dp)
+			// the match may overlap the output being written, so it is copied byte by byte
+			for i := 0; i < length; i++ {
+				destination[dp] = destination[dp-offset]
+				dp++
+			}
+			control >>= 1
+		}
+	}
+	if dp != len(destination) {
+		return errors.Errorf("pglz: decompressed %d bytes instead of %d", dp, len(destination))
+	}
+	return nil
+}
+
+// applyDataFile writes the pages of the data file stored in the backup to the local file
+func applyDataFile(reader *dataFileReader, localFile *os.File) error {
+	for {
+		page, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := localFile.WriteAt(page.data, page.block*reader.blockSize); err != nil {
+			return err
+		}
+	}
+	if reader.truncatedBlock >= 0 {
+		return localFile.Truncate(reader.truncatedBlock * reader.blockSize)
+	}
+	return nil
+}
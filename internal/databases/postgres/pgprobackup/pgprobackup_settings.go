@@ -0,0 +1,246 @@
+package pgprobackup
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	BackupsFolderName  = "backups"
+	WalFolderName      = "wal"
+	DatabaseFolderName = "database"
+	BackupControlFile  = "backup.control"
+	BackupContentFile  = "backup_content.control"
+	PageHeaderMapFile  = "page_header_map"
+
+	BackupModeFull   = "FULL"
+	BackupStatusOK   = "OK"
+	BackupStatusDone = "DONE"
+
+	// bytesInvalid is the size of the file which is not changed since the parent backup
+	bytesInvalid = -1
+)
+
+// BackupControl is the backup.control file pg_probackup keeps in the directory of every backup
+type BackupControl struct {
+	BackupID       string
+	Mode           string
+	Status         string
+	CompressAlg    string
+	ProgramVersion string
+	ServerVersion  string
+	Timeline       uint32
+	StartLsn       postgres.LSN
+	StopLsn        postgres.LSN
+	StartTime      time.Time
+	EndTime        time.Time
+	ParentBackupID string
+	BlockSize      int64
+}
+
+// IsValid reports whether the backup can be restored
+func (control *BackupControl) IsValid() bool {
+	return control.Status == BackupStatusOK || control.Status == BackupStatusDone
+}
+
+// ContentFile is the entry of backup_content.control
+type ContentFile struct {
+	Path           string
+	Kind           string
+	Mode           uint32
+	Size           int64
+	IsDatafile     bool
+	IsCfs          bool
+	CompressAlg    string
+	ExternalDirNum int
+	NBlocks        int64
+	Linked         string
+	// HeaderOffset, HeaderSize and HeadersCount locate the page headers of the data file in page_header_map,
+	// the data files of the backups taken by pg_probackup before 2.4 keep the headers inline
+	HeaderOffset int64
+	HeaderSize   int64
+	HeadersCount int64
+}
+
+// parseControlFile parses the "key = value" lines of backup.control
+func parseControlFile(reader io.Reader) (map[string]string, error) {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		values[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), "'")
+	}
+	return values, scanner.Err()
+}
+
+func newBackupControl(backupID string, values map[string]string) (*BackupControl, error) {
+	control := &BackupControl{
+		BackupID:       backupID,
+		Mode:           values["backup-mode"],
+		Status:         values["status"],
+		CompressAlg:    values["compress-alg"],
+		ProgramVersion: values["program-version"],
+		ServerVersion:  values["server-version"],
+		ParentBackupID: values["parent-backup-id"],
+		BlockSize:      postgres.DatabasePageSize,
+	}
+	var err error
+	if control.StartTime, err = postgres.ParseForeignBackupTime(values["start-time"]); err != nil {
+		return nil, errors.Wrap(err, "invalid start-time")
+	}
+	if control.EndTime, err = postgres.ParseForeignBackupTime(values["end-time"]); err != nil {
+		return nil, errors.Wrap(err, "invalid end-time")
+	}
+	if control.StartLsn, err = postgres.ParseLSN(values["start-lsn"]); err != nil {
+		return nil, errors.Wrap(err, "invalid start-lsn")
+	}
+	if stopLsn, ok := values["stop-lsn"]; ok {
+		if control.StopLsn, err = postgres.ParseLSN(stopLsn); err != nil {
+			return nil, errors.Wrap(err, "invalid stop-lsn")
+		}
+	}
+	timeline, err := strconv.ParseUint(values["timelineid"], 10, 32)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid timelineid")
+	}
+	control.Timeline = uint32(timeline)
+	if blockSize, ok := values["block-size"]; ok {
+		if control.BlockSize, err = strconv.ParseInt(blockSize, 10, 64); err != nil {
+			return nil, errors.Wrap(err, "invalid block-size")
+		}
+	}
+	return control, nil
+}
+
+// parseContentFile parses the line of backup_content.control, a JSON object with the string values
+func parseContentFile(line string) (ContentFile, error) {
+	var values map[string]string
+	if err := json.Unmarshal([]byte(line), &values); err != nil {
+		return ContentFile{}, err
+	}
+	file := ContentFile{
+		Path:        values["path"],
+		Kind:        values["kind"],
+		IsDatafile:  values["is_datafile"] == "1",
+		IsCfs:       values["is_cfs"] == "1",
+		CompressAlg: values["compress_alg"],
+		Linked:      values["linked"],
+		NBlocks:     -1,
+	}
+	integers := map[string]*int64{
+		"size": &file.Size, "n_blocks": &file.NBlocks, "hdr_off": &file.HeaderOffset,
+		"hdr_size": &file.HeaderSize, "n_headers": &file.HeadersCount,
+	}
+	for key, target := range integers {
+		value, ok := values[key]
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return ContentFile{}, errors.Wrapf(err, "invalid %s of %s", key, file.Path)
+		}
+		*target = parsed
+	}
+	if mode, ok := values["mode"]; ok {
+		parsed, err := strconv.ParseUint(mode, 10, 32)
+		if err != nil {
+			return ContentFile{}, errors.Wrapf(err, "invalid mode of %s", file.Path)
+		}
+		file.Mode = uint32(parsed)
+	}
+	if externalDirNum, ok := values["external_dir_num"]; ok {
+		parsed, err := strconv.Atoi(externalDirNum)
+		if err != nil {
+			return ContentFile{}, errors.Wrapf(err, "invalid external_dir_num of %s", file.Path)
+		}
+		file.ExternalDirNum = parsed
+	}
+	return file, nil
+}
+
+func backupsFolder(folder storage.Folder, instance string) storage.Folder {
+	return folder.GetSubFolder(BackupsFolderName).GetSubFolder(instance)
+}
+
+func LoadBackupControl(ctx context.Context, folder storage.Folder, instance, backupID string) (*BackupControl, error) {
+	reader, err := backupsFolder(folder, instance).GetSubFolder(backupID).ReadObject(ctx, BackupControlFile)
+	if err != nil {
+		return nil, err
+	}
+	defer utility.LoggedClose(reader, "")
+
+	values, err := parseControlFile(reader)
+	if err != nil {
+		return nil, err
+	}
+	control, err := newBackupControl(backupID, values)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s of backup %s", BackupControlFile, backupID)
+	}
+	return control, nil
+}
+
+// LoadBackupControls returns the valid backups of the instance
+func LoadBackupControls(ctx context.Context, folder storage.Folder, instance string) ([]*BackupControl, error) {
+	_, backupFolders, err := backupsFolder(folder, instance).ListFolder(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var controls []*BackupControl
+	for _, backupFolder := range backupFolders {
+		backupID := path.Base(strings.TrimSuffix(backupFolder.GetPath(), "/"))
+		control, err := LoadBackupControl(ctx, folder, instance, backupID)
+		if err != nil {
+			return nil, err
+		}
+		if !control.IsValid() {
+			continue
+		}
+		controls = append(controls, control)
+	}
+	return controls, nil
+}
+
+func LoadBackupContent(ctx context.Context, folder storage.Folder, instance, backupID string) (map[string]ContentFile, error) {
+	reader, err := backupsFolder(folder, instance).GetSubFolder(backupID).ReadObject(ctx, BackupContentFile)
+	if err != nil {
+		return nil, err
+	}
+	defer utility.LoggedClose(reader, "")
+
+	files := make(map[string]ContentFile)
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		file, err := parseContentFile(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s of backup %s", BackupContentFile, backupID)
+		}
+		files[file.Path] = file
+	}
+	return files, scanner.Err()
+}
@@ -0,0 +1,57 @@
+package pgprobackup
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/gzip"
+	"github.com/wal-g/wal-g/internal/ioextensions"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// openWalFile opens the WAL file of the archive, pg_probackup adds the .gz extension to the compressed files
+func openWalFile(ctx context.Context, walFolder storage.Folder, walFileName string) (io.ReadCloser, error) {
+	compressedName := walFileName + "." + gzip.FileExtension
+	exists, err := walFolder.Exists(ctx, compressedName)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return walFolder.ReadObject(ctx, walFileName)
+	}
+
+	reader, err := walFolder.ReadObject(ctx, compressedName)
+	if err != nil {
+		return nil, err
+	}
+	decompressed, err := compression.FindDecompressor(gzip.FileExtension).Decompress(reader)
+	if err != nil {
+		utility.LoggedClose(reader, "")
+		return nil, errors.Wrapf(err, "failed to decompress %s", compressedName)
+	}
+	return ioextensions.ReadCascadeCloser{
+		Reader: decompressed,
+		Closer: ioextensions.NewMultiCloser([]io.Closer{decompressed, reader}),
+	}, nil
+}
+
+func HandleWalFetch(ctx context.Context, folder storage.Folder, instance string, walFileName string, location string) error {
+	walFolder := folder.GetSubFolder(WalFolderName).GetSubFolder(instance)
+	reader, err := openWalFile(ctx, walFolder, walFileName)
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(reader, "")
+
+	file, err := os.OpenFile(location, os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_EXCL, 0666)
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(file, "")
+	_, err = utility.FastCopy(file, reader)
+	return err
+}
@@ -22,23 +22,46 @@ import (
 )
 
 // TODO : unit tests
-// HandleWALPrefetch is invoked by wal-fetch command to speed up database restoration
+// HandleWALPrefetch is invoked by wal-fetch command to speed up database restoration.
+// The number of prefetched segments and download concurrency adapt to the observed replay and download rates.
 func HandleWALPrefetch(ctx context.Context, folderReader internal.StorageFolderReader, walFileName string, location string) error {
 	var fileName = walFileName
 	location = path.Dir(location)
 	waitGroup := &sync.WaitGroup{}
-	concurrency, err := conf.GetMaxDownloadConcurrency()
+	planner, err := NewPrefetchPlanner()
 	if err != nil {
 		return fmt.Errorf("get max concurrency: %v", err)
 	}
+	prefetchLocation, _, _, _ := getPrefetchLocations(location, walFileName)
+	stateStorage := newPrefetchStateStorage(prefetchLocation)
+	state := stateStorage.ObserveFetch(walFileName)
+	concurrency, lookahead := planner.Plan(state)
+	tracelog.DebugLogger.Printf("WAL-prefetch %s: replay rate %.2f seg/s, download time %.2fs, concurrency %d, lookahead %d",
+		walFileName, state.ReplayRate, state.DownloadSeconds, concurrency, lookahead)
 
-	for i := 0; i < concurrency; i++ {
+	go func() {
+		CleanupPrefetchDirectories(walFileName, location, fsutil.FileSystemCleaner{})
+		planner.EnforcePrefetchDiskLimit(walFileName, location, fsutil.FileSystemCleaner{})
+	}()
+
+	walReader := newWalBundleCachingReader(folderReader.SubFolder(utility.WalPath))
+	downloadSlots := make(chan struct{}, concurrency)
+	downloadTimes := make(chan time.Duration, lookahead)
+	for i := 0; i < lookahead; i++ {
 		fileName, err = GetNextWalFilename(fileName)
 		if err != nil {
 			return fmt.Errorf("get next filename: %v", err)
 		}
+		select {
+		case downloadSlots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 		waitGroup.Add(1)
-		go prefetchFile(ctx, location, folderReader.SubFolder(utility.WalPath), fileName, waitGroup)
+		go func(fileName string) {
+			defer func() { <-downloadSlots }()
+			prefetchFile(ctx, location, walReader, fileName, waitGroup, downloadTimes)
+		}(fileName)
 
 		prefaultStartLsn, shouldPrefault, timelineID, err := shouldPrefault(fileName)
 		if err != nil {
@@ -52,9 +75,10 @@ func HandleWALPrefetch(ctx context.Context, folderReader internal.StorageFolderR
 		time.Sleep(10 * time.Millisecond) // ramp up in order
 	}
 
-	go CleanupPrefetchDirectories(walFileName, location, fsutil.FileSystemCleaner{})
-
 	waitGroup.Wait()
+	close(downloadTimes)
+
+	stateStorage.ObserveDownloads(downloadTimes)
 	return nil
 }
 
@@ -198,7 +222,7 @@ func (bundle *Bundle) prefaultFile(ctx context.Context, path string, info os.Fil
 
 // TODO : unit tests
 func prefetchFile(ctx context.Context, location string,
-	reader internal.StorageFolderReader, walFileName string, waitGroup *sync.WaitGroup) {
+	reader internal.StorageFolderReader, walFileName string, waitGroup *sync.WaitGroup, downloadTimes chan<- time.Duration) {
 	defer func() {
 		if r := recover(); r != nil {
 			tracelog.ErrorLogger.Println("WAL-prefetch unsuccessful ", walFileName, r)
@@ -221,11 +245,13 @@ func prefetchFile(ctx context.Context, location string,
 	}
 
 	tracelog.DebugLogger.Printf("File prefetched to %s", oldPath)
-	err = internal.DownloadFileTo(ctx, reader, walFileName, oldPath)
+	downloadStart := time.Now()
+	err = downloadWALFileTo(ctx, reader, walFileName, oldPath)
 	if err != nil {
 		tracelog.ErrorLogger.Printf("WAL-prefetch %s, download: %v", walFileName, err)
 	} else {
 		tracelog.DebugLogger.Printf("WAL-prefetch %s, download OK", walFileName)
+		downloadTimes <- time.Since(downloadStart)
 	}
 
 	_, errO = os.Stat(oldPath)
@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -671,6 +672,49 @@ func (queryRunner *PgQueryRunner) getTables(ctx context.Context) (map[string]Tab
 	return formatedTables, nil
 }
 
+// getOrioledbRelnodes returns the relnodes of the orioledb trees of the current database keyed by the table oid.
+// The result is empty if the orioledb extension is not installed in the database.
+func (queryRunner *PgQueryRunner) getOrioledbRelnodes(ctx context.Context) (map[uint32][]uint32, error) {
+	queryRunner.Mu.Lock()
+	defer queryRunner.Mu.Unlock()
+
+	conn := queryRunner.Connection
+	relnodes := make(map[uint32][]uint32)
+	var extensionSchema string
+	err := conn.QueryRow(ctx, "SELECT n.nspname FROM pg_catalog.pg_extension e "+
+		"JOIN pg_catalog.pg_namespace n ON e.extnamespace OPERATOR(pg_catalog.=) n.oid "+
+		"WHERE e.extname OPERATOR(pg_catalog.=) 'orioledb'").Scan(&extensionSchema)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return relnodes, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "QueryRunner GetOrioledbRelnodes: checking orioledb extension failed")
+	}
+
+	query := fmt.Sprintf("SELECT table_reloid, table_relnode, index_relnode FROM %s() "+
+		"WHERE datoid OPERATOR(pg_catalog.=) (SELECT oid FROM pg_catalog.pg_database "+
+		"WHERE datname OPERATOR(pg_catalog.=) pg_catalog.current_database())",
+		pgx.Identifier{extensionSchema, "orioledb_index_oids"}.Sanitize())
+	rows, err := conn.Query(ctx, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "QueryRunner GetOrioledbRelnodes: query failed")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tableOid, tableRelnode, indexRelnode uint32
+		if err := rows.Scan(&tableOid, &tableRelnode, &indexRelnode); err != nil {
+			return nil, errors.Wrap(err, "QueryRunner GetOrioledbRelnodes: scan failed")
+		}
+		for _, relnode := range []uint32{tableRelnode, indexRelnode} {
+			if !slices.Contains(relnodes[tableOid], relnode) {
+				relnodes[tableOid] = append(relnodes[tableOid], relnode)
+			}
+		}
+	}
+	return relnodes, rows.Err()
+}
+
 func (queryRunner *PgQueryRunner) processTables(ctx context.Context, conn *pgx.Conn,
 	getTablesQuery string, process func(relFileNode, oid uint32, tableName, namespaceName, parentTableName string)) error {
 	rows, err := conn.Query(ctx, getTablesQuery)
@@ -783,3 +827,61 @@ func (queryRunner *PgQueryRunner) IsStandby(ctx context.Context) (bool, error) {
 	}
 	return standby, nil
 }
+
+// buildGetStandbyStatus formats a query to get WAL receive/replay positions and upstream timeline of a standby
+func (queryRunner *PgQueryRunner) buildGetStandbyStatus() (string, error) {
+	switch {
+	case queryRunner.Version >= 100000:
+		return "SELECT pg_catalog.pg_last_wal_receive_lsn()::text, pg_catalog.pg_last_wal_replay_lsn()::text, " +
+			"CASE WHEN pg_catalog.pg_last_wal_receive_lsn() OPERATOR(pg_catalog.=) pg_catalog.pg_last_wal_replay_lsn() THEN 0 " +
+			"ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_catalog.pg_last_xact_replay_timestamp()), 0) END::float8, " +
+			"(SELECT received_tli FROM pg_catalog.pg_stat_wal_receiver LIMIT 1)", nil
+	case queryRunner.Version >= 90600:
+		return "SELECT pg_catalog.pg_last_xlog_receive_location()::text, pg_catalog.pg_last_xlog_replay_location()::text, " +
+			"CASE WHEN pg_catalog.pg_last_xlog_receive_location() OPERATOR(pg_catalog.=) pg_catalog.pg_last_xlog_replay_location() " +
+			"THEN 0 ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_catalog.pg_last_xact_replay_timestamp()), 0) END::float8, " +
+			"(SELECT received_tli FROM pg_catalog.pg_stat_wal_receiver LIMIT 1)", nil
+	case queryRunner.Version == 0:
+		return "", NewNoPostgresVersionError()
+	default:
+		return "", NewUnsupportedPostgresVersionError(queryRunner.Version)
+	}
+}
+
+// GetStandbyStatus reads replication progress of a standby server
+func (queryRunner *PgQueryRunner) GetStandbyStatus(ctx context.Context) (StandbyStatus, error) {
+	queryRunner.Mu.Lock()
+	defer queryRunner.Mu.Unlock()
+
+	query, err := queryRunner.buildGetStandbyStatus()
+	if err != nil {
+		return StandbyStatus{}, errors.Wrap(err, "GetStandbyStatus: building query failed")
+	}
+
+	var receiveLSN, replayLSN *string
+	var lagSeconds float64
+	var receivedTimeline *int32
+	err = queryRunner.Connection.QueryRow(ctx, query).Scan(&receiveLSN, &replayLSN, &lagSeconds, &receivedTimeline)
+	if err != nil {
+		return StandbyStatus{}, errors.Wrap(err, "GetStandbyStatus: query failed")
+	}
+	return newStandbyStatus(receiveLSN, replayLSN, lagSeconds, receivedTimeline)
+}
+
+// GetCurrentWalLsn returns the current WAL write location, valid only on a primary server
+func (queryRunner *PgQueryRunner) GetCurrentWalLsn(ctx context.Context) (LSN, error) {
+	lsnStr, err := queryRunner.getCurrentLsn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return ParseLSN(lsnStr)
+}
+
+// Checkpoint requests an immediate checkpoint, which is performed as a restartpoint on a standby
+func (queryRunner *PgQueryRunner) Checkpoint(ctx context.Context) error {
+	queryRunner.Mu.Lock()
+	defer queryRunner.Mu.Unlock()
+
+	_, err := queryRunner.Connection.Exec(ctx, "CHECKPOINT")
+	return errors.Wrap(err, "Checkpoint: CHECKPOINT failed")
+}
@@ -182,9 +182,14 @@ func (c *RatingTarBallComposer) FinishComposing() (internal.TarFileSets, error)
 	tarFileSets := internal.NewRegularTarFileSets()
 	tarFileSets.AddFiles(headersTarName, headersNames)
 
+	packGroup, packCtx := errgroup.WithContext(c.reqCtx)
 	for _, tarFilesCollection := range tarFilesCollections {
-		tarBall, err := c.tarBallQueue.Deque(c.reqCtx)
+		tarBall, err := c.tarBallQueue.Deque(packCtx)
 		if err != nil {
+			// the queue is canceled when some file can't be packed
+			if packErr := packGroup.Wait(); packErr != nil {
+				return nil, packErr
+			}
 			return nil, err
 		}
 		tarBall.SetUp(c.reqCtx, c.crypter)
@@ -193,20 +198,21 @@ func (c *RatingTarBallComposer) FinishComposing() (internal.TarFileSets, error)
 		}
 		// tarFilesCollection closure
 		tarFilesCollectionLocal := tarFilesCollection
-		go func() {
+		packGroup.Go(func() error {
 			for _, fileInfo := range tarFilesCollectionLocal.files {
-				err := c.tarFilePacker.PackFileIntoTar(c.reqCtx, &fileInfo.ComposeFileInfo, tarBall)
+				err := c.tarFilePacker.PackFileIntoTar(packCtx, &fileInfo.ComposeFileInfo, tarBall)
 				if err != nil {
-					panic(err)
+					return err
 				}
 			}
-			err := c.tarBallQueue.FinishTarBall(tarBall)
-			if err != nil {
-				panic(err)
-			}
-		}()
+			return c.tarBallQueue.FinishTarBall(tarBall)
+		})
 	}
 
+	err = packGroup.Wait()
+	if err != nil {
+		return nil, err
+	}
 	return tarFileSets, nil
 }
 
@@ -0,0 +1,193 @@
+package postgres
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/walparser"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+type RecordsCheckDetails struct {
+	SegmentsCount     int                   `json:"segments_count"`
+	RecordsCount      int                   `json:"records_count"`
+	CorruptedSegments []CorruptedWalSegment `json:"corrupted_segments"`
+}
+
+// CorruptedWalSegment describes the first problem found in the WAL segment
+type CorruptedWalSegment struct {
+	Segment string `json:"segment"`
+	Lsn     string `json:"lsn,omitempty"`
+	Error   string `json:"error"`
+}
+
+func (details RecordsCheckDetails) NewPlainTextReader() (io.Reader, error) {
+	var outputBuffer bytes.Buffer
+
+	fmt.Fprintf(&outputBuffer, "Segments checked: %d\n", details.SegmentsCount)
+	fmt.Fprintf(&outputBuffer, "Records checked: %d\n", details.RecordsCount)
+	if len(details.CorruptedSegments) == 0 {
+		return &outputBuffer, nil
+	}
+
+	tableWriter := table.NewWriter()
+	tableWriter.SetOutputMirror(&outputBuffer)
+	defer tableWriter.Render()
+
+	tableWriter.AppendHeader(table.Row{"Segment", "LSN", "Error"})
+	for _, row := range details.CorruptedSegments {
+		tableWriter.AppendRow(table.Row{row.Segment, row.Lsn, row.Error})
+	}
+
+	return &outputBuffer, nil
+}
+
+// RecordsCheckRunner downloads the WAL segments in storage starting from the earliest backup (or the specified one)
+// up to the current cluster segment and checks their page headers and record CRCs without restoring them
+type RecordsCheckRunner struct {
+	ctx         context.Context //nolint:containedctx // the check runners are run without a context
+	reader      internal.StorageFolderReader
+	segments    []WalSegmentDescription
+	concurrency int
+}
+
+func NewRecordsCheckRunner(
+	ctx context.Context,
+	rootFolder storage.Folder,
+	walFolderFilenames []string,
+	currentWalSegment WalSegmentDescription,
+	backupSearchParams BackupSearchParams,
+) (RecordsCheckRunner, error) {
+	walFolder := rootFolder.GetSubFolder(utility.WalPath)
+
+	timelineSwitchMap, err := createTimelineSwitchMap(ctx, currentWalSegment.Timeline, walFolder)
+	if err != nil {
+		return RecordsCheckRunner{}, errors.Wrap(err, "Failed to initialize timeline history map")
+	}
+	startWalSegmentNo, _, err := getStopWalSegmentNo(ctx, rootFolder, timelineSwitchMap,
+		currentWalSegment.Timeline, backupSearchParams)
+	if err != nil {
+		return RecordsCheckRunner{}, err
+	}
+
+	concurrency, err := conf.GetMaxDownloadConcurrency()
+	if err != nil {
+		return RecordsCheckRunner{}, errors.Wrap(err, "Failed to resolve MaxDownloadConcurrency")
+	}
+
+	return RecordsCheckRunner{
+		ctx:         ctx,
+		reader:      newWalBundleCachingReader(internal.NewFolderReader(walFolder)),
+		segments:    selectSegmentsToCheck(walFolderFilenames, startWalSegmentNo, currentWalSegment),
+		concurrency: concurrency,
+	}, nil
+}
+
+// selectSegmentsToCheck returns the storage segments in the range [start segment, current segment]
+// ordered by timeline, so that the records crossing the segment boundaries are checked
+func selectSegmentsToCheck(walFolderFilenames []string, startWalSegmentNo WalSegmentNo,
+	currentWalSegment WalSegmentDescription) []WalSegmentDescription {
+	segments := make([]WalSegmentDescription, 0)
+	for segment := range getSegmentsFromFiles(walFolderFilenames) {
+		if segment.Number < startWalSegmentNo || segment.Number > currentWalSegment.Number ||
+			segment.Timeline > currentWalSegment.Timeline {
+			continue
+		}
+		segments = append(segments, segment)
+	}
+	slices.SortFunc(segments, func(a, b WalSegmentDescription) int {
+		return cmp.Or(cmp.Compare(a.Timeline, b.Timeline), cmp.Compare(a.Number, b.Number))
+	})
+	return segments
+}
+
+type downloadedWalSegment struct {
+	data []byte
+	err  error
+}
+
+func (check RecordsCheckRunner) Run() (WalVerifyCheckResult, error) {
+	downloads := make(chan chan downloadedWalSegment, check.concurrency)
+	go func() {
+		defer close(downloads)
+		for _, segment := range check.segments {
+			download := make(chan downloadedWalSegment, 1)
+			downloads <- download
+			go func() {
+				download <- check.downloadSegment(segment)
+			}()
+		}
+	}()
+
+	defer func() {
+		// let the producer finish if the check is interrupted
+		for range downloads {
+		}
+	}()
+
+	details := RecordsCheckDetails{CorruptedSegments: make([]CorruptedWalSegment, 0)}
+	verifier := walparser.NewWalVerifier(WalSegmentSize)
+	for _, segment := range check.segments {
+		downloaded := <-<-downloads // the downloads are started in the order of the segments
+		if check.ctx.Err() != nil {
+			return WalVerifyCheckResult{}, check.ctx.Err()
+		}
+		details.SegmentsCount++
+		err := downloaded.err
+		if err == nil {
+			err = verifier.VerifySegment(bytes.NewReader(downloaded.data), walparser.XLogRecordPtr(segment.Number.firstLsn()))
+		}
+		if err == nil {
+			continue
+		}
+		tracelog.WarningLogger.Printf("WAL segment %s is corrupted: %v\n", segment.GetFileName(), err)
+		corrupted := CorruptedWalSegment{Segment: segment.GetFileName(), Error: err.Error()}
+		var corruptionErr walparser.WalCorruptionError
+		if errors.As(err, &corruptionErr) {
+			corrupted.Lsn = walparser.FormatLsn(corruptionErr.Lsn)
+		}
+		details.CorruptedSegments = append(details.CorruptedSegments, corrupted)
+	}
+	details.RecordsCount = verifier.RecordsCount
+
+	return newRecordsCheckResult(details), nil
+}
+
+func (check RecordsCheckRunner) downloadSegment(segment WalSegmentDescription) downloadedWalSegment {
+	reader, err := openWALFile(check.ctx, check.reader, segment.GetFileName())
+	if err != nil {
+		return downloadedWalSegment{err: err}
+	}
+	defer utility.LoggedClose(reader, "")
+	data, err := io.ReadAll(reader)
+	return downloadedWalSegment{data: data, err: err}
+}
+
+func (check RecordsCheckRunner) Type() WalVerifyCheckType {
+	return WalVerifyRecordsCheck
+}
+
+// newRecordsCheckResult check produces the WalVerifyCheckResult with status:
+// StatusOk if all the checked segments are correct
+// StatusWarning if there are no segments to check
+// StatusFailure if some segments are corrupted or could not be read
+func newRecordsCheckResult(details RecordsCheckDetails) WalVerifyCheckResult {
+	result := WalVerifyCheckResult{Status: StatusOk, Details: details}
+	switch {
+	case len(details.CorruptedSegments) > 0:
+		result.Status = StatusFailure
+	case details.SegmentsCount == 0:
+		result.Status = StatusWarning
+	}
+	return result
+}
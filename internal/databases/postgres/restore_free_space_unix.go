@@ -0,0 +1,29 @@
+//go:build !windows
+// +build !windows
+
+package postgres
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// getFreeSpace returns the space available to the unprivileged user and the device of the filesystem of the path
+func getFreeSpace(path string) (available uint64, device uint64, err error) {
+	var fs syscall.Statfs_t
+	if err = syscall.Statfs(path, &fs); err != nil {
+		return 0, 0, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, errors.Errorf("failed to get the device of %s", path)
+	}
+	//nolint:unconvert // the field types differ between the platforms
+	return fs.Bavail * uint64(fs.Bsize), uint64(stat.Dev), nil
+}
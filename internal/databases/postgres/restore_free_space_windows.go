@@ -0,0 +1,10 @@
+//go:build windows
+// +build windows
+
+package postgres
+
+import "github.com/pkg/errors"
+
+func getFreeSpace(path string) (available uint64, device uint64, err error) {
+	return 0, 0, errors.New("free space check is not supported on Windows")
+}
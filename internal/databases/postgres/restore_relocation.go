@@ -0,0 +1,290 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// relocatedConfigFiles are the configuration files in the data directory
+// which may refer to the original data directory
+var relocatedConfigFiles = []string{"postgresql.auto.conf", "postgresql.conf"}
+
+type InvalidRestoreRelocationError struct {
+	error
+}
+
+func newInvalidRestoreRelocationError(format string, args ...interface{}) InvalidRestoreRelocationError {
+	return InvalidRestoreRelocationError{errors.Errorf(format, args...)}
+}
+
+func (err InvalidRestoreRelocationError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+type InsufficientFreeSpaceError struct {
+	error
+}
+
+func newInsufficientFreeSpaceError(directory string, required, available uint64) InsufficientFreeSpaceError {
+	return InsufficientFreeSpaceError{errors.Errorf(
+		"not enough free space for %s: %d bytes are required, but only %d bytes are available",
+		directory, required, available)}
+}
+
+func (err InsufficientFreeSpaceError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// RestoreRelocation describes how backup-fetch moves the data directory and the tablespaces
+// to the new locations. It is an alternative to the hand-written restore specification:
+// the TablespaceSpec is generated from the one stored in the backup sentinel.
+type RestoreRelocation struct {
+	// tablespaces maps the original tablespace location or the tablespace OID to the new location
+	tablespaces map[string]string
+	// oldPgData is the original data directory, the one stored in the backup is used if empty
+	oldPgData string
+	// newPgData replaces oldPgData in the tablespace locations and the configuration files
+	newPgData      string
+	skipSpaceCheck bool
+}
+
+// NewRestoreRelocation parses the OLD=NEW pairs of --tablespace-map and the NEW or OLD=NEW value of --pgdata
+func NewRestoreRelocation(tablespaceMap []string, pgDataMap string, skipSpaceCheck bool) (*RestoreRelocation, error) {
+	relocation := &RestoreRelocation{tablespaces: make(map[string]string), skipSpaceCheck: skipSpaceCheck}
+	for _, mapping := range tablespaceMap {
+		oldLocation, newLocation, ok := strings.Cut(mapping, "=")
+		if !ok || oldLocation == "" {
+			return nil, newInvalidRestoreRelocationError("invalid tablespace mapping %q, expected OLD=NEW", mapping)
+		}
+		if !filepath.IsAbs(newLocation) {
+			return nil, newInvalidRestoreRelocationError("new tablespace location %q is not an absolute path", newLocation)
+		}
+		oldLocation = utility.NormalizePath(oldLocation)
+		if _, ok := relocation.tablespaces[oldLocation]; ok {
+			return nil, newInvalidRestoreRelocationError("tablespace %s is mapped more than once", oldLocation)
+		}
+		relocation.tablespaces[oldLocation] = utility.NormalizePath(newLocation)
+	}
+
+	if pgDataMap != "" {
+		oldPgData, newPgData, ok := strings.Cut(pgDataMap, "=")
+		if !ok {
+			oldPgData, newPgData = "", pgDataMap
+		}
+		if !filepath.IsAbs(newPgData) {
+			return nil, newInvalidRestoreRelocationError("new data directory %q is not an absolute path", newPgData)
+		}
+		relocation.oldPgData = utility.NormalizePath(oldPgData)
+		relocation.newPgData = utility.NormalizePath(newPgData)
+	}
+	return relocation, nil
+}
+
+// Empty reports whether the relocation was not requested
+func (relocation *RestoreRelocation) Empty() bool {
+	return relocation == nil || len(relocation.tablespaces) == 0 && relocation.newPgData == ""
+}
+
+// originalPgData returns the data directory the backup was taken from
+func originalPgData(ctx context.Context, backup Backup, sentinelSpec *TablespaceSpec) (string, error) {
+	if sentinelSpec != nil {
+		if basePrefix, ok := sentinelSpec.BasePrefix(); ok {
+			return basePrefix, nil
+		}
+	}
+	meta, err := backup.FetchMeta(ctx)
+	if err == nil && meta.DataDir != "" {
+		return utility.NormalizePath(meta.DataDir), nil
+	}
+	return "", newInvalidRestoreRelocationError(
+		"the original data directory is not stored in the backup, specify it as --pgdata OLD=NEW")
+}
+
+// makeTablespaceSpec generates the restore specification from the one stored in the backup sentinel.
+// The tablespace symlinks are created in dbDataDirectory and point to the new locations.
+func (relocation *RestoreRelocation) makeTablespaceSpec(sentinelSpec *TablespaceSpec,
+	dbDataDirectory string) (*TablespaceSpec, error) {
+	spec := NewTablespaceSpec(dbDataDirectory)
+	mapped := make(map[string]bool)
+	if sentinelSpec != nil {
+		for _, name := range sentinelSpec.TablespaceNames() {
+			location, ok := sentinelSpec.location(name)
+			if !ok {
+				return nil, errors.Errorf("tablespace %s has no location in the backup", name)
+			}
+			newLocation := location.Location
+			if mappedLocation, ok := relocation.tablespaces[name]; ok {
+				newLocation = mappedLocation
+				mapped[name] = true
+			} else if mappedLocation, ok := relocation.tablespaces[location.Location]; ok {
+				newLocation = mappedLocation
+				mapped[location.Location] = true
+			} else if relocation.newPgData != "" && utility.IsInDirectory(location.Location, relocation.oldPgData) {
+				newLocation = path.Join(relocation.newPgData,
+					utility.GetSubdirectoryRelativePath(location.Location, relocation.oldPgData))
+			}
+			tracelog.InfoLogger.Printf("Tablespace %s will be restored to %s", name, newLocation)
+			spec.AddTablespace(name, newLocation)
+		}
+	}
+
+	for oldLocation := range relocation.tablespaces {
+		if !mapped[oldLocation] {
+			return nil, newInvalidRestoreRelocationError("tablespace %s is not found in the backup", oldLocation)
+		}
+	}
+	return &spec, nil
+}
+
+// rewriteConfigFiles replaces the original data directory with the new one in the restored configuration files
+func (relocation *RestoreRelocation) rewriteConfigFiles(dbDataDirectory string) error {
+	oldPgData := relocation.oldPgData
+	if relocation.newPgData == "" || oldPgData == relocation.newPgData {
+		return nil
+	}
+	// match the whole path components only, so /data is not replaced in /data2
+	pattern := regexp.MustCompile(regexp.QuoteMeta(oldPgData) + `(/|'|"|\s|$)`)
+	for _, fileName := range relocatedConfigFiles {
+		filePath := path.Join(dbDataDirectory, fileName)
+		content, err := os.ReadFile(filePath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		rewritten := pattern.ReplaceAll(content, []byte(relocation.newPgData+"$1"))
+		if string(rewritten) == string(content) {
+			continue
+		}
+		tracelog.InfoLogger.Printf("Rewriting %s to %s in %s", oldPgData, relocation.newPgData, fileName)
+		if err = os.WriteFile(filePath, rewritten, 0600); err != nil {
+			return errors.Wrapf(err, "failed to rewrite %s", filePath)
+		}
+	}
+	return nil
+}
+
+// prepareRestore generates the tablespace restore specification and checks the free space of its locations
+func (relocation *RestoreRelocation) prepareRestore(ctx context.Context, backup Backup,
+	dbDataDirectory string) (*TablespaceSpec, error) {
+	sentinel, err := backup.GetSentinel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if relocation.newPgData != "" && relocation.oldPgData == "" {
+		if relocation.oldPgData, err = originalPgData(ctx, backup, sentinel.TablespaceSpec); err != nil {
+			return nil, err
+		}
+	}
+	spec, err := relocation.makeTablespaceSpec(sentinel.TablespaceSpec, dbDataDirectory)
+	if err != nil {
+		return nil, err
+	}
+	if relocation.skipSpaceCheck {
+		return spec, nil
+	}
+	return spec, checkRestoreFreeSpace(ctx, backup, sentinel, spec)
+}
+
+// FinishRestore rewrites the paths of the original data directory in the restored configuration files
+func (relocation *RestoreRelocation) FinishRestore(dbDataDirectory string) error {
+	if relocation.Empty() {
+		return nil
+	}
+	return relocation.rewriteConfigFiles(dbDataDirectory)
+}
+
+// checkRestoreFreeSpace checks that the filesystems of the data directory and the tablespaces
+// have enough free space for the backup. The sizes of the tablespaces are taken from the backup
+// manifest, if there is no manifest the whole DataCatalogSize is required from every filesystem.
+func checkRestoreFreeSpace(ctx context.Context, backup Backup, sentinel BackupSentinelDto, spec *TablespaceSpec) error {
+	dbDataDirectory, _ := spec.BasePrefix()
+	required := make(map[string]uint64)
+	if _, manifest, err := fetchBackupManifest(ctx, backup); err == nil {
+		for _, file := range manifest.Files {
+			required[restoreLocation(spec, file.Path)] += uint64(file.Size)
+		}
+	} else if sentinel.DataCatalogSize > 0 {
+		tracelog.WarningLogger.Printf("Failed to get the sizes of the tablespaces, "+
+			"expecting the whole backup in every location: %v", err)
+		required[dbDataDirectory] = uint64(sentinel.DataCatalogSize)
+		for _, location := range spec.tablespaceLocations() {
+			required[location.Location] = uint64(sentinel.DataCatalogSize)
+		}
+	} else {
+		tracelog.WarningLogger.Println("The backup size is unknown, skipping the free space check")
+		return nil
+	}
+	return checkFreeSpace(required)
+}
+
+// restoreLocation returns the directory the file of the backup is restored to
+func restoreLocation(spec *TablespaceSpec, filePath string) string {
+	if rest, ok := strings.CutPrefix(filePath, TablespaceFolder+"/"); ok {
+		name, _, _ := strings.Cut(rest, "/")
+		if location, ok := spec.location(name); ok {
+			return location.Location
+		}
+	}
+	basePrefix, _ := spec.BasePrefix()
+	return basePrefix
+}
+
+// checkFreeSpace sums up the required sizes of the directories located on the same filesystem
+// and compares them with the free space of the filesystem
+func checkFreeSpace(required map[string]uint64) error {
+	type filesystem struct {
+		directories []string
+		required    uint64
+		available   uint64
+	}
+	filesystems := make(map[uint64]*filesystem)
+	for directory, size := range required {
+		available, device, err := getFreeSpace(existingParent(directory))
+		if err != nil {
+			tracelog.WarningLogger.Printf("Failed to get the free space of %s, skipping the check: %v", directory, err)
+			continue
+		}
+		fs, ok := filesystems[device]
+		if !ok {
+			fs = &filesystem{available: available}
+			filesystems[device] = fs
+		}
+		fs.directories = append(fs.directories, directory)
+		fs.required += size
+	}
+	for _, fs := range filesystems {
+		directories := strings.Join(fs.directories, ", ")
+		if fs.required > fs.available {
+			return newInsufficientFreeSpaceError(directories, fs.required, fs.available)
+		}
+		tracelog.InfoLogger.Printf("Free space check passed for %s: %d bytes required, %d bytes available",
+			directories, fs.required, fs.available)
+	}
+	return nil
+}
+
+// existingParent returns the directory itself or its closest existing parent,
+// the tablespace locations may not exist before the restore
+func existingParent(directory string) string {
+	for {
+		if _, err := os.Stat(directory); err == nil {
+			return directory
+		}
+		parent := filepath.Dir(directory)
+		if parent == directory {
+			return directory
+		}
+		directory = parent
+	}
+}
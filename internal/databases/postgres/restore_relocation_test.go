@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makeSourceTablespaceSpec() *TablespaceSpec {
+	spec := NewTablespaceSpec("/old/pgdata")
+	spec.AddTablespace("16384", "/mnt/tbs1")
+	spec.AddTablespace("16385", "/old/pgdata/inner_tbs")
+	spec.AddTablespace("16386", "/mnt/tbs3")
+	return &spec
+}
+
+func TestNewRestoreRelocation(t *testing.T) {
+	relocation, err := NewRestoreRelocation([]string{"/mnt/tbs1/=/new/tbs1", "16386=/new/tbs3"}, "/new/pgdata", false)
+	require.NoError(t, err)
+	assert.False(t, relocation.Empty())
+	assert.Equal(t, map[string]string{"/mnt/tbs1": "/new/tbs1", "16386": "/new/tbs3"}, relocation.tablespaces)
+	assert.Equal(t, "", relocation.oldPgData)
+	assert.Equal(t, "/new/pgdata", relocation.newPgData)
+
+	relocation, err = NewRestoreRelocation(nil, "/old/pgdata=/new/pgdata/", false)
+	require.NoError(t, err)
+	assert.Equal(t, "/old/pgdata", relocation.oldPgData)
+	assert.Equal(t, "/new/pgdata", relocation.newPgData)
+
+	relocation, err = NewRestoreRelocation(nil, "", false)
+	require.NoError(t, err)
+	assert.True(t, relocation.Empty())
+	assert.True(t, (*RestoreRelocation)(nil).Empty())
+}
+
+func TestNewRestoreRelocation_Invalid(t *testing.T) {
+	for _, testCase := range []struct {
+		tablespaceMap []string
+		pgDataMap     string
+	}{
+		{tablespaceMap: []string{"/mnt/tbs1"}},
+		{tablespaceMap: []string{"=/new/tbs1"}},
+		{tablespaceMap: []string{"/mnt/tbs1=new/tbs1"}},
+		{tablespaceMap: []string{"/mnt/tbs1=/new/tbs1", "/mnt/tbs1/=/new/tbs2"}},
+		{pgDataMap: "new/pgdata"},
+	} {
+		_, err := NewRestoreRelocation(testCase.tablespaceMap, testCase.pgDataMap, false)
+		assert.IsType(t, InvalidRestoreRelocationError{}, err, testCase)
+	}
+}
+
+func TestRestoreRelocation_MakeTablespaceSpec(t *testing.T) {
+	relocation, err := NewRestoreRelocation([]string{"/mnt/tbs1=/new/tbs1", "16386=/new/tbs3"},
+		"/old/pgdata=/new/pgdata", false)
+	require.NoError(t, err)
+
+	spec, err := relocation.makeTablespaceSpec(makeSourceTablespaceSpec(), "/restore/pgdata")
+	require.NoError(t, err)
+	basePrefix, ok := spec.BasePrefix()
+	assert.True(t, ok)
+	assert.Equal(t, "/restore/pgdata", basePrefix)
+	assert.Equal(t, []string{"16384", "16385", "16386"}, spec.TablespaceNames())
+	for name, expected := range map[string]string{
+		"16384": "/new/tbs1",
+		"16385": "/new/pgdata/inner_tbs",
+		"16386": "/new/tbs3",
+	} {
+		location, ok := spec.location(name)
+		require.True(t, ok)
+		assert.Equal(t, TablespaceLocation{Location: expected, Symlink: "pg_tblspc/" + name}, location)
+	}
+}
+
+func TestRestoreRelocation_MakeTablespaceSpec_KeepsUnmapped(t *testing.T) {
+	relocation, err := NewRestoreRelocation([]string{"16384=/new/tbs1"}, "", false)
+	require.NoError(t, err)
+
+	spec, err := relocation.makeTablespaceSpec(makeSourceTablespaceSpec(), "/restore/pgdata")
+	require.NoError(t, err)
+	location, _ := spec.location("16384")
+	assert.Equal(t, "/new/tbs1", location.Location)
+	location, _ = spec.location("16385")
+	assert.Equal(t, "/old/pgdata/inner_tbs", location.Location)
+}
+
+func TestRestoreRelocation_MakeTablespaceSpec_UnknownTablespace(t *testing.T) {
+	relocation, err := NewRestoreRelocation([]string{"/mnt/tbs9=/new/tbs9"}, "", false)
+	require.NoError(t, err)
+
+	_, err = relocation.makeTablespaceSpec(makeSourceTablespaceSpec(), "/restore/pgdata")
+	assert.IsType(t, InvalidRestoreRelocationError{}, err)
+	_, err = relocation.makeTablespaceSpec(nil, "/restore/pgdata")
+	assert.IsType(t, InvalidRestoreRelocationError{}, err)
+}
+
+func TestRestoreRelocation_RewriteConfigFiles(t *testing.T) {
+	dataDirectory := t.TempDir()
+	autoConf := "# Do not edit this file manually!\n" +
+		"archive_command = 'cp %p /old/pgdata/archive/%f'\n" +
+		"hba_file = '/old/pgdata'\n" +
+		"ident_file = '/old/pgdata2/pg_ident.conf'\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dataDirectory, "postgresql.auto.conf"), []byte(autoConf), 0600))
+
+	relocation, err := NewRestoreRelocation(nil, "/old/pgdata=/new/pgdata", false)
+	require.NoError(t, err)
+	require.NoError(t, relocation.FinishRestore(dataDirectory))
+
+	rewritten, err := os.ReadFile(filepath.Join(dataDirectory, "postgresql.auto.conf"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Do not edit this file manually!\n"+
+		"archive_command = 'cp %p /new/pgdata/archive/%f'\n"+
+		"hba_file = '/new/pgdata'\n"+
+		"ident_file = '/old/pgdata2/pg_ident.conf'\n", string(rewritten))
+}
+
+func TestRestoreLocation(t *testing.T) {
+	relocation, err := NewRestoreRelocation([]string{"16384=/new/tbs1"}, "", false)
+	require.NoError(t, err)
+	spec, err := relocation.makeTablespaceSpec(makeSourceTablespaceSpec(), "/restore/pgdata")
+	require.NoError(t, err)
+
+	assert.Equal(t, "/new/tbs1", restoreLocation(spec, "pg_tblspc/16384/PG_17_202406281/5/16390"))
+	assert.Equal(t, "/mnt/tbs3", restoreLocation(spec, "pg_tblspc/16386/PG_17_202406281/5/16391"))
+	assert.Equal(t, "/restore/pgdata", restoreLocation(spec, "base/5/16392"))
+	assert.Equal(t, "/restore/pgdata", restoreLocation(spec, "pg_tblspc/99999/PG_17_202406281/5/16393"))
+}
+
+func TestCheckFreeSpace(t *testing.T) {
+	directory := t.TempDir()
+	// the missing tablespace location is checked at its closest existing parent
+	missing := filepath.Join(directory, "tablespaces", "tbs1")
+
+	assert.NoError(t, checkFreeSpace(map[string]uint64{directory: 1, missing: 1}))
+	err := checkFreeSpace(map[string]uint64{directory: math.MaxUint64 / 2, missing: math.MaxUint64 / 2})
+	assert.IsType(t, InsufficientFreeSpaceError{}, err)
+}
@@ -0,0 +1,252 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	standbyCatchupTimeout      = 5 * time.Minute
+	standbyCatchupPollInterval = time.Second
+)
+
+// StandbyStatus describes the replication progress of a standby server
+type StandbyStatus struct {
+	// ReceiveLSN is nil when the standby restores WAL from archive only
+	ReceiveLSN *LSN
+	ReplayLSN  LSN
+	ReplayLag  time.Duration
+	// ReceivedTimeline is the upstream timeline reported by the WAL receiver, 0 when it is not running
+	ReceivedTimeline uint32
+}
+
+func newStandbyStatus(receiveLSN, replayLSN *string, lagSeconds float64, receivedTimeline *int32) (StandbyStatus, error) {
+	status := StandbyStatus{ReplayLag: time.Duration(lagSeconds * float64(time.Second))}
+	if replayLSN == nil {
+		return status, errors.New("server is not in recovery")
+	}
+	lsn, err := ParseLSN(*replayLSN)
+	if err != nil {
+		return status, err
+	}
+	status.ReplayLSN = lsn
+	if receiveLSN != nil {
+		lsn, err := ParseLSN(*receiveLSN)
+		if err != nil {
+			return status, err
+		}
+		status.ReceiveLSN = &lsn
+	}
+	if receivedTimeline != nil {
+		status.ReceivedTimeline = uint32(*receivedTimeline)
+	}
+	return status, nil
+}
+
+// StandbyBackupInfo describes the primary server of a backup taken from a standby
+type StandbyBackupInfo struct {
+	PrimarySystemIdentifier *uint64
+	PrimaryTimeline         uint32
+}
+
+type StandbyLagError struct {
+	error
+}
+
+func newStandbyLagError(lag, maxLag time.Duration) StandbyLagError {
+	return StandbyLagError{errors.Errorf("standby replay lag %s exceeds %s (%s)", lag, maxLag, conf.StandbyMaxReplayLag)}
+}
+
+func (err StandbyLagError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+type StandbyTimelineError struct {
+	error
+}
+
+func newStandbyTimelineError(standbyTimeline, primaryTimeline uint32) StandbyTimelineError {
+	return StandbyTimelineError{errors.Errorf("standby is on timeline %d, but its primary is on timeline %d",
+		standbyTimeline, primaryTimeline)}
+}
+
+func (err StandbyTimelineError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// CheckStandbyStatus validates that the standby is close enough to its primary to take a backup from it
+func CheckStandbyStatus(status StandbyStatus, standbyTimeline uint32, maxReplayLag time.Duration) error {
+	if maxReplayLag > 0 && status.ReplayLag > maxReplayLag {
+		return newStandbyLagError(status.ReplayLag, maxReplayLag)
+	}
+	if status.ReceivedTimeline != 0 && status.ReceivedTimeline != standbyTimeline {
+		return newStandbyTimelineError(standbyTimeline, status.ReceivedTimeline)
+	}
+	return nil
+}
+
+// prepareStandbyBackup checks the standby replication state before pg_backup_start
+// and optionally forces a checkpoint on the primary, so that the backup starts from a recent restartpoint
+func (bh *BackupHandler) prepareStandbyBackup(ctx context.Context) (*StandbyBackupInfo, error) {
+	queryRunner := bh.Workers.QueryRunner
+	status, err := queryRunner.GetStandbyStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	standbyTimeline, err := queryRunner.ReadTimeline(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tracelog.InfoLogger.Printf("Backup from standby: replay LSN %s, replay lag %s, timeline %d, upstream timeline %d",
+		status.ReplayLSN, status.ReplayLag, standbyTimeline, status.ReceivedTimeline)
+	if status.ReceiveLSN == nil {
+		tracelog.WarningLogger.Println("WAL receiver is not running on the standby, cannot verify its primary timeline")
+	}
+
+	maxReplayLag, err := conf.GetDurationSettingDefault(conf.StandbyMaxReplayLag, 0)
+	if err != nil {
+		return nil, err
+	}
+	err = CheckStandbyStatus(status, standbyTimeline, maxReplayLag)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &StandbyBackupInfo{
+		PrimarySystemIdentifier: queryRunner.SystemIdentifier,
+		PrimaryTimeline:         status.ReceivedTimeline,
+	}
+	if viper.GetBool(conf.StandbyPrimaryCheckpoint) {
+		info, err = bh.coordinateWithPrimary(ctx, standbyTimeline)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if info.PrimaryTimeline == 0 {
+		info.PrimaryTimeline = standbyTimeline
+	}
+	return info, nil
+}
+
+// coordinateWithPrimary connects to the primary using the standby's primary_conninfo, verifies that both servers
+// belong to the same cluster, forces a checkpoint there and waits for the standby to replay it
+func (bh *BackupHandler) coordinateWithPrimary(ctx context.Context, standbyTimeline uint32) (*StandbyBackupInfo, error) {
+	standbyRunner := bh.Workers.QueryRunner
+	primaryConnInfo, err := standbyRunner.GetParameter(ctx, "primary_conninfo")
+	if err != nil {
+		return nil, err
+	}
+	if primaryConnInfo == "" {
+		return nil, errors.Errorf("%s is set, but primary_conninfo of the standby is empty", conf.StandbyPrimaryCheckpoint)
+	}
+
+	tracelog.DebugLogger.Println("Connecting to the primary server")
+	config, err := pgx.ParseConfig(primaryConnInfo)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse primary_conninfo")
+	}
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to the primary server")
+	}
+	defer utility.LoggedCloseContext(ctx, conn, "")
+
+	primaryRunner, err := NewPgQueryRunner(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	if primaryRunner.SystemIdentifier != nil && standbyRunner.SystemIdentifier != nil &&
+		*primaryRunner.SystemIdentifier != *standbyRunner.SystemIdentifier {
+		return nil, errors.Errorf("primary system identifier %d does not match standby system identifier %d",
+			*primaryRunner.SystemIdentifier, *standbyRunner.SystemIdentifier)
+	}
+
+	tracelog.InfoLogger.Println("Forcing checkpoint on the primary server")
+	err = primaryRunner.Checkpoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	checkpointLSN, err := primaryRunner.GetCurrentWalLsn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	primaryTimeline, err := primaryRunner.ReadTimeline(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if primaryTimeline != standbyTimeline {
+		return nil, newStandbyTimelineError(standbyTimeline, primaryTimeline)
+	}
+
+	err = waitForStandbyReplay(ctx, standbyRunner, checkpointLSN)
+	if err != nil {
+		return nil, err
+	}
+	return &StandbyBackupInfo{
+		PrimarySystemIdentifier: primaryRunner.SystemIdentifier,
+		PrimaryTimeline:         primaryTimeline,
+	}, nil
+}
+
+func waitForStandbyReplay(ctx context.Context, queryRunner *PgQueryRunner, lsn LSN) error {
+	tracelog.InfoLogger.Printf("Waiting for the standby to replay the primary checkpoint at %s", lsn)
+	ctx, cancel := context.WithTimeout(ctx, standbyCatchupTimeout)
+	defer cancel()
+	ticker := time.NewTicker(standbyCatchupPollInterval)
+	defer ticker.Stop()
+	for {
+		status, err := queryRunner.GetStandbyStatus(ctx)
+		if err != nil {
+			return err
+		}
+		if status.ReplayLSN >= lsn {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return errors.Errorf("standby did not replay LSN %s within %s (replayed up to %s)",
+				lsn, standbyCatchupTimeout, status.ReplayLSN)
+		}
+	}
+}
+
+// addStandbyPromotionChecker terminates the backup as soon as the standby is promoted:
+// the copied files can't be made consistent with WAL of the new timeline.
+func addStandbyPromotionChecker(ctx context.Context, queryRunner *PgQueryRunner, errCh chan error) error {
+	interval, err := conf.GetDurationSettingDefault(conf.PgAliveCheckInterval, time.Minute)
+	if err != nil {
+		return err
+	}
+	tracelog.InfoLogger.Printf("Initializing the standby promotion checker (interval=%s)...", interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			standby, err := queryRunner.IsStandby(ctx)
+			if err != nil {
+				tracelog.WarningLogger.Printf("Failed to check if the standby was promoted: %v", err)
+				continue
+			}
+			if !standby {
+				errCh <- errors.New("standby was promoted during backup")
+				return
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,65 @@
+package postgres_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+func TestCheckStandbyStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       postgres.StandbyStatus
+		timeline     uint32
+		maxReplayLag time.Duration
+		wantErr      interface{}
+	}{
+		{
+			name:     "caught up standby",
+			status:   postgres.StandbyStatus{ReplayLag: 0, ReceivedTimeline: 3},
+			timeline: 3,
+		},
+		{
+			name:         "lag within limit",
+			status:       postgres.StandbyStatus{ReplayLag: 10 * time.Second, ReceivedTimeline: 3},
+			timeline:     3,
+			maxReplayLag: time.Minute,
+		},
+		{
+			name:         "lag exceeds limit",
+			status:       postgres.StandbyStatus{ReplayLag: 2 * time.Minute, ReceivedTimeline: 3},
+			timeline:     3,
+			maxReplayLag: time.Minute,
+			wantErr:      postgres.StandbyLagError{},
+		},
+		{
+			name:     "lag is not checked without limit",
+			status:   postgres.StandbyStatus{ReplayLag: time.Hour},
+			timeline: 3,
+		},
+		{
+			name:     "upstream switched timeline",
+			status:   postgres.StandbyStatus{ReceivedTimeline: 4},
+			timeline: 3,
+			wantErr:  postgres.StandbyTimelineError{},
+		},
+		{
+			name:     "archive only standby",
+			status:   postgres.StandbyStatus{ReceivedTimeline: 0},
+			timeline: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := postgres.CheckStandbyStatus(tt.status, tt.timeline, tt.maxReplayLag)
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			assert.IsType(t, tt.wantErr, err)
+		})
+	}
+}
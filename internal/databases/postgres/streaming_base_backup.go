@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"io"
 	"iter"
+	"path"
 	"slices"
 	"strconv"
 	"strings"
@@ -20,6 +21,7 @@ import (
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgproto3"
 	"github.com/pkg/errors"
+	"github.com/spf13/viper"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/ioextensions"
@@ -44,6 +46,12 @@ type StreamingBaseBackup struct {
 	uploader         internal.Uploader
 	fileNo           int
 	pgVersion        int
+	// Manifest holds the backup_manifest sent by Postgres, it is requested on PG15+ only
+	Manifest []byte
+	// IncrementFromName is the name of the base backup of the delta backup, it is empty for the full backup
+	IncrementFromName string
+	// IncrementFromManifest is the backup_manifest of the base backup, if set BASE_BACKUP sends PG17 increments
+	IncrementFromManifest []byte
 }
 
 // NewStreamingBaseBackup will define a new StreamingBaseBackup object
@@ -56,15 +64,25 @@ func NewStreamingBaseBackup(pgDataDir string, maxTarSize int64, pgVersion int, p
 	}
 }
 
-// Start will start a base_backup read the backup info, and prepare for uploading tar files
-func (bb *StreamingBaseBackup) Start(ctx context.Context, verifyChecksum bool, diskLimit int32) (err error) {
+// Start will start a base_backup read the backup info, and prepare for uploading tar files.
+// The page checksums are verified by wal-g on the streamed pages, so Postgres does not verify them.
+func (bb *StreamingBaseBackup) Start(ctx context.Context, diskLimit int32) (err error) {
 	options := pglogrepl.BaseBackupOptions{
 		// Following implementation for local backup.
 		Fast:              true,
 		TablespaceMap:     true,
 		Label:             "wal-g",
-		NoVerifyChecksums: !verifyChecksum,
+		NoVerifyChecksums: true,
 		MaxRate:           diskLimit,
+		// before PG15 the manifest comes in an extra CopyOut session, which is not handled
+		Manifest: bb.pgVersion >= 150000,
+	}
+	if bb.IncrementFromManifest != nil {
+		err = pglogrepl.UploadManifest(ctx, bb.pgConn, bytes.NewReader(bb.IncrementFromManifest))
+		if err != nil {
+			return err
+		}
+		options.Incremental = true
 	}
 	result, err := pglogrepl.StartBaseBackup(ctx, bb.pgConn, options)
 	if err != nil {
@@ -128,8 +146,14 @@ func remapsForArchive(arch *archive) (TarballStreamerRemaps, []string, error) {
 // TarballStreamer (and therefore a fresh inner tar.Reader per archive),
 // rotating into wal-g part files when maxTarSize is exceeded. The Tee tar
 // (pg_control) is uploaded at the end from the streamer that produced it.
-func (bb *StreamingBaseBackup) Upload(ctx context.Context, uploader internal.Uploader, bundleFiles internal.BundleFiles) error {
+// The regular files are stored by the fileHandler, the content of every part is recorded to tarFileSets.
+func (bb *StreamingBaseBackup) Upload(ctx context.Context, uploader internal.Uploader,
+	fileHandler *streamingFileHandler, tarFileSets internal.TarFileSets) error {
 	bb.uploader = uploader
+	crypter, err := internal.ConfigureCrypterForSpecificConfig(viper.GetViper())
+	if err != nil {
+		return errors.Wrap(err, "can't configure crypter")
+	}
 
 	var teeStreamer *TarballStreamer
 
@@ -137,7 +161,8 @@ func (bb *StreamingBaseBackup) Upload(ctx context.Context, uploader internal.Upl
 		if err != nil {
 			return err
 		}
-		streamer := NewTarballStreamer(arch.reader, bb.maxTarSize, bundleFiles)
+		streamer := NewTarballStreamer(arch.reader, bb.maxTarSize, fileHandler.files)
+		streamer.FileHandler = fileHandler
 		remaps, tee, err := remapsForArchive(arch)
 		if err != nil {
 			return err
@@ -149,12 +174,13 @@ func (bb *StreamingBaseBackup) Upload(ctx context.Context, uploader internal.Upl
 		}
 
 		for {
-			tbsTar := ioextensions.NewNamedReaderImpl(streamer, bb.FileName())
-			compressedFile := internal.CompressAndEncrypt(tbsTar, uploader.Compression(), internal.ConfigureCrypter())
+			tbsTar := ioextensions.NewNamedReaderImpl(bb.countStoredSize(streamer), bb.FileName())
+			compressedFile := internal.CompressAndEncrypt(tbsTar, uploader.Compression(), crypter)
 			dstPath := utility.AddFileExtension(bb.Path(), uploader.Compression().FileExtension())
 			if err := uploader.Upload(ctx, dstPath, compressedFile); err != nil {
 				return err
 			}
+			tarFileSets.AddFiles(path.Base(dstPath), streamer.TakePartFiles())
 			bb.fileNo++
 			if streamer.ArchiveDone() {
 				break
@@ -170,8 +196,8 @@ func (bb *StreamingBaseBackup) Upload(ctx context.Context, uploader internal.Upl
 	}
 
 	if teeStreamer != nil {
-		teeTar := ioextensions.NewNamedReaderImpl(teeStreamer.TeeIo, bb.FileName())
-		teeCompressedFile := internal.CompressAndEncrypt(teeTar, bb.uploader.Compression(), internal.ConfigureCrypter())
+		teeTar := ioextensions.NewNamedReaderImpl(bb.countStoredSize(teeStreamer.TeeIo), bb.FileName())
+		teeCompressedFile := internal.CompressAndEncrypt(teeTar, bb.uploader.Compression(), crypter)
 		teeFileName := utility.AddFileExtension("pg_control.tar", bb.uploader.Compression().FileExtension())
 		teeFilePath := storage.JoinPath(bb.BackupName(), internal.TarPartitionFolderName, teeFileName)
 		if err := bb.uploader.Upload(ctx, teeFilePath, teeCompressedFile); err != nil {
@@ -179,12 +205,34 @@ func (bb *StreamingBaseBackup) Upload(ctx context.Context, uploader internal.Upl
 		}
 	}
 
-	return nil
+	bb.Manifest, err = fileHandler.adjustManifest(bb.Manifest)
+	return err
+}
+
+// countStoredSize makes UncompressedSize account the bytes read from the tar
+func (bb *StreamingBaseBackup) countStoredSize(tar io.Reader) io.Reader {
+	return &sizeCountingReader{Reader: tar, size: &bb.UncompressedSize}
+}
+
+// sizeCountingReader adds the number of the read bytes to size
+type sizeCountingReader struct {
+	io.Reader
+	size *int64
+}
+
+func (reader *sizeCountingReader) Read(p []byte) (int, error) {
+	n, err := reader.Reader.Read(p)
+	*reader.size += int64(n)
+	return n, err
 }
 
 // BackupName returns the name of the folder where the backup should be stored.
 func (bb *StreamingBaseBackup) BackupName() string {
-	return "base_" + formatWALFileName(bb.TimeLine, uint64(bb.StartLSN)/WalSegmentSize)
+	name := "base_" + formatWALFileName(bb.TimeLine, uint64(bb.StartLSN)/WalSegmentSize)
+	if bb.IncrementFromName != "" {
+		name += "_D_" + utility.StripWalFileName(bb.IncrementFromName)
+	}
+	return name
 }
 
 // FileName returns the filename of a tablespace backup file.
@@ -204,7 +252,7 @@ func (bb *StreamingBaseBackup) Path() string {
 func (bb *StreamingBaseBackup) GetTablespaceSpec() *TablespaceSpec {
 	spec := NewTablespaceSpec(bb.dataDir)
 	for _, tbs := range bb.tablespaces {
-		spec.addTablespace(fmt.Sprintf("%d", tbs.OID), tbs.Location)
+		spec.AddTablespace(fmt.Sprintf("%d", tbs.OID), tbs.Location)
 	}
 	return &spec
 }
@@ -279,7 +327,6 @@ func (r *compatReader) Read(p []byte) (int, error) {
 	}
 	n := copy(p, r.chunk[r.chunkPos:])
 	r.chunkPos += n
-	r.bb.UncompressedSize += int64(n)
 	return n, nil
 }
 
@@ -361,7 +408,7 @@ type streamPump struct {
 	archiveEnd bool     // current archive done (boundary tag seen on wire)
 	streamEnd  bool     // CopyDone seen
 	pendingArc *archive // 'n' parsed but not yet yielded
-	inManifest bool     // 'm' seen, swallowing 'd' until CopyDone
+	inManifest bool     // 'm' seen, collecting 'd' into bb.Manifest until CopyDone
 }
 
 func (s *streamPump) run() {
@@ -425,6 +472,7 @@ func (s *streamPump) handleCopyData(data []byte) error {
 	switch tag {
 	case 'd':
 		if s.inManifest {
+			s.bb.Manifest = append(s.bb.Manifest, body...)
 			return nil
 		}
 		s.chunk = body
@@ -446,7 +494,7 @@ func (s *streamPump) handleCopyData(data []byte) error {
 		s.archiveEnd = true
 		s.pendingArc = arch
 	case 'm':
-		tracelog.WarningLogger.Print("BASE_BACKUP: manifest stream received but not requested; dropping")
+		tracelog.DebugLogger.Print("BASE_BACKUP: receiving backup manifest")
 		s.inManifest = true
 		s.archiveEnd = true
 	default:
@@ -511,6 +559,5 @@ func (s *streamPump) Read(p []byte) (int, error) {
 	}
 	n := copy(p, s.chunk[s.chunkPos:])
 	s.chunkPos += n
-	s.bb.UncompressedSize += int64(n)
 	return n, nil
 }
@@ -0,0 +1,366 @@
+package postgres
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/RoaringBitmap/roaring/v2"
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	pg_errors "github.com/wal-g/wal-g/internal/databases/postgres/errors"
+	"github.com/wal-g/wal-g/utility"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// serverIncrementPrefix is the file name prefix of the increments sent by BASE_BACKUP INCREMENTAL (PG17+)
+	serverIncrementPrefix = "INCREMENTAL."
+	// serverIncrementMagic starts every PG17 incremental file
+	serverIncrementMagic uint32 = 0xd3ae1f0d
+	// serverIncrementHeaderSize is the size of the magic number, the block count and the truncation block length
+	serverIncrementHeaderSize = 3 * sizeofInt32
+	// incrementalBackupLabelPrefix starts the backup_label lines which prevent PG17 from starting on the restored backup
+	incrementalBackupLabelPrefix = "INCREMENTAL FROM "
+)
+
+type InvalidServerIncrementError struct {
+	error
+}
+
+func newInvalidServerIncrementError(format string, args ...interface{}) InvalidServerIncrementError {
+	return InvalidServerIncrementError{errors.Errorf("invalid PostgreSQL incremental file: "+format, args...)}
+}
+
+func (err InvalidServerIncrementError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// streamingIncrementBase describes the backup the remote delta backup is taken from
+type streamingIncrementBase struct {
+	name  string
+	lsn   LSN
+	files internal.BackupFileList
+	// deltaMap, if set, selects the changed pages instead of the page LSN scan
+	deltaMap PagedFileDeltaMap
+	// manifest is the backup_manifest of the base backup, it is set when BASE_BACKUP sends the PG17 increments itself
+	manifest []byte
+}
+
+func (base *streamingIncrementBase) isServerIncremental() bool {
+	return base != nil && base.manifest != nil
+}
+
+// streamingFileHandler stores the files received from BASE_BACKUP the same way the local backup stores them:
+// the unchanged files are skipped, the changed paged files are stored as increments and the pages are verified.
+type streamingFileHandler struct {
+	files   internal.BundleFiles
+	options TarBallFilePackerOptions
+	// base is nil for the full backup
+	base *streamingIncrementBase
+	// dataCatalogSize is the total size of the data files, not the size of the stored content
+	dataCatalogSize int64
+	// partialFileSizes holds the full sizes of the files which are skipped or stored as increments
+	partialFileSizes map[string]int64
+	current          *streamingFile
+}
+
+// streamingFile is the state of the file being stored
+type streamingFile struct {
+	name          string
+	fileInfo      os.FileInfo
+	isIncremented bool
+	spool         *os.File
+	verifier      *errgroup.Group
+	verifierInput *io.PipeWriter
+	corruptBlocks []uint32
+}
+
+func newStreamingFileHandler(files internal.BundleFiles, options TarBallFilePackerOptions,
+	base *streamingIncrementBase) *streamingFileHandler {
+	return &streamingFileHandler{
+		files:            files,
+		options:          options,
+		base:             base,
+		partialFileSizes: make(map[string]int64),
+	}
+}
+
+// OpenFile implements TarballStreamerFileHandler
+func (handler *streamingFileHandler) OpenFile(header *tar.Header, input io.Reader) (io.Reader, error) {
+	file := &streamingFile{}
+	var content io.Reader = input
+	isServerIncrement := handler.base.isServerIncremental() &&
+		strings.HasPrefix(path.Base(header.Name), serverIncrementPrefix)
+	if isServerIncrement {
+		var fileSize int64
+		var err error
+		content, fileSize, err = readServerIncrement(header, input)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read the increment of %s", header.Name)
+		}
+		file.isIncremented = true
+		file.fileInfo = fileInfoWithSize(header, fileSize)
+	} else {
+		file.fileInfo = fileInfoWithSize(header, header.Size)
+	}
+	file.name = strings.TrimPrefix(header.Name, "./")
+	handler.dataCatalogSize += file.fileInfo.Size()
+
+	if handler.isUnchanged(file) {
+		tracelog.DebugLogger.Println("Skipped due to unchanged modification time: " + file.name)
+		return nil, handler.skipFile(header, file, input)
+	}
+
+	var err error
+	switch {
+	case handler.isLsnIncremented(file):
+		content, err = handler.readLsnIncrement(header, file, input)
+		if _, ok := err.(NoBitmapFoundError); ok {
+			tracelog.DebugLogger.Println("Skipped due to no changes in the delta map: " + file.name)
+			return nil, handler.skipFile(header, file, input)
+		}
+	case handler.base.isServerIncremental() && file.name == BackupLabelFilename:
+		content, err = stripIncrementalBackupLabel(header, input)
+	}
+	if err != nil {
+		_ = file.close()
+		return nil, err
+	}
+	if file.isIncremented {
+		handler.partialFileSizes[file.name] = file.fileInfo.Size()
+	}
+
+	if handler.options.verifyPageChecksums {
+		content = file.startVerification(content)
+	}
+	handler.current = file
+	return content, nil
+}
+
+// CloseFile implements TarballStreamerFileHandler
+func (handler *streamingFileHandler) CloseFile(header *tar.Header) error {
+	file := handler.current
+	handler.current = nil
+	if file == nil {
+		return errors.Errorf("file %s was not opened", header.Name)
+	}
+	if err := file.close(); err != nil {
+		return err
+	}
+	if handler.options.verifyPageChecksums {
+		handler.files.AddFileWithCorruptBlocks(header, file.fileInfo, file.isIncremented,
+			file.corruptBlocks, handler.options.storeAllCorruptBlocks)
+	} else {
+		handler.files.AddFile(header, file.fileInfo, file.isIncremented)
+	}
+	return nil
+}
+
+// isUnchanged reports whether the file has the same modification time as in the base backup.
+// pg_control is always stored since it is required to restore the backup.
+func (handler *streamingFileHandler) isUnchanged(file *streamingFile) bool {
+	if handler.base == nil || "/"+file.name == PgControlPath {
+		return false
+	}
+	baseFile, wasInBase := handler.base.files[file.name]
+	return wasInBase && file.fileInfo.ModTime().Equal(baseFile.MTime)
+}
+
+func (handler *streamingFileHandler) isLsnIncremented(file *streamingFile) bool {
+	if handler.base == nil || handler.base.isServerIncremental() || file.isIncremented {
+		return false
+	}
+	_, wasInBase := handler.base.files[file.name]
+	return wasInBase && isPagedFile(file.fileInfo, file.name)
+}
+
+func (handler *streamingFileHandler) skipFile(header *tar.Header, file *streamingFile, input io.Reader) error {
+	if _, err := io.Copy(io.Discard, input); err != nil {
+		return err
+	}
+	handler.partialFileSizes[file.name] = file.fileInfo.Size()
+	handler.files.AddSkippedFile(header, file.fileInfo)
+	return nil
+}
+
+// readLsnIncrement spools the paged file to a temporary file and makes the increment of the pages
+// changed since the base backup. The file is stored in full if it has invalid pages.
+func (handler *streamingFileHandler) readLsnIncrement(header *tar.Header, file *streamingFile,
+	input io.Reader) (io.Reader, error) {
+	var err error
+	var deltaBitmap *roaring.Bitmap
+	if handler.base.deltaMap != nil {
+		deltaBitmap, err = handler.base.deltaMap.GetDeltaBitmapFor(file.name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	file.spool, err = os.CreateTemp("", "walg-streamed-file-")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the spool file")
+	}
+	if _, err = io.Copy(file.spool, input); err != nil {
+		return nil, errors.Wrapf(err, "failed to spool %s", file.name)
+	}
+	if _, err = file.spool.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	pageReader := &IncrementalPageReader{PagedFile: file.spool, FileSize: header.Size, Lsn: handler.base.lsn}
+	incrementSize, err := pageReader.initialize(deltaBitmap)
+	switch err.(type) {
+	case nil:
+		file.isIncremented = true
+		header.Size = incrementSize
+		return pageReader, nil
+	case pg_errors.InvalidBlockError:
+		tracelog.WarningLogger.Printf("failed to read file '%s' as incremented\n", file.name)
+		_, err = file.spool.Seek(0, io.SeekStart)
+		return file.spool, err
+	default:
+		return nil, err
+	}
+}
+
+// startVerification verifies the pages of the content while it is read
+func (file *streamingFile) startVerification(content io.Reader) io.Reader {
+	pipeReader, pipeWriter := io.Pipe()
+	file.verifierInput = pipeWriter
+	file.verifier = &errgroup.Group{}
+	file.verifier.Go(func() error {
+		corruptBlocks, err := verifyFile(file.name, file.fileInfo, pipeReader, file.isIncremented)
+		if err == nil {
+			// the verifier may stop before the end of the content, the rest is drained to not block the reader
+			_, err = io.Copy(io.Discard, pipeReader)
+		}
+		_ = pipeReader.CloseWithError(err)
+		file.corruptBlocks = corruptBlocks
+		return err
+	})
+	return io.TeeReader(content, pipeWriter)
+}
+
+// close waits for the verification to finish and removes the spool file
+func (file *streamingFile) close() error {
+	var err error
+	if file.verifier != nil {
+		_ = file.verifierInput.Close()
+		err = file.verifier.Wait()
+	}
+	if file.spool != nil {
+		utility.LoggedClose(file.spool, "failed to close the spool file")
+		if removeErr := os.Remove(file.spool.Name()); removeErr != nil {
+			tracelog.WarningLogger.Printf("Failed to remove the spool file %s: %v", file.spool.Name(), removeErr)
+		}
+	}
+	return err
+}
+
+// adjustManifest makes the backup_manifest sent by Postgres describe the files the same way the local backup does:
+// the increments are listed under the names of the files with their full sizes and without checksums
+func (handler *streamingFileHandler) adjustManifest(data []byte) ([]byte, error) {
+	if len(data) == 0 || len(handler.partialFileSizes) == 0 {
+		return data, nil
+	}
+	manifest, err := ParseBackupManifest(data)
+	if err != nil {
+		return nil, err
+	}
+	for i, file := range manifest.Files {
+		name := file.Path
+		if handler.base.isServerIncremental() {
+			name = path.Join(path.Dir(name), strings.TrimPrefix(path.Base(name), serverIncrementPrefix))
+		}
+		fileSize, isPartial := handler.partialFileSizes[name]
+		if !isPartial {
+			continue
+		}
+		manifest.Files[i].Path = name
+		manifest.Files[i].Size = fileSize
+		manifest.Files[i].ChecksumAlgorithm = ""
+		manifest.Files[i].Checksum = ""
+	}
+	return manifest.Marshal()
+}
+
+// readServerIncrement converts the PG17 incremental file into the wal-g increment and renames the header after the file.
+// The PG17 file starts with the magic number, the block count, the truncation block length and the block numbers
+// padded to the page size if there are any blocks, the blocks follow.
+func readServerIncrement(header *tar.Header, input io.Reader) (content io.Reader, fileSize int64, err error) {
+	var fields [3]uint32
+	if err = binary.Read(input, binary.LittleEndian, &fields); err != nil {
+		return nil, 0, err
+	}
+	magic, blockCount, truncationBlockLength := fields[0], fields[1], fields[2]
+	if magic != serverIncrementMagic {
+		return nil, 0, newInvalidServerIncrementError("unexpected magic number %x", magic)
+	}
+	if int64(blockCount)*DatabasePageSize+serverIncrementHeaderSize > header.Size {
+		return nil, 0, newInvalidServerIncrementError("%d blocks do not fit into %d bytes", blockCount, header.Size)
+	}
+	blocks := make([]uint32, blockCount)
+	if err = binary.Read(input, binary.LittleEndian, blocks); err != nil {
+		return nil, 0, err
+	}
+	headerSize := int64(serverIncrementHeaderSize + sizeofInt32*len(blocks))
+	if blockCount > 0 && headerSize%DatabasePageSize != 0 {
+		padding := DatabasePageSize - headerSize%DatabasePageSize
+		if _, err = io.CopyN(io.Discard, input, padding); err != nil {
+			return nil, 0, err
+		}
+		headerSize += padding
+	}
+	dataSize := int64(blockCount) * DatabasePageSize
+	if headerSize+dataSize != header.Size {
+		return nil, 0, newInvalidServerIncrementError("expected %d bytes, got %d", headerSize+dataSize, header.Size)
+	}
+
+	// the file is as long as it was in the base backup unless it is truncated or extended by the increment
+	blockLength := truncationBlockLength
+	for _, blockNo := range blocks {
+		blockLength = max(blockLength, blockNo+1)
+	}
+	fileSize = int64(blockLength) * DatabasePageSize
+
+	var incrementHeader bytes.Buffer
+	incrementHeader.Write(IncrementFileHeader)
+	incrementHeader.Write(utility.ToBytes(uint64(fileSize)))
+	(&IncrementalPageReader{Blocks: blocks}).WriteDiffMapToHeader(&incrementHeader)
+
+	header.Name = path.Join(path.Dir(header.Name), strings.TrimPrefix(path.Base(header.Name), serverIncrementPrefix))
+	header.Size = int64(incrementHeader.Len()) + dataSize
+	return io.MultiReader(&incrementHeader, io.LimitReader(input, dataSize)), fileSize, nil
+}
+
+// stripIncrementalBackupLabel removes the lines which tell PG17 that the data directory is an incremental backup
+// to be combined by pg_combinebackup, wal-g applies the increments on restore itself
+func stripIncrementalBackupLabel(header *tar.Header, input io.Reader) (io.Reader, error) {
+	label, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	var stripped bytes.Buffer
+	for _, line := range strings.SplitAfter(string(label), "\n") {
+		if !strings.HasPrefix(line, incrementalBackupLabelPrefix) {
+			stripped.WriteString(line)
+		}
+	}
+	header.Size = int64(stripped.Len())
+	return &stripped, nil
+}
+
+// fileInfoWithSize describes the file of the tar entry as if it had the provided size
+func fileInfoWithSize(header *tar.Header, size int64) os.FileInfo {
+	fileHeader := *header
+	fileHeader.Name = strings.TrimPrefix(path.Base(header.Name), serverIncrementPrefix)
+	fileHeader.Size = size
+	return fileHeader.FileInfo()
+}
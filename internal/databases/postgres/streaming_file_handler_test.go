@@ -0,0 +1,216 @@
+package postgres
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+)
+
+func makeTestPage(lsn LSN, fill byte) []byte {
+	page := bytes.Repeat([]byte{fill}, int(DatabasePageSize))
+	binary.LittleEndian.PutUint32(page[0:], uint32(lsn>>32))
+	binary.LittleEndian.PutUint32(page[4:], uint32(lsn))
+	binary.LittleEndian.PutUint16(page[8:], 0) // no checksum
+	binary.LittleEndian.PutUint16(page[10:], 0)
+	binary.LittleEndian.PutUint16(page[12:], headerSize)
+	binary.LittleEndian.PutUint16(page[14:], uint16(DatabasePageSize))
+	binary.LittleEndian.PutUint16(page[16:], uint16(DatabasePageSize))
+	binary.LittleEndian.PutUint16(page[18:], uint16(DatabasePageSize)|4)
+	return page
+}
+
+func makeServerIncrement(truncationBlockLength uint32, blocks []uint32, pages ...[]byte) []byte {
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.LittleEndian, []uint32{serverIncrementMagic, uint32(len(blocks)), truncationBlockLength})
+	_ = binary.Write(&buf, binary.LittleEndian, blocks)
+	if len(blocks) > 0 {
+		buf.Write(make([]byte, int(DatabasePageSize)-buf.Len()))
+	}
+	for _, page := range pages {
+		buf.Write(page)
+	}
+	return buf.Bytes()
+}
+
+func TestReadServerIncrement(t *testing.T) {
+	pages := [][]byte{makeTestPage(0x200, 1), makeTestPage(0x300, 3)}
+	data := makeServerIncrement(2, []uint32{1, 3}, pages...)
+	header := &tar.Header{Name: "base/5/INCREMENTAL.16384", Size: int64(len(data))}
+
+	content, fileSize, err := readServerIncrement(header, bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, "base/5/16384", header.Name)
+	assert.Equal(t, 4*DatabasePageSize, fileSize)
+	increment, err := io.ReadAll(content)
+	require.NoError(t, err)
+	assert.Equal(t, header.Size, int64(len(increment)))
+
+	// the increment is applied to the file of the base backup
+	filePath := filepath.Join(t.TempDir(), "16384")
+	base := append(makeTestPage(0x100, 7), makeTestPage(0x100, 8)...)
+	require.NoError(t, os.WriteFile(filePath, base, 0600))
+	require.NoError(t, ApplyFileIncrement(filePath, bytes.NewReader(increment), false, false))
+	restored, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	require.Equal(t, fileSize, int64(len(restored)))
+	assert.Equal(t, base[:DatabasePageSize], restored[:DatabasePageSize])
+	assert.Equal(t, pages[0], restored[DatabasePageSize:2*DatabasePageSize])
+	assert.Equal(t, make([]byte, DatabasePageSize), restored[2*DatabasePageSize:3*DatabasePageSize])
+	assert.Equal(t, pages[1], restored[3*DatabasePageSize:])
+}
+
+func TestReadServerIncrement_Truncated(t *testing.T) {
+	data := makeServerIncrement(1, nil)
+	header := &tar.Header{Name: "base/5/INCREMENTAL.16384.1", Size: int64(len(data))}
+
+	content, fileSize, err := readServerIncrement(header, bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, "base/5/16384.1", header.Name)
+	assert.Equal(t, DatabasePageSize, fileSize)
+	increment, err := io.ReadAll(content)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(IncrementFileHeader)+sizeofInt64+sizeofInt32), int64(len(increment)))
+}
+
+func TestReadServerIncrement_InvalidMagic(t *testing.T) {
+	data := makeServerIncrement(1, nil)
+	data[0] ^= 0xff
+	header := &tar.Header{Name: "base/5/INCREMENTAL.16384", Size: int64(len(data))}
+
+	_, _, err := readServerIncrement(header, bytes.NewReader(data))
+	assert.IsType(t, InvalidServerIncrementError{}, err)
+}
+
+func TestStripIncrementalBackupLabel(t *testing.T) {
+	label := "START WAL LOCATION: 0/2000028 (file 000000010000000000000002)\n" +
+		"INCREMENTAL FROM LSN: 0/1000028\n" +
+		"INCREMENTAL FROM TLI: 1\n" +
+		"LABEL: wal-g\n"
+	header := &tar.Header{Name: BackupLabelFilename, Size: int64(len(label))}
+
+	content, err := stripIncrementalBackupLabel(header, bytes.NewBufferString(label))
+	require.NoError(t, err)
+	stripped, err := io.ReadAll(content)
+	require.NoError(t, err)
+	assert.Equal(t, "START WAL LOCATION: 0/2000028 (file 000000010000000000000002)\nLABEL: wal-g\n", string(stripped))
+	assert.Equal(t, int64(len(stripped)), header.Size)
+}
+
+type testStreamedFile struct {
+	name  string
+	data  []byte
+	mTime time.Time
+}
+
+func streamTestFiles(t *testing.T, handler *streamingFileHandler, files []testStreamedFile) (map[string][]byte, []string) {
+	var input bytes.Buffer
+	writer := tar.NewWriter(&input)
+	for _, file := range files {
+		require.NoError(t, writer.WriteHeader(&tar.Header{Name: file.name, Size: int64(len(file.data)),
+			Mode: 0600, ModTime: file.mTime, Typeflag: tar.TypeReg}))
+		_, err := writer.Write(file.data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+
+	streamer := NewTarballStreamer(&input, 1<<30, handler.files)
+	streamer.FileHandler = handler
+	output, err := io.ReadAll(streamer)
+	require.ErrorIs(t, err, nil)
+
+	stored := make(map[string][]byte)
+	reader := tar.NewReader(bytes.NewReader(output))
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		stored[header.Name], err = io.ReadAll(reader)
+		require.NoError(t, err)
+	}
+	return stored, streamer.TakePartFiles()
+}
+
+func TestStreamingFileHandler_Delta(t *testing.T) {
+	baseTime := time.Unix(1700000000, 0)
+	newTime := baseTime.Add(time.Hour)
+	changedPage := makeTestPage(0x200, 2)
+	relation := append(makeTestPage(0x10, 1), changedPage...)
+	base := &streamingIncrementBase{
+		name: "base_000000010000000000000002",
+		lsn:  0x100,
+		files: internal.BackupFileList{
+			"base/5/16384":      {MTime: baseTime},
+			"base/5/PG_VERSION": {MTime: baseTime},
+			"global/pg_control": {MTime: baseTime},
+		},
+	}
+	handler := newStreamingFileHandler(&internal.RegularBundleFiles{}, NewTarBallFilePackerOptions(true, false), base)
+
+	stored, partFiles := streamTestFiles(t, handler, []testStreamedFile{
+		{name: "base/5/16384", data: relation, mTime: newTime},
+		{name: "base/5/PG_VERSION", data: []byte("17\n"), mTime: baseTime},
+		{name: "global/pg_control", data: []byte("control"), mTime: baseTime},
+		{name: "base/5/16385", data: makeTestPage(0x10, 3), mTime: newTime},
+	})
+
+	assert.ElementsMatch(t, []string{"base/5/16384", "global/pg_control", "base/5/16385"}, partFiles)
+	assert.NotContains(t, stored, "base/5/PG_VERSION")
+	assert.Equal(t, []byte("control"), stored["global/pg_control"])
+	// the relation unknown to the base backup is stored in full
+	assert.Equal(t, makeTestPage(0x10, 3), stored["base/5/16385"])
+
+	fileSize, blockCount, diffMap, err := GetIncrementHeaderFields(bytes.NewReader(stored["base/5/16384"]))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(len(relation)), fileSize)
+	assert.Equal(t, uint32(1), blockCount)
+	assert.Equal(t, uint32(1), binary.LittleEndian.Uint32(diffMap))
+	assert.Equal(t, changedPage, stored["base/5/16384"][len(stored["base/5/16384"])-int(DatabasePageSize):])
+
+	files := internal.BackupFileList{}
+	handler.files.GetUnderlyingMap().Range(func(key, value interface{}) bool {
+		files[key.(string)] = value.(internal.BackupFileDescription)
+		return true
+	})
+	assert.True(t, files["base/5/16384"].IsIncremented)
+	assert.True(t, files["base/5/PG_VERSION"].IsSkipped)
+	assert.False(t, files["global/pg_control"].IsSkipped)
+	assert.False(t, files["base/5/16385"].IsIncremented)
+	assert.Equal(t, int64(len(relation)+3+len("control"))+DatabasePageSize, handler.dataCatalogSize)
+}
+
+func TestStreamingFileHandler_AdjustManifest(t *testing.T) {
+	handler := newStreamingFileHandler(&internal.RegularBundleFiles{}, NewTarBallFilePackerOptions(false, false),
+		&streamingIncrementBase{manifest: []byte("{}")})
+	handler.partialFileSizes["base/5/16384"] = 2 * DatabasePageSize
+	source := &BackupManifest{
+		Version: 2,
+		Files: []BackupManifestFile{
+			{Path: "base/5/INCREMENTAL.16384", Size: 100, LastModified: "2024-01-01 00:00:00 GMT",
+				ChecksumAlgorithm: backupManifestChecksumAlgorithm, Checksum: "00000000"},
+			{Path: "base/5/PG_VERSION", Size: 3, LastModified: "2024-01-01 00:00:00 GMT",
+				ChecksumAlgorithm: backupManifestChecksumAlgorithm, Checksum: "11111111"},
+		},
+	}
+	data, err := source.Marshal()
+	require.NoError(t, err)
+
+	adjusted, err := handler.adjustManifest(data)
+	require.NoError(t, err)
+	manifest, err := ParseBackupManifest(adjusted)
+	require.NoError(t, err)
+	assert.Equal(t, "base/5/16384", manifest.Files[0].Path)
+	assert.Equal(t, 2*DatabasePageSize, manifest.Files[0].Size)
+	assert.False(t, manifest.Files[0].HasChecksum())
+	assert.True(t, manifest.Files[1].HasChecksum())
+}
@@ -92,7 +92,7 @@ func (spec *TablespaceSpec) BasePrefix() (string, bool) {
 	return "", false
 }
 
-func (spec *TablespaceSpec) addTablespace(symlinkName string, actualLocation string) {
+func (spec *TablespaceSpec) AddTablespace(symlinkName string, actualLocation string) {
 	actualLocation = utility.NormalizePath(actualLocation)
 	spec.tablespaceNames = append(spec.tablespaceNames, symlinkName)
 	spec.tablespaceLocationMap[symlinkName] = TablespaceLocation{
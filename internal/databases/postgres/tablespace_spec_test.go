@@ -13,7 +13,7 @@ import (
 
 func addTablespaces(spec *TablespaceSpec, strs []TablespaceLocation) {
 	for _, loc := range strs {
-		spec.addTablespace(loc.Symlink, loc.Location)
+		spec.AddTablespace(loc.Symlink, loc.Location)
 	}
 }
 
@@ -140,7 +140,7 @@ func TestIsTablespaceSymlink_SymlinkSlash(t *testing.T) {
 
 func TestMakeTablespaceSymlinkPath(t *testing.T) {
 	spec := NewTablespaceSpec("/psql/")
-	spec.addTablespace("1", "/home/ismirn0ff/space1/")
+	spec.AddTablespace("1", "/home/ismirn0ff/space1/")
 
 	marshalAndUnmarshal(t, &spec)
 
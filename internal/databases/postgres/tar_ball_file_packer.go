@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"hash"
 	"io"
 	"os"
 
@@ -36,6 +37,7 @@ func (err SkippedFileError) Error() string {
 type TarBallFilePackerOptions struct {
 	verifyPageChecksums   bool
 	storeAllCorruptBlocks bool
+	manifest              *BackupManifestBuilder
 }
 
 func NewTarBallFilePackerOptions(verifyPageChecksums, storeAllCorruptBlocks bool) TarBallFilePackerOptions {
@@ -87,6 +89,7 @@ func (p *TarBallFilePackerImpl) PackFileIntoTar(ctx context.Context, cfi *intern
 			// File was deleted before opening.
 			// We should ignore file here as if it did not exist.
 			tracelog.WarningLogger.Println(err)
+			p.options.manifest.RemoveFile(cfi.Header.Name)
 			return nil
 		default:
 			return err
@@ -94,6 +97,15 @@ func (p *TarBallFilePackerImpl) PackFileIntoTar(ctx context.Context, cfi *intern
 	}
 	errorGroup, _ := errgroup.WithContext(ctx)
 
+	var checksum hash.Hash32
+	if !cfi.IsIncremented {
+		checksum = NewCRC32CHash()
+		fileReadCloser = &ioextensions.ReadCascadeCloser{
+			Reader: io.TeeReader(fileReadCloser, checksum),
+			Closer: fileReadCloser,
+		}
+	}
+
 	if p.options.verifyPageChecksums {
 		var secondReadCloser io.ReadCloser
 		// newTeeReadCloser is used to provide the fileReadCloser to two consumers:
@@ -125,6 +137,9 @@ func (p *TarBallFilePackerImpl) PackFileIntoTar(ctx context.Context, cfi *intern
 		if packedFileSize != cfi.Header.Size {
 			return newTarSizeError(packedFileSize, cfi.Header.Size)
 		}
+		if checksum != nil {
+			p.options.manifest.AddFileWithChecksum(cfi.Header.Name, packedFileSize, cfi.FileInfo.ModTime(), checksum.Sum32())
+		}
 		return nil
 	})
 
@@ -179,6 +194,9 @@ func (p *TarBallFilePackerImpl) createFileReadCloser(ctx context.Context, cfi *i
 }
 
 func verifyFile(path string, fileInfo os.FileInfo, fileReader io.Reader, isIncremented bool) ([]uint32, error) {
+	if orioledb.IsOrioledbDataFile(fileInfo, path) {
+		return orioledb.VerifyDataFile(path, fileInfo.Size(), fileReader, isIncremented)
+	}
 	if !isChecksumValidatableFile(fileInfo, path) {
 		tracelog.DebugLogger.Printf(
 			"verifyFile: %s does not meet the criteria for checksum validation. "+
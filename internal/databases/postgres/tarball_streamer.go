@@ -37,10 +37,21 @@ func NewTarballStreamerRemap(from string, to string) (tsr *TarballStreamerRemap,
 	return &TarballStreamerRemap{from: fromRe, to: to}, nil
 }
 
+// TarballStreamerFileHandler decides how the regular files of the input tar are stored in the output tar
+type TarballStreamerFileHandler interface {
+	// OpenFile returns the content to store for the file and adjusts the header to it.
+	// The nil content means that the file is not stored, the input is drained by the handler then.
+	OpenFile(header *tar.Header, input io.Reader) (io.Reader, error)
+	// CloseFile is called once the content returned by OpenFile is fully read.
+	// The handler is responsible for adding the regular files to the list of processed files.
+	CloseFile(header *tar.Header) error
+}
+
 // TarballStreamer is used to modify tar files which are received streaming.
-// Two modifications are:
-// * remap: change (some of) the paths for files in the tar file, and
-// * tee: copy some files to a second tar file
+// Three modifications are:
+// * remap: change (some of) the paths for files in the tar file,
+// * tee: copy some files to a second tar file, and
+// * file handler: replace the content of the regular files, e.g. with the increments
 // In addition TarballStreamer maintains a list of files with their info
 type TarballStreamer struct {
 	// The tar stream we read from
@@ -53,6 +64,8 @@ type TarballStreamer struct {
 	curHeader *tar.Header
 	// Number of bytes read from current file from tar
 	fileReadIndex int64
+	// The content of the current file, either the tar stream or the one provided by FileHandler
+	fileInput io.Reader
 	// Buffer to read data from current file from tar
 	inputBuf []byte
 	// Index in buffer until where we have already forwarded to outputTar
@@ -75,6 +88,10 @@ type TarballStreamer struct {
 	Remaps TarballStreamerRemaps
 	// list of processed files
 	Files internal.BundleFiles
+	// optional handler of the regular files content
+	FileHandler TarballStreamerFileHandler
+	// names of the entries written to the current output tar
+	partFiles []string
 	// set when inputTar.Next() returns io.EOF, distinguishing natural archive
 	// end from errTarStreamerOutputEOF part rotation
 	inputExhausted bool
@@ -115,19 +132,60 @@ func (streamer *TarballStreamer) NextInputFile() (err error) {
 		return errTarInputHeaderAlreadySet
 	}
 
-	tracelog.DebugLogger.Printf("Next file")
-	streamer.curHeader, err = streamer.inputTar.Next()
-	if err != nil {
-		if err == io.EOF {
-			streamer.inputExhausted = true
+	for {
+		tracelog.DebugLogger.Printf("Next file")
+		streamer.curHeader, err = streamer.inputTar.Next()
+		if err != nil {
+			if err == io.EOF {
+				streamer.inputExhausted = true
+			}
+			return err
+		}
+		streamer.fileReadIndex = 0
+
+		streamer.remap()
+
+		isStored, err := streamer.openFile()
+		if err != nil {
+			return err
+		}
+		if isStored {
+			return streamer.addFile()
 		}
-		return err
 	}
-	streamer.fileReadIndex = 0
+}
 
-	streamer.remap()
+// TakePartFiles returns the names of the entries written to the output tar since the previous call
+func (streamer *TarballStreamer) TakePartFiles() []string {
+	partFiles := streamer.partFiles
+	streamer.partFiles = nil
+	return partFiles
+}
 
-	return streamer.addFile()
+func (streamer *TarballStreamer) isHandled(header *tar.Header) bool {
+	return streamer.FileHandler != nil && header.Typeflag == tar.TypeReg
+}
+
+// openFile sets up the content of the current file, it returns false if the file handler skipped the file
+func (streamer *TarballStreamer) openFile() (bool, error) {
+	streamer.fileInput = streamer.inputTar
+	if !streamer.isHandled(streamer.curHeader) {
+		return true, nil
+	}
+	content, err := streamer.FileHandler.OpenFile(streamer.curHeader, streamer.inputTar)
+	if err != nil || content == nil {
+		return false, err
+	}
+	streamer.fileInput = content
+	return true, nil
+}
+
+// closeFile notifies the file handler that the current file is fully read
+func (streamer *TarballStreamer) closeFile() error {
+	if !streamer.isHandled(streamer.curHeader) {
+		return nil
+	}
+	return streamer.FileHandler.CloseFile(streamer.curHeader)
 }
 
 // addFile adds the new file to the stream
@@ -159,10 +217,13 @@ func (streamer *TarballStreamer) addFile() (err error) {
 			break
 		}
 	}
+	streamer.partFiles = append(streamer.partFiles, streamer.curHeader.Name)
 	if !streamer.curHeader.FileInfo().IsDir() {
-		filePath := streamer.curHeader.Name
-		filePath = strings.TrimPrefix(filePath, "./")
-		streamer.Files.AddFileDescription(filePath, internal.BackupFileDescription{MTime: streamer.curHeader.ModTime})
+		if !streamer.isHandled(streamer.curHeader) {
+			filePath := streamer.curHeader.Name
+			filePath = strings.TrimPrefix(filePath, "./")
+			streamer.Files.AddFileDescription(filePath, internal.BackupFileDescription{MTime: streamer.curHeader.ModTime})
+		}
 		streamer.tarFileReadIndex += streamer.curHeader.Size
 	}
 	return nil
@@ -189,7 +250,7 @@ func (streamer *TarballStreamer) readFileData() (err error) {
 		return nil
 	}
 	// read index is at last byte. All is read. Read next block.
-	streamer.bufDataSize, err = streamer.inputTar.Read(streamer.inputBuf)
+	streamer.bufDataSize, err = streamer.fileInput.Read(streamer.inputBuf)
 	streamer.bufReadIndex = 0
 	// Update index as read from file
 	streamer.fileReadIndex += int64(streamer.bufDataSize)
@@ -198,8 +259,9 @@ func (streamer *TarballStreamer) readFileData() (err error) {
 		return tar.ErrWriteTooLong
 	} else if streamer.fileReadIndex == streamer.curHeader.Size {
 		// Seems we have read all from this file. Next file.
+		err = streamer.closeFile()
 		streamer.curHeader = nil
-		return nil
+		return err
 	}
 	if err == io.EOF && streamer.bufDataSize > 0 {
 		// stream reached end of file. Bytes where read, but let's ignore on this pass.
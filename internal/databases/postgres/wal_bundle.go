@@ -0,0 +1,283 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/ioextensions"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	// WalBundlesFolder is the subfolder of the WAL folder holding the bundles made by wal-compact
+	WalBundlesFolder = "bundles"
+
+	walBundleDataSuffix  = ".bundle"
+	walBundleIndexSuffix = ".index.json"
+)
+
+// WalBundle is a storage object holding a contiguous range of WAL segments of one timeline.
+// Its name starts with the first segment, so delete retention compares bundles the same way as segments.
+type WalBundle struct {
+	Timeline uint32
+	FirstNo  WalSegmentNo
+	LastNo   WalSegmentNo
+}
+
+// WalBundleIndex describes where every segment object is located inside the bundle
+type WalBundleIndex struct {
+	Segments []WalBundleSegment `json:"segments"`
+}
+
+// WalBundleSegment is a WAL segment object stored in the bundle verbatim, i.e. compressed and encrypted.
+// Name keeps the compression extension of the original object.
+type WalBundleSegment struct {
+	Name   string `json:"name"`
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+}
+
+type bundleFolderReader interface {
+	ReadObject(ctx context.Context, objectRelativePath string) (io.ReadCloser, error)
+	ReadObjectRange(ctx context.Context, objectRelativePath string, offset, length int64) (io.ReadCloser, error)
+	ListFolder(ctx context.Context) (objects []storage.Object, subFolders []storage.Folder, err error)
+}
+
+func (bundle WalBundle) baseName() string {
+	return bundle.FirstNo.GetFilename(bundle.Timeline) + "_" + bundle.LastNo.GetFilename(bundle.Timeline)
+}
+
+// DataName is the name of the object with the concatenated segments
+func (bundle WalBundle) DataName() string {
+	return bundle.baseName() + walBundleDataSuffix
+}
+
+// IndexName is the name of the object with the bundle index
+func (bundle WalBundle) IndexName() string {
+	return bundle.baseName() + walBundleIndexSuffix
+}
+
+func (bundle WalBundle) Contains(timeline uint32, segmentNo WalSegmentNo) bool {
+	return bundle.Timeline == timeline && bundle.FirstNo <= segmentNo && segmentNo <= bundle.LastNo
+}
+
+// SegmentNames returns the WAL file names of all the segments in the bundle
+func (bundle WalBundle) SegmentNames() []string {
+	names := make([]string, 0, bundle.LastNo-bundle.FirstNo+1)
+	for segmentNo := bundle.FirstNo; segmentNo <= bundle.LastNo; segmentNo++ {
+		names = append(names, segmentNo.GetFilename(bundle.Timeline))
+	}
+	return names
+}
+
+// ParseWalBundleName parses the name of the bundle data object
+func ParseWalBundleName(name string) (WalBundle, bool) {
+	baseName, found := strings.CutSuffix(name, walBundleDataSuffix)
+	if !found {
+		return WalBundle{}, false
+	}
+	first, last, found := strings.Cut(baseName, "_")
+	if !found {
+		return WalBundle{}, false
+	}
+	timeline, firstNo, err := ParseWALFilename(first)
+	if err != nil {
+		return WalBundle{}, false
+	}
+	lastTimeline, lastNo, err := ParseWALFilename(last)
+	if err != nil || lastTimeline != timeline || lastNo < firstNo {
+		return WalBundle{}, false
+	}
+	return WalBundle{Timeline: timeline, FirstNo: WalSegmentNo(firstNo), LastNo: WalSegmentNo(lastNo)}, true
+}
+
+// ListWalBundles lists the bundles stored in the bundles folder
+func ListWalBundles(ctx context.Context, bundlesFolder storage.Folder) ([]WalBundle, error) {
+	objects, _, err := bundlesFolder.ListFolder(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list WAL bundles")
+	}
+	return parseWalBundles(objects), nil
+}
+
+func parseWalBundles(objects []storage.Object) []WalBundle {
+	bundles := make([]WalBundle, 0)
+	for _, object := range objects {
+		bundle, ok := ParseWalBundleName(object.GetName())
+		if ok {
+			bundles = append(bundles, bundle)
+		}
+	}
+	sort.Slice(bundles, func(i, j int) bool {
+		return bundles[i].Timeline < bundles[j].Timeline ||
+			bundles[i].Timeline == bundles[j].Timeline && bundles[i].FirstNo < bundles[j].FirstNo
+	})
+	return bundles
+}
+
+func readWalBundleIndex(ctx context.Context, folder bundleFolderReader, bundle WalBundle) (*WalBundleIndex, error) {
+	reader, err := folder.ReadObject(ctx, bundle.IndexName())
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read index of WAL bundle %s", bundle.DataName())
+	}
+	defer utility.LoggedClose(reader, "")
+	index := &WalBundleIndex{}
+	err = json.NewDecoder(reader).Decode(index)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse index of WAL bundle %s", bundle.DataName())
+	}
+	return index, nil
+}
+
+// downloadWALFileTo downloads the WAL file like internal.DownloadFileTo,
+// but also looks for it in the bundles if there is no such segment object
+func downloadWALFileTo(ctx context.Context, reader internal.StorageFolderReader, walFileName, dstPath string) error {
+	err := internal.DownloadFileTo(ctx, reader, walFileName, dstPath)
+	if _, ok := err.(internal.ArchiveNonExistenceError); !ok {
+		return err
+	}
+	bundleErr := downloadWALFileFromBundle(ctx, reader, walFileName, dstPath)
+	if bundleErr != nil {
+		tracelog.DebugLogger.Printf("Failed to fetch %s from WAL bundles: %v", walFileName, bundleErr)
+		return err
+	}
+	return nil
+}
+
+func downloadWALFileFromBundle(ctx context.Context, reader internal.StorageFolderReader, walFileName, dstPath string) error {
+	file, err := os.OpenFile(dstPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_EXCL, 0666)
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(file, "")
+
+	segmentReader, err := openBundledWALFile(ctx, reader, walFileName)
+	if err != nil {
+		_ = os.Remove(dstPath)
+		return err
+	}
+	defer utility.LoggedClose(segmentReader, "")
+
+	_, err = utility.FastCopy(file, segmentReader)
+	return err
+}
+
+// openWALFile returns the decompressed contents of the WAL file, which is looked for in the bundles too
+func openWALFile(ctx context.Context, reader internal.StorageFolderReader, walFileName string) (io.ReadCloser, error) {
+	segmentReader, err := internal.DownloadAndDecompressStorageFile(ctx, reader, walFileName)
+	if _, ok := err.(internal.ArchiveNonExistenceError); !ok {
+		return segmentReader, err
+	}
+	segmentReader, bundleErr := openBundledWALFile(ctx, reader, walFileName)
+	if bundleErr != nil {
+		tracelog.DebugLogger.Printf("Failed to read %s from WAL bundles: %v", walFileName, bundleErr)
+		return nil, err
+	}
+	return segmentReader, nil
+}
+
+func openBundledWALFile(ctx context.Context, reader internal.StorageFolderReader, walFileName string) (io.ReadCloser, error) {
+	timeline, segmentNo, err := ParseWALFilename(walFileName)
+	if err != nil {
+		return nil, err
+	}
+	bundlesFolder, ok := reader.SubFolder(WalBundlesFolder).(bundleFolderReader)
+	if !ok {
+		return nil, errors.New("storage folder does not support WAL bundles")
+	}
+	bundles, err := listReaderWalBundles(ctx, reader, bundlesFolder)
+	if err != nil {
+		return nil, err
+	}
+	for _, bundle := range bundles {
+		if !bundle.Contains(timeline, WalSegmentNo(segmentNo)) {
+			continue
+		}
+		tracelog.DebugLogger.Printf("Reading %s from WAL bundle %s", walFileName, bundle.DataName())
+		return openBundledSegment(ctx, bundlesFolder, bundle, walFileName)
+	}
+	return nil, errors.Errorf("no WAL bundle contains %s", walFileName)
+}
+
+// walBundleCachingReader lists the bundles once per run,
+// so wal-prefetch doesn't issue a LIST for each segment missing in the WAL folder
+type walBundleCachingReader struct {
+	internal.StorageFolderReader
+	once    sync.Once
+	bundles []WalBundle
+	err     error
+}
+
+func newWalBundleCachingReader(reader internal.StorageFolderReader) *walBundleCachingReader {
+	return &walBundleCachingReader{StorageFolderReader: reader}
+}
+
+func listReaderWalBundles(ctx context.Context, reader internal.StorageFolderReader,
+	bundlesFolder bundleFolderReader) ([]WalBundle, error) {
+	list := func() ([]WalBundle, error) {
+		objects, _, err := bundlesFolder.ListFolder(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return parseWalBundles(objects), nil
+	}
+	cachingReader, ok := reader.(*walBundleCachingReader)
+	if !ok {
+		return list()
+	}
+	cachingReader.once.Do(func() {
+		cachingReader.bundles, cachingReader.err = list()
+	})
+	return cachingReader.bundles, cachingReader.err
+}
+
+func openBundledSegment(ctx context.Context, folder bundleFolderReader, bundle WalBundle,
+	walFileName string) (io.ReadCloser, error) {
+	index, err := readWalBundleIndex(ctx, folder, bundle)
+	if err != nil {
+		return nil, err
+	}
+	var segment *WalBundleSegment
+	for i := range index.Segments {
+		if utility.TrimFileExtension(index.Segments[i].Name) == walFileName {
+			segment = &index.Segments[i]
+			break
+		}
+	}
+	if segment == nil {
+		return nil, errors.Errorf("WAL bundle %s index has no %s", bundle.DataName(), walFileName)
+	}
+
+	var decompressor compression.Decompressor
+	if ext := utility.GetFileExtension(segment.Name); ext != "" {
+		decompressor = compression.FindDecompressor(ext)
+		if decompressor == nil {
+			return nil, fmt.Errorf("decompressor for extension '%s' was not found", ext)
+		}
+	}
+
+	archiveReader, err := folder.ReadObjectRange(ctx, bundle.DataName(), segment.Offset, segment.Size)
+	if err != nil {
+		return nil, err
+	}
+	decompressed, err := internal.DecompressDecryptBytes(archiveReader, decompressor)
+	if err != nil {
+		utility.LoggedClose(archiveReader, "")
+		return nil, err
+	}
+	return ioextensions.ReadCascadeCloser{
+		Reader: decompressed,
+		Closer: ioextensions.NewMultiCloser([]io.Closer{archiveReader, decompressed}),
+	}, nil
+}
@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+type listCountingFolder struct {
+	storage.Folder
+	lists *int
+}
+
+func (folder listCountingFolder) ListFolder(ctx context.Context) ([]storage.Object, []storage.Folder, error) {
+	*folder.lists++
+	return folder.Folder.ListFolder(ctx)
+}
+
+func (folder listCountingFolder) GetSubFolder(subFolderRelativePath string) storage.Folder {
+	return listCountingFolder{folder.Folder.GetSubFolder(subFolderRelativePath), folder.lists}
+}
+
+func TestWalBundleCachingReaderListsOnce(t *testing.T) {
+	rootFolder := memory.NewFolder("in_memory/", memory.NewKVS())
+	walFolder := rootFolder.GetSubFolder(utility.WalPath)
+	segments := []string{"000000010000000000000001", "000000010000000000000002", "000000010000000000000003"}
+	for _, name := range segments {
+		var data bytes.Buffer
+		writer := lz4.Compressor{}.NewWriter(&data)
+		_, err := writer.Write([]byte(name))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+		require.NoError(t, walFolder.PutObject(t.Context(), name+".lz4", &data))
+	}
+	require.NoError(t, rootFolder.GetSubFolder(utility.BaseBackupPath).PutObject(t.Context(),
+		"base_000000010000000000000003"+utility.SentinelSuffix, bytes.NewReader([]byte("{}"))))
+	require.NoError(t, HandleWalCompact(t.Context(), rootFolder, 0, true))
+
+	lists := 0
+	reader := newWalBundleCachingReader(internal.NewFolderReader(listCountingFolder{walFolder, &lists}))
+	for _, name := range segments[:2] {
+		segmentReader, err := openWALFile(t.Context(), reader, name)
+		require.NoError(t, err)
+		data, err := io.ReadAll(segmentReader)
+		require.NoError(t, err)
+		require.NoError(t, segmentReader.Close())
+		assert.Equal(t, name, string(data))
+	}
+	_, err := openWALFile(t.Context(), reader, "000000010000000000000004")
+	assert.ErrorAs(t, err, &internal.ArchiveNonExistenceError{})
+	assert.Equal(t, 1, lists)
+}
@@ -0,0 +1,191 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// WalBundlePlan is a bundle to be made by wal-compact from the listed segment objects
+type WalBundlePlan struct {
+	Bundle   WalBundle
+	Segments []storage.Object
+}
+
+func (plan WalBundlePlan) size() int64 {
+	var size int64
+	for _, segment := range plan.Segments {
+		size += segment.GetSize()
+	}
+	return size
+}
+
+// HandleWalCompact packs sealed WAL segments older than the latest backup into bundles.
+// Without confirmation it only prints the bundles which would be made.
+func HandleWalCompact(ctx context.Context, rootFolder storage.Folder, maxBundleSize int64, confirmed bool) error {
+	backups, err := internal.GetBackups(ctx, rootFolder.GetSubFolder(utility.BaseBackupPath))
+	if err != nil {
+		return err
+	}
+	backupStarts := make(map[WalSegmentNo]bool, len(backups))
+	var latestStart WalSegmentNo
+	for _, backup := range backups {
+		_, segmentNo, ok := TryFetchTimelineAndLogSegNo(backup.BackupName)
+		if !ok {
+			continue
+		}
+		backupStarts[WalSegmentNo(segmentNo)] = true
+		latestStart = max(latestStart, WalSegmentNo(segmentNo))
+	}
+
+	walFolder := rootFolder.GetSubFolder(utility.WalPath)
+	objects, _, err := walFolder.ListFolder(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to list WAL folder")
+	}
+	plans := PlanWalBundles(objects, backupStarts, latestStart, maxBundleSize)
+	if len(plans) == 0 {
+		tracelog.InfoLogger.Println("No WAL segments to compact")
+		return nil
+	}
+
+	bundlesFolder := walFolder.GetSubFolder(WalBundlesFolder)
+	for _, plan := range plans {
+		if !confirmed {
+			tracelog.InfoLogger.Printf("Would pack %d segment(s), %d bytes into %s",
+				len(plan.Segments), plan.size(), plan.Bundle.DataName())
+			continue
+		}
+		err = compactWalBundle(ctx, walFolder, bundlesFolder, plan)
+		if err != nil {
+			return err
+		}
+	}
+	if !confirmed {
+		tracelog.InfoLogger.Println("Dry run finished, use --confirm to compact WAL")
+	}
+	return nil
+}
+
+// PlanWalBundles groups the segment objects preceding latestStart into runs of contiguous segments of one timeline.
+// A backup start segment always begins a new bundle, so delete retention never has to split a bundle.
+// Single segments are left as they are.
+func PlanWalBundles(objects []storage.Object, backupStarts map[WalSegmentNo]bool,
+	latestStart WalSegmentNo, maxBundleSize int64) []WalBundlePlan {
+	type segmentObject struct {
+		timeline  uint32
+		segmentNo WalSegmentNo
+		object    storage.Object
+	}
+	segments := make([]segmentObject, 0, len(objects))
+	for _, object := range objects {
+		name := object.GetName()
+		if ext := utility.GetFileExtension(name); ext != "" && compression.FindDecompressor(ext) == nil {
+			// WAL metadata and other auxiliary files
+			continue
+		}
+		timeline, segmentNo, err := ParseWALFilename(utility.TrimFileExtension(name))
+		if err != nil || WalSegmentNo(segmentNo) >= latestStart {
+			continue
+		}
+		segments = append(segments, segmentObject{timeline, WalSegmentNo(segmentNo), object})
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].timeline < segments[j].timeline ||
+			segments[i].timeline == segments[j].timeline && segments[i].segmentNo < segments[j].segmentNo
+	})
+
+	plans := make([]WalBundlePlan, 0)
+	var current *WalBundlePlan
+	var currentSize int64
+	flush := func() {
+		if current != nil && len(current.Segments) > 1 {
+			plans = append(plans, *current)
+		}
+		current = nil
+	}
+	for _, segment := range segments {
+		size := segment.object.GetSize()
+		if current == nil || current.Bundle.Timeline != segment.timeline ||
+			current.Bundle.LastNo+1 != segment.segmentNo || backupStarts[segment.segmentNo] ||
+			maxBundleSize > 0 && currentSize+size > maxBundleSize {
+			flush()
+			current = &WalBundlePlan{Bundle: WalBundle{Timeline: segment.timeline, FirstNo: segment.segmentNo}}
+			currentSize = 0
+		}
+		current.Bundle.LastNo = segment.segmentNo
+		current.Segments = append(current.Segments, segment.object)
+		currentSize += size
+	}
+	flush()
+	return plans
+}
+
+// compactWalBundle uploads the segment objects concatenated with the index and then deletes them
+func compactWalBundle(ctx context.Context, walFolder, bundlesFolder storage.Folder, plan WalBundlePlan) error {
+	tracelog.InfoLogger.Printf("Packing %d segment(s), %d bytes into %s",
+		len(plan.Segments), plan.size(), plan.Bundle.DataName())
+
+	index := WalBundleIndex{Segments: make([]WalBundleSegment, 0, len(plan.Segments))}
+	var offset int64
+	for _, segment := range plan.Segments {
+		index.Segments = append(index.Segments, WalBundleSegment{
+			Name:   segment.GetName(),
+			Offset: offset,
+			Size:   segment.GetSize(),
+		})
+		offset += segment.GetSize()
+	}
+
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		pipeWriter.CloseWithError(copyWalBundleSegments(ctx, walFolder, index, pipeWriter))
+	}()
+	err := bundlesFolder.PutObject(ctx, plan.Bundle.DataName(), pipeReader)
+	_ = pipeReader.CloseWithError(err)
+	if err != nil {
+		return errors.Wrapf(err, "failed to upload WAL bundle %s", plan.Bundle.DataName())
+	}
+
+	indexData, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	err = bundlesFolder.PutObject(ctx, plan.Bundle.IndexName(), bytes.NewReader(indexData))
+	if err != nil {
+		return errors.Wrapf(err, "failed to upload index of WAL bundle %s", plan.Bundle.DataName())
+	}
+
+	err = walFolder.DeleteObjects(ctx, plan.Segments)
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete segments packed into %s", plan.Bundle.DataName())
+	}
+	return nil
+}
+
+func copyWalBundleSegments(ctx context.Context, walFolder storage.Folder, index WalBundleIndex, dst io.Writer) error {
+	for _, segment := range index.Segments {
+		reader, err := walFolder.ReadObject(ctx, segment.Name)
+		if err != nil {
+			return err
+		}
+		written, err := io.Copy(dst, reader)
+		utility.LoggedClose(reader, "")
+		if err != nil {
+			return errors.Wrapf(err, "failed to copy %s", segment.Name)
+		}
+		if written != segment.Size {
+			return errors.Errorf("%s has size %d, but %d bytes were listed", segment.Name, written, segment.Size)
+		}
+	}
+	return nil
+}
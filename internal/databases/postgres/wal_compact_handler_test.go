@@ -0,0 +1,133 @@
+package postgres_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/testtools"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func TestPlanWalBundles(t *testing.T) {
+	modTime := time.Now()
+	objects := []storage.Object{
+		storage.NewLocalObject("000000010000000000000001.lz4", modTime, 10),
+		storage.NewLocalObject("000000010000000000000002.lz4", modTime, 10),
+		storage.NewLocalObject("000000010000000000000002.json", modTime, 10),
+		storage.NewLocalObject("000000010000000000000003.lz4", modTime, 10),
+		storage.NewLocalObject("000000010000000000000004.lz4", modTime, 10),
+		storage.NewLocalObject("000000010000000000000006.lz4", modTime, 10),
+		storage.NewLocalObject("000000010000000000000007.lz4", modTime, 10),
+		storage.NewLocalObject("000000020000000000000007.lz4", modTime, 10),
+		storage.NewLocalObject("000000020000000000000008.lz4", modTime, 10),
+		storage.NewLocalObject("00000002.history", modTime, 10),
+		storage.NewLocalObject("000000020000000000000009.lz4", modTime, 10),
+	}
+	backupStarts := map[postgres.WalSegmentNo]bool{3: true, 9: true}
+
+	plans := postgres.PlanWalBundles(objects, backupStarts, 9, 0)
+
+	names := make([]string, 0, len(plans))
+	for _, plan := range plans {
+		names = append(names, plan.Bundle.DataName())
+	}
+	assert.Equal(t, []string{
+		"000000010000000000000001_000000010000000000000002.bundle",
+		"000000010000000000000003_000000010000000000000004.bundle",
+		"000000010000000000000006_000000010000000000000007.bundle",
+		"000000020000000000000007_000000020000000000000008.bundle",
+	}, names)
+	assert.Len(t, plans[0].Segments, 2)
+}
+
+func TestPlanWalBundlesMaxSize(t *testing.T) {
+	modTime := time.Now()
+	objects := make([]storage.Object, 0)
+	for _, name := range []string{"000000010000000000000001", "000000010000000000000002",
+		"000000010000000000000003", "000000010000000000000004", "000000010000000000000005"} {
+		objects = append(objects, storage.NewLocalObject(name+".lz4", modTime, 10))
+	}
+
+	plans := postgres.PlanWalBundles(objects, nil, 10, 20)
+
+	require.Len(t, plans, 2)
+	assert.Equal(t, postgres.WalBundle{Timeline: 1, FirstNo: 1, LastNo: 2}, plans[0].Bundle)
+	assert.Equal(t, postgres.WalBundle{Timeline: 1, FirstNo: 3, LastNo: 4}, plans[1].Bundle)
+}
+
+func TestParseWalBundleName(t *testing.T) {
+	bundle, ok := postgres.ParseWalBundleName("000000020000000100000000_000000020000000100000002.bundle")
+	require.True(t, ok)
+	assert.Equal(t, []string{"000000020000000100000000", "000000020000000100000001", "000000020000000100000002"},
+		bundle.SegmentNames())
+
+	_, ok = postgres.ParseWalBundleName("000000020000000100000000_000000020000000100000002.index.json")
+	assert.False(t, ok)
+	_, ok = postgres.ParseWalBundleName("000000020000000100000002_000000020000000100000000.bundle")
+	assert.False(t, ok)
+}
+
+func TestWalCompactAndFetch(t *testing.T) {
+	rootFolder := testtools.MakeDefaultInMemoryStorageFolder()
+	walFolder := rootFolder.GetSubFolder(utility.WalPath)
+	segments := []string{"000000010000000000000001", "000000010000000000000002", "000000010000000000000003"}
+	for _, name := range segments {
+		var data bytes.Buffer
+		writer := lz4.Compressor{}.NewWriter(&data)
+		_, err := writer.Write([]byte(strings.Repeat(name, 100)))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+		require.NoError(t, walFolder.PutObject(t.Context(), name+".lz4", &data))
+	}
+	require.NoError(t, rootFolder.GetSubFolder(utility.BaseBackupPath).PutObject(t.Context(),
+		"base_000000010000000000000003"+utility.SentinelSuffix, strings.NewReader("{}")))
+
+	require.NoError(t, postgres.HandleWalCompact(t.Context(), rootFolder, 0, false))
+	exists, err := walFolder.Exists(t.Context(), "000000010000000000000001.lz4")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	require.NoError(t, postgres.HandleWalCompact(t.Context(), rootFolder, 0, true))
+	objects, _, err := walFolder.ListFolder(t.Context())
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	assert.Equal(t, "000000010000000000000003.lz4", objects[0].GetName())
+
+	dir := t.TempDir()
+	for _, name := range segments {
+		location := filepath.Join(dir, name)
+		err = postgres.HandleWALFetch(t.Context(), internal.NewFolderReader(rootFolder), name, location,
+			postgres.NopPrefetcher{})
+		require.NoError(t, err)
+		data, err := os.ReadFile(location)
+		require.NoError(t, err)
+		assert.Equal(t, strings.Repeat(name, 100), string(data))
+	}
+
+	err = postgres.HandleWALFetch(t.Context(), internal.NewFolderReader(rootFolder), "000000010000000000000004",
+		filepath.Join(dir, "missing"), postgres.NopPrefetcher{})
+	assert.ErrorAs(t, err, &internal.ArchiveNonExistenceError{})
+}
+
+func TestIsPermanentWalBundle(t *testing.T) {
+	permanentWals := map[postgres.PermanentObject]bool{
+		{Name: "000000010000000000000002", StorageName: "default"}: true,
+	}
+	bundlePath := utility.WalPath + postgres.WalBundlesFolder + "/000000010000000000000001_000000010000000000000003"
+
+	assert.True(t, postgres.IsPermanent(bundlePath+".bundle", "default", nil, permanentWals))
+	assert.True(t, postgres.IsPermanent(bundlePath+".index.json", "default", nil, permanentWals))
+	assert.False(t, postgres.IsPermanent(bundlePath+".bundle", "other", nil, permanentWals))
+	assert.False(t, postgres.IsPermanent(utility.WalPath+postgres.WalBundlesFolder+
+		"/000000010000000000000004_000000010000000000000005.bundle", "default", nil, permanentWals))
+}
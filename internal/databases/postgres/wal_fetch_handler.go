@@ -93,8 +93,12 @@ func HandleWALFetch(ctx context.Context,
 		time.Sleep(2 * time.Millisecond)
 	}
 
+	if fetchWALFileFromPeers(ctx, walFileName, location) {
+		return nil
+	}
+
 	tracelog.DebugLogger.Printf("Statring external storage download for file %s at %v", walFileName, time.Now())
-	return internal.DownloadFileTo(ctx, reader, walFileName, location)
+	return downloadWALFileTo(ctx, reader, walFileName, location)
 }
 
 // TODO : unit tests
@@ -0,0 +1,297 @@
+package postgres
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jackc/pglogrepl"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/daemon"
+	"github.com/wal-g/wal-g/internal/walparser"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// fetchWALFileFromPeers tries to get the WAL file from pg_wal of the configured peers before the storage is used:
+// first from the peer daemons, then via streaming replication. It reports whether the file was fetched.
+func fetchWALFileFromPeers(ctx context.Context, walFileName, location string) bool {
+	peers := strings.FieldsFunc(viper.GetString(conf.WalFetchPeers), func(r rune) bool { return r == ',' || r == ' ' })
+	connInfo := viper.GetString(conf.WalFetchPeerConnInfo)
+	if len(peers) == 0 && connInfo == "" {
+		return false
+	}
+	timeout, err := conf.GetDurationSetting(conf.WalFetchPeerTimeout)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to read %s: %v", conf.WalFetchPeerTimeout, err)
+		return false
+	}
+
+	for _, peer := range peers {
+		err = fetchWALFileFromPeerDaemon(ctx, peer, walFileName, location, timeout)
+		if err == nil {
+			tracelog.InfoLogger.Printf("Fetched %s from peer %s", walFileName, peer)
+			return true
+		}
+		tracelog.WarningLogger.Printf("Failed to fetch %s from peer %s: %v", walFileName, peer, err)
+	}
+	if connInfo != "" {
+		err = streamWALFileFromPeer(ctx, connInfo, walFileName, location, timeout)
+		if err == nil {
+			tracelog.InfoLogger.Printf("Fetched %s from peer via streaming replication", walFileName)
+			return true
+		}
+		tracelog.WarningLogger.Printf("Failed to stream %s from peer: %v", walFileName, err)
+	}
+	return false
+}
+
+func fetchWALFileFromPeerDaemon(ctx context.Context, peer, walFileName, location string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return writePeerWALFile(walFileName, location, func(file *os.File) error {
+		response, err := FetchFromPeer(ctx, peer, walFileName, file)
+		if err != nil {
+			return err
+		}
+		if response == daemon.ArchiveNonExistenceType {
+			return errors.New("peer has no such file in pg_wal")
+		}
+		return nil
+	})
+}
+
+// FetchFromPeer connects to the peer daemon, secured by the WALG_PEER_* settings, and requests the file from its pg_wal
+func FetchFromPeer(ctx context.Context, address, fileName string, dst io.Writer) (daemon.SocketMessageType, error) {
+	conn, err := dialPeerChannel(ctx, address)
+	if err != nil {
+		return daemon.ErrorType, err
+	}
+	defer utility.LoggedClose(conn, "close peer connection")
+	return daemon.FetchFromPeer(ctx, conn, fileName, dst)
+}
+
+// dialPeerChannel connects to the peer daemon with mutual TLS and pre-shared key authentication when they are set
+func dialPeerChannel(ctx context.Context, address string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	config, err := newCatchupChannelConfig(peerChannelSettings, false, host)
+	if err != nil {
+		return nil, err
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to peer")
+	}
+	return config.secure(ctx, conn, false)
+}
+
+// streamWALFileFromPeer receives the WAL segment or timeline history file using the replication protocol
+func streamWALFileFromPeer(ctx context.Context, connInfo, walFileName, location string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	config, err := pgconn.ParseConfig(connInfo)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse peer connection string")
+	}
+	config.RuntimeParams["replication"] = "yes"
+	conn, err := pgconn.ConnectConfig(ctx, config)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to peer")
+	}
+	defer conn.Close(context.Background())
+
+	if strings.HasSuffix(walFileName, ".history") {
+		matches := timelineHistoryFileRegexp.FindStringSubmatch(walFileName)
+		if matches == nil {
+			return errors.Errorf("%s is not a timeline history file", walFileName)
+		}
+		timeline, err := ParseTimelineFromString(matches[1])
+		if err != nil {
+			return err
+		}
+		history, err := pglogrepl.TimelineHistory(ctx, conn, int32(timeline))
+		if err != nil {
+			return err
+		}
+		return writePeerWALFile(walFileName, location, func(file *os.File) error {
+			_, err := file.Write(history.Content)
+			return err
+		})
+	}
+
+	timeline, segmentNo, err := ParseWALFilename(walFileName)
+	if err != nil {
+		return err
+	}
+	segment := NewWalSegment(timeline, pglogrepl.LSN(WalSegmentNo(segmentNo).firstLsn()), WalSegmentSize)
+	err = pglogrepl.StartReplication(ctx, conn, "", segment.StartLSN,
+		pglogrepl.StartReplicationOptions{Timeline: int32(timeline), Mode: pglogrepl.PhysicalReplication})
+	if err != nil {
+		return err
+	}
+	err = receivePeerWalSegment(ctx, conn, segment)
+	if err != nil {
+		return err
+	}
+	return writePeerWALFile(walFileName, location, func(file *os.File) error {
+		_, err := utility.FastCopy(file, segment)
+		return err
+	})
+}
+
+// receivePeerWalSegment reads the replication stream until the segment is complete.
+// Unlike WalSegment.Stream it never terminates the process, so that wal-fetch can fall back to the storage.
+func receivePeerWalSegment(ctx context.Context, conn *pgconn.PgConn, segment *WalSegment) error {
+	for !segment.isComplete() {
+		msg, err := conn.ReceiveMessage(ctx)
+		if err != nil {
+			return err
+		}
+		result, err := segment.processMessage(msg)
+		switch result {
+		case ProcessMessageOK:
+		case ProcessMessageReplyRequested:
+			err = pglogrepl.SendStandbyStatusUpdate(ctx, conn,
+				pglogrepl.StandbyStatusUpdate{WALWritePosition: segment.StartLSN})
+			if err != nil {
+				return err
+			}
+		case ProcessMessageCopyDone:
+			return errors.Errorf("peer timeline %d ends before the end of %s", segment.TimeLine, segment.Name())
+		default:
+			return errors.Errorf("unexpected replication message for %s: %v", segment.Name(), err)
+		}
+	}
+	return nil
+}
+
+// writePeerWALFile creates the file as internal.DownloadFileTo does and removes it if it could not be written.
+// The received WAL segment is verified, so a corrupted or forged segment is never replayed.
+func writePeerWALFile(walFileName, location string, write func(file *os.File) error) error {
+	file, err := os.OpenFile(location, os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_EXCL, 0666)
+	if err != nil {
+		return err
+	}
+	err = write(file)
+	if err == nil {
+		err = verifyPeerWALFile(walFileName, file)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(location)
+	}
+	return err
+}
+
+// checkPeerWalSegment makes sure that the pg_wal segment is complete before it is sent to a peer:
+// the segment being written, preallocated and recycled segments would be replayed as corrupted or wrong WAL.
+// The file is rewound to the beginning after the check.
+func checkPeerWalSegment(ctx context.Context, walDir, fileName string, file io.ReadSeeker,
+	getCurrentSegmentNo func(ctx context.Context) (WalSegmentNo, error)) error {
+	segmentNo, err := newWalSegmentNoFromFilename(fileName)
+	if err != nil {
+		return err
+	}
+	finished, err := isPeerWalSegmentFinished(walDir, fileName)
+	if err != nil {
+		return err
+	}
+	if !finished {
+		currentSegmentNo, err := getCurrentSegmentNo(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to get the current WAL segment")
+		}
+		if segmentNo >= currentSegmentNo {
+			return errors.Errorf("%s is not finished yet", fileName)
+		}
+	}
+
+	return verifyWalSegmentPages(file, segmentNo)
+}
+
+// verifyPeerWALFile checks the page headers of the WAL segment received from a peer,
+// timeline history files have no headers to check
+func verifyPeerWALFile(walFileName string, file io.ReadSeeker) error {
+	if !isWalFilename(walFileName) {
+		return nil
+	}
+	segmentNo, err := newWalSegmentNoFromFilename(walFileName)
+	if err != nil {
+		return err
+	}
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	err = verifyWalSegmentPages(file, segmentNo)
+	return errors.Wrapf(err, "received %s is not a valid WAL segment", walFileName)
+}
+
+// verifyWalSegmentPages checks the magic and the page headers of the segment, the file is rewound after the check
+func verifyWalSegmentPages(file io.ReadSeeker, segmentNo WalSegmentNo) error {
+	magic := make([]byte, 2)
+	_, err := io.ReadFull(file, magic)
+	if err != nil {
+		return err
+	}
+	if binary.LittleEndian.Uint16(magic) < 0xD061 {
+		return newInvalidWalFileMagicError()
+	}
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	err = walparser.NewWalVerifier(WalSegmentSize).VerifySegment(file, walparser.XLogRecordPtr(segmentNo.firstLsn()))
+	if err != nil {
+		return err
+	}
+	_, err = file.Seek(0, io.SeekStart)
+	return err
+}
+
+// isPeerWalSegmentFinished checks archive_status: Postgres marks the segment .ready when it is completely written,
+// the mark is renamed to .done after archiving. Preallocated and recycled segments are never marked.
+func isPeerWalSegmentFinished(walDir, fileName string) (bool, error) {
+	for _, suffix := range []string{".ready", ".done"} {
+		_, err := os.Stat(filepath.Join(walDir, archiveStatusDir, fileName+suffix))
+		if err == nil {
+			return true, nil
+		}
+		if !os.IsNotExist(err) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// queryCurrentWalSegmentNo returns the segment Postgres writes to or, on a standby, receives from the primary
+func queryCurrentWalSegmentNo(ctx context.Context) (WalSegmentNo, error) {
+	conn, err := Connect(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { tracelog.WarningLogger.PrintOnError(conn.Close(ctx)) }()
+	queryRunner, err := NewPgQueryRunner(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	lsn, err := queryRunner.GetCurrentWalLsn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return NewWalSegmentNo(lsn), nil
+}
@@ -0,0 +1,133 @@
+package postgres_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/daemon"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/testtools"
+)
+
+func startPeerDaemon(t *testing.T) (string, string) {
+	pgData := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(pgData, "pg_wal"), 0700))
+	viper.Set(conf.PgDataSetting, pgData)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	require.NoError(t, postgres.ServePeers(ctx, listener))
+	return pgData, listener.Addr().String()
+}
+
+func TestFetchFromPeer(t *testing.T) {
+	pgData, address := startPeerDaemon(t)
+	contents := []byte("timeline history")
+	require.NoError(t, os.WriteFile(filepath.Join(pgData, "pg_wal", "00000002.history"), contents, 0600))
+
+	var received bytes.Buffer
+	response, err := postgres.FetchFromPeer(t.Context(), address, "00000002.history", &received)
+	require.NoError(t, err)
+	assert.Equal(t, daemon.OkType, response)
+	assert.Equal(t, contents, received.Bytes())
+
+	response, err = postgres.FetchFromPeer(t.Context(), address, "000000010000000000000006", &received)
+	require.NoError(t, err)
+	assert.Equal(t, daemon.ArchiveNonExistenceType, response)
+
+	response, err = postgres.FetchFromPeer(t.Context(), address, "../postgresql.conf", &received)
+	assert.Error(t, err)
+	assert.Equal(t, daemon.ErrorType, response)
+}
+
+func TestWalFetchFromPeerBeforeStorage(t *testing.T) {
+	pgData, address := startPeerDaemon(t)
+	contents := []byte("timeline history")
+	require.NoError(t, os.WriteFile(filepath.Join(pgData, "pg_wal", "00000002.history"), contents, 0600))
+	viper.Set(conf.WalFetchPeers, "127.0.0.1:1,"+address)
+	t.Cleanup(func() { viper.Set(conf.WalFetchPeers, "") })
+
+	reader := internal.NewFolderReader(testtools.MakeDefaultInMemoryStorageFolder())
+	location := filepath.Join(t.TempDir(), "RECOVERYHISTORY")
+	err := postgres.HandleWALFetch(t.Context(), reader, "00000002.history", location, postgres.NopPrefetcher{})
+	require.NoError(t, err)
+	fetched, err := os.ReadFile(location)
+	require.NoError(t, err)
+	assert.Equal(t, contents, fetched)
+
+	// neither the peers nor the storage have the file
+	location = filepath.Join(t.TempDir(), "RECOVERYXLOG")
+	err = postgres.HandleWALFetch(t.Context(), reader, "000000020000000000000003", location, postgres.NopPrefetcher{})
+	assert.ErrorAs(t, err, &internal.ArchiveNonExistenceError{})
+	assert.NoFileExists(t, location)
+}
+
+func TestFetchFromPeerWithPSK(t *testing.T) {
+	viper.Set(conf.PgPeerPSK, "secret")
+	t.Cleanup(func() { viper.Set(conf.PgPeerPSK, "") })
+	pgData, address := startPeerDaemon(t)
+	contents := []byte("timeline history")
+	require.NoError(t, os.WriteFile(filepath.Join(pgData, "pg_wal", "00000002.history"), contents, 0600))
+
+	var received bytes.Buffer
+	response, err := postgres.FetchFromPeer(t.Context(), address, "00000002.history", &received)
+	require.NoError(t, err)
+	assert.Equal(t, daemon.OkType, response)
+	assert.Equal(t, contents, received.Bytes())
+
+	viper.Set(conf.PgPeerPSK, "other secret")
+	received.Reset()
+	_, err = postgres.FetchFromPeer(t.Context(), address, "00000002.history", &received)
+	assert.Error(t, err)
+	assert.Empty(t, received.Bytes())
+}
+
+// startForgingPeer answers every peer request with the provided contents
+func startForgingPeer(t *testing.T, contents []byte) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			header := make([]byte, 3)
+			if _, err = io.ReadFull(conn, header); err == nil {
+				_, err = io.CopyN(io.Discard, conn, int64(binary.BigEndian.Uint16(header[1:]))-3)
+			}
+			if err == nil {
+				_, _ = conn.Write(binary.BigEndian.AppendUint64(daemon.OkType.ToBytes(), uint64(len(contents))))
+				_, _ = conn.Write(contents)
+			}
+			_ = conn.Close()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestWalFetchFromPeerRejectsInvalidSegment(t *testing.T) {
+	address := startForgingPeer(t, make([]byte, postgres.WalSegmentSize))
+	viper.Set(conf.WalFetchPeers, address)
+	t.Cleanup(func() { viper.Set(conf.WalFetchPeers, "") })
+
+	reader := internal.NewFolderReader(testtools.MakeDefaultInMemoryStorageFolder())
+	location := filepath.Join(t.TempDir(), "RECOVERYXLOG")
+	err := postgres.HandleWALFetch(t.Context(), reader, "000000010000000000000003", location, postgres.NopPrefetcher{})
+	assert.ErrorAs(t, err, &internal.ArchiveNonExistenceError{})
+	assert.NoFileExists(t, location)
+}
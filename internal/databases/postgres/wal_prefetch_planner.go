@@ -0,0 +1,249 @@
+package postgres
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+)
+
+const (
+	prefetchStateFileName = "prefetch_state.json"
+
+	// prefetchRateSmoothing is the weight of the newest observation in replay and download rate averages
+	prefetchRateSmoothing = 0.3
+	// prefetchSafetyFactor inflates the computed lookahead to absorb storage latency spikes
+	prefetchSafetyFactor = 1.5
+	// prefetchIdleGap is the pause between wal-fetch calls after which the replay rate is considered stale
+	prefetchIdleGap = 10 * time.Minute
+)
+
+// PrefetchState is persisted in the prefetch directory between wal-fetch invocations.
+// It holds smoothed measurements of how fast Postgres replays WAL and how long it takes to download a segment.
+type PrefetchState struct {
+	TimelineID      uint32    `json:"timeline_id"`
+	LogSegNo        uint64    `json:"log_seg_no"`
+	LastFetchTime   time.Time `json:"last_fetch_time"`
+	ReplayRate      float64   `json:"replay_rate"`
+	DownloadSeconds float64   `json:"download_seconds"`
+}
+
+// ObserveFetch records that Postgres requested the segment: everything before it is already replayed.
+func (state *PrefetchState) ObserveFetch(timelineID uint32, logSegNo uint64, now time.Time) {
+	defer func() {
+		state.TimelineID = timelineID
+		state.LogSegNo = logSegNo
+		state.LastFetchTime = now
+	}()
+
+	if state.LastFetchTime.IsZero() || state.TimelineID != timelineID || logSegNo <= state.LogSegNo {
+		return
+	}
+	elapsed := now.Sub(state.LastFetchTime)
+	if elapsed <= 0 {
+		return
+	}
+	if elapsed > prefetchIdleGap {
+		state.ReplayRate = 0
+		return
+	}
+	rate := float64(logSegNo-state.LogSegNo) / elapsed.Seconds()
+	state.ReplayRate = smoothPrefetchRate(state.ReplayRate, rate)
+}
+
+// ObserveDownload records the time it took to download one prefetched segment.
+func (state *PrefetchState) ObserveDownload(duration time.Duration) {
+	if duration <= 0 {
+		return
+	}
+	state.DownloadSeconds = smoothPrefetchRate(state.DownloadSeconds, duration.Seconds())
+}
+
+func smoothPrefetchRate(previous, observed float64) float64 {
+	if previous <= 0 {
+		return observed
+	}
+	return previous*(1-prefetchRateSmoothing) + observed*prefetchRateSmoothing
+}
+
+// PrefetchPlanner decides how many segments to prefetch ahead and how many of them to download at once
+type PrefetchPlanner struct {
+	MaxConcurrency int
+	MaxLookahead   int
+	MaxDiskUsage   int64
+	SegmentSize    uint64
+}
+
+func NewPrefetchPlanner() (PrefetchPlanner, error) {
+	concurrency, err := conf.GetMaxDownloadConcurrency()
+	if err != nil {
+		return PrefetchPlanner{}, err
+	}
+	return PrefetchPlanner{
+		MaxConcurrency: concurrency,
+		MaxLookahead:   viper.GetInt(conf.PrefetchMaxLookahead),
+		MaxDiskUsage:   int64(viper.GetSizeInBytes(conf.PrefetchMaxDiskUsage)),
+		SegmentSize:    WalSegmentSize,
+	}, nil
+}
+
+// Plan returns the download concurrency and the number of segments to prefetch after the requested one.
+// Until both replay and download rates are known it falls back to prefetching MaxConcurrency segments.
+func (planner PrefetchPlanner) Plan(state PrefetchState) (concurrency int, lookahead int) {
+	maxLookahead := planner.maxLookahead()
+	if state.ReplayRate <= 0 || state.DownloadSeconds <= 0 {
+		lookahead = min(planner.MaxConcurrency, maxLookahead)
+		return max(lookahead, 1), lookahead
+	}
+
+	// number of segments Postgres replays while a single segment is being downloaded
+	inflight := state.ReplayRate * state.DownloadSeconds * prefetchSafetyFactor
+	concurrency = max(min(int(math.Ceil(inflight)), planner.MaxConcurrency), 1)
+	lookahead = min(max(int(math.Ceil(2*inflight)), concurrency), maxLookahead)
+	return max(min(concurrency, lookahead), 1), lookahead
+}
+
+// maxLookahead is the lookahead limit imposed by configuration and disk usage cap
+func (planner PrefetchPlanner) maxLookahead() int {
+	maxLookahead := planner.MaxLookahead
+	if maxLookahead <= 0 {
+		maxLookahead = math.MaxInt32
+	}
+	if limit := planner.maxDiskSegments(); limit >= 0 {
+		maxLookahead = min(maxLookahead, limit)
+	}
+	return maxLookahead
+}
+
+// maxDiskSegments returns how many segments fit into the disk usage cap, or -1 if there is no cap
+func (planner PrefetchPlanner) maxDiskSegments() int {
+	if planner.MaxDiskUsage <= 0 || planner.SegmentSize == 0 {
+		return -1
+	}
+	return int(uint64(planner.MaxDiskUsage) / planner.SegmentSize)
+}
+
+// EnforcePrefetchDiskLimit evicts the prefetched segments that are farthest from the requested one
+// until the prefetch directory fits into the disk usage cap.
+func (planner PrefetchPlanner) EnforcePrefetchDiskLimit(walFileName string, location string, cleaner Cleaner) {
+	limit := planner.maxDiskSegments()
+	if limit < 0 {
+		return
+	}
+	timelineID, logSegNo, err := ParseWALFilename(walFileName)
+	if err != nil {
+		return
+	}
+	prefetchLocation, _, _, _ := getPrefetchLocations(location, walFileName)
+	files, err := cleaner.GetFiles(prefetchLocation)
+	if err != nil {
+		tracelog.WarningLogger.Printf("WAL-prefetch disk limit: cannot enumerate files in dir %s: %v", prefetchLocation, err)
+		return
+	}
+
+	type prefetchedSegment struct {
+		name     string
+		logSegNo uint64
+	}
+	segments := make([]prefetchedSegment, 0, len(files))
+	for _, file := range files {
+		fileTimelineID, fileLogSegNo, err := ParseWALFilename(file)
+		if err != nil || fileTimelineID != timelineID || fileLogSegNo <= logSegNo {
+			continue
+		}
+		segments = append(segments, prefetchedSegment{name: file, logSegNo: fileLogSegNo})
+	}
+	if len(segments) <= limit {
+		return
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].logSegNo < segments[j].logSegNo
+	})
+	for _, segment := range segments[limit:] {
+		tracelog.DebugLogger.Printf("WAL-prefetch disk limit: evicting %s", segment.name)
+		cleaner.Remove(path.Join(prefetchLocation, segment.name))
+	}
+}
+
+// prefetchStateStorage loads and saves PrefetchState under a file lock, so concurrent
+// wal-prefetch processes and daemon goroutines do not corrupt it
+type prefetchStateStorage struct {
+	path string
+}
+
+func newPrefetchStateStorage(prefetchLocation string) prefetchStateStorage {
+	return prefetchStateStorage{path: path.Join(prefetchLocation, prefetchStateFileName)}
+}
+
+// ObserveFetch records the wal-fetch request in the stored state. Failures are logged, since
+// prefetch can always fall back to the default plan.
+func (storage prefetchStateStorage) ObserveFetch(walFileName string) PrefetchState {
+	timelineID, logSegNo, err := ParseWALFilename(walFileName)
+	if err != nil {
+		return PrefetchState{}
+	}
+	state, err := storage.Update(func(state *PrefetchState) {
+		state.ObserveFetch(timelineID, logSegNo, time.Now())
+	})
+	if err != nil {
+		tracelog.WarningLogger.Printf("WAL-prefetch: failed to update prefetch state: %v", err)
+	}
+	return state
+}
+
+// ObserveDownloads records durations of finished segment downloads in the stored state
+func (storage prefetchStateStorage) ObserveDownloads(durations <-chan time.Duration) {
+	_, err := storage.Update(func(state *PrefetchState) {
+		for duration := range durations {
+			state.ObserveDownload(duration)
+		}
+	})
+	if err != nil {
+		tracelog.WarningLogger.Printf("WAL-prefetch: failed to update prefetch state: %v", err)
+	}
+}
+
+// Update applies modify to the stored state and returns the result
+func (storage prefetchStateStorage) Update(modify func(state *PrefetchState)) (PrefetchState, error) {
+	var state PrefetchState
+	err := os.MkdirAll(path.Dir(storage.path), 0755)
+	if err != nil {
+		return state, err
+	}
+	lock := flock.New(storage.path + ".lock")
+	err = lock.Lock()
+	if err != nil {
+		return state, err
+	}
+	defer func() { _ = lock.Unlock() }()
+
+	data, err := os.ReadFile(storage.path)
+	if err == nil {
+		if err := json.Unmarshal(data, &state); err != nil {
+			tracelog.WarningLogger.Printf("WAL-prefetch: resetting malformed state %s: %v", storage.path, err)
+			state = PrefetchState{}
+		}
+	} else if !os.IsNotExist(err) {
+		return state, err
+	}
+
+	modify(&state)
+
+	data, err = json.Marshal(state)
+	if err != nil {
+		return state, err
+	}
+	tmpPath := storage.path + ".tmp"
+	err = os.WriteFile(tmpPath, data, 0644)
+	if err != nil {
+		return state, err
+	}
+	return state, os.Rename(tmpPath, storage.path)
+}
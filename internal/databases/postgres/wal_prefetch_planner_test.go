@@ -0,0 +1,117 @@
+package postgres_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/internal/databases/postgres/mocks"
+	"go.uber.org/mock/gomock"
+)
+
+func TestPrefetchStateObserveFetch(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	state := postgres.PrefetchState{}
+
+	state.ObserveFetch(1, 100, start)
+	assert.Equal(t, 0.0, state.ReplayRate)
+
+	state.ObserveFetch(1, 104, start.Add(2*time.Second))
+	assert.InDelta(t, 2.0, state.ReplayRate, 1e-9)
+
+	state.ObserveFetch(1, 105, start.Add(3*time.Second))
+	assert.InDelta(t, 2.0*0.7+1.0*0.3, state.ReplayRate, 1e-9)
+	assert.Equal(t, uint64(105), state.LogSegNo)
+}
+
+func TestPrefetchStateObserveFetchIgnoresTimelineSwitch(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	state := postgres.PrefetchState{}
+
+	state.ObserveFetch(1, 100, start)
+	state.ObserveFetch(1, 101, start.Add(time.Second))
+	state.ObserveFetch(2, 102, start.Add(2*time.Second))
+
+	assert.InDelta(t, 1.0, state.ReplayRate, 1e-9)
+	assert.Equal(t, uint32(2), state.TimelineID)
+}
+
+func TestPrefetchStateObserveFetchResetsAfterIdle(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	state := postgres.PrefetchState{}
+
+	state.ObserveFetch(1, 100, start)
+	state.ObserveFetch(1, 110, start.Add(time.Second))
+	state.ObserveFetch(1, 111, start.Add(time.Hour))
+
+	assert.Equal(t, 0.0, state.ReplayRate)
+}
+
+func TestPrefetchPlannerWithoutMeasurements(t *testing.T) {
+	planner := postgres.PrefetchPlanner{MaxConcurrency: 10, MaxLookahead: 64}
+
+	concurrency, lookahead := planner.Plan(postgres.PrefetchState{})
+
+	assert.Equal(t, 10, concurrency)
+	assert.Equal(t, 10, lookahead)
+}
+
+func TestPrefetchPlannerScalesWithReplayRate(t *testing.T) {
+	planner := postgres.PrefetchPlanner{MaxConcurrency: 10, MaxLookahead: 64}
+
+	concurrency, lookahead := planner.Plan(postgres.PrefetchState{ReplayRate: 0.5, DownloadSeconds: 1})
+	assert.Equal(t, 1, concurrency)
+	assert.Equal(t, 2, lookahead)
+
+	concurrency, lookahead = planner.Plan(postgres.PrefetchState{ReplayRate: 4, DownloadSeconds: 2})
+	assert.Equal(t, 10, concurrency)
+	assert.Equal(t, 24, lookahead)
+
+	concurrency, lookahead = planner.Plan(postgres.PrefetchState{ReplayRate: 100, DownloadSeconds: 2})
+	assert.Equal(t, 10, concurrency)
+	assert.Equal(t, 64, lookahead)
+}
+
+func TestPrefetchPlannerRespectsDiskUsage(t *testing.T) {
+	planner := postgres.PrefetchPlanner{
+		MaxConcurrency: 10,
+		MaxLookahead:   64,
+		MaxDiskUsage:   4 * 16 * 1024 * 1024,
+		SegmentSize:    16 * 1024 * 1024,
+	}
+
+	concurrency, lookahead := planner.Plan(postgres.PrefetchState{ReplayRate: 4, DownloadSeconds: 2})
+	assert.Equal(t, 4, concurrency)
+	assert.Equal(t, 4, lookahead)
+
+	concurrency, lookahead = planner.Plan(postgres.PrefetchState{})
+	assert.Equal(t, 4, concurrency)
+	assert.Equal(t, 4, lookahead)
+}
+
+func TestEnforcePrefetchDiskLimitEvictsFarthestSegments(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	planner := postgres.PrefetchPlanner{MaxDiskUsage: 2 * 16, SegmentSize: 16}
+
+	cleaner := mocks.NewMockCleaner(ctrl)
+	cleaner.EXPECT().GetFiles("/A/.wal-g/prefetch").Return([]string{
+		"000000010000000100000059",
+		"00000001000000010000005B",
+		"00000001000000010000005A",
+		"00000001000000010000005C",
+		"prefetch_state.json",
+	}, nil)
+	cleaner.EXPECT().Remove("/A/.wal-g/prefetch/00000001000000010000005B")
+	cleaner.EXPECT().Remove("/A/.wal-g/prefetch/00000001000000010000005C")
+
+	planner.EnforcePrefetchDiskLimit("000000010000000100000058", "/A", cleaner)
+}
+
+func TestEnforcePrefetchDiskLimitWithoutCap(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	planner := postgres.PrefetchPlanner{}
+
+	cleaner := mocks.NewMockCleaner(ctrl)
+	planner.EnforcePrefetchDiskLimit("000000010000000100000058", "/A", cleaner)
+}
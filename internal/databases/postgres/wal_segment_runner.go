@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"path"
 
 	"github.com/pkg/errors"
 	"github.com/wal-g/tracelog"
@@ -117,9 +118,9 @@ func (r *WalSegmentRunner) getNextSegment() WalSegmentDescription {
 	return nextSegment
 }
 
-// getFolderFilenames returns a set of filenames in provided storage folder
+// getFolderFilenames lists the WAL folder, segments packed into bundles by wal-compact are listed as plain segments
 func getFolderFilenames(ctx context.Context, folder storage.Folder) ([]string, error) {
-	objects, _, err := folder.ListFolder(ctx)
+	objects, subFolders, err := folder.ListFolder(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -127,6 +128,18 @@ func getFolderFilenames(ctx context.Context, folder storage.Folder) ([]string, e
 	for _, object := range objects {
 		filenames = append(filenames, object.GetName())
 	}
+	for _, subFolder := range subFolders {
+		if path.Base(subFolder.GetPath()) != WalBundlesFolder {
+			continue
+		}
+		bundles, err := ListWalBundles(ctx, subFolder)
+		if err != nil {
+			return nil, err
+		}
+		for _, bundle := range bundles {
+			filenames = append(filenames, bundle.SegmentNames()...)
+		}
+	}
 	return filenames, nil
 }
 
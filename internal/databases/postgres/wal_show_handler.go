@@ -5,6 +5,7 @@ import (
 	"context"
 	"slices"
 
+	"github.com/pkg/errors"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/pkg/storages/storage"
@@ -115,9 +116,17 @@ func (seq *WalSegmentsSequence) FindMissingSegments() ([]WalSegmentDescription,
 // HandleWalShow gets the list of files inside WAL folder, detects the available WAL segments,
 // groups WAL segments by the timeline and shows detailed info about each timeline stored in storage
 func HandleWalShow(ctx context.Context, rootFolder storage.Folder, showBackups bool, outputWriter WalShowOutputWriter) {
+	err := WriteWalShow(ctx, rootFolder, showBackups, outputWriter)
+	tracelog.ErrorLogger.FatalOnError(err)
+}
+
+// WriteWalShow collects the WAL segments info grouped by timelines and writes it with the outputWriter
+func WriteWalShow(ctx context.Context, rootFolder storage.Folder, showBackups bool, outputWriter WalShowOutputWriter) error {
 	walFolder := rootFolder.GetSubFolder(utility.WalPath)
 	filenames, err := getFolderFilenames(ctx, walFolder)
-	tracelog.ErrorLogger.FatalfOnError("Failed to get the WAL folder filenames %v\n", err)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the WAL folder filenames")
+	}
 
 	walSegments := getSegmentsFromFiles(filenames)
 	segmentsByTimelines := groupSegmentsByTimelines(walSegments)
@@ -127,18 +136,22 @@ func HandleWalShow(ctx context.Context, rootFolder storage.Folder, showBackups b
 		historyRecords, err := GetTimeLineHistoryRecords(ctx, segmentsSequence.TimelineID, walFolder)
 		if err != nil {
 			if _, ok := err.(HistoryFileNotFoundError); !ok {
-				tracelog.ErrorLogger.Fatalf("Error while loading .history file %v\n", err)
+				return errors.Wrap(err, "error while loading .history file")
 			}
 		}
 
 		info, err := NewTimelineInfo(segmentsSequence, historyRecords)
-		tracelog.ErrorLogger.FatalfOnError("Error while creating TimeLineInfo %v\n", err)
+		if err != nil {
+			return errors.Wrap(err, "error while creating TimeLineInfo")
+		}
 		timelineInfos = append(timelineInfos, info)
 	}
 
 	if showBackups {
 		timelineInfos, err = addBackupsInfo(ctx, timelineInfos, rootFolder)
-		tracelog.ErrorLogger.FatalfOnError("Failed to add backups info: %v\n", err)
+		if err != nil {
+			return errors.Wrap(err, "failed to add backups info")
+		}
 	}
 
 	// order timelines by ID
@@ -147,7 +160,7 @@ func HandleWalShow(ctx context.Context, rootFolder storage.Folder, showBackups b
 	})
 
 	err = outputWriter.Write(timelineInfos)
-	tracelog.ErrorLogger.FatalfOnError("Error writing output: %v\n", err)
+	return errors.Wrap(err, "error writing output")
 }
 
 func groupSegmentsByTimelines(segments map[WalSegmentDescription]bool) map[uint32]*WalSegmentsSequence {
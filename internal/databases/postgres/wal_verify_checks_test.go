@@ -0,0 +1,114 @@
+package postgres_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/internal/walparser"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// makeSwitchWalSegment makes the segment which consists of the WAL switch record only
+func makeSwitchWalSegment(segmentNo postgres.WalSegmentNo) []byte {
+	segment := make([]byte, postgres.WalSegmentSize)
+	binary.LittleEndian.PutUint16(segment[0:], 0xD10D)
+	binary.LittleEndian.PutUint16(segment[2:], walparser.XlpLongHeader)
+	binary.LittleEndian.PutUint32(segment[4:], 1)
+	binary.LittleEndian.PutUint64(segment[8:], uint64(segmentNo)*postgres.WalSegmentSize)
+	binary.LittleEndian.PutUint32(segment[32:], uint32(postgres.WalSegmentSize))
+	binary.LittleEndian.PutUint32(segment[36:], 8192)
+
+	record := segment[40 : 40+walparser.XLogRecordHeaderSize]
+	binary.LittleEndian.PutUint32(record[0:], walparser.XLogRecordHeaderSize)
+	record[16] = walparser.XLogSwitch
+	binary.LittleEndian.PutUint32(record[20:], crc32.Checksum(record[:20], crc32.MakeTable(crc32.Castagnoli)))
+	return segment
+}
+
+func TestWalVerify_RecordsCheck(t *testing.T) {
+	rootFolder := setupTestStorageFolder()
+	walFolder := rootFolder.GetSubFolder(utility.WalPath)
+	corrupted := makeSwitchWalSegment(3)
+	corrupted[40+walparser.XLogRecordHeaderSize-1] ^= 0xFF
+	for name, segment := range map[string][]byte{
+		"000000010000000000000002": makeSwitchWalSegment(2),
+		"000000010000000000000003": corrupted,
+	} {
+		var data bytes.Buffer
+		writer := lz4.Compressor{}.NewWriter(&data)
+		_, err := writer.Write(segment)
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+		require.NoError(t, walFolder.PutObject(t.Context(), name+".lz4", &data))
+	}
+
+	currentSegment, _ := postgres.NewWalSegmentDescription("000000010000000000000004")
+	runner, err := postgres.BuildWalVerifyCheckRunner(t.Context(), postgres.WalVerifyRecordsCheck, rootFolder,
+		[]string{"000000010000000000000002.lz4", "000000010000000000000003.lz4"}, currentSegment,
+		postgres.BackupSearchParams{FindEarliestBackup: true})
+	require.NoError(t, err)
+	result, err := runner.Run()
+	require.NoError(t, err)
+
+	assert.Equal(t, postgres.StatusFailure, result.Status)
+	details := result.Details.(postgres.RecordsCheckDetails)
+	assert.Equal(t, 2, details.SegmentsCount)
+	assert.Equal(t, 1, details.RecordsCount)
+	require.Len(t, details.CorruptedSegments, 1)
+	assert.Equal(t, "000000010000000000000003", details.CorruptedSegments[0].Segment)
+	assert.Equal(t, "0/03000028", details.CorruptedSegments[0].Lsn)
+	assert.Contains(t, details.CorruptedSegments[0].Error, "incorrect record CRC")
+}
+
+func TestWalVerify_BackupCoverageCheck(t *testing.T) {
+	firstBackup := newMockExtendedMetadataDto(false)
+	firstBackup.StartLsn = postgres.LSN(2 * postgres.WalSegmentSize)
+	firstBackup.FinishLsn = postgres.LSN(3*postgres.WalSegmentSize - 100)
+	secondBackup := newMockExtendedMetadataDto(false)
+	secondBackup.StartLsn = postgres.LSN(5*postgres.WalSegmentSize + 100)
+	secondBackup.FinishLsn = postgres.LSN(6*postgres.WalSegmentSize + 100)
+	storageFiles := make(map[string]*bytes.Buffer)
+	addMockBackupsStorageFiles(map[string]postgres.ExtendedMetadataDto{
+		"000000010000000000000002": firstBackup,
+		"000000010000000000000005": secondBackup,
+	}, storageFiles)
+
+	rootFolder := setupTestStorageFolder()
+	for name, content := range storageFiles {
+		require.NoError(t, rootFolder.PutObject(t.Context(), name, content))
+	}
+	walFilenames := []string{"000000010000000000000002.lz4", "000000010000000000000003.lz4",
+		"000000010000000000000005.lz4", "000000010000000000000006.lz4"}
+
+	runner, err := postgres.BuildWalVerifyCheckRunner(t.Context(), postgres.WalVerifyBackupCoverageCheck, rootFolder,
+		walFilenames, postgres.WalSegmentDescription{}, postgres.BackupSearchParams{FindEarliestBackup: true})
+	require.NoError(t, err)
+	result, err := runner.Run()
+	require.NoError(t, err)
+
+	assert.Equal(t, postgres.StatusFailure, result.Status)
+	assert.Equal(t, postgres.BackupCoverageCheckDetails{
+		{
+			BackupName:           "base_000000010000000000000002",
+			NextBackupName:       "base_000000010000000000000005",
+			StartSegment:         "000000010000000000000002",
+			EndSegment:           "000000010000000000000005",
+			MissingSegmentsCount: 1,
+			FirstMissingSegment:  "000000010000000000000004",
+			LastMissingSegment:   "000000010000000000000004",
+			Status:               postgres.CoverageBroken,
+		},
+		{
+			BackupName:   "base_000000010000000000000005",
+			StartSegment: "000000010000000000000005",
+			EndSegment:   "000000010000000000000006",
+			Status:       postgres.CoverageComplete,
+		},
+	}, result.Details)
+}
@@ -16,10 +16,12 @@ type WalVerifyCheckType int
 const (
 	WalVerifyIntegrityCheck = iota + 1
 	WalVerifyTimelineCheck
+	WalVerifyRecordsCheck
+	WalVerifyBackupCoverageCheck
 )
 
 func (checkType WalVerifyCheckType) String() string {
-	return [...]string{"", "integrity", "timeline"}[checkType]
+	return [...]string{"", "integrity", "timeline", "records", "backup-coverage"}[checkType]
 }
 
 func (checkType WalVerifyCheckType) MarshalText() (text []byte, err error) {
@@ -126,6 +128,10 @@ func BuildWalVerifyCheckRunner(
 		checkRunner, err = NewTimelineCheckRunner(walFolderFilenames, currentWalSegment)
 	case WalVerifyIntegrityCheck:
 		checkRunner, err = NewIntegrityCheckRunner(ctx, rootFolder, walFolderFilenames, currentWalSegment, backupSearchParams)
+	case WalVerifyRecordsCheck:
+		checkRunner, err = NewRecordsCheckRunner(ctx, rootFolder, walFolderFilenames, currentWalSegment, backupSearchParams)
+	case WalVerifyBackupCoverageCheck:
+		checkRunner, err = NewBackupCoverageCheckRunner(ctx, rootFolder, walFolderFilenames)
 	default:
 		return nil, NewUnknownWalVerifyCheckError(checkType)
 	}
@@ -78,6 +78,24 @@ func IsPermanentFunc(isPermanent func(storage.Object) bool) DeleteHandlerOption
 	}
 }
 
+// AdjustTargetFunc sets the function which may change the target found by delete before, retain and retain FULL,
+// e.g. move it back to keep more backups
+func AdjustTargetFunc(adjustTarget func(target BackupObject) (BackupObject, error)) DeleteHandlerOption {
+	return func(h *DeleteHandler) {
+		h.adjustTarget = adjustTarget
+	}
+}
+
+// DeleteBeforeTargetFunc sets the function which deletes the objects before the target
+// found by delete before, retain and retain FULL
+func DeleteBeforeTargetFunc(
+	deleteBeforeTarget func(ctx context.Context, target BackupObject, confirmed bool) error,
+) DeleteHandlerOption {
+	return func(h *DeleteHandler) {
+		h.deleteBeforeTarget = deleteBeforeTarget
+	}
+}
+
 func NewDeleteHandler(
 	folder storage.Folder,
 	backups []BackupObject,
@@ -93,7 +111,11 @@ func NewDeleteHandler(
 		},
 		// by default, all storage objects are impermanent
 		isPermanent: func(storage.Object) bool { return false },
+		adjustTarget: func(target BackupObject) (BackupObject, error) {
+			return target, nil
+		},
 	}
+	deleteHandler.deleteBeforeTarget = deleteHandler.DeleteBeforeTarget
 
 	for _, option := range options {
 		option(deleteHandler)
@@ -110,6 +132,9 @@ type DeleteHandler struct {
 	greater func(object1, object2 storage.Object) bool
 
 	isPermanent func(object storage.Object) bool
+
+	adjustTarget       func(target BackupObject) (BackupObject, error)
+	deleteBeforeTarget func(ctx context.Context, target BackupObject, confirmed bool) error
 }
 
 func (h *DeleteHandler) HandleDeleteBefore(ctx context.Context, args []string, confirmed bool) {
@@ -117,13 +142,7 @@ func (h *DeleteHandler) HandleDeleteBefore(ctx context.Context, args []string, c
 
 	target, err := h.FindTargetBefore(beforeStr, modifier)
 	tracelog.ErrorLogger.FatalOnError(err)
-	if target == nil {
-		tracelog.InfoLogger.Printf("No backup found for deletion")
-		os.Exit(0)
-	}
-
-	err = h.DeleteBeforeTarget(ctx, target, confirmed)
-	tracelog.ErrorLogger.FatalOnError(err)
+	h.handleDeleteBeforeTarget(ctx, target, confirmed)
 }
 
 func (h *DeleteHandler) HandleDeleteRetain(ctx context.Context, args []string, confirmed bool) {
@@ -133,12 +152,7 @@ func (h *DeleteHandler) HandleDeleteRetain(ctx context.Context, args []string, c
 
 	target, err := h.FindTargetRetain(retentionCount, modifier)
 	tracelog.ErrorLogger.FatalOnError(err)
-	if target == nil {
-		tracelog.InfoLogger.Printf("No backup found for deletion")
-		os.Exit(0)
-	}
-	err = h.DeleteBeforeTarget(ctx, target, confirmed)
-	tracelog.ErrorLogger.FatalOnError(err)
+	h.handleDeleteBeforeTarget(ctx, target, confirmed)
 }
 
 func (h *DeleteHandler) HandleDeleteRetainAfter(ctx context.Context, args []string, confirmed bool) {
@@ -148,13 +162,18 @@ func (h *DeleteHandler) HandleDeleteRetainAfter(ctx context.Context, args []stri
 
 	target, err := h.FindTargetRetainAfter(retentionCount, afterStr, modifier)
 	tracelog.ErrorLogger.FatalOnError(err)
+	h.handleDeleteBeforeTarget(ctx, target, confirmed)
+}
 
+func (h *DeleteHandler) handleDeleteBeforeTarget(ctx context.Context, target BackupObject, confirmed bool) {
+	target, err := h.adjustTarget(target)
+	tracelog.ErrorLogger.FatalOnError(err)
 	if target == nil {
 		tracelog.InfoLogger.Printf("No backup found for deletion")
 		os.Exit(0)
 	}
 
-	err = h.DeleteBeforeTarget(ctx, target, confirmed)
+	err = h.deleteBeforeTarget(ctx, target, confirmed)
 	tracelog.ErrorLogger.FatalOnError(err)
 }
 
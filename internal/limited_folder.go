@@ -35,6 +35,17 @@ func (lf *LimitedFolder) ReadObject(ctx context.Context, objectRelativePath stri
 	}, nil
 }
 
+func (lf *LimitedFolder) ReadObjectRange(ctx context.Context, objectRelativePath string, offset, length int64) (io.ReadCloser, error) {
+	readCloser, err := storage.ReadObjectRange(ctx, lf.Folder, objectRelativePath, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	return ioextensions.ReadCascadeCloser{
+		Reader: limiters.NewReader(ctx, readCloser, lf.limiter),
+		Closer: readCloser,
+	}, nil
+}
+
 func (lf *LimitedFolder) PutObject(ctx context.Context, name string, content io.Reader) error {
 	limitedReader := limiters.NewReader(ctx, content, lf.limiter)
 	return lf.Folder.PutObject(ctx, name, limitedReader)
@@ -43,3 +43,23 @@ func TestLimiter(t *testing.T) {
 		t.Errorf("Rate limiter did not work")
 	}
 }
+
+func TestNetworkLimitWriter(t *testing.T) {
+	limiters.NetworkLimiter = rate.NewLimiter(rate.Limit(10000), int(1024))
+	defer func() {
+		limiters.NetworkLimiter = nil
+	}()
+	var buffer bytes.Buffer
+	start := utility.TimeNowCrossPlatformLocal()
+
+	writer := limiters.NewNetworkLimitWriter(t.Context(), &buffer)
+	n, err := writer.Write(make([]byte, 2000))
+	assert.NoError(t, err)
+	assert.Equal(t, 2000, n)
+	assert.Equal(t, 2000, buffer.Len())
+	end := utility.TimeNowCrossPlatformLocal()
+
+	if end.Sub(start) < time.Millisecond*80 {
+		t.Errorf("Rate limiter did not work")
+	}
+}
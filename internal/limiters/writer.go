@@ -0,0 +1,47 @@
+package limiters
+
+import (
+	"context"
+	"io"
+
+	"golang.org/x/time/rate"
+)
+
+type Writer struct {
+	writer  io.Writer
+	limiter *rate.Limiter
+	ctx     context.Context //nolint:containedctx // ctx-aware io.Writer; Write carries no ctx
+}
+
+func NewWriter(ctx context.Context, writer io.Writer, limiter *rate.Limiter) *Writer {
+	return &Writer{
+		writer:  writer,
+		limiter: limiter,
+		ctx:     ctx,
+	}
+}
+
+// NewNetworkLimitWriter returns a writer that is rate limited by network limiter
+func NewNetworkLimitWriter(ctx context.Context, w io.Writer) io.Writer {
+	if NetworkLimiter == nil {
+		return w
+	}
+	return NewWriter(ctx, w, NetworkLimiter)
+}
+
+func (w *Writer) Write(buf []byte) (int, error) {
+	written := 0
+	for len(buf) > 0 {
+		chunk := buf[:min(len(buf), w.limiter.Burst())]
+		if err := w.limiter.WaitN(w.ctx, len(chunk)); err != nil {
+			return written, err
+		}
+		n, err := w.writer.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		buf = buf[n:]
+	}
+	return written, nil
+}
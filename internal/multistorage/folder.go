@@ -380,6 +380,41 @@ func (mf Folder) ReadObjectFoundFirst(ctx context.Context, objectRelativePath st
 	return nil, consts.AllStorages, storage.NewObjectNotFoundError(objectRelativePath)
 }
 
+// ReadObjectRange reads a part of the object, see storage.RangeReadableFolder. The storage to read from is selected
+// the same way as in ReadObject.
+func (mf Folder) ReadObjectRange(ctx context.Context, objectRelativePath string, offset, length int64) (io.ReadCloser, error) {
+	if len(mf.usedFolders) == 0 {
+		return nil, ErrNoUsedStorages
+	}
+	candidates := mf.usedFolders
+	if mf.policies.Read == policies.ReadPolicyFirst {
+		candidates = candidates[:1]
+	}
+	for _, f := range candidates {
+		if mf.policies.Read == policies.ReadPolicyFoundFirst {
+			exists, err := f.Exists(ctx, objectRelativePath)
+			if err != nil {
+				mf.statsCollector.ReportOperationResult(f.StorageName, stats.OperationExists, false)
+				return nil, fmt.Errorf("check file for existence in %q: %w", f.StorageName, err)
+			}
+			if !exists {
+				continue
+			}
+		}
+		file, err := storage.ReadObjectRange(ctx, f.Folder, objectRelativePath, offset, length)
+		if err != nil {
+			if _, ok := err.(storage.ObjectNotFoundError); ok {
+				mf.statsCollector.ReportOperationResult(f.StorageName, stats.OperationRead(0), true)
+				return nil, err
+			}
+			mf.statsCollector.ReportOperationResult(f.StorageName, stats.OperationRead(0), false)
+			return nil, fmt.Errorf("read object range from %q: %w", f.StorageName, err)
+		}
+		return newReportReadCloser(file, mf.statsCollector, f.StorageName), nil
+	}
+	return nil, storage.NewObjectNotFoundError(objectRelativePath)
+}
+
 // ListFolder lists the folder in multiple storages. A specific implementation is selected using policies.Policies
 func (mf Folder) ListFolder(ctx context.Context) (objects []storage.Object, subFolders []storage.Folder, err error) {
 	switch mf.policies.List {
@@ -27,6 +27,11 @@ func (fsr *FolderReaderImpl) SubFolder(subFolderRelativePath string) StorageFold
 	return NewFolderReader(fsr.GetSubFolder(subFolderRelativePath))
 }
 
+// ReadObjectRange reads a part of the object, see storage.ReadObjectRange
+func (fsr *FolderReaderImpl) ReadObjectRange(ctx context.Context, objectRelativePath string, offset, length int64) (io.ReadCloser, error) {
+	return storage.ReadObjectRange(ctx, fsr.Folder, objectRelativePath, offset, length)
+}
+
 func PrepareMultiStorageFolderReader(ctx context.Context, folder storage.Folder, targetStorage string) (StorageFolderReader, error) {
 	folder = multistorage.SetPolicies(folder, policies.MergeAllStorages)
 	var err error
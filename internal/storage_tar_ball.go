@@ -111,7 +111,10 @@ func (tarBall *StorageTarBall) startUpload(ctx context.Context, name string, cry
 		encryptedWriter, err := crypter.Encrypt(pipeWriter)
 
 		if err != nil {
-			tracelog.ErrorLogger.Fatal("upload: encryption error ", err)
+			tracelog.ErrorLogger.Printf("upload: encryption error: %v", err)
+			// the tar packing fails on the first write, the upload goroutine gets the same error
+			_ = pipeWriter.CloseWithError(err)
+			return pipeWriter
 		}
 
 		writerToCompress = &utility.CascadeWriteCloser{WriteCloser: encryptedWriter, Underlying: pipeWriter}
@@ -0,0 +1,264 @@
+package walparser
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+const (
+	/* This flag indicates that the continuation of an aborted record was overwritten */
+	XlpFirstIsOverwriteContRecord = 0x0008
+
+	xLogShortPageHeaderSize = 24
+	xLogLongPageHeaderSize  = 40
+	// xLogRecordCrcOffset is offsetof(XLogRecord, xl_crc)
+	xLogRecordCrcOffset = 20
+	// xLogRecordMaxSize is XLogRecordMaxSize of postgres
+	xLogRecordMaxSize = 1020 * 1024 * 1024
+)
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+type WalCorruptionError struct {
+	error
+	Lsn XLogRecordPtr
+}
+
+func newWalCorruptionError(lsn XLogRecordPtr, format string, args ...interface{}) WalCorruptionError {
+	return WalCorruptionError{
+		errors.Errorf("%s: %s", FormatLsn(lsn), fmt.Sprintf(format, args...)),
+		lsn,
+	}
+}
+
+func (err WalCorruptionError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+func FormatLsn(lsn XLogRecordPtr) string {
+	return fmt.Sprintf("%X/%08X", uint64(lsn)>>32, uint32(lsn))
+}
+
+// WalVerifier checks the page headers, the record lengths, CRCs and back links of WAL segments
+// without replaying them. The records crossing the segment boundaries are checked
+// when the consecutive segments are verified one after another.
+type WalVerifier struct {
+	segmentSize uint64
+	// nextSegmentLsn is the start of the segment following the last verified one
+	nextSegmentLsn XLogRecordPtr
+	magic          uint16
+	timeline       TimeLineID
+
+	// synced is false until a record boundary is found after a gap
+	synced        bool
+	record        []byte
+	recordLength  uint32
+	recordLsn     XLogRecordPtr
+	prevRecordLsn XLogRecordPtr
+
+	RecordsCount int
+}
+
+func NewWalVerifier(segmentSize uint64) *WalVerifier {
+	return &WalVerifier{segmentSize: segmentSize}
+}
+
+func (verifier *WalVerifier) reset() {
+	verifier.nextSegmentLsn = 0
+	verifier.timeline = 0
+	verifier.synced = false
+	verifier.record = nil
+	verifier.prevRecordLsn = 0
+}
+
+// VerifySegment reads the whole WAL segment starting at segmentLsn and returns WalCorruptionError on the first problem
+func (verifier *WalVerifier) VerifySegment(reader io.Reader, segmentLsn XLogRecordPtr) error {
+	if segmentLsn != verifier.nextSegmentLsn || segmentLsn == 0 {
+		verifier.reset()
+	}
+	page := make([]byte, WalPageSize)
+	switched := false
+	for offset := uint64(0); offset < verifier.segmentSize; offset += uint64(WalPageSize) {
+		pageLsn := segmentLsn + XLogRecordPtr(offset)
+		_, err := io.ReadFull(reader, page)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			verifier.reset()
+			return newWalCorruptionError(pageLsn, "segment is truncated to %d bytes", offset)
+		}
+		if err != nil {
+			verifier.reset()
+			return errors.WithStack(err)
+		}
+		// the rest of the segment after the WAL switch record is not used
+		if switched {
+			continue
+		}
+		switched, err = verifier.verifyPage(page, pageLsn, offset == 0)
+		if err != nil {
+			verifier.reset()
+			return err
+		}
+	}
+	verifier.nextSegmentLsn = segmentLsn + XLogRecordPtr(verifier.segmentSize)
+	return nil
+}
+
+// verifyPage reports whether the page contains the WAL switch record
+func (verifier *WalVerifier) verifyPage(page []byte, pageLsn XLogRecordPtr, firstInSegment bool) (bool, error) {
+	headerSize, info, remainingDataLen, err := verifier.verifyPageHeader(page, pageLsn, firstInSegment)
+	if err != nil {
+		return false, err
+	}
+	data := page[headerSize:]
+	position, err := verifier.readContinuation(data, pageLsn, info, remainingDataLen)
+	if err != nil || position >= len(data) {
+		return false, err
+	}
+
+	for position < len(data) {
+		lsn := pageLsn + XLogRecordPtr(headerSize+position)
+		// records are aligned, so the length of the record is always on the same page
+		recordLength := binary.LittleEndian.Uint32(data[position:])
+		if recordLength == 0 {
+			return false, newWalCorruptionError(lsn, "WAL ends before the end of the segment")
+		}
+		if recordLength < XLogRecordHeaderSize || recordLength > xLogRecordMaxSize {
+			return false, newWalCorruptionError(lsn, "invalid record length %d", recordLength)
+		}
+		verifier.record = make([]byte, 0, recordLength)
+		verifier.recordLength = recordLength
+		verifier.recordLsn = lsn
+
+		part := data[position:min(len(data), position+int(recordLength))]
+		verifier.record = append(verifier.record, part...)
+		position += alignRecordLength(len(part))
+		if len(verifier.record) < int(recordLength) {
+			return false, nil
+		}
+		switched, err := verifier.finishRecord()
+		if err != nil || switched {
+			return switched, err
+		}
+	}
+	return false, nil
+}
+
+func (verifier *WalVerifier) verifyPageHeader(page []byte, pageLsn XLogRecordPtr,
+	firstInSegment bool) (int, uint16, uint32, error) {
+	magic := binary.LittleEndian.Uint16(page[0:])
+	info := binary.LittleEndian.Uint16(page[2:])
+	timeline := TimeLineID(binary.LittleEndian.Uint32(page[4:]))
+	pageAddress := XLogRecordPtr(binary.LittleEndian.Uint64(page[8:]))
+	remainingDataLen := binary.LittleEndian.Uint32(page[16:])
+
+	if magic == 0 && info == 0 && timeline == 0 && pageAddress == 0 {
+		return 0, 0, 0, newWalCorruptionError(pageLsn, "zero page, WAL ends before the end of the segment")
+	}
+	if verifier.magic == 0 {
+		verifier.magic = magic
+	} else if magic != verifier.magic {
+		return 0, 0, 0, newWalCorruptionError(pageLsn, "invalid page magic %04X, expected %04X", magic, verifier.magic)
+	}
+	if info&^(XlpAllFlags|XlpFirstIsOverwriteContRecord) != 0 {
+		return 0, 0, 0, newWalCorruptionError(pageLsn, "invalid page flags %04X", info)
+	}
+	if pageAddress != pageLsn {
+		return 0, 0, 0, newWalCorruptionError(pageLsn, "unexpected page address %s", FormatLsn(pageAddress))
+	}
+	if timeline < verifier.timeline {
+		return 0, 0, 0, newWalCorruptionError(pageLsn, "page timeline %d is lower than the previous page timeline %d",
+			timeline, verifier.timeline)
+	}
+	verifier.timeline = timeline
+
+	if !firstInSegment {
+		return xLogShortPageHeaderSize, info, remainingDataLen, nil
+	}
+	if info&XlpLongHeader == 0 {
+		return 0, 0, 0, newWalCorruptionError(pageLsn, "the first page of the segment has no long header")
+	}
+	segmentSize := binary.LittleEndian.Uint32(page[32:])
+	if uint64(segmentSize) != verifier.segmentSize {
+		return 0, 0, 0, newWalCorruptionError(pageLsn, "segment size %d, expected %d", segmentSize, verifier.segmentSize)
+	}
+	return xLogLongPageHeaderSize, info, remainingDataLen, nil
+}
+
+// readContinuation consumes the part of the record started on the previous pages and returns the next record position
+func (verifier *WalVerifier) readContinuation(data []byte, pageLsn XLogRecordPtr, info uint16,
+	remainingDataLen uint32) (int, error) {
+	if info&XlpFirstIsOverwriteContRecord != 0 {
+		// the record was aborted by a crash, its continuation was overwritten by XLOG_OVERWRITE_CONTRECORD
+		verifier.record = nil
+		verifier.prevRecordLsn = 0
+	}
+	if info&XlpFirstIsContRecord == 0 {
+		if verifier.record != nil {
+			return 0, newWalCorruptionError(verifier.recordLsn, "record is not continued on the page %s", FormatLsn(pageLsn))
+		}
+		verifier.synced = true
+		return 0, nil
+	}
+	if remainingDataLen == 0 {
+		return 0, newWalCorruptionError(pageLsn, "continuation record of zero length")
+	}
+
+	partLength := min(len(data), int(remainingDataLen))
+	if verifier.record == nil {
+		if verifier.synced {
+			return 0, newWalCorruptionError(pageLsn, "unexpected continuation record")
+		}
+		// the beginning of the record is in a segment which is not verified
+		if partLength == len(data) {
+			return len(data), nil
+		}
+		verifier.synced = true
+		return alignRecordLength(partLength), nil
+	}
+
+	if expected := verifier.recordLength - uint32(len(verifier.record)); remainingDataLen != expected {
+		return 0, newWalCorruptionError(pageLsn, "continuation record length %d, expected %d", remainingDataLen, expected)
+	}
+	verifier.record = append(verifier.record, data[:partLength]...)
+	if len(verifier.record) < int(verifier.recordLength) {
+		return len(data), nil
+	}
+	switched, err := verifier.finishRecord()
+	if err != nil {
+		return 0, err
+	}
+	if switched {
+		return len(data), nil
+	}
+	return alignRecordLength(partLength), nil
+}
+
+func (verifier *WalVerifier) finishRecord() (bool, error) {
+	record := verifier.record
+	verifier.record = nil
+
+	crc := crc32.Update(0, castagnoliTable, record[XLogRecordHeaderSize:])
+	crc = crc32.Update(crc, castagnoliTable, record[:xLogRecordCrcOffset])
+	if expectedCrc := binary.LittleEndian.Uint32(record[xLogRecordCrcOffset:]); crc != expectedCrc {
+		return false, newWalCorruptionError(verifier.recordLsn, "incorrect record CRC %08X, expected %08X", crc, expectedCrc)
+	}
+	prevRecordLsn := XLogRecordPtr(binary.LittleEndian.Uint64(record[8:]))
+	if verifier.prevRecordLsn != 0 && prevRecordLsn != verifier.prevRecordLsn {
+		return false, newWalCorruptionError(verifier.recordLsn, "record points to the previous record at %s, expected %s",
+			FormatLsn(prevRecordLsn), FormatLsn(verifier.prevRecordLsn))
+	}
+	verifier.prevRecordLsn = verifier.recordLsn
+	verifier.RecordsCount++
+
+	info, resourceManagerID := record[16], record[17]
+	return resourceManagerID == RmXlogID && (info&^XlrInfoMask) == XLogSwitch, nil
+}
+
+func alignRecordLength(length int) int {
+	return (length + XLogRecordAlignment - 1) &^ (XLogRecordAlignment - 1)
+}
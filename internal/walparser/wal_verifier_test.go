@@ -0,0 +1,81 @@
+package walparser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSegmentSize = 2 * 8192
+
+func makeTestSwitchSegment(segmentLsn XLogRecordPtr) []byte {
+	segment := make([]byte, testSegmentSize)
+	binary.LittleEndian.PutUint16(segment[0:], 0xD10D)
+	binary.LittleEndian.PutUint16(segment[2:], XlpLongHeader)
+	binary.LittleEndian.PutUint32(segment[4:], 1)
+	binary.LittleEndian.PutUint64(segment[8:], uint64(segmentLsn))
+	binary.LittleEndian.PutUint32(segment[32:], testSegmentSize)
+	binary.LittleEndian.PutUint32(segment[36:], 8192)
+
+	record := segment[xLogLongPageHeaderSize : xLogLongPageHeaderSize+XLogRecordHeaderSize]
+	binary.LittleEndian.PutUint32(record[0:], XLogRecordHeaderSize)
+	record[16] = XLogSwitch
+	record[17] = RmXlogID
+	crc := crc32.Checksum(record[:xLogRecordCrcOffset], castagnoliTable)
+	binary.LittleEndian.PutUint32(record[xLogRecordCrcOffset:], crc)
+	return segment
+}
+
+func TestWalVerifierChecksRealPages(t *testing.T) {
+	data, err := os.ReadFile(LongRecordTestPath)
+	require.NoError(t, err)
+	verifier := NewWalVerifier(16 * 1024 * 1024)
+	for offset := 0; offset < len(data); offset += int(WalPageSize) {
+		page := data[offset : offset+int(WalPageSize)]
+		pageLsn := XLogRecordPtr(binary.LittleEndian.Uint64(page[8:]))
+		_, err = verifier.verifyPage(page, pageLsn, uint64(pageLsn)%verifier.segmentSize == 0)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 215, verifier.RecordsCount)
+}
+
+func TestWalVerifierDetectsCorruptedRecord(t *testing.T) {
+	data, err := os.ReadFile(WalSwitchTestPath)
+	require.NoError(t, err)
+	page := bytes.Clone(data[:WalPageSize])
+	page[4400] ^= 0xFF
+
+	verifier := NewWalVerifier(16 * 1024 * 1024)
+	_, err = verifier.verifyPage(page, XLogRecordPtr(binary.LittleEndian.Uint64(page[8:])), false)
+	var corruptionErr WalCorruptionError
+	require.ErrorAs(t, err, &corruptionErr)
+	assert.Contains(t, err.Error(), "incorrect record CRC")
+}
+
+func TestWalVerifierVerifySegment(t *testing.T) {
+	verifier := NewWalVerifier(testSegmentSize)
+	segment := makeTestSwitchSegment(testSegmentSize)
+	require.NoError(t, verifier.VerifySegment(bytes.NewReader(segment), testSegmentSize))
+	assert.Equal(t, 1, verifier.RecordsCount)
+
+	err := verifier.VerifySegment(bytes.NewReader(segment[:WalPageSize]), testSegmentSize)
+	assert.ErrorContains(t, err, "segment is truncated to 8192 bytes")
+
+	err = verifier.VerifySegment(bytes.NewReader(segment), 2*testSegmentSize)
+	assert.ErrorContains(t, err, "unexpected page address")
+
+	corrupted := bytes.Clone(segment)
+	corrupted[xLogLongPageHeaderSize+8] = 1
+	err = verifier.VerifySegment(bytes.NewReader(corrupted), testSegmentSize)
+	assert.ErrorContains(t, err, "incorrect record CRC")
+
+	withoutSwitch := bytes.Clone(segment)
+	clear(withoutSwitch[xLogLongPageHeaderSize:])
+	err = verifier.VerifySegment(bytes.NewReader(withoutSwitch), testSegmentSize)
+	assert.ErrorContains(t, err, "WAL ends before the end of the segment")
+}
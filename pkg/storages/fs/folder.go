@@ -13,6 +13,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal/contextio"
+	"github.com/wal-g/wal-g/internal/ioextensions"
 	"github.com/wal-g/wal-g/pkg/storages/storage"
 )
 
@@ -138,6 +139,24 @@ func (folder *Folder) ReadObject(_ context.Context, objectRelativePath string) (
 	return file, nil
 }
 
+// ReadObjectRange reads a part of the file, see storage.RangeReadableFolder
+func (folder *Folder) ReadObjectRange(_ context.Context, objectRelativePath string, offset, length int64) (io.ReadCloser, error) {
+	filePath := folder.GetFilePath(objectRelativePath)
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil, storage.NewObjectNotFoundError(filePath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file %v: %w", filePath, err)
+	}
+	_, err = file.Seek(offset, io.SeekStart)
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("unable to seek file %v: %w", filePath, err)
+	}
+	return ioextensions.ReadCascadeCloser{Reader: io.LimitReader(file, length), Closer: file}, nil
+}
+
 func (folder *Folder) PutObject(ctx context.Context, name string, content io.Reader) error {
 	tracelog.DebugLogger.Printf("Put %v into %v\n", name, folder.subPath)
 	content = contextio.NewReader(ctx, content)
@@ -161,18 +161,7 @@ func (folder *Folder) CopyObject(ctx context.Context, srcPath string, dstPath st
 
 func (folder *Folder) ReadObject(ctx context.Context, objectRelativePath string) (io.ReadCloser, error) {
 	objectPath := folder.path + objectRelativePath
-	input := &s3.GetObjectInput{
-		Bucket: folder.bucket,
-		Key:    aws.String(objectPath),
-	}
-
-	if folder.uploader.serverSideEncryption != "" && folder.uploader.SSECustomerKey != "" {
-		input.SSECustomerAlgorithm = aws.String(folder.uploader.serverSideEncryption)
-		input.SSECustomerKey = aws.String(folder.uploader.SSECustomerKey)
-
-		customerKeyMD5 := GetSSECustomerKeyMD5(folder.uploader.SSECustomerKey)
-		input.SSECustomerKeyMD5 = aws.String(customerKeyMD5)
-	}
+	input := folder.newGetObjectInput(objectPath)
 
 	object, err := folder.s3API.GetObjectWithContext(ctx, input)
 	if err != nil {
@@ -188,6 +177,38 @@ func (folder *Folder) ReadObject(ctx context.Context, objectRelativePath string)
 	return NewContentLengthValidator(reader, aws.Int64Value(object.ContentLength), objectPath), nil
 }
 
+// ReadObjectRange reads a part of the object using the Range header, see storage.RangeReadableFolder
+func (folder *Folder) ReadObjectRange(ctx context.Context, objectRelativePath string, offset, length int64) (io.ReadCloser, error) {
+	objectPath := folder.path + objectRelativePath
+	input := folder.newGetObjectInput(objectPath)
+	input.Range = aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+
+	object, err := folder.s3API.GetObjectWithContext(ctx, input)
+	if err != nil {
+		if isAwsNotExist(err) {
+			return nil, storage.NewObjectNotFoundError(objectPath)
+		}
+		return nil, errors.Wrapf(err, "failed to read range %s of object: '%s' from S3", aws.StringValue(input.Range), objectPath)
+	}
+	return NewContentLengthValidator(object.Body, aws.Int64Value(object.ContentLength), objectPath), nil
+}
+
+func (folder *Folder) newGetObjectInput(objectPath string) *s3.GetObjectInput {
+	input := &s3.GetObjectInput{
+		Bucket: folder.bucket,
+		Key:    aws.String(objectPath),
+	}
+
+	if folder.uploader.serverSideEncryption != "" && folder.uploader.SSECustomerKey != "" {
+		input.SSECustomerAlgorithm = aws.String(folder.uploader.serverSideEncryption)
+		input.SSECustomerKey = aws.String(folder.uploader.SSECustomerKey)
+
+		customerKeyMD5 := GetSSECustomerKeyMD5(folder.uploader.SSECustomerKey)
+		input.SSECustomerKeyMD5 = aws.String(customerKeyMD5)
+	}
+	return input
+}
+
 func (folder *Folder) GetSubFolder(subFolderRelativePath string) storage.Folder {
 	subFolder := NewFolder(
 		folder.s3API,
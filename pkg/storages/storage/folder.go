@@ -6,6 +6,8 @@ import (
 	"io"
 	"path"
 	"strings"
+
+	"github.com/wal-g/wal-g/internal/ioextensions"
 )
 
 //go:generate mockery --name Folder
@@ -242,3 +244,31 @@ func SetShowAllVersions(folder Folder, show bool) bool {
 	}
 	return false
 }
+
+// RangeReadableFolder is an optional interface that folders can implement
+// to read a part of an object without downloading it entirely.
+type RangeReadableFolder interface {
+	ReadObjectRange(ctx context.Context, objectRelativePath string, offset, length int64) (io.ReadCloser, error)
+}
+
+// ReadObjectRange reads length bytes of the object starting from offset.
+// If the folder doesn't support ranged reads, the object is read from the beginning and the preceding bytes are skipped.
+func ReadObjectRange(ctx context.Context, folder Folder, objectRelativePath string, offset, length int64) (io.ReadCloser, error) {
+	rrf, ok := folder.(RangeReadableFolder)
+	if ok {
+		return rrf.ReadObjectRange(ctx, objectRelativePath, offset, length)
+	}
+	readCloser, err := folder.ReadObject(ctx, objectRelativePath)
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.CopyN(io.Discard, readCloser, offset)
+	if err != nil {
+		_ = readCloser.Close()
+		return nil, fmt.Errorf("skip %d bytes of %q: %w", offset, objectRelativePath, err)
+	}
+	return ioextensions.ReadCascadeCloser{
+		Reader: io.LimitReader(readCloser, length),
+		Closer: readCloser,
+	}, nil
+}